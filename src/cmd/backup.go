@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainBackup "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/backup"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupOutput  = ""
+	restoreVerify = false
+)
+
+// backupCmd writes an encrypted snapshot of the session and chat databases
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Create an encrypted backup of the session and chat storage",
+	Long: `Snapshot the WhatsApp session store (SQLite or Postgres) and chat storage into one file encrypted with
+--backup-passphrase. Snapshots are consistent and can be taken while the server is running.
+Without --output the backup is written to --backup-dir, keeping the newest --backup-keep backups.`,
+	Args:   cobra.NoArgs,
+	PreRun: initBackup,
+	Run:    createBackup,
+}
+
+// restoreCmd loads a backup created by backupCmd
+var restoreCmd = &cobra.Command{
+	Use:   "restore <file>",
+	Short: "Restore the session and chat storage from an encrypted backup",
+	Long: `Decrypt a backup with --backup-passphrase, verify the checksum and integrity of every snapshot in it and
+replace the configured databases. Stop the server before restoring, the databases must not be in use.
+With --verify the backup is only checked and nothing is restored.`,
+	Args:   cobra.ExactArgs(1),
+	PreRun: initBackup,
+	Run:    restoreBackup,
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+
+	backupCmd.Flags().StringVarP(
+		&backupOutput,
+		"output", "o",
+		backupOutput,
+		`write the backup to this file instead of the backup directory | example: --output=whatsapp.enc`,
+	)
+	restoreCmd.Flags().BoolVarP(
+		&restoreVerify,
+		"verify", "",
+		restoreVerify,
+		`only decrypt and verify the backup, without restoring it --verify <true/false> | example: --verify=true`,
+	)
+}
+
+func createBackup(_ *cobra.Command, _ []string) {
+	ctx := context.Background()
+	defer releaseResources(ctx)
+
+	response, err := backupUsecase.CreateBackup(ctx, domainBackup.CreateBackupRequest{Output: backupOutput})
+	if err != nil {
+		logrus.Fatalf("failed to create backup: %v", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Backup written to %s (%d bytes)\n", response.Path, response.Size)
+}
+
+func restoreBackup(_ *cobra.Command, args []string) {
+	ctx := context.Background()
+	request := domainBackup.RestoreBackupRequest{
+		Path:       args[0],
+		Passphrase: config.AppBackupPassphrase,
+		VerifyOnly: restoreVerify,
+	}
+
+	if restoreVerify {
+		defer releaseResources(ctx)
+	} else {
+		// The databases are replaced underneath this process, so let go of them first
+		releaseResources(ctx)
+	}
+
+	response, err := backupUsecase.RestoreBackup(ctx, request)
+	if err != nil {
+		logrus.Fatalf("failed to restore backup: %v", err)
+	}
+
+	for _, file := range response.Files {
+		fmt.Fprintf(os.Stderr, "  %s (%s, %d bytes) ok\n", file.Role, file.Kind, file.Size)
+	}
+	if !response.Restored {
+		fmt.Fprintf(os.Stderr, "Backup from %s created by %s is valid\n", response.CreatedAt.Format(time.RFC3339), response.AppVersion)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Restored backup from %s created by %s\n", response.CreatedAt.Format(time.RFC3339), response.AppVersion)
+}
+
+// startBackupSchedule creates a backup every config.AppBackupSchedule until ctx is done
+func startBackupSchedule(ctx context.Context) {
+	if config.AppBackupSchedule <= 0 {
+		return
+	}
+	if config.AppBackupPassphrase == "" {
+		logrus.Warn("[BACKUP] --backup-schedule is set but no --backup-passphrase, scheduled backups are disabled")
+		return
+	}
+
+	logrus.Infof("[BACKUP] Creating a backup every %s in %s", config.AppBackupSchedule, config.AppBackupDir)
+	ticker := time.NewTicker(config.AppBackupSchedule)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		// A backup that started finishes, shutdown waits for it
+		if _, err := backupUsecase.CreateBackup(context.WithoutCancel(ctx), domainBackup.CreateBackupRequest{}); err != nil {
+			logrus.Errorf("[BACKUP] Scheduled backup failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	domainChat "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chat"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/whatsapp"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormat       = domainChat.ExportFormatJSON
+	exportIncludeMedia = false
+	exportOutput       = ""
+)
+
+// exportCmd writes the stored history of a chat to a transcript file
+var exportCmd = &cobra.Command{
+	Use:   "export <chat_jid>",
+	Short: "Export a chat transcript from chat storage",
+	Long: `Export every stored message of a chat as JSON, WhatsApp-style TXT or an HTML page that renders offline.
+With --include-media the transcript and the chat's downloaded media are bundled into a zip.`,
+	Args:   cobra.ExactArgs(1),
+	PreRun: initExport,
+	Run:    exportChat,
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVarP(
+		&exportFormat,
+		"format", "f",
+		exportFormat,
+		`transcript format --format <json/txt/html> | example: --format=html`,
+	)
+	exportCmd.Flags().BoolVarP(
+		&exportIncludeMedia,
+		"include-media", "",
+		exportIncludeMedia,
+		`download the chat's media and bundle it with the transcript into a zip --include-media <true/false> | example: --include-media=true`,
+	)
+	exportCmd.Flags().StringVarP(
+		&exportOutput,
+		"output", "o",
+		exportOutput,
+		`output file, "-" writes to stdout, defaults to chat-<number>.<format> in the current directory | example: --output=transcript.html`,
+	)
+}
+
+// initExport connects to WhatsApp only when media has to be downloaded
+func initExport(cmd *cobra.Command, args []string) {
+	if exportIncludeMedia {
+		initApp(cmd, args)
+		return
+	}
+	initChatHistory(cmd, args)
+}
+
+func exportChat(_ *cobra.Command, args []string) {
+	ctx := context.Background()
+	defer releaseResources(ctx)
+
+	if exportIncludeMedia {
+		waitForConnection(30 * time.Second)
+	}
+
+	request := domainChat.ExportChatRequest{
+		ChatJID:      args[0],
+		Format:       exportFormat,
+		IncludeMedia: exportIncludeMedia,
+	}
+
+	if exportOutput == "-" {
+		if _, err := chatUsecase.ExportChat(ctx, request, os.Stdout); err != nil {
+			logrus.Fatalf("failed to export chat: %v", err)
+		}
+		return
+	}
+
+	// The default file name is only known once the chat is found, so write next to it first
+	file, err := os.CreateTemp(".", ".chat-export-*")
+	if err != nil {
+		logrus.Fatalf("failed to create export file: %v", err)
+	}
+
+	response, err := chatUsecase.ExportChat(ctx, request, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		logrus.Fatalf("failed to export chat: %v", err)
+	}
+
+	output := exportOutput
+	if output == "" {
+		output = response.Filename
+	}
+	if err := os.Rename(file.Name(), output); err != nil {
+		os.Remove(file.Name())
+		logrus.Fatalf("failed to write %s: %v", output, err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d messages and %d media files to %s\n", response.MessageCount, response.MediaCount, output)
+}
+
+// waitForConnection gives a logged in client time to connect, media can only be downloaded while connected
+func waitForConnection(timeout time.Duration) {
+	client := whatsapp.GetClient()
+	if client == nil || client.Store.ID == nil {
+		logrus.Warn("Not logged in, media cannot be downloaded for the export")
+		return
+	}
+
+	deadline := time.Now().Add(timeout)
+	for !client.IsConnected() || !client.IsLoggedIn() {
+		if time.Now().After(deadline) {
+			logrus.Warnf("Not connected after %s, media that cannot be downloaded is left out of the export", timeout)
+			return
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	domainChat "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chat"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importChatName  = ""
+	importSelfName  = ""
+	importDateOrder = ""
+	importTimezone  = ""
+)
+
+// importCmd loads a transcript created by WhatsApp's "Export chat" into chat storage
+var importCmd = &cobra.Command{
+	Use:   "import <chat_jid> <file>",
+	Short: "Import a WhatsApp \"Export chat\" file into chat storage",
+	Long: `Import the .txt transcript or .zip archive created by "Export chat" on the phone, so history that was never
+synced to this device becomes available. Senders are mapped to JIDs where possible and messages that are
+already stored, from history sync or an earlier import, are skipped. Attachments in a zip are copied to the media folder.`,
+	Args:   cobra.ExactArgs(2),
+	PreRun: initChatHistory,
+	Run:    importChat,
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringVarP(
+		&importChatName,
+		"chat-name", "",
+		importChatName,
+		`chat name, defaults to the name in the export file name | example: --chat-name="Family"`,
+	)
+	importCmd.Flags().StringVarP(
+		&importSelfName,
+		"self-name", "",
+		importSelfName,
+		`your name as it appears in the transcript, defaults to your push name | example: --self-name="John"`,
+	)
+	importCmd.Flags().StringVarP(
+		&importDateOrder,
+		"date-order", "",
+		importDateOrder,
+		`date order of the transcript --date-order <dmy/mdy/ymd>, detected when empty | example: --date-order=mdy`,
+	)
+	importCmd.Flags().StringVarP(
+		&importTimezone,
+		"timezone", "",
+		importTimezone,
+		`IANA timezone of the exporting phone, defaults to the server timezone | example: --timezone=Asia/Jakarta`,
+	)
+}
+
+func importChat(_ *cobra.Command, args []string) {
+	ctx := context.Background()
+	defer releaseResources(ctx)
+
+	file, err := os.Open(args[1])
+	if err != nil {
+		logrus.Fatalf("failed to open %s: %v", args[1], err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		logrus.Fatalf("failed to read %s: %v", args[1], err)
+	}
+
+	response, err := chatUsecase.ImportChat(ctx, domainChat.ImportChatRequest{
+		ChatJID:   args[0],
+		ChatName:  importChatName,
+		SelfName:  importSelfName,
+		DateOrder: importDateOrder,
+		Timezone:  importTimezone,
+		Filename:  info.Name(),
+	}, file, info.Size())
+	if err != nil {
+		logrus.Fatalf("failed to import chat: %v", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Imported %d messages and %d media files into %s, skipped %d already stored\n",
+		response.MessagesImported, response.MediaImported, response.ChatJID, response.DuplicatesSkipped)
+	if len(response.UnmappedSenders) > 0 {
+		fmt.Fprintf(os.Stderr, "Senders kept as names, not matched to a contact: %s\n", strings.Join(response.UnmappedSenders, ", "))
+	}
+}
@@ -11,8 +11,8 @@ func corsMiddleware(next http.Handler) http.Handler {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, mcp-session-id, mcp-protocol-version, *")
-		w.Header().Set("Access-Control-Expose-Headers", "mcp-session-id, mcp-protocol-version")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, mcp-session-id, mcp-protocol-version, X-Request-ID, traceparent, tracestate, *")
+		w.Header().Set("Access-Control-Expose-Headers", "mcp-session-id, mcp-protocol-version, X-Request-ID")
 		
 		// Handle preflight requests
 		if r.Method == "OPTIONS" {
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"net/http"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/logging"
+)
+
+// requestIDMiddleware tags each MCP request with an ID, taken from the X-Request-ID header when present.
+// Tool handlers receive the request context, so their usecase logs carry the same ID.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := logging.RequestIDFrom(r.Header.Get(logging.RequestIDHeader))
+		w.Header().Set(logging.RequestIDHeader, requestID)
+
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), requestID)))
+	})
+}
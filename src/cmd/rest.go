@@ -6,27 +6,31 @@ import (
 	"strings"
 
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/logging"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/ui/rest"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/ui/rest/helpers"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/ui/rest/middleware"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/ui/websocket"
 	"github.com/dustin/go-humanize"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/fiber/v2/middleware/basicauth"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/filesystem"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/template/html/v2"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 // rootCmd represents the base command when called without any subcommands
 var restCmd = &cobra.Command{
-	Use:   "rest",
-	Short: "Send whatsapp API over http",
-	Long:  `This application is from clone https://github.com/aldinokemal/go-whatsapp-web-multidevice`,
-	Run:   restServer,
+	Use:    "rest",
+	Short:  "Send whatsapp API over http",
+	Long:   `This application is from clone https://github.com/aldinokemal/go-whatsapp-web-multidevice`,
+	PreRun: initApp,
+	Run:    restServer,
 }
 
 func init() {
@@ -38,12 +42,18 @@ func restServer(_ *cobra.Command, _ []string) {
 		return token != nil
 	})
 	app := fiber.New(fiber.Config{
-		Views:     engine,
-		BodyLimit: int(config.WhatsappSettingMaxVideoSize),
-		Network:   "tcp",
+		Views:   engine,
+		Network: "tcp",
+
+		// Bodies are read by the handlers as a stream, multipart files end up in temporary files
+		// instead of memory. middleware.BodyLimit caps their size.
+		BodyLimit:                    int(config.WhatsappSettingMaxVideoSize),
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+
+		DisableStartupMessage: logging.IsJSON(),
 	})
 
-	app.Static(config.AppBasePath+"/statics", "./statics")
 	app.Use(config.AppBasePath+"/components", filesystem.New(filesystem.Config{
 		Root:       http.FS(EmbedViews),
 		PathPrefix: "views/components",
@@ -55,14 +65,21 @@ func restServer(_ *cobra.Command, _ []string) {
 		Browse:     true,
 	}))
 
-	app.Use(middleware.Recovery())
-	app.Use(middleware.BasicAuth())
+	app.Use(middleware.RequestID())
+	app.Use(middleware.Tracing())
 	if config.AppDebug {
-		app.Use(logger.New())
+		if logging.IsJSON() {
+			app.Use(middleware.AccessLog())
+		} else {
+			app.Use(logger.New())
+		}
 	}
+	app.Use(middleware.Recovery())
+	app.Use(middleware.BasicAuth())
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept",
+		AllowOrigins:  "*",
+		AllowHeaders:  "Origin, Content-Type, Accept, X-Request-ID, traceparent, tracestate, Idempotency-Key",
+		ExposeHeaders: "X-Request-ID",
 	}))
 
 	if len(config.AppBasicAuthCredential) > 0 {
@@ -77,8 +94,15 @@ func restServer(_ *cobra.Command, _ []string) {
 
 		app.Use(basicauth.New(basicauth.Config{
 			Users: account,
+			Next:  middleware.SignedURL,
 		}))
 	}
+	// After basic auth, bodies of unauthenticated requests are never spooled to disk
+	app.Use(middleware.BodyLimit(config.WhatsappSettingMaxVideoSize))
+
+	// Only the login QR codes are served as files, behind basic auth. Downloaded media is served by
+	// GET /media/:sha256, which also accepts signed URLs, and scratch files of sent media not at all.
+	app.Static(config.AppBasePath+"/"+config.PathQrCode, "./"+config.PathQrCode)
 
 	// Create base path group or use app directly
 	var apiGroup fiber.Router = app
@@ -94,6 +118,12 @@ func restServer(_ *cobra.Command, _ []string) {
 	rest.InitRestMessage(apiGroup, messageUsecase)
 	rest.InitRestGroup(apiGroup, groupUsecase)
 	rest.InitRestNewsletter(apiGroup, newsletterUsecase)
+	rest.InitRestCall(apiGroup, callUsecase)
+	rest.InitRestBackup(apiGroup, backupUsecase)
+	rest.InitRestRetention(apiGroup, retentionUsecase)
+	rest.InitRestMedia(apiGroup, mediaUsecase)
+
+	apiGroup.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
 
 	apiGroup.Get("/", func(c *fiber.Ctx) error {
 		return c.Render("views/index", fiber.Map{
@@ -111,10 +141,11 @@ func restServer(_ *cobra.Command, _ []string) {
 
 	// Set auto reconnect to whatsapp server after booting
 	go helpers.SetAutoConnectAfterBooting(appUsecase)
-	// Set auto reconnect checking
-	go helpers.SetAutoReconnectChecking(whatsappCli)
+	goBackground(startBackupSchedule)
+	goBackground(startRetentionSchedule)
 
-	if err := app.Listen(":" + config.AppPort); err != nil {
-		logrus.Fatalln("Failed to start: ", err.Error())
-	}
+	serveUntilShutdown(
+		func() error { return app.Listen(":" + config.AppPort) },
+		app.ShutdownWithContext,
+	)
 }
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	"github.com/sirupsen/logrus"
+)
+
+// startRetentionSchedule applies the retention policies on start and every
+// config.ChatStorageRetentionInterval until ctx is done
+func startRetentionSchedule(ctx context.Context) {
+	if config.ChatStorageRetentionInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(config.ChatStorageRetentionInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := retentionUsecase.Purge(context.WithoutCancel(ctx)); err != nil {
+			logrus.Errorf("[RETENTION] Retention purge failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
 	"embed"
+	"encoding/hex"
 	"fmt"
 	"go.mau.fi/whatsmeow/store/sqlstore"
 	"os"
@@ -12,15 +14,26 @@ import (
 
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
 	domainApp "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/app"
+	domainBackup "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/backup"
+	domainCall "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/call"
 	domainChat "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chat"
 	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
 	domainGroup "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/group"
+	domainMedia "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/media"
+	domainMediaStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/mediastorage"
 	domainMessage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/message"
 	domainNewsletter "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/newsletter"
+	domainRetention "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/retention"
 	domainSend "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/send"
 	domainUser "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/user"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/mediastorage"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/whatsapp"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/autodownload"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/fieldcrypt"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/logging"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/s3"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/tracing"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/usecase"
 	_ "github.com/lib/pq"
@@ -28,16 +41,12 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"go.mau.fi/whatsmeow"
 )
 
 var (
 	EmbedIndex embed.FS
 	EmbedViews embed.FS
 
-	// Whatsapp
-	whatsappCli *whatsmeow.Client
-
 	// Chat Storage
 	chatStorageDB   *sql.DB
 	chatStorageRepo domainChatStorage.IChatStorageRepository
@@ -50,6 +59,10 @@ var (
 	messageUsecase    domainMessage.IMessageUsecase
 	groupUsecase      domainGroup.IGroupUsecase
 	newsletterUsecase domainNewsletter.INewsletterUsecase
+	callUsecase       domainCall.ICallUsecase
+	backupUsecase     domainBackup.IBackupUsecase
+	retentionUsecase  domainRetention.IRetentionUsecase
+	mediaUsecase      domainMedia.IMediaUsecase
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -70,13 +83,12 @@ func init() {
 	// Initialize flags first, before any subcommands are added
 	initFlags()
 
-	// Then initialize other components
-	cobra.OnInitialize(initEnvConfig, initApp)
+	// Then initialize other components, each command opens what it needs in its PreRun
+	cobra.OnInitialize(initEnvConfig, initLogging)
 }
 
 // initEnvConfig loads configuration from environment variables
 func initEnvConfig() {
-	fmt.Println(viper.AllSettings())
 	// Application settings
 	if envPort := viper.GetString("app_port"); envPort != "" {
 		config.AppPort = envPort
@@ -94,6 +106,42 @@ func initEnvConfig() {
 	if envBasePath := viper.GetString("app_base_path"); envBasePath != "" {
 		config.AppBasePath = envBasePath
 	}
+	if envPublicURL := viper.GetString("app_public_url"); envPublicURL != "" {
+		config.AppPublicURL = envPublicURL
+	}
+	if envShutdownTimeout := viper.GetDuration("app_shutdown_timeout"); envShutdownTimeout > 0 {
+		config.AppShutdownTimeout = envShutdownTimeout
+	}
+	if envLogFormat := viper.GetString("app_log_format"); envLogFormat != "" {
+		config.AppLogFormat = envLogFormat
+	}
+	if envLogRedact := viper.GetString("app_log_redact"); envLogRedact != "" {
+		config.AppLogRedact = strings.Split(envLogRedact, ",")
+	}
+	if envTracingEndpoint := viper.GetString("app_tracing_endpoint"); envTracingEndpoint != "" {
+		config.AppTracingEndpoint = envTracingEndpoint
+	}
+	if envIdempotencyTTL := viper.GetDuration("app_idempotency_ttl"); envIdempotencyTTL > 0 {
+		config.AppIdempotencyTTL = envIdempotencyTTL
+	}
+	if envIdempotencyLease := viper.GetDuration("app_idempotency_lease"); envIdempotencyLease > 0 {
+		config.AppIdempotencyLease = envIdempotencyLease
+	}
+	if envBackupPassphrase := viper.GetString("app_backup_passphrase"); envBackupPassphrase != "" {
+		config.AppBackupPassphrase = envBackupPassphrase
+	}
+	if envBackupDir := viper.GetString("app_backup_dir"); envBackupDir != "" {
+		config.AppBackupDir = envBackupDir
+	}
+	if envBackupSchedule := viper.GetDuration("app_backup_schedule"); envBackupSchedule > 0 {
+		config.AppBackupSchedule = envBackupSchedule
+	}
+	if envBackupKeep := viper.GetInt("app_backup_keep"); envBackupKeep > 0 {
+		config.AppBackupKeep = envBackupKeep
+	}
+	if envBackupDownloadUsers := viper.GetString("app_backup_download_users"); envBackupDownloadUsers != "" {
+		config.AppBackupDownloadUsers = strings.Split(envBackupDownloadUsers, ",")
+	}
 
 	// Database settings
 	if envDBURI := viper.GetString("db_uri"); envDBURI != "" {
@@ -103,6 +151,61 @@ func initEnvConfig() {
 		config.DBKeysURI = envDBKEYSURI
 	}
 
+	// Media storage settings
+	if envMediaStorage := viper.GetString("media_storage_driver"); envMediaStorage != "" {
+		config.MediaStorageDriver = envMediaStorage
+	}
+	if envMediaURLExpiry := viper.GetDuration("media_storage_url_expiry"); envMediaURLExpiry > 0 {
+		config.MediaStorageURLExpiry = envMediaURLExpiry
+	}
+	if envMediaURLSecret := viper.GetString("media_storage_url_secret"); envMediaURLSecret != "" {
+		config.MediaStorageURLSecret = envMediaURLSecret
+	}
+	if envS3Endpoint := viper.GetString("media_storage_s3_endpoint"); envS3Endpoint != "" {
+		config.MediaStorageS3Endpoint = envS3Endpoint
+	}
+	if envS3Region := viper.GetString("media_storage_s3_region"); envS3Region != "" {
+		config.MediaStorageS3Region = envS3Region
+	}
+	if envS3Bucket := viper.GetString("media_storage_s3_bucket"); envS3Bucket != "" {
+		config.MediaStorageS3Bucket = envS3Bucket
+	}
+	if envS3AccessKey := viper.GetString("media_storage_s3_access_key"); envS3AccessKey != "" {
+		config.MediaStorageS3AccessKey = envS3AccessKey
+	}
+	if envS3SecretKey := viper.GetString("media_storage_s3_secret_key"); envS3SecretKey != "" {
+		config.MediaStorageS3SecretKey = envS3SecretKey
+	}
+	if envS3Prefix := viper.GetString("media_storage_s3_prefix"); envS3Prefix != "" {
+		config.MediaStorageS3Prefix = envS3Prefix
+	}
+	if viper.IsSet("media_storage_s3_path_style") {
+		config.MediaStorageS3PathStyle = viper.GetBool("media_storage_s3_path_style")
+	}
+	if envS3Timeout := viper.GetDuration("media_storage_s3_timeout"); envS3Timeout > 0 {
+		config.MediaStorageS3Timeout = envS3Timeout
+	}
+
+	// Chat storage settings
+	if envEncryptionKey := viper.GetString("chat_storage_encryption_key"); envEncryptionKey != "" {
+		config.ChatStorageEncryptionKey = envEncryptionKey
+	}
+	if envEncryptionKeyFile := viper.GetString("chat_storage_encryption_key_file"); envEncryptionKeyFile != "" {
+		config.ChatStorageEncryptionKeyFile = envEncryptionKeyFile
+	}
+	if envRetentionDays := viper.GetInt("chat_storage_retention_days"); envRetentionDays > 0 {
+		config.ChatStorageRetentionDays = envRetentionDays
+	}
+	if envRetentionMessages := viper.GetInt("chat_storage_retention_messages"); envRetentionMessages > 0 {
+		config.ChatStorageRetentionMessages = envRetentionMessages
+	}
+	if envRetentionInterval := viper.GetDuration("chat_storage_retention_interval"); envRetentionInterval > 0 {
+		config.ChatStorageRetentionInterval = envRetentionInterval
+	}
+	if envImportMaxSize := viper.GetInt64("chat_storage_import_max_size"); envImportMaxSize > 0 {
+		config.ChatStorageImportMaxSize = envImportMaxSize
+	}
+
 	// WhatsApp settings
 	if envAutoReply := viper.GetString("whatsapp_auto_reply"); envAutoReply != "" {
 		config.WhatsappAutoReplyMessage = envAutoReply
@@ -110,6 +213,15 @@ func initEnvConfig() {
 	if viper.IsSet("whatsapp_auto_mark_read") {
 		config.WhatsappAutoMarkRead = viper.GetBool("whatsapp_auto_mark_read")
 	}
+	if viper.IsSet("whatsapp_auto_reject_call") {
+		config.WhatsappAutoRejectCall = viper.GetBool("whatsapp_auto_reject_call")
+	}
+	if envRejectCallMessage := viper.GetString("whatsapp_auto_reject_call_message"); envRejectCallMessage != "" {
+		config.WhatsappAutoRejectCallMessage = envRejectCallMessage
+	}
+	if viper.IsSet("whatsapp_exit_on_stream_replaced") {
+		config.WhatsappExitOnStreamReplaced = viper.GetBool("whatsapp_exit_on_stream_replaced")
+	}
 	if envWebhook := viper.GetString("whatsapp_webhook"); envWebhook != "" {
 		webhook := strings.Split(envWebhook, ",")
 		config.WhatsappWebhook = webhook
@@ -120,6 +232,27 @@ func initEnvConfig() {
 	if viper.IsSet("whatsapp_account_validation") {
 		config.WhatsappAccountValidation = viper.GetBool("whatsapp_account_validation")
 	}
+	if envAutoDownloadMedia := viper.GetString("whatsapp_auto_download_media"); envAutoDownloadMedia != "" {
+		config.WhatsappAutoDownloadMedia = strings.Split(envAutoDownloadMedia, ",")
+	}
+	if envAutoDownloadMaxSize := viper.GetInt64("whatsapp_auto_download_max_size"); envAutoDownloadMaxSize > 0 {
+		config.WhatsappAutoDownloadMaxSize = envAutoDownloadMaxSize
+	}
+	if envAutoDownloadChatType := viper.GetString("whatsapp_auto_download_chat_type"); envAutoDownloadChatType != "" {
+		config.WhatsappAutoDownloadChatType = envAutoDownloadChatType
+	}
+	if envAutoDownloadAllow := viper.GetString("whatsapp_auto_download_allow"); envAutoDownloadAllow != "" {
+		config.WhatsappAutoDownloadAllow = strings.Split(envAutoDownloadAllow, ",")
+	}
+	if envAutoDownloadDeny := viper.GetString("whatsapp_auto_download_deny"); envAutoDownloadDeny != "" {
+		config.WhatsappAutoDownloadDeny = strings.Split(envAutoDownloadDeny, ",")
+	}
+	if envAutoDownloadWorkers := viper.GetInt("whatsapp_auto_download_workers"); envAutoDownloadWorkers > 0 {
+		config.WhatsappAutoDownloadWorkers = envAutoDownloadWorkers
+	}
+	if viper.IsSet("whatsapp_media_send_concurrency") {
+		config.WhatsappMediaSendConcurrency = viper.GetInt("whatsapp_media_send_concurrency")
+	}
 }
 
 func initFlags() {
@@ -155,6 +288,78 @@ func initFlags() {
 		config.AppBasePath,
 		`base path for subpath deployment --base-path <string> | example: --base-path="/gowa"`,
 	)
+	rootCmd.PersistentFlags().StringVarP(
+		&config.AppPublicURL,
+		"public-url", "",
+		config.AppPublicURL,
+		`external URL the app is reachable at, used for media links in webhooks --public-url <url> | example: --public-url="https://wa.example.com"`,
+	)
+	rootCmd.PersistentFlags().DurationVarP(
+		&config.AppShutdownTimeout,
+		"shutdown-timeout", "",
+		config.AppShutdownTimeout,
+		`time allowed to drain requests and webhooks on SIGTERM --shutdown-timeout <duration> | example: --shutdown-timeout=30s`,
+	)
+	rootCmd.PersistentFlags().StringVarP(
+		&config.AppLogFormat,
+		"log-format", "",
+		config.AppLogFormat,
+		`log output format --log-format <text/json> | example: --log-format=json`,
+	)
+	rootCmd.PersistentFlags().StringSliceVarP(
+		&config.AppLogRedact,
+		"log-redact", "",
+		config.AppLogRedact,
+		`mask sensitive data in logs --log-redact <content,phone,media_key/all> | example: --log-redact=all`,
+	)
+	rootCmd.PersistentFlags().StringVarP(
+		&config.AppTracingEndpoint,
+		"tracing-endpoint", "",
+		config.AppTracingEndpoint,
+		`export OpenTelemetry traces to an OTLP/HTTP collector --tracing-endpoint <url> | example: --tracing-endpoint="http://localhost:4318"`,
+	)
+	rootCmd.PersistentFlags().DurationVarP(
+		&config.AppIdempotencyTTL,
+		"idempotency-ttl", "",
+		config.AppIdempotencyTTL,
+		`how long Idempotency-Key responses of send requests are kept --idempotency-ttl <duration> | example: --idempotency-ttl=24h`,
+	)
+	rootCmd.PersistentFlags().DurationVarP(
+		&config.AppIdempotencyLease,
+		"idempotency-lease", "",
+		config.AppIdempotencyLease,
+		`how long an unfinished send holds its Idempotency-Key before a retry may send again --idempotency-lease <duration> | example: --idempotency-lease=10m`,
+	)
+	rootCmd.PersistentFlags().StringVarP(
+		&config.AppBackupPassphrase,
+		"backup-passphrase", "",
+		config.AppBackupPassphrase,
+		`passphrase backups are encrypted with, prefer the APP_BACKUP_PASSPHRASE env --backup-passphrase <string> | example: --backup-passphrase="long random phrase"`,
+	)
+	rootCmd.PersistentFlags().StringVarP(
+		&config.AppBackupDir,
+		"backup-dir", "",
+		config.AppBackupDir,
+		`directory backups are written to --backup-dir <string> | example: --backup-dir="/var/backups/whatsapp"`,
+	)
+	rootCmd.PersistentFlags().DurationVarP(
+		&config.AppBackupSchedule,
+		"backup-schedule", "",
+		config.AppBackupSchedule,
+		`create a backup at this interval while serving, disabled when 0 --backup-schedule <duration> | example: --backup-schedule=24h`,
+	)
+	rootCmd.PersistentFlags().IntVarP(
+		&config.AppBackupKeep,
+		"backup-keep", "",
+		config.AppBackupKeep,
+		`number of backups kept in the backup directory --backup-keep <int> | example: --backup-keep=7`,
+	)
+	rootCmd.PersistentFlags().StringSliceVarP(
+		&config.AppBackupDownloadUsers,
+		"backup-download-users", "",
+		config.AppBackupDownloadUsers,
+		`basic auth users allowed to download backups over REST, nobody when empty --backup-download-users <user,...> | example: --backup-download-users=admin`,
+	)
 
 	// Database flags
 	rootCmd.PersistentFlags().StringVarP(
@@ -170,6 +375,112 @@ func initFlags() {
 		`the database uri to store the keys database uri (by default, we'll use the same database uri). database uri --db-keys-uri <string> | example: --db-keys-uri="file::memory:?cache=shared&_foreign_keys=on"`,
 	)
 
+	// Media storage flags
+	rootCmd.PersistentFlags().StringVarP(
+		&config.MediaStorageDriver,
+		"media-storage", "",
+		config.MediaStorageDriver,
+		`where downloaded media is kept: local or s3 --media-storage <string> | example: --media-storage=s3`,
+	)
+	rootCmd.PersistentFlags().DurationVarP(
+		&config.MediaStorageURLExpiry,
+		"media-url-expiry", "",
+		config.MediaStorageURLExpiry,
+		`how long presigned and signed media URLs stay valid --media-url-expiry <duration> | example: --media-url-expiry=15m`,
+	)
+	rootCmd.PersistentFlags().StringVarP(
+		&config.MediaStorageURLSecret,
+		"media-url-secret", "",
+		config.MediaStorageURLSecret,
+		`key signing /media URLs so they survive restarts, prefer the MEDIA_STORAGE_URL_SECRET env --media-url-secret <string> | example: --media-url-secret="long random string"`,
+	)
+	rootCmd.PersistentFlags().StringVarP(
+		&config.MediaStorageS3Endpoint,
+		"s3-endpoint", "",
+		config.MediaStorageS3Endpoint,
+		`S3-compatible endpoint, AWS S3 when empty --s3-endpoint <url> | example: --s3-endpoint="http://localhost:9000"`,
+	)
+	rootCmd.PersistentFlags().StringVarP(
+		&config.MediaStorageS3Region,
+		"s3-region", "",
+		config.MediaStorageS3Region,
+		`region of the S3 bucket --s3-region <string> | example: --s3-region=eu-west-1`,
+	)
+	rootCmd.PersistentFlags().StringVarP(
+		&config.MediaStorageS3Bucket,
+		"s3-bucket", "",
+		config.MediaStorageS3Bucket,
+		`bucket media is stored in --s3-bucket <string> | example: --s3-bucket=whatsapp-media`,
+	)
+	rootCmd.PersistentFlags().StringVarP(
+		&config.MediaStorageS3AccessKey,
+		"s3-access-key", "",
+		config.MediaStorageS3AccessKey,
+		`access key of the S3 bucket --s3-access-key <string> | example: --s3-access-key=minioadmin`,
+	)
+	rootCmd.PersistentFlags().StringVarP(
+		&config.MediaStorageS3SecretKey,
+		"s3-secret-key", "",
+		config.MediaStorageS3SecretKey,
+		`secret key of the S3 bucket, prefer the MEDIA_STORAGE_S3_SECRET_KEY env --s3-secret-key <string> | example: --s3-secret-key=minioadmin`,
+	)
+	rootCmd.PersistentFlags().StringVarP(
+		&config.MediaStorageS3Prefix,
+		"s3-prefix", "",
+		config.MediaStorageS3Prefix,
+		`key prefix of media objects in the bucket --s3-prefix <string> | example: --s3-prefix=production`,
+	)
+	rootCmd.PersistentFlags().BoolVarP(
+		&config.MediaStorageS3PathStyle,
+		"s3-path-style", "",
+		config.MediaStorageS3PathStyle,
+		`address the bucket in the URL path, needed by MinIO --s3-path-style <true/false> | example: --s3-path-style=true`,
+	)
+	rootCmd.PersistentFlags().DurationVarP(
+		&config.MediaStorageS3Timeout,
+		"s3-timeout", "",
+		config.MediaStorageS3Timeout,
+		`deadline of one S3 request, transferring the media included --s3-timeout <duration> | example: --s3-timeout=2m`,
+	)
+
+	// Chat storage flags
+	rootCmd.PersistentFlags().StringVarP(
+		&config.ChatStorageEncryptionKey,
+		"chat-storage-encryption-key", "",
+		config.ChatStorageEncryptionKey,
+		`encrypt stored messages with this base64 32 byte key, optionally id:key, first of a comma separated list is active --chat-storage-encryption-key <string> | example: --chat-storage-encryption-key="2026:base64key"`,
+	)
+	rootCmd.PersistentFlags().StringVarP(
+		&config.ChatStorageEncryptionKeyFile,
+		"chat-storage-encryption-key-file", "",
+		config.ChatStorageEncryptionKeyFile,
+		`read chat storage encryption keys from a file, one id:key per line --chat-storage-encryption-key-file <string> | example: --chat-storage-encryption-key-file=/run/secrets/chatstorage.keys`,
+	)
+	rootCmd.PersistentFlags().IntVarP(
+		&config.ChatStorageRetentionDays,
+		"retention-days", "",
+		config.ChatStorageRetentionDays,
+		`delete messages and their media older than this many days, disabled when 0 --retention-days <int> | example: --retention-days=180`,
+	)
+	rootCmd.PersistentFlags().IntVarP(
+		&config.ChatStorageRetentionMessages,
+		"retention-messages", "",
+		config.ChatStorageRetentionMessages,
+		`keep only the newest messages of each chat, disabled when 0 --retention-messages <int> | example: --retention-messages=10000`,
+	)
+	rootCmd.PersistentFlags().DurationVarP(
+		&config.ChatStorageRetentionInterval,
+		"retention-interval", "",
+		config.ChatStorageRetentionInterval,
+		`how often retention policies are applied while serving --retention-interval <duration> | example: --retention-interval=6h`,
+	)
+	rootCmd.PersistentFlags().Int64VarP(
+		&config.ChatStorageImportMaxSize,
+		"import-max-size", "",
+		config.ChatStorageImportMaxSize,
+		`largest uncompressed size in bytes of an imported chat export --import-max-size <int> | example: --import-max-size=2000000000`,
+	)
+
 	// WhatsApp flags
 	rootCmd.PersistentFlags().StringVarP(
 		&config.WhatsappAutoReplyMessage,
@@ -183,6 +494,24 @@ func initFlags() {
 		config.WhatsappAutoMarkRead,
 		`auto mark incoming messages as read --auto-mark-read <true/false> | example: --auto-mark-read=true`,
 	)
+	rootCmd.PersistentFlags().BoolVarP(
+		&config.WhatsappAutoRejectCall,
+		"auto-reject-call", "",
+		config.WhatsappAutoRejectCall,
+		`auto reject incoming calls --auto-reject-call <true/false> | example: --auto-reject-call=true`,
+	)
+	rootCmd.PersistentFlags().StringVarP(
+		&config.WhatsappAutoRejectCallMessage,
+		"auto-reject-call-message", "",
+		config.WhatsappAutoRejectCallMessage,
+		`text sent to the caller after auto rejecting a call --auto-reject-call-message <string> | example: --auto-reject-call-message="Sorry, we can't take calls. Please send a message"`,
+	)
+	rootCmd.PersistentFlags().BoolVarP(
+		&config.WhatsappExitOnStreamReplaced,
+		"exit-on-stream-replaced", "",
+		config.WhatsappExitOnStreamReplaced,
+		`exit the process when another client takes over the session --exit-on-stream-replaced <true/false> | example: --exit-on-stream-replaced=true`,
+	)
 	rootCmd.PersistentFlags().StringSliceVarP(
 		&config.WhatsappWebhook,
 		"webhook", "w",
@@ -201,6 +530,48 @@ func initFlags() {
 		config.WhatsappAccountValidation,
 		`enable or disable account validation --account-validation <true/false> | example: --account-validation=true`,
 	)
+	rootCmd.PersistentFlags().StringSliceVarP(
+		&config.WhatsappAutoDownloadMedia,
+		"auto-download-media", "",
+		config.WhatsappAutoDownloadMedia,
+		`media types downloaded when they arrive: image, video, audio, document, sticker, all or none --auto-download-media <string> | example: --auto-download-media="image,audio"`,
+	)
+	rootCmd.PersistentFlags().Int64VarP(
+		&config.WhatsappAutoDownloadMaxSize,
+		"auto-download-max-size", "",
+		config.WhatsappAutoDownloadMaxSize,
+		`largest media in bytes downloaded when it arrives, 0 for no limit --auto-download-max-size <int> | example: --auto-download-max-size=10000000`,
+	)
+	rootCmd.PersistentFlags().StringVarP(
+		&config.WhatsappAutoDownloadChatType,
+		"auto-download-chat-type", "",
+		config.WhatsappAutoDownloadChatType,
+		`chats media is downloaded from when it arrives: all, direct or group --auto-download-chat-type <string> | example: --auto-download-chat-type=direct`,
+	)
+	rootCmd.PersistentFlags().StringSliceVarP(
+		&config.WhatsappAutoDownloadAllow,
+		"auto-download-allow", "",
+		config.WhatsappAutoDownloadAllow,
+		`only download media from these chats when it arrives --auto-download-allow <string> | example: --auto-download-allow="6281234567890,120363025246125486@g.us"`,
+	)
+	rootCmd.PersistentFlags().StringSliceVarP(
+		&config.WhatsappAutoDownloadDeny,
+		"auto-download-deny", "",
+		config.WhatsappAutoDownloadDeny,
+		`never download media from these chats when it arrives --auto-download-deny <string> | example: --auto-download-deny="6281234567890"`,
+	)
+	rootCmd.PersistentFlags().IntVarP(
+		&config.WhatsappAutoDownloadWorkers,
+		"auto-download-workers", "",
+		config.WhatsappAutoDownloadWorkers,
+		`number of media downloads running at the same time --auto-download-workers <int> | example: --auto-download-workers=4`,
+	)
+	rootCmd.PersistentFlags().IntVarP(
+		&config.WhatsappMediaSendConcurrency,
+		"media-send-concurrency", "",
+		config.WhatsappMediaSendConcurrency,
+		`number of media sends processed at the same time, 0 for no limit --media-send-concurrency <int> | example: --media-send-concurrency=8`,
+	)
 }
 
 func initChatStorage() (*sql.DB, error) {
@@ -227,28 +598,121 @@ func initChatStorage() (*sql.DB, error) {
 	return db, nil
 }
 
-func initApp() {
+// chatStorageKeyring returns the configured chat storage encryption keys, nil when none are set
+func chatStorageKeyring() (*fieldcrypt.Keyring, error) {
+	spec := config.ChatStorageEncryptionKey
+	if config.ChatStorageEncryptionKeyFile != "" {
+		content, err := os.ReadFile(config.ChatStorageEncryptionKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		spec += "\n" + string(content)
+	}
+
+	keys, err := fieldcrypt.ParseKeys(spec)
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+	return fieldcrypt.NewKeyring(keys)
+}
+
+// initMediaStorage returns the configured storage of downloaded media
+func initMediaStorage() (domainMediaStorage.IMediaStorage, error) {
+	switch config.MediaStorageDriver {
+	case "local":
+		return mediastorage.NewLocalStorage(config.PathMedia), nil
+	case "s3":
+		return mediastorage.NewS3Storage(s3.Config{
+			Endpoint:  config.MediaStorageS3Endpoint,
+			Region:    config.MediaStorageS3Region,
+			Bucket:    config.MediaStorageS3Bucket,
+			AccessKey: config.MediaStorageS3AccessKey,
+			SecretKey: config.MediaStorageS3SecretKey,
+			PathStyle: config.MediaStorageS3PathStyle,
+			Timeout:   config.MediaStorageS3Timeout,
+		}, config.MediaStorageS3Prefix)
+	default:
+		return nil, fmt.Errorf("unknown media storage %q, use local or s3", config.MediaStorageDriver)
+	}
+}
+
+// initLogging configures logging and tracing, which every command uses
+func initLogging() {
 	if config.AppDebug {
 		config.WhatsappLogLevel = "DEBUG"
 		logrus.SetLevel(logrus.DebugLevel)
 	}
+	if err := logging.Setup(); err != nil {
+		logrus.Fatalf("failed to configure logging: %v", err)
+	}
+	if err := tracing.Setup(context.Background()); err != nil {
+		logrus.Fatalf("failed to configure tracing: %v", err)
+	}
+}
 
-	//preparing folder if not exist
-	err := utils.CreateFolder(config.PathQrCode, config.PathSendItems, config.PathStorages, config.PathMedia)
+// openChatStorage opens chat storage and brings its schema up to date
+func openChatStorage() {
+	var err error
+	chatStorageDB, err = initChatStorage()
 	if err != nil {
-		logrus.Errorln(err)
+		// Terminate the application if chat storage fails to initialize to avoid nil pointer panics later.
+		logrus.Fatalf("failed to initialize chat storage: %v", err)
+	}
+
+	keyring, err := chatStorageKeyring()
+	if err != nil {
+		logrus.Fatalf("failed to load chat storage encryption key: %v", err)
+	}
+	if keyring != nil {
+		chatStorageRepo = chatstorage.NewEncryptedStorageRepository(chatStorageDB, keyring)
+	} else {
+		chatStorageRepo = chatstorage.NewStorageRepository(chatStorageDB)
 	}
+	if err := chatStorageRepo.InitializeSchema(); err != nil {
+		logrus.Fatalf("failed to initialize chat storage schema: %v", err)
+	}
+}
+
+// initBackup prepares the backup and restore commands, the backup service opens the databases itself
+func initBackup(_ *cobra.Command, _ []string) {
+	backupUsecase = usecase.NewBackupService()
+}
+
+// initChatStorageOnly prepares commands that only work on chat storage
+func initChatStorageOnly(_ *cobra.Command, _ []string) {
+	openChatStorage()
+}
 
+// initChatHistory prepares the import and export commands: chat storage, media storage and the
+// session store for contact names, without connecting to WhatsApp
+func initChatHistory(_ *cobra.Command, _ []string) {
 	ctx := context.Background()
+	openChatStorage()
 
-	chatStorageDB, err = initChatStorage()
+	mediaStorage, err := initMediaStorage()
 	if err != nil {
-		// Terminate the application if chat storage fails to initialize to avoid nil pointer panics later.
-		logrus.Fatalf("failed to initialize chat storage: %v", err)
+		logrus.Fatalf("failed to initialize media storage: %v", err)
+	}
+	if err := whatsapp.LoadDevice(ctx, whatsapp.InitWaDB(ctx, config.DBURI)); err != nil {
+		logrus.Fatalf("failed to load WhatsApp session: %v", err)
 	}
 
-	chatStorageRepo = chatstorage.NewStorageRepository(chatStorageDB)
-	chatStorageRepo.InitializeSchema()
+	whatsapp.SetChatStorageRepository(chatStorageRepo)
+	whatsapp.SetMediaStorage(mediaStorage)
+	chatUsecase = usecase.NewChatService(chatStorageRepo, mediaStorage)
+}
+
+// initApp prepares the servers, which use everything and connect to WhatsApp
+func initApp(_ *cobra.Command, _ []string) {
+	//preparing folder if not exist
+	err := utils.CreateFolder(config.PathQrCode, config.PathSendItems, config.PathStorages, config.PathMedia)
+	if err != nil {
+		logrus.Errorln(err)
+	}
+
+	ctx := context.Background()
+
+	openChatStorage()
 
 	whatsappDB := whatsapp.InitWaDB(ctx, config.DBURI)
 	var keysDB *sqlstore.Container
@@ -256,17 +720,51 @@ func initApp() {
 		keysDB = whatsapp.InitWaDB(ctx, config.DBKeysURI)
 	}
 
+	mediaStorage, err := initMediaStorage()
+	if err != nil {
+		logrus.Fatalf("failed to initialize media storage: %v", err)
+	}
+	if config.MediaStorageURLSecret == "" {
+		logrus.Warn("No media URL secret configured, signed media URLs stop working on restart. Set MEDIA_STORAGE_URL_SECRET to keep them valid")
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			logrus.Fatalf("failed to generate media URL secret: %v", err)
+		}
+		config.MediaStorageURLSecret = hex.EncodeToString(secret)
+	}
+
 	whatsapp.SetChatStorageRepository(chatStorageRepo)
+	whatsapp.SetMediaStorage(mediaStorage)
 		whatsapp.InitWaCLI(ctx, whatsappDB, keysDB, chatStorageRepo)
 
+	autoDownloadPolicy, err := autodownload.NewPolicy(
+		config.WhatsappAutoDownloadMedia,
+		config.WhatsappAutoDownloadMaxSize,
+		config.WhatsappAutoDownloadChatType,
+		config.WhatsappAutoDownloadAllow,
+		config.WhatsappAutoDownloadDeny,
+	)
+	if err != nil {
+		logrus.Fatalf("invalid auto download policy: %v", err)
+	}
+	whatsapp.StartAutoDownload(autoDownloadPolicy, config.WhatsappAutoDownloadWorkers)
+
 	// Usecase
 	appUsecase = usecase.NewAppService(chatStorageRepo)
-	chatUsecase = usecase.NewChatService(chatStorageRepo)
-	sendUsecase = usecase.NewSendService(appUsecase, chatStorageRepo)
+	chatUsecase = usecase.NewChatService(chatStorageRepo, mediaStorage)
+	sendUsecase = usecase.NewIdempotentSendService(usecase.NewSendService(appUsecase, chatStorageRepo), chatStorageRepo)
 	userUsecase = usecase.NewUserService()
-	messageUsecase = usecase.NewMessageService(chatStorageRepo)
-	groupUsecase = usecase.NewGroupService()
+	messageUsecase = usecase.NewMessageService(chatStorageRepo, mediaStorage)
+	groupUsecase = usecase.NewGroupService(chatStorageRepo)
 	newsletterUsecase = usecase.NewNewsletterService()
+	callUsecase = usecase.NewCallService(chatStorageRepo)
+	backupUsecase = usecase.NewBackupService()
+	retentionUsecase = usecase.NewRetentionService(chatStorageRepo, mediaStorage)
+	mediaUsecase = usecase.NewMediaService(chatStorageRepo, mediaStorage)
+
+	// Metrics
+	chatstorage.RegisterMetrics(chatStorageDB, chatStorageRepo)
+	whatsapp.RegisterMetrics()
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// rotateKeyCmd re-encrypts chat storage with a new data key
+var rotateKeyCmd = &cobra.Command{
+	Use:   "rotate-storage-key",
+	Short: "Re-encrypt chat storage with a new data key",
+	Long: `Create a new data key for chat storage encryption and re-encrypt every stored message with it, including
+messages stored in plaintext before --chat-storage-encryption-key was set. Stop the server first.
+To replace the encryption key itself, put the new key first and keep the old one after it: the data keys are
+rewrapped on the next start, after which the old key can be removed.`,
+	Args:   cobra.NoArgs,
+	PreRun: initChatStorageOnly,
+	Run:    rotateStorageKey,
+}
+
+func init() {
+	rootCmd.AddCommand(rotateKeyCmd)
+}
+
+func rotateStorageKey(_ *cobra.Command, _ []string) {
+	ctx := context.Background()
+	defer releaseResources(ctx)
+
+	count, err := chatStorageRepo.RotateEncryptionKey()
+	if err != nil {
+		logrus.Fatalf("failed to rotate chat storage key: %v", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Re-encrypted %d messages with a new data key\n", count)
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/whatsapp"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/tracing"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/usecase"
+	"github.com/sirupsen/logrus"
+)
+
+// Background jobs of a server, such as the backup and retention schedules, run until
+// releaseResources stops them
+var (
+	backgroundCtx, stopBackground = context.WithCancel(context.Background())
+	backgroundJobs                sync.WaitGroup
+)
+
+// goBackground runs job in a new goroutine with a context that is done once the server shuts down
+func goBackground(job func(ctx context.Context)) {
+	backgroundJobs.Add(1)
+	go func() {
+		defer backgroundJobs.Done()
+		job(backgroundCtx)
+	}()
+}
+
+// serveUntilShutdown runs serve until it fails, SIGINT/SIGTERM is received or WhatsApp requests a
+// shutdown, then stops the server and releases the WhatsApp client and databases within
+// config.AppShutdownTimeout
+func serveUntilShutdown(serve func() error, stop func(ctx context.Context) error) {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- serve()
+	}()
+
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	select {
+	case err := <-serveErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logrus.Fatalln("Failed to start: ", err.Error())
+		}
+		return
+	case <-signalCtx.Done():
+		logrus.Infof("[SHUTDOWN] Signal received, shutting down (timeout %s)", config.AppShutdownTimeout)
+	case <-whatsapp.ShutdownRequested():
+		logrus.Infof("[SHUTDOWN] Session replaced, shutting down (timeout %s)", config.AppShutdownTimeout)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.AppShutdownTimeout)
+	defer cancel()
+
+	// Stop accepting requests and wait for in-flight ones, including sends, to finish
+	if err := stop(ctx); err != nil {
+		logrus.Errorf("[SHUTDOWN] Failed to stop server gracefully: %v", err)
+	}
+
+	releaseResources(ctx)
+	logrus.Info("[SHUTDOWN] Shutdown completed")
+}
+
+// releaseResources disconnects WhatsApp, stops and drains everything that still writes to the
+// databases, then closes them
+func releaseResources(ctx context.Context) {
+	// No events arrive once disconnected, so nothing queues new work
+	whatsapp.StopReconnect()
+	whatsapp.Disconnect()
+
+	stopBackground()
+	if err := utils.WaitGroupContext(ctx, &backgroundJobs); err != nil {
+		logrus.Warnf("[SHUTDOWN] Scheduled jobs did not finish: %v", err)
+	}
+	if err := whatsapp.StopAutoDownload(ctx); err != nil {
+		logrus.Warnf("[SHUTDOWN] Queued media downloads were dropped: %v", err)
+	}
+	if err := whatsapp.WaitForWebhooks(ctx); err != nil {
+		logrus.Warnf("[SHUTDOWN] Pending webhook deliveries were dropped: %v", err)
+	}
+	if err := usecase.WaitForSentMessages(ctx); err != nil {
+		logrus.Warnf("[SHUTDOWN] Sent messages were not stored: %v", err)
+	}
+
+	if err := whatsapp.Close(); err != nil {
+		logrus.Errorf("[SHUTDOWN] Failed to close WhatsApp client: %v", err)
+	}
+
+	if chatStorageDB != nil {
+		// Fold the WAL back into the main database file before closing
+		if _, err := chatStorageDB.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+			logrus.Warnf("[SHUTDOWN] Failed to checkpoint chat storage: %v", err)
+		}
+		if err := chatStorageDB.Close(); err != nil {
+			logrus.Errorf("[SHUTDOWN] Failed to close chat storage: %v", err)
+		}
+	}
+
+	if err := tracing.Shutdown(ctx); err != nil {
+		logrus.Warnf("[SHUTDOWN] Failed to flush traces: %v", err)
+	}
+}
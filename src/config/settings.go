@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"go.mau.fi/whatsmeow/proto/waCompanionReg"
 )
 
@@ -12,10 +14,34 @@ var (
 	AppPlatform            = waCompanionReg.DeviceProps_PlatformType(1)
 	AppBasicAuthCredential []string
 	AppBasePath            = ""
+	AppPublicURL           = ""               // External URL of the app, makes links to it in webhooks absolute
+	AppShutdownTimeout     = 30 * time.Second // Deadline for draining requests and webhooks on shutdown
+	AppLogFormat           = "text"           // Log output format: text or json
+	AppLogRedact           []string           // Data masked in logs: content, phone, media_key or all
+	AppTracingEndpoint     = ""               // OTLP/HTTP collector URL, tracing is disabled when empty
+	AppIdempotencyTTL      = 24 * time.Hour   // How long send responses are kept for replay by Idempotency-Key
+	AppIdempotencyLease    = 10 * time.Minute // How long a running send holds its Idempotency-Key before a retry may take it over
+	AppBackupPassphrase    = ""               // Passphrase backups are encrypted with, backups are disabled when empty
+	AppBackupDir           = "backups"        // Directory backups are written to and rotated in
+	AppBackupSchedule      time.Duration      // Interval of automatic backups while serving, disabled when zero
+	AppBackupKeep          = 7                // Number of backups kept in AppBackupDir, older ones are deleted
+	AppBackupDownloadUsers []string           // Basic auth users allowed to download backups over REST, nobody when empty
 
 	McpPort = "8080"
 	McpHost = "localhost"
 
+	MediaStorageDriver      = "local"   // Where media is kept: local (PathMedia) or s3
+	MediaStorageURLExpiry   = time.Hour // Validity of presigned and signed media URLs
+	MediaStorageURLSecret   = ""        // Key signing /media URLs, a random key per process when empty
+	MediaStorageS3Endpoint  = ""        // S3-compatible endpoint, AWS S3 of MediaStorageS3Region when empty
+	MediaStorageS3Region    = "us-east-1"
+	MediaStorageS3Bucket    = ""
+	MediaStorageS3AccessKey = ""
+	MediaStorageS3SecretKey = ""
+	MediaStorageS3Prefix    = ""              // Key prefix of media objects in the bucket
+	MediaStorageS3PathStyle = false           // Address the bucket in the path instead of the host name, needed by MinIO
+	MediaStorageS3Timeout   = 5 * time.Minute // Deadline of one S3 request, reading the response body included
+
 	PathQrCode    = "statics/qrcode"
 	PathSendItems = "statics/senditems"
 	PathMedia     = "statics/media"
@@ -26,6 +52,9 @@ var (
 
 	WhatsappAutoReplyMessage       string
 	WhatsappAutoMarkRead           = false // Auto-mark incoming messages as read
+	WhatsappAutoRejectCall         = false // Auto-reject incoming calls
+	WhatsappAutoRejectCallMessage  string  // Text sent to the caller after an auto-rejected call
+	WhatsappExitOnStreamReplaced   = false // Exit the process when another client replaces the session
 	WhatsappWebhook                []string
 	WhatsappWebhookSecret                = "secret"
 	WhatsappLogLevel                     = "ERROR"
@@ -36,8 +65,22 @@ var (
 	WhatsappTypeUser                     = "@s.whatsapp.net"
 	WhatsappTypeGroup                    = "@g.us"
 	WhatsappAccountValidation            = true
+	WhatsappMediaSendConcurrency         = 4 // Media sends prepared and uploaded at the same time, further sends wait, unlimited when zero
+
+	WhatsappAutoDownloadMedia             = []string{"image"} // Media types downloaded on arrival: image, video, audio, document, sticker, all or none
+	WhatsappAutoDownloadMaxSize  int64                        // Largest media downloaded on arrival in bytes, only WhatsappSettingMaxDownloadSize applies when zero
+	WhatsappAutoDownloadChatType = "all"                      // Chats media is downloaded from on arrival: all, direct or group
+	WhatsappAutoDownloadAllow    []string                     // Only download media on arrival from these chats, phone numbers or JIDs
+	WhatsappAutoDownloadDeny     []string                     // Never download media on arrival from these chats, phone numbers or JIDs
+	WhatsappAutoDownloadWorkers  = 2                          // Concurrent media downloads on arrival
 
 	ChatStorageURI               = "file:storages/chatstorage.db"
 	ChatStorageEnableForeignKeys = true
 	ChatStorageEnableWAL         = true
+	ChatStorageEncryptionKey     = ""                // Key encryption keys as id:base64, message content is stored in plaintext when empty
+	ChatStorageEncryptionKeyFile = ""                // File with one id:base64 key per line, used together with ChatStorageEncryptionKey
+	ChatStorageRetentionDays     = 0                 // Global retention, messages older than this are purged, disabled when zero
+	ChatStorageRetentionMessages = 0                 // Global retention, only the newest messages of each chat are kept, disabled when zero
+	ChatStorageRetentionInterval = time.Hour         // Interval of the retention job while serving
+	ChatStorageImportMaxSize     = int64(1000000000) // 1GB, largest uncompressed size of an imported chat export, all files together
 )
@@ -10,6 +10,7 @@ type IAppUsecase interface {
 	LoginWithCode(ctx context.Context, phoneNumber string) (loginCode string, err error)
 	Logout(ctx context.Context) (err error)
 	Reconnect(ctx context.Context) (err error)
+	Takeover(ctx context.Context) (err error)
 	FirstDevice(ctx context.Context) (response DevicesResponse, err error)
 	FetchDevices(ctx context.Context) (response []DevicesResponse, err error)
 }
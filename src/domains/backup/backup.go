@@ -0,0 +1,42 @@
+package backup
+
+import (
+	"context"
+	"time"
+)
+
+type IBackupUsecase interface {
+	CreateBackup(ctx context.Context, request CreateBackupRequest) (response BackupInfo, err error)
+	ListBackups(ctx context.Context) (response []BackupInfo, err error)
+	RestoreBackup(ctx context.Context, request RestoreBackupRequest) (response RestoreBackupResponse, err error)
+}
+
+type CreateBackupRequest struct {
+	Output string `json:"-"` // File to write instead of a rotated file in the backup directory, CLI only
+}
+
+type BackupInfo struct {
+	Filename  string    `json:"filename"`
+	Path      string    `json:"-"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RestoreBackupRequest struct {
+	Path       string `json:"path"`
+	Passphrase string `json:"passphrase"`
+	VerifyOnly bool   `json:"verify_only"` // Decrypt and check the backup without touching the databases
+}
+
+type RestoreBackupResponse struct {
+	AppVersion string         `json:"app_version"` // Release that created the backup
+	CreatedAt  time.Time      `json:"created_at"`
+	Files      []RestoredFile `json:"files"`
+	Restored   bool           `json:"restored"`
+}
+
+type RestoredFile struct {
+	Role string `json:"role"`
+	Kind string `json:"kind"`
+	Size int64  `json:"size"`
+}
@@ -0,0 +1,34 @@
+package call
+
+// Request and Response structures for call log operations
+
+type ListCallsRequest struct {
+	Limit     int    `json:"limit" query:"limit"`
+	Offset    int    `json:"offset" query:"offset"`
+	Status    string `json:"status" query:"status"`
+	CallerJID string `json:"caller_jid" query:"caller_jid"`
+}
+
+type ListCallsResponse struct {
+	Data       []CallInfo         `json:"data"`
+	Pagination PaginationResponse `json:"pagination"`
+}
+
+type CallInfo struct {
+	ID           string `json:"id"`
+	CallerJID    string `json:"caller_jid"`
+	GroupJID     string `json:"group_jid,omitempty"`
+	IsVideo      bool   `json:"is_video"`
+	IsGroup      bool   `json:"is_group"`
+	Status       string `json:"status"`
+	Reason       string `json:"reason,omitempty"`
+	AutoRejected bool   `json:"auto_rejected"`
+	StartedAt    string `json:"started_at"`
+	EndedAt      string `json:"ended_at,omitempty"`
+}
+
+type PaginationResponse struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Total  int `json:"total"`
+}
@@ -0,0 +1,10 @@
+package call
+
+import (
+	"context"
+)
+
+// ICallUsecase defines the interface for call log operations
+type ICallUsecase interface {
+	ListCalls(ctx context.Context, request ListCallsRequest) (response ListCallsResponse, err error)
+}
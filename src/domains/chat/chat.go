@@ -5,6 +5,7 @@ package chat
 type ListChatsRequest struct {
 	Limit    int    `json:"limit" query:"limit"`
 	Offset   int    `json:"offset" query:"offset"`
+	Cursor   string `json:"cursor" query:"cursor"`
 	Search   string `json:"search" query:"search"`
 	HasMedia bool   `json:"has_media" query:"has_media"`
 }
@@ -18,6 +19,7 @@ type GetChatMessagesRequest struct {
 	ChatJID   string  `json:"chat_jid" uri:"chat_jid"`
 	Limit     int     `json:"limit" query:"limit"`
 	Offset    int     `json:"offset" query:"offset"`
+	Cursor    string  `json:"cursor" query:"cursor"`
 	StartTime *string `json:"start_time" query:"start_time"`
 	EndTime   *string `json:"end_time" query:"end_time"`
 	MediaOnly bool    `json:"media_only" query:"media_only"`
@@ -72,14 +74,17 @@ type MessageInfo struct {
 	Filename   string `json:"filename"`
 	URL        string `json:"url"`
 	FileLength uint64 `json:"file_length"`
+	MediaPath  string `json:"media_path"`
 	CreatedAt  string `json:"created_at"`
 	UpdatedAt  string `json:"updated_at"`
 }
 
 type PaginationResponse struct {
-	Limit  int `json:"limit"`
-	Offset int `json:"offset"`
-	Total  int `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"` // Pass as cursor to fetch the following (older) page
+	PrevCursor string `json:"prev_cursor,omitempty"` // Pass as cursor to fetch the preceding (newer) page
 }
 
 // Archive Chat operations
@@ -117,3 +122,42 @@ type MarkChatAsReadResponse struct {
 	Message string `json:"message"`
 	ChatJID string `json:"chat_jid"`
 }
+
+// Export Chat operations
+const (
+	ExportFormatJSON = "json"
+	ExportFormatTXT  = "txt"
+	ExportFormatHTML = "html"
+)
+
+type ExportChatRequest struct {
+	ChatJID      string `json:"chat_jid" uri:"chat_jid"`
+	Format       string `json:"format" query:"format"`               // json, txt or html
+	IncludeMedia bool   `json:"include_media" query:"include_media"` // Bundle the transcript and downloaded media into a zip
+}
+
+type ExportChatResponse struct {
+	ChatJID      string `json:"chat_jid"`
+	Filename     string `json:"filename"`
+	ContentType  string `json:"content_type"`
+	MessageCount int    `json:"message_count"`
+	MediaCount   int    `json:"media_count"`
+}
+
+// Import Chat operations
+type ImportChatRequest struct {
+	ChatJID   string `json:"chat_jid" uri:"chat_jid"`
+	ChatName  string `json:"chat_name" form:"chat_name"`   // Defaults to the name in the export file name
+	SelfName  string `json:"self_name" form:"self_name"`   // Sender name of your own messages, defaults to your push name
+	DateOrder string `json:"date_order" form:"date_order"` // dmy, mdy or ymd, detected when empty
+	Timezone  string `json:"timezone" form:"timezone"`     // IANA timezone of the exporting phone, defaults to the server timezone
+	Filename  string `json:"filename" form:"-"`
+}
+
+type ImportChatResponse struct {
+	ChatJID           string   `json:"chat_jid"`
+	MessagesImported  int      `json:"messages_imported"`
+	DuplicatesSkipped int      `json:"duplicates_skipped"`
+	MediaImported     int      `json:"media_imported"`
+	UnmappedSenders   []string `json:"unmapped_senders"`
+}
@@ -2,6 +2,7 @@ package chat
 
 import (
 	"context"
+	"io"
 )
 
 // IChatUsecase defines the interface for chat-related operations
@@ -12,4 +13,6 @@ type IChatUsecase interface {
 	ArchiveChat(ctx context.Context, request ArchiveChatRequest) (response ArchiveChatResponse, err error)
 	DeleteChat(ctx context.Context, request DeleteChatRequest) (response DeleteChatResponse, err error)
 	MarkChatAsRead(ctx context.Context, request MarkChatAsReadRequest) (response MarkChatAsReadResponse, err error)
+	ExportChat(ctx context.Context, request ExportChatRequest, w io.Writer) (response ExportChatResponse, err error)
+	ImportChat(ctx context.Context, request ImportChatRequest, file io.ReaderAt, size int64) (response ImportChatResponse, err error)
 }
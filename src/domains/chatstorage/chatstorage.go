@@ -27,6 +27,8 @@ type Message struct {
 	FileSHA256    []byte    `db:"file_sha256"`
 	FileEncSHA256 []byte    `db:"file_enc_sha256"`
 	FileLength    uint64    `db:"file_length"`
+	MediaPath     string    `db:"media_path"` // Media storage key of the downloaded media, empty until it is downloaded
+	Thumbnail     []byte    `db:"thumbnail"`  // JPEG preview embedded in image messages
 	CreatedAt     time.Time `db:"created_at"`
 	UpdatedAt     time.Time `db:"updated_at"`
 }
@@ -53,6 +55,7 @@ type MessageFilter struct {
 	EndTime   *time.Time
 	MediaOnly bool
 	IsFromMe  *bool
+	Cursor    *Cursor // Keyset position, replaces Offset when set
 }
 
 // ChatFilter represents query filters for chats
@@ -61,4 +64,153 @@ type ChatFilter struct {
 	Offset     int
 	SearchName string
 	HasMedia   bool
+	Cursor     *Cursor // Keyset position, replaces Offset when set
+}
+
+// Cursor is a keyset position in a listing ordered newest first by timestamp, then ID.
+// Rows strictly after the position are returned, newest first, or when Previous is set
+// the rows strictly before it, oldest first.
+type Cursor struct {
+	Timestamp time.Time
+	ID        string
+	Previous  bool
+}
+
+// Call represents an incoming WhatsApp call recorded in the call log
+type Call struct {
+	ID           string     `db:"id"`
+	CallerJID    string     `db:"caller_jid"`
+	GroupJID     string     `db:"group_jid"`
+	IsVideo      bool       `db:"is_video"`
+	IsGroup      bool       `db:"is_group"`
+	Status       string     `db:"status"`
+	Reason       string     `db:"reason"`
+	AutoRejected bool       `db:"auto_rejected"`
+	StartedAt    time.Time  `db:"started_at"`
+	EndedAt      *time.Time `db:"ended_at"`
+	CreatedAt    time.Time  `db:"created_at"`
+	UpdatedAt    time.Time  `db:"updated_at"`
+}
+
+// Call log statuses
+const (
+	CallStatusOffered  = "offered"
+	CallStatusAccepted = "accepted"
+	CallStatusRejected = "rejected"
+	CallStatusMissed   = "missed"
+	CallStatusEnded    = "ended"
+)
+
+// CallFilter represents query filters for the call log
+type CallFilter struct {
+	CallerJID string
+	Status    string
+	Limit     int
+	Offset    int
+}
+
+// GroupEvent represents a recorded change to a group's metadata or participants
+type GroupEvent struct {
+	ID           int64     `db:"id"`
+	GroupJID     string    `db:"group_jid"`
+	EventType    string    `db:"event_type"`
+	ActorJID     string    `db:"actor_jid"`
+	OldValue     *string   `db:"old_value"` // nil when the previous value is unknown
+	NewValue     string    `db:"new_value"`
+	Participants []string  `db:"participants"`
+	Timestamp    time.Time `db:"timestamp"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+// Group event types
+const (
+	GroupEventName               = "name"
+	GroupEventTopic              = "topic"
+	GroupEventLocked             = "locked"
+	GroupEventAnnounce           = "announce"
+	GroupEventEphemeral          = "ephemeral"
+	GroupEventMembershipApproval = "membership_approval"
+	GroupEventJoin               = "join"
+	GroupEventLeave              = "leave"
+	GroupEventPromote            = "promote"
+	GroupEventDemote             = "demote"
+)
+
+// GroupEventFilter represents query filters for the group event history
+type GroupEventFilter struct {
+	GroupJID  string
+	EventType string
+	Limit     int
+	Offset    int
+}
+
+// IdempotencyKey records a send request made with a client supplied idempotency key
+type IdempotencyKey struct {
+	Key         string    `db:"key"`
+	Operation   string    `db:"operation"`
+	RequestHash string    `db:"request_hash"`
+	Status      string    `db:"status"`
+	Response    string    `db:"response"` // JSON encoded response, set once completed
+	CreatedAt   time.Time `db:"created_at"`
+	ExpiresAt   time.Time `db:"expires_at"`
+}
+
+// Idempotency key statuses
+const (
+	IdempotencyStatusPending   = "pending"
+	IdempotencyStatusCompleted = "completed"
+)
+
+// RetentionPolicy limits how long the messages of a chat are kept. A zero limit is not enforced, so a
+// chat policy with both limits zero keeps that chat forever regardless of the global policy.
+type RetentionPolicy struct {
+	ChatJID     string    `db:"chat_jid"`
+	MaxAgeDays  int       `db:"max_age_days"` // Delete messages older than this many days
+	MaxMessages int       `db:"max_messages"` // Keep only the newest messages of the chat
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+// RetentionPurge describes the messages of one chat that a retention policy removes
+type RetentionPurge struct {
+	ChatJID     string
+	Messages    int64
+	MediaPaths  []string // Downloaded media files no longer linked to any message once these are removed
+	ChatDeleted bool     // The chat has no messages left and is removed as well
+}
+
+// RetentionAudit records what a retention run deleted from one chat, ChatJID is empty for media
+// files that were not linked to a message
+type RetentionAudit struct {
+	ID              int64     `db:"id"`
+	RunAt           time.Time `db:"run_at"`
+	ChatJID         string    `db:"chat_jid"`
+	MaxAgeDays      int       `db:"max_age_days"`
+	MaxMessages     int       `db:"max_messages"`
+	MessagesDeleted int64     `db:"messages_deleted"`
+	MediaDeleted    int       `db:"media_deleted"`
+	ChatDeleted     bool      `db:"chat_deleted"`
+}
+
+// MediaFile is a downloaded media file, kept once per content and linked to every message carrying it
+type MediaFile struct {
+	SHA256    string    `db:"sha256"` // Hex SHA-256 of the decrypted content, the FileSHA256 of the messages
+	Path      string    `db:"path"`   // Media storage key of the file
+	MimeType  string    `db:"mime_type"`
+	Size      int64     `db:"size"`
+	RefCount  int64     `db:"ref_count"` // Messages linked to the file, the file is removed by retention at zero
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// MediaUpload is media uploaded to the WhatsApp servers, sends of the same content reuse it instead
+// of uploading again until its URL expires
+type MediaUpload struct {
+	SHA256        string    `db:"sha256"`
+	MediaType     string    `db:"media_type"` // The media key is derived for one media type, so uploads are kept per type
+	URL           string    `db:"url"`
+	DirectPath    string    `db:"direct_path"`
+	MediaKey      []byte    `db:"media_key"`
+	FileEncSHA256 []byte    `db:"file_enc_sha256"`
+	FileLength    uint64    `db:"file_length"`
+	ExpiresAt     time.Time `db:"expires_at"`
+	CreatedAt     time.Time `db:"created_at"`
 }
@@ -0,0 +1,49 @@
+package chatstorage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// cursorToken is the serialized form of a Cursor, clients only ever see it base64 encoded
+type cursorToken struct {
+	Timestamp string `json:"t"`
+	ID        string `json:"id"`
+	Previous  bool   `json:"p,omitempty"`
+}
+
+// Encode returns the opaque token handed to clients as next_cursor/prev_cursor
+func (c Cursor) Encode() string {
+	payload, _ := json.Marshal(cursorToken{
+		Timestamp: c.Timestamp.UTC().Format(time.RFC3339Nano),
+		ID:        c.ID,
+		Previous:  c.Previous,
+	})
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+// DecodeCursor parses a token produced by Cursor.Encode
+func DecodeCursor(token string) (*Cursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+
+	var decoded cursorToken
+	if err := json.Unmarshal(payload, &decoded); err != nil || decoded.ID == "" {
+		return nil, errors.New("malformed cursor")
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, decoded.Timestamp)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+
+	return &Cursor{
+		Timestamp: timestamp,
+		ID:        decoded.ID,
+		Previous:  decoded.Previous,
+	}, nil
+}
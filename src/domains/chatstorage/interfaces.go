@@ -14,6 +14,8 @@ type IChatStorageRepository interface {
 	StoreChat(chat *Chat) error
 	GetChat(jid string) (*Chat, error)
 	GetChats(filter *ChatFilter) ([]*Chat, error)
+	GetChatCount(filter *ChatFilter) (int64, error)
+	GetChatJIDs() ([]string, error)
 	DeleteChat(jid string) error
 	DeleteChatAndMessages(jid string) error
 
@@ -24,8 +26,43 @@ type IChatStorageRepository interface {
 	GetMessages(filter *MessageFilter) ([]*Message, error)
 	SearchMessages(chatJID, searchText string, limit int) ([]*Message, error) // Database-level search
 	DeleteMessage(id, chatJID string) error
+	UpdateMessageMediaURL(chatJID, messageID, url string) error // Replaces the expired media URL of a message
 	StoreSentMessageWithContext(ctx context.Context, messageID string, senderJID string, recipientJID string, content string, timestamp time.Time) error
 
+	// Call log operations
+	StoreCall(call *Call) error
+	GetCall(id string) (*Call, error)
+	GetCalls(filter *CallFilter) ([]*Call, error)
+	GetCallCount(filter *CallFilter) (int64, error)
+
+	// Group event history operations
+	StoreGroupEvent(event *GroupEvent) error
+	GetLastGroupEvent(groupJID, eventType string) (*GroupEvent, error)
+	GetGroupEvents(filter *GroupEventFilter) ([]*GroupEvent, error)
+	GetGroupEventCount(filter *GroupEventFilter) (int64, error)
+
+	// Idempotency key operations
+	ClaimIdempotencyKey(record *IdempotencyKey, pendingBefore time.Time) (existing *IdempotencyKey, err error) // existing is nil when the key was claimed
+	CompleteIdempotencyKey(key string, claimedAt time.Time, response string) error
+	DeleteIdempotencyKey(key string, claimedAt time.Time) error
+
+	// Retention operations
+	StoreRetentionPolicy(policy *RetentionPolicy) error
+	GetRetentionPolicies() ([]*RetentionPolicy, error)
+	DeleteRetentionPolicy(chatJID string) error
+	PurgeMessages(chatJID string, before *time.Time, keep int, dryRun bool) (*RetentionPurge, error) // before nil and keep 0 disable that limit
+	StoreRetentionAudit(audit *RetentionAudit) error
+	StoreMediaFile(chatJID, messageID, path string) error // Links a media storage key to its message and sets its media path
+	IsMediaFileTracked(path string) (bool, error)
+	PurgeUnreferencedMedia(dryRun bool) ([]string, error) // Media files no message links to anymore
+	Vacuum() error
+
+	// Media store operations
+	StoreMediaContent(file *MediaFile) error
+	GetMediaContent(sha256 string) (*MediaFile, error)
+	StoreMediaUpload(upload *MediaUpload) error
+	GetMediaUpload(sha256, mediaType string, validAt time.Time) (*MediaUpload, error) // Only returns uploads that have not expired at validAt
+
 	// Statistics
 	GetChatMessageCount(chatJID string) (int64, error)
 	GetTotalMessageCount() (int64, error)
@@ -39,4 +76,7 @@ type IChatStorageRepository interface {
 
 	// Schema operations
 	InitializeSchema() error
+
+	// Encryption operations
+	RotateEncryptionKey() (reencrypted int64, err error) // New data key for all messages, fails when encryption is disabled
 }
@@ -112,3 +112,32 @@ type GetGroupInviteLinkResponse struct {
 type GroupInfoResponse struct {
 	Data any `json:"data"`
 }
+
+type GroupEventsRequest struct {
+	GroupID string `json:"group_id" query:"group_id"`
+	Type    string `json:"type" query:"type"`
+	Limit   int    `json:"limit" query:"limit"`
+	Offset  int    `json:"offset" query:"offset"`
+}
+
+type GroupEventInfo struct {
+	ID           int64    `json:"id"`
+	GroupID      string   `json:"group_id"`
+	Type         string   `json:"type"`
+	ChangedBy    string   `json:"changed_by"`
+	OldValue     *string  `json:"old_value"`
+	NewValue     string   `json:"new_value,omitempty"`
+	Participants []string `json:"participants,omitempty"`
+	Timestamp    string   `json:"timestamp"`
+}
+
+type GroupEventsResponse struct {
+	Data       []GroupEventInfo      `json:"data"`
+	Pagination GroupEventsPagination `json:"pagination"`
+}
+
+type GroupEventsPagination struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Total  int `json:"total"`
+}
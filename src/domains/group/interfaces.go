@@ -30,9 +30,15 @@ type IGroupSettings interface {
 	SetGroupTopic(ctx context.Context, request SetGroupTopicRequest) (err error)
 }
 
+// IGroupHistory handles the recorded group event history
+type IGroupHistory interface {
+	GroupEvents(ctx context.Context, request GroupEventsRequest) (response GroupEventsResponse, err error)
+}
+
 // IGroupUsecase combines all group interfaces for backward compatibility
 type IGroupUsecase interface {
 	IGroupManagement
 	IGroupParticipants
 	IGroupSettings
+	IGroupHistory
 }
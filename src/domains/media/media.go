@@ -0,0 +1,26 @@
+package media
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+type IMediaUsecase interface {
+	GetMedia(ctx context.Context, request GetMediaRequest) (response MediaFile, err error)
+	OpenMedia(ctx context.Context, request GetMediaRequest) (response MediaFile, content io.ReadCloser, err error)
+}
+
+type GetMediaRequest struct {
+	SHA256 string `json:"sha256"`
+}
+
+type MediaFile struct {
+	SHA256    string    `json:"sha256"`
+	Key       string    `json:"-"`             // Media storage key of the file
+	URL       string    `json:"url,omitempty"` // Presigned URL when the storage serves the file directly
+	MimeType  string    `json:"mime_type"`
+	Size      int64     `json:"size"`
+	RefCount  int64     `json:"ref_count"` // Number of messages linked to the file
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,29 @@
+package mediastorage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned when an object does not exist
+var ErrNotFound = errors.New("media object not found")
+
+// Object describes a stored media file. Keys are slash separated paths relative to the storage
+// root, such as 628123456789/2025-07-13/1752400000-<uuid>.jpg.
+type Object struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+type IMediaStorage interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Stat(ctx context.Context, key string) (Object, error) // ErrNotFound when the object does not exist
+	Delete(ctx context.Context, key string) error         // Deleting a missing object succeeds
+	Walk(ctx context.Context, fn func(object Object) error) error
+	URL(ctx context.Context, key string, expiry time.Duration) (string, error) // Direct download URL valid for expiry, empty when the storage has none
+	Locate(key string) string                                                  // Where the object lives, a file path or an s3:// URL
+}
@@ -48,6 +48,7 @@ type DownloadMediaResponse struct {
 	Status    string `json:"status"`
 	MediaType string `json:"media_type"`
 	Filename  string `json:"filename"`
-	FilePath  string `json:"file_path"`
+	FilePath  string `json:"file_path"` // Where the file lives in the media storage, a path on the server or an s3:// URL
+	URL       string `json:"url"`       // Presigned URL of the file, or a signed URL of GET /media/:sha256 when the storage has none
 	FileSize  int64  `json:"file_size"`
 }
@@ -0,0 +1,59 @@
+package retention
+
+import (
+	"context"
+	"time"
+)
+
+type IRetentionUsecase interface {
+	ListPolicies(ctx context.Context) (response ListPoliciesResponse, err error)
+	SetPolicy(ctx context.Context, request SetPolicyRequest) (response Policy, err error)
+	DeletePolicy(ctx context.Context, chatJID string) (err error)
+	Report(ctx context.Context) (response Report, err error) // What Purge would remove, without deleting anything
+	Purge(ctx context.Context) (response Report, err error)
+}
+
+// Policy sources
+const (
+	SourceGlobal = "global"
+	SourceChat   = "chat"
+)
+
+type Policy struct {
+	ChatJID     string     `json:"chat_jid,omitempty"`
+	MaxAgeDays  int        `json:"max_age_days"`
+	MaxMessages int        `json:"max_messages"`
+	Source      string     `json:"source"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+type ListPoliciesResponse struct {
+	Global Policy   `json:"global"`
+	Chats  []Policy `json:"chats"`
+}
+
+type SetPolicyRequest struct {
+	ChatJID     string `json:"chat_jid"`
+	MaxAgeDays  int    `json:"max_age_days"`
+	MaxMessages int    `json:"max_messages"`
+}
+
+type Report struct {
+	DryRun            bool         `json:"dry_run"`
+	RunAt             time.Time    `json:"run_at"`
+	Chats             []ChatReport `json:"chats"`
+	MessagesDeleted   int64        `json:"messages_deleted"`
+	MediaDeleted      int          `json:"media_deleted"`
+	MediaBytes        int64        `json:"media_bytes"`
+	UntrackedMedia    int          `json:"untracked_media"`    // Media files older than the global max age that are not linked to a message
+	UnreferencedMedia int          `json:"unreferenced_media"` // Stored media files no message links to anymore
+}
+
+type ChatReport struct {
+	ChatJID         string `json:"chat_jid"`
+	Policy          Policy `json:"policy"`
+	MessagesDeleted int64  `json:"messages_deleted"`
+	MediaDeleted    int    `json:"media_deleted"`
+	MediaBytes      int64  `json:"media_bytes"`
+	ChatDeleted     bool   `json:"chat_deleted"`
+}
@@ -0,0 +1,30 @@
+package send
+
+import "mime/multipart"
+
+// AlbumItem is a single image or video of an album, provided either as an uploaded file or a URL
+type AlbumItem struct {
+	File    *multipart.FileHeader `json:"-" form:"-"`
+	URL     *string               `json:"url,omitempty" form:"url"`
+	Caption string                `json:"caption" form:"caption"`
+}
+
+type AlbumRequest struct {
+	BaseRequest
+	Items    []AlbumItem `json:"items" form:"-"`
+	Compress bool        `json:"compress" form:"compress"`
+}
+
+type AlbumItemResponse struct {
+	Index     int    `json:"index"`
+	MediaType string `json:"media_type,omitempty"`
+	MessageID string `json:"message_id,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type AlbumResponse struct {
+	AlbumID string              `json:"album_id"`
+	Status  string              `json:"status"`
+	Partial bool                `json:"partial"`
+	Items   []AlbumItemResponse `json:"items"`
+}
@@ -0,0 +1,33 @@
+package send
+
+import "context"
+
+// IdempotencyKeyHeader is the REST header carrying the client supplied idempotency key
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+type idempotencyKeyCtx struct{}
+
+type idempotencyScopeCtx struct{}
+
+// WithIdempotencyKey returns a copy of ctx carrying the idempotency key of a send request
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtx{}, key)
+}
+
+// IdempotencyKeyFromContext returns the idempotency key carried by ctx, or an empty string
+func IdempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyCtx{}).(string)
+	return key
+}
+
+// WithIdempotencyScope returns a copy of ctx whose idempotency keys belong to scope, such as the
+// authenticated user, so clients cannot see or block each other's keys
+func WithIdempotencyScope(ctx context.Context, scope string) context.Context {
+	return context.WithValue(ctx, idempotencyScopeCtx{}, scope)
+}
+
+// IdempotencyScopeFromContext returns the idempotency scope carried by ctx, or an empty string
+func IdempotencyScopeFromContext(ctx context.Context) string {
+	scope, _ := ctx.Value(idempotencyScopeCtx{}).(string)
+	return scope
+}
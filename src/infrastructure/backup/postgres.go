@@ -0,0 +1,257 @@
+package backup
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Postgres snapshots are JSON lines, one table row each, in an order that satisfies foreign keys:
+//
+//	{"table":"whatsmeow_device","row":{...}}
+//
+// The schema itself is not part of the snapshot. whatsmeow creates it on start, so a snapshot is
+// restored into a database initialized by the same release.
+
+// postgresTablePrefix selects the tables of the whatsmeow store
+const postgresTablePrefix = "whatsmeow_"
+
+// postgresVersionTable holds the schema version, which is compared instead of restored
+const postgresVersionTable = "whatsmeow_version"
+
+type postgresRow struct {
+	Table string          `json:"table"`
+	Row   json.RawMessage `json:"row"`
+}
+
+func snapshotPostgres(ctx context.Context, uri string, path string) error {
+	db, err := sql.Open("postgres", uri)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// One repeatable read transaction sees every table as of the same instant
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tables, err := postgresTables(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	writer := bufio.NewWriter(out)
+	encoder := json.NewEncoder(writer)
+
+	for _, table := range append([]string{postgresVersionTable}, tables...) {
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT row_to_json(t)::text FROM %s t", pq.QuoteIdentifier(table)))
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var row string
+			if err = rows.Scan(&row); err != nil {
+				break
+			}
+			if err = encoder.Encode(postgresRow{Table: table, Row: json.RawMessage(row)}); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = rows.Err()
+		}
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("failed to dump %s: %w", table, err)
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return out.Sync()
+}
+
+func restorePostgres(ctx context.Context, uri string, path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	db, err := sql.Open("postgres", uri)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tables, err := postgresTables(ctx, tx)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(tables))
+	quoted := make([]string, 0, len(tables))
+	for _, table := range tables {
+		known[table] = true
+		quoted = append(quoted, pq.QuoteIdentifier(table))
+	}
+
+	currentVersion, err := postgresSchemaVersion(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if len(quoted) > 0 {
+		if _, err := tx.ExecContext(ctx, "TRUNCATE "+strings.Join(quoted, ", ")); err != nil {
+			return err
+		}
+	}
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var row postgresRow
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			return fmt.Errorf("invalid snapshot line %d: %w", line, err)
+		}
+
+		if row.Table == postgresVersionTable {
+			var version struct {
+				Version int `json:"version"`
+			}
+			if err := json.Unmarshal(row.Row, &version); err != nil {
+				return fmt.Errorf("invalid snapshot line %d: %w", line, err)
+			}
+			if version.Version != currentVersion {
+				return fmt.Errorf("snapshot has store schema version %d but the database has %d, restore with the release that created the backup", version.Version, currentVersion)
+			}
+			continue
+		}
+		if !known[row.Table] {
+			return fmt.Errorf("snapshot table %s does not exist in the database", row.Table)
+		}
+
+		table := pq.QuoteIdentifier(row.Table)
+		query := fmt.Sprintf("INSERT INTO %s SELECT * FROM json_populate_record(NULL::%s, $1::json)", table, table)
+		if _, err := tx.ExecContext(ctx, query, string(row.Row)); err != nil {
+			return fmt.Errorf("failed to restore %s row on line %d: %w", row.Table, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// postgresTables lists the store tables, parents before the tables referencing them
+func postgresTables(ctx context.Context, tx *sql.Tx) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT tablename FROM pg_tables
+		WHERE schemaname = current_schema() AND tablename LIKE $1 AND tablename <> $2
+		ORDER BY tablename
+	`, postgresTablePrefix+"%", postgresVersionTable)
+	if err != nil {
+		return nil, err
+	}
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+		SELECT child.relname, parent.relname
+		FROM pg_constraint c
+		JOIN pg_class child ON child.oid = c.conrelid
+		JOIN pg_class parent ON parent.oid = c.confrelid
+		WHERE c.contype = 'f' AND c.connamespace = current_schema()::regnamespace
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	parents := make(map[string][]string)
+	for rows.Next() {
+		var child, parent string
+		if err := rows.Scan(&child, &parent); err != nil {
+			return nil, err
+		}
+		if child != parent {
+			parents[child] = append(parents[child], parent)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sortByDependency(tables, parents), nil
+}
+
+// sortByDependency orders tables so every table comes after the tables it references
+func sortByDependency(tables []string, parents map[string][]string) []string {
+	sort.Strings(tables)
+	ordered := make([]string, 0, len(tables))
+	visited := make(map[string]bool, len(tables))
+	inTables := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		inTables[table] = true
+	}
+
+	var visit func(table string)
+	visit = func(table string) {
+		if visited[table] || !inTables[table] {
+			return
+		}
+		visited[table] = true
+		for _, parent := range parents[table] {
+			visit(parent)
+		}
+		ordered = append(ordered, table)
+	}
+	for _, table := range tables {
+		visit(table)
+	}
+	return ordered
+}
+
+func postgresSchemaVersion(ctx context.Context, tx *sql.Tx) (int, error) {
+	var version int
+	err := tx.QueryRowContext(ctx, "SELECT version FROM "+pq.QuoteIdentifier(postgresVersionTable)+" LIMIT 1").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read store schema version: %w", err)
+	}
+	return version, nil
+}
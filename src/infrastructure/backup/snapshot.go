@@ -0,0 +1,183 @@
+// Package backup takes consistent snapshots of the session and chat databases while they are in use
+// and loads them back.
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pkgBackup "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/backup"
+)
+
+// Database roles, the database a snapshot belongs to
+const (
+	RoleWhatsApp     = "whatsapp"
+	RoleWhatsAppKeys = "whatsapp_keys"
+	RoleChatStorage  = "chatstorage"
+)
+
+// Database is a configured database that is part of a backup
+type Database struct {
+	Role string
+	URI  string
+}
+
+// Kind returns the snapshot kind of a database URI, following the drivers supported by whatsapp.InitWaDB
+func Kind(uri string) (string, error) {
+	switch {
+	case strings.HasPrefix(uri, "file:"):
+		return pkgBackup.KindSQLite, nil
+	case strings.HasPrefix(uri, "postgres:"):
+		return pkgBackup.KindPostgres, nil
+	default:
+		return "", fmt.Errorf("unsupported database uri for backup: %s", redactURI(uri))
+	}
+}
+
+// Snapshot writes a consistent copy of the database into dir without blocking writers
+func Snapshot(ctx context.Context, database Database, dir string) (pkgBackup.File, error) {
+	kind, err := Kind(database.URI)
+	if err != nil {
+		return pkgBackup.File{}, err
+	}
+
+	file := pkgBackup.File{Role: database.Role, Kind: kind}
+	switch kind {
+	case pkgBackup.KindSQLite:
+		file.Name = database.Role + ".db"
+		err = snapshotSQLite(ctx, database.URI, filepath.Join(dir, file.Name))
+	case pkgBackup.KindPostgres:
+		file.Name = database.Role + ".jsonl"
+		err = snapshotPostgres(ctx, database.URI, filepath.Join(dir, file.Name))
+	}
+	if err != nil {
+		return file, fmt.Errorf("failed to snapshot %s database: %w", database.Role, err)
+	}
+	return file, nil
+}
+
+// Verify checks that an extracted snapshot can be loaded, beyond the checksums of the archive
+func Verify(ctx context.Context, file pkgBackup.File, dir string) error {
+	if file.Kind != pkgBackup.KindSQLite {
+		return nil
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(dir, file.Name)+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRowContext(ctx, "PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("%w: %s: %v", pkgBackup.ErrCorrupted, file.Name, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%w: %s failed integrity check: %s", pkgBackup.ErrCorrupted, file.Name, result)
+	}
+	return nil
+}
+
+// Restore replaces the content of the database with a snapshot extracted to dir. SQLite databases
+// must not be open while they are replaced.
+func Restore(ctx context.Context, database Database, file pkgBackup.File, dir string) error {
+	kind, err := Kind(database.URI)
+	if err != nil {
+		return err
+	}
+	if kind != file.Kind {
+		return fmt.Errorf("cannot restore a %s snapshot of the %s database into %s", file.Kind, database.Role, kind)
+	}
+
+	switch kind {
+	case pkgBackup.KindSQLite:
+		err = restoreSQLite(filepath.Join(dir, file.Name), sqlitePath(database.URI))
+	case pkgBackup.KindPostgres:
+		err = restorePostgres(ctx, database.URI, filepath.Join(dir, file.Name))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore %s database: %w", database.Role, err)
+	}
+	return nil
+}
+
+// snapshotSQLite uses VACUUM INTO, which reads a single transaction and so sees a consistent
+// database even while the application keeps writing to it
+func snapshotSQLite(ctx context.Context, uri string, path string) error {
+	db, err := sql.Open("sqlite3", uri)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	_, err = db.ExecContext(ctx, fmt.Sprintf("VACUUM INTO '%s'", strings.ReplaceAll(path, "'", "''")))
+	return err
+}
+
+// restoreSQLite moves the snapshot into place, dropping the write-ahead log of the old database so
+// SQLite does not replay it on top of the restored file
+func restoreSQLite(snapshot string, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	staged := path + ".restore"
+	if err := copyFile(snapshot, staged); err != nil {
+		return err
+	}
+
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if err := os.Remove(path + suffix); err != nil && !os.IsNotExist(err) {
+			os.Remove(staged)
+			return err
+		}
+	}
+	return os.Rename(staged, path)
+}
+
+// sqlitePath returns the file of a "file:" URI
+func sqlitePath(uri string) string {
+	path := strings.TrimPrefix(uri, "file:")
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	return path
+}
+
+func copyFile(src string, dst string) error {
+	data, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = out.ReadFrom(data); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err = out.Sync(); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
+// redactURI hides the credentials of a database URI in error messages
+func redactURI(uri string) string {
+	if at := strings.LastIndex(uri, "@"); at >= 0 {
+		if scheme := strings.Index(uri, "://"); scheme >= 0 && scheme < at {
+			return uri[:scheme+3] + "***" + uri[at:]
+		}
+	}
+	return uri
+}
@@ -0,0 +1,337 @@
+package chatstorage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/fieldcrypt"
+	"github.com/sirupsen/logrus"
+)
+
+// Message content, media keys and file hashes are encrypted with a data key stored in the
+// encryption_keys table, wrapped by a key encryption key from the configuration. Rotating the key
+// encryption key only rewraps the data keys, RotateEncryptionKey creates a new data key and
+// re-encrypts every message with it. Media content hashes, which the media store is keyed by, are
+// replaced by a blind index keyed by the oldest data key, which rotation never removes.
+
+// ErrEncryptionDisabled is returned by RotateEncryptionKey when no encryption key is configured
+var ErrEncryptionDisabled = errors.New("chat storage encryption is not enabled, set an encryption key first")
+
+// rotateBatchSize is the number of messages re-encrypted per transaction
+const rotateBatchSize = 500
+
+// sealedMessage holds the column values of a message as they are written to the database
+type sealedMessage struct {
+	content       string
+	mediaKey      []byte
+	fileSHA256    []byte
+	fileEncSHA256 []byte
+	thumbnail     []byte
+}
+
+// loadDataKeys unwraps the stored data keys, rewraps the ones wrapped by a retired key encryption
+// key and creates the first data key when encryption was just enabled
+func (r *SQLiteRepository) loadDataKeys() error {
+	if r.keyring == nil {
+		var count int64
+		if err := r.db.QueryRow("SELECT COUNT(*) FROM encryption_keys").Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			logrus.Warn("[CHAT_STORAGE] Chat storage contains encrypted messages but no encryption key is configured, they cannot be read")
+		}
+		return nil
+	}
+
+	rows, err := r.db.Query("SELECT id, wrapped_key, kek_id, active FROM encryption_keys ORDER BY created_at, rowid")
+	if err != nil {
+		return err
+	}
+	type storedKey struct {
+		id      string
+		wrapped []byte
+		kekID   string
+		active  bool
+	}
+	var stored []storedKey
+	for rows.Next() {
+		var key storedKey
+		if err := rows.Scan(&key.id, &key.wrapped, &key.kekID, &key.active); err != nil {
+			rows.Close()
+			return err
+		}
+		stored = append(stored, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	dataCipher := fieldcrypt.NewCipher()
+	hasActive := false
+	for _, key := range stored {
+		dataKey, err := r.keyring.Unwrap(key.id, key.kekID, key.wrapped)
+		if err != nil {
+			return fmt.Errorf("failed to unwrap chat storage data key: %w", err)
+		}
+		if err := dataCipher.AddKey(key.id, dataKey, key.active); err != nil {
+			return err
+		}
+		if r.mediaIndex == nil {
+			r.mediaIndex = fieldcrypt.NewBlindIndex(dataKey, "media sha256")
+		}
+		hasActive = hasActive || key.active
+
+		if key.kekID != r.keyring.ActiveID() {
+			wrapped, kekID := r.keyring.Wrap(key.id, dataKey)
+			if _, err := r.db.Exec("UPDATE encryption_keys SET wrapped_key = ?, kek_id = ? WHERE id = ?", wrapped, kekID, key.id); err != nil {
+				return fmt.Errorf("failed to rewrap data key %s: %w", key.id, err)
+			}
+			logrus.Infof("[CHAT_STORAGE] Rewrapped data key %s from key %s to %s", key.id, key.kekID, kekID)
+		}
+	}
+	r.cipher = dataCipher
+
+	if !hasActive {
+		return r.addDataKey()
+	}
+	return nil
+}
+
+// addDataKey generates a data key and makes it the one new values are encrypted with
+func (r *SQLiteRepository) addDataKey() error {
+	id, dataKey, err := fieldcrypt.NewDataKey()
+	if err != nil {
+		return err
+	}
+	wrapped, kekID := r.keyring.Wrap(id, dataKey)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE encryption_keys SET active = FALSE WHERE active = TRUE"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO encryption_keys (id, wrapped_key, kek_id, active) VALUES (?, ?, ?, TRUE)", id, wrapped, kekID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if r.mediaIndex == nil {
+		r.mediaIndex = fieldcrypt.NewBlindIndex(dataKey, "media sha256")
+	}
+	return r.cipher.AddKey(id, dataKey, true)
+}
+
+// RotateEncryptionKey switches to a new data key and re-encrypts all messages with it, including
+// plaintext messages stored before encryption was enabled. Retired data keys stay in the database
+// so messages written by a server still running with them remain readable.
+func (r *SQLiteRepository) RotateEncryptionKey() (int64, error) {
+	if r.cipher == nil {
+		return 0, ErrEncryptionDisabled
+	}
+	if err := r.addDataKey(); err != nil {
+		return 0, fmt.Errorf("failed to create data key: %w", err)
+	}
+
+	var total int64
+	lastRowID := int64(0)
+	for {
+		count, next, err := r.reencryptBatch(lastRowID)
+		if err != nil {
+			return total, err
+		}
+		total += count
+		if next == lastRowID {
+			return total, nil
+		}
+		lastRowID = next
+	}
+}
+
+// reencryptBatch re-encrypts the messages following lastRowID and returns the last row it visited
+func (r *SQLiteRepository) reencryptBatch(lastRowID int64) (count int64, next int64, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, lastRowID, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT rowid, id, chat_jid, content, media_key, file_sha256, file_enc_sha256, thumbnail
+		FROM messages
+		WHERE rowid > ?
+		ORDER BY rowid
+		LIMIT ?
+	`, lastRowID, rotateBatchSize)
+	if err != nil {
+		return 0, lastRowID, err
+	}
+
+	var messages []*domainChatStorage.Message
+	var rowIDs []int64
+	next = lastRowID
+	for rows.Next() {
+		var message domainChatStorage.Message
+		var content sql.NullString
+		if err = rows.Scan(&next, &message.ID, &message.ChatJID, &content, &message.MediaKey, &message.FileSHA256, &message.FileEncSHA256, &message.Thumbnail); err != nil {
+			rows.Close()
+			return 0, lastRowID, err
+		}
+		message.Content = content.String
+		if err = r.openMessage(&message); err != nil {
+			rows.Close()
+			return 0, lastRowID, fmt.Errorf("failed to decrypt message %s: %w", message.ID, err)
+		}
+		messages = append(messages, &message)
+		rowIDs = append(rowIDs, next)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, lastRowID, err
+	}
+
+	for i, message := range messages {
+		sealed, err := r.sealMessage(message)
+		if err != nil {
+			return 0, lastRowID, err
+		}
+		_, err = tx.Exec(`
+			UPDATE messages SET content = ?, media_key = ?, file_sha256 = ?, file_enc_sha256 = ?, thumbnail = ?
+			WHERE rowid = ?
+		`, sealed.content, sealed.mediaKey, sealed.fileSHA256, sealed.fileEncSHA256, sealed.thumbnail, rowIDs[i])
+		if err != nil {
+			return 0, lastRowID, fmt.Errorf("failed to re-encrypt message %s: %w", message.ID, err)
+		}
+	}
+
+	return int64(len(messages)), next, tx.Commit()
+}
+
+// sealMessage returns the sensitive column values of message, encrypted when encryption is enabled
+func (r *SQLiteRepository) sealMessage(message *domainChatStorage.Message) (sealed sealedMessage, err error) {
+	sealed = sealedMessage{
+		content:       message.Content,
+		mediaKey:      message.MediaKey,
+		fileSHA256:    message.FileSHA256,
+		fileEncSHA256: message.FileEncSHA256,
+		thumbnail:     message.Thumbnail,
+	}
+	if r.cipher == nil {
+		return sealed, nil
+	}
+
+	if sealed.content, err = r.cipher.EncryptString(message.Content, columnContext("content", message)); err != nil {
+		return sealed, err
+	}
+	if sealed.mediaKey, err = r.cipher.EncryptBytes(message.MediaKey, columnContext("media_key", message)); err != nil {
+		return sealed, err
+	}
+	if sealed.fileSHA256, err = r.cipher.EncryptBytes(message.FileSHA256, columnContext("file_sha256", message)); err != nil {
+		return sealed, err
+	}
+	if sealed.fileEncSHA256, err = r.cipher.EncryptBytes(message.FileEncSHA256, columnContext("file_enc_sha256", message)); err != nil {
+		return sealed, err
+	}
+	if sealed.thumbnail, err = r.cipher.EncryptBytes(message.Thumbnail, columnContext("thumbnail", message)); err != nil {
+		return sealed, err
+	}
+	return sealed, nil
+}
+
+// openMessage decrypts the sensitive fields of a message read from the database in place. A field
+// that fails to decrypt keeps its stored value.
+func (r *SQLiteRepository) openMessage(message *domainChatStorage.Message) error {
+	if r.cipher == nil {
+		return nil
+	}
+
+	var errs []error
+	content, err := r.cipher.DecryptString(message.Content, columnContext("content", message))
+	if err == nil {
+		message.Content = content
+	}
+	errs = append(errs, err)
+	for column, field := range map[string]*[]byte{
+		"media_key":       &message.MediaKey,
+		"file_sha256":     &message.FileSHA256,
+		"file_enc_sha256": &message.FileEncSHA256,
+		"thumbnail":       &message.Thumbnail,
+	} {
+		value, err := r.cipher.DecryptBytes(*field, columnContext(column, message))
+		if err == nil {
+			*field = value
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// searchEncryptedMessages matches searchText against decrypted content, since encrypted content
+// cannot be searched in SQL
+func (r *SQLiteRepository) searchEncryptedMessages(chatJID, searchText string, limit int) ([]*domainChatStorage.Message, error) {
+	rows, err := r.db.Query(`
+		SELECT id, chat_jid, sender, content, timestamp, is_from_me,
+			media_type, filename, url, media_key, file_sha256,
+			file_enc_sha256, file_length, media_path, thumbnail, created_at, updated_at
+		FROM messages
+		WHERE chat_jid = ? AND content != ''
+		ORDER BY timestamp DESC
+	`, chatJID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	defer rows.Close()
+
+	if limit > 1000 {
+		limit = 1000
+	}
+	needle := strings.ToLower(searchText)
+	messages := []*domainChatStorage.Message{}
+	for rows.Next() {
+		message, err := r.scanMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		if !strings.Contains(strings.ToLower(message.Content), needle) {
+			continue
+		}
+		messages = append(messages, message)
+		if limit > 0 && len(messages) >= limit {
+			break
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// mediaKey returns the key of media content in the media_files and media_uploads tables, the hex
+// SHA-256 itself or its blind index when encryption is enabled
+func (r *SQLiteRepository) mediaKey(sha256 string) string {
+	if r.mediaIndex == nil {
+		return sha256
+	}
+	return r.mediaIndex.Sum([]byte(sha256))
+}
+
+// uploadContext binds an encrypted media key to its upload
+func uploadContext(key, mediaType string) string {
+	return "media_uploads.media_key\x00" + key + "\x00" + mediaType
+}
+
+// columnContext binds an encrypted value to its column and message
+func columnContext(column string, message *domainChatStorage.Message) string {
+	return "messages." + column + "\x00" + message.ChatJID + "\x00" + message.ID
+}
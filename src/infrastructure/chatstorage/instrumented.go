@@ -0,0 +1,202 @@
+package chatstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/metrics"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentedDB records the latency of every statement the repository runs and traces it, including
+// the ones inside transactions and prepared statements. Statements run without a context start their
+// own trace. Times are bound in UTC, see utcArgs.
+type instrumentedDB struct {
+	*sql.DB
+}
+
+func (db instrumentedDB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+func (db instrumentedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, done := observe(ctx, query)
+	result, err := db.DB.ExecContext(ctx, query, utcArgs(args)...)
+	done(err)
+	return result, err
+}
+
+func (db instrumentedDB) Query(query string, args ...any) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+func (db instrumentedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, done := observe(ctx, query)
+	rows, err := db.DB.QueryContext(ctx, query, utcArgs(args)...)
+	done(err)
+	return rows, err
+}
+
+func (db instrumentedDB) QueryRow(query string, args ...any) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+func (db instrumentedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, done := observe(ctx, query)
+	row := db.DB.QueryRowContext(ctx, query, utcArgs(args)...)
+	done(row.Err())
+	return row
+}
+
+func (db instrumentedDB) Begin() (*instrumentedTx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+func (db instrumentedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*instrumentedTx, error) {
+	ctx, span := tracing.StartClient(ctx, "chatstorage.transaction", attribute.String("db.system", "sqlite"))
+	_, done := observe(ctx, "BEGIN")
+	tx, err := db.DB.BeginTx(ctx, opts)
+	done(err)
+	if err != nil {
+		tracing.End(span, err)
+		return nil, err
+	}
+	return &instrumentedTx{Tx: tx, ctx: ctx, span: span}, nil
+}
+
+// instrumentedTx is a transaction whose statements are recorded like the ones of instrumentedDB. Its
+// span lasts until Commit or Rollback, statements run without a context are children of it.
+type instrumentedTx struct {
+	*sql.Tx
+	ctx  context.Context
+	span trace.Span
+}
+
+func (tx *instrumentedTx) Exec(query string, args ...any) (sql.Result, error) {
+	return tx.ExecContext(tx.ctx, query, args...)
+}
+
+func (tx *instrumentedTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, done := observe(ctx, query)
+	result, err := tx.Tx.ExecContext(ctx, query, utcArgs(args)...)
+	done(err)
+	return result, err
+}
+
+func (tx *instrumentedTx) Query(query string, args ...any) (*sql.Rows, error) {
+	return tx.QueryContext(tx.ctx, query, args...)
+}
+
+func (tx *instrumentedTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, done := observe(ctx, query)
+	rows, err := tx.Tx.QueryContext(ctx, query, utcArgs(args)...)
+	done(err)
+	return rows, err
+}
+
+func (tx *instrumentedTx) QueryRow(query string, args ...any) *sql.Row {
+	return tx.QueryRowContext(tx.ctx, query, args...)
+}
+
+func (tx *instrumentedTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, done := observe(ctx, query)
+	row := tx.Tx.QueryRowContext(ctx, query, utcArgs(args)...)
+	done(row.Err())
+	return row
+}
+
+func (tx *instrumentedTx) Prepare(query string) (*instrumentedStmt, error) {
+	return tx.PrepareContext(tx.ctx, query)
+}
+
+func (tx *instrumentedTx) PrepareContext(ctx context.Context, query string) (*instrumentedStmt, error) {
+	stmt, err := tx.Tx.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedStmt{Stmt: stmt, query: query, ctx: tx.ctx}, nil
+}
+
+func (tx *instrumentedTx) Commit() error {
+	_, done := observe(tx.ctx, "COMMIT")
+	err := tx.Tx.Commit()
+	done(err)
+	tracing.End(tx.span, err)
+	return err
+}
+
+// Rollback ends the transaction span. Called after Commit, as deferred rollbacks are, it changes nothing.
+func (tx *instrumentedTx) Rollback() error {
+	err := tx.Tx.Rollback()
+	if !errors.Is(err, sql.ErrTxDone) {
+		tracing.End(tx.span, err)
+	}
+	return err
+}
+
+// instrumentedStmt is a prepared statement recorded under the SQL it was prepared from
+type instrumentedStmt struct {
+	*sql.Stmt
+	query string
+	ctx   context.Context
+}
+
+func (s *instrumentedStmt) Exec(args ...any) (sql.Result, error) {
+	return s.ExecContext(s.ctx, args...)
+}
+
+func (s *instrumentedStmt) ExecContext(ctx context.Context, args ...any) (sql.Result, error) {
+	ctx, done := observe(ctx, s.query)
+	result, err := s.Stmt.ExecContext(ctx, utcArgs(args)...)
+	done(err)
+	return result, err
+}
+
+// observe starts the span of a statement, the returned function ends it and records its latency
+func observe(ctx context.Context, query string) (context.Context, func(error)) {
+	started := time.Now()
+	ctx, span := tracing.StartClient(ctx, "chatstorage."+metrics.QueryOperation(query),
+		attribute.String("db.system", "sqlite"),
+		attribute.String("db.statement", query),
+	)
+	return ctx, func(err error) {
+		metrics.ObserveQuery(query, started)
+		tracing.End(span, err)
+	}
+}
+
+// utcArgs converts time arguments to UTC. The driver stores a time as text in the offset of its
+// location and SQLite compares that text, so only times stored in one offset order by the instant
+// they represent. Migration 12 converted the rows stored before.
+func utcArgs(args []any) []any {
+	converted := args
+	cloned := false
+	for i, arg := range args {
+		var value time.Time
+		switch arg := arg.(type) {
+		case time.Time:
+			value = arg
+		case *time.Time:
+			if arg == nil {
+				continue
+			}
+			value = *arg
+		default:
+			continue
+		}
+		if value.Location() == time.UTC {
+			continue
+		}
+		if !cloned {
+			// The caller may reuse its slice
+			converted, cloned = slices.Clone(args), true
+		}
+		converted[i] = value.UTC()
+	}
+	return converted
+}
@@ -0,0 +1,57 @@
+package chatstorage
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// countCacheTTL is how long a row count is reused across scrapes, counting a large table is a full scan
+const countCacheTTL = time.Minute
+
+// cachedCount runs count at most once per countCacheTTL
+type cachedCount struct {
+	name  string
+	count func() (int64, error)
+
+	mu        sync.Mutex
+	last      float64
+	updatedAt time.Time
+}
+
+func (c *cachedCount) value() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.updatedAt) < countCacheTTL {
+		return c.last
+	}
+	count, err := c.count()
+	if err != nil {
+		// Keep serving the previous value and try again on the next scrape
+		logrus.Debugf("Failed to count %s for metrics: %v", c.name, err)
+		return c.last
+	}
+	c.last, c.updatedAt = float64(count), time.Now()
+	return c.last
+}
+
+// RegisterMetrics exposes the chat storage size as Prometheus gauges
+func RegisterMetrics(db *sql.DB, repo domainChatStorage.IChatStorageRepository) {
+	chats := &cachedCount{name: "chats", count: repo.GetTotalChatCount}
+	metrics.RegisterGauge("chatstorage_chats", "Chats stored in chat storage.", chats.value)
+	messages := &cachedCount{name: "messages", count: repo.GetTotalMessageCount}
+	metrics.RegisterGauge("chatstorage_messages", "Messages stored in chat storage.", messages.value)
+	metrics.RegisterGauge("chatstorage_size_bytes", "Size of the chat storage database in bytes.", func() float64 {
+		var size int64
+		err := db.QueryRow(`SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()`).Scan(&size)
+		if err != nil {
+			logrus.Debugf("Failed to read chat storage size for metrics: %v", err)
+		}
+		return float64(size)
+	})
+}
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/fieldcrypt"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/tracing"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
 	"github.com/sirupsen/logrus"
 	"go.mau.fi/whatsmeow/types"
@@ -16,12 +18,21 @@ import (
 
 // SQLiteRepository implements Repository using SQLite
 type SQLiteRepository struct {
-	db *sql.DB
+	db         instrumentedDB
+	keyring    *fieldcrypt.Keyring    // key encryption keys, nil when encryption is disabled
+	cipher     *fieldcrypt.Cipher     // data keys, loaded by InitializeSchema
+	mediaIndex *fieldcrypt.BlindIndex // hashes media content hashes, nil when encryption is disabled
 }
 
 // NewSQLiteRepository creates a new SQLite repository
 func NewStorageRepository(db *sql.DB) domainChatStorage.IChatStorageRepository {
-	return &SQLiteRepository{db: db}
+	return &SQLiteRepository{db: instrumentedDB{DB: db}}
+}
+
+// NewEncryptedStorageRepository creates a SQLite repository that encrypts message content, media keys,
+// file hashes and thumbnails with data keys wrapped by keyring
+func NewEncryptedStorageRepository(db *sql.DB, keyring *fieldcrypt.Keyring) domainChatStorage.IChatStorageRepository {
+	return &SQLiteRepository{db: instrumentedDB{DB: db}, keyring: keyring}
 }
 
 // StoreChat creates or updates a chat
@@ -65,7 +76,7 @@ func (r *SQLiteRepository) GetMessageByID(id string) (*domainChatStorage.Message
 	query := `
 		SELECT id, chat_jid, sender, content, timestamp, is_from_me,
 			media_type, filename, url, media_key, file_sha256,
-			file_enc_sha256, file_length, created_at, updated_at
+			file_enc_sha256, file_length, media_path, thumbnail, created_at, updated_at
 		FROM messages
 		WHERE id = ?
 		LIMIT 1
@@ -81,29 +92,28 @@ func (r *SQLiteRepository) GetMessageByID(id string) (*domainChatStorage.Message
 
 // GetChats retrieves chats with filtering
 func (r *SQLiteRepository) GetChats(filter *domainChatStorage.ChatFilter) ([]*domainChatStorage.Chat, error) {
-	var conditions []string
-	var args []any
+	conditions, args := r.buildChatConditions(filter)
 
 	query := `
 		SELECT c.jid, c.name, c.last_message_time, c.ephemeral_expiration, c.created_at, c.updated_at
 		FROM chats c
 	`
 
-	if filter.SearchName != "" {
-		conditions = append(conditions, "c.name LIKE ?")
-		args = append(args, "%"+filter.SearchName+"%")
-	}
-
-	if filter.HasMedia {
-		query += " INNER JOIN messages m ON c.jid = m.chat_jid"
-		conditions = append(conditions, "m.media_type != ''")
+	order := "DESC"
+	if filter.Cursor != nil {
+		keyset, keysetArgs := keysetCondition("c.last_message_time", "c.jid", filter.Cursor)
+		conditions = append(conditions, keyset)
+		args = append(args, keysetArgs...)
+		if filter.Cursor.Previous {
+			order = "ASC"
+		}
 	}
 
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	query += " ORDER BY c.last_message_time DESC"
+	query += " ORDER BY c.last_message_time " + order + ", c.jid " + order
 
 	// Safely add LIMIT and OFFSET using parameterized values
 	if filter.Limit > 0 {
@@ -114,7 +124,7 @@ func (r *SQLiteRepository) GetChats(filter *domainChatStorage.ChatFilter) ([]*do
 		query += " LIMIT ?"
 		args = append(args, filter.Limit)
 
-		if filter.Offset > 0 {
+		if filter.Offset > 0 && filter.Cursor == nil {
 			query += " OFFSET ?"
 			args = append(args, filter.Offset)
 		}
@@ -138,6 +148,62 @@ func (r *SQLiteRepository) GetChats(filter *domainChatStorage.ChatFilter) ([]*do
 	return chats, rows.Err()
 }
 
+// GetChatCount counts chats matching the filter, ignoring its pagination
+func (r *SQLiteRepository) GetChatCount(filter *domainChatStorage.ChatFilter) (int64, error) {
+	conditions, args := r.buildChatConditions(filter)
+
+	query := "SELECT COUNT(*) FROM chats c"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	return r.getCount(query, args...)
+}
+
+// GetChatJIDs returns the JID of every stored chat
+func (r *SQLiteRepository) GetChatJIDs() ([]string, error) {
+	rows, err := r.db.Query("SELECT jid FROM chats")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jids []string
+	for rows.Next() {
+		var jid string
+		if err := rows.Scan(&jid); err != nil {
+			return nil, err
+		}
+		jids = append(jids, jid)
+	}
+
+	return jids, rows.Err()
+}
+
+// buildChatConditions is a private helper building the WHERE conditions for chat queries
+func (r *SQLiteRepository) buildChatConditions(filter *domainChatStorage.ChatFilter) (conditions []string, args []any) {
+	if filter.SearchName != "" {
+		conditions = append(conditions, "c.name LIKE ?")
+		args = append(args, "%"+filter.SearchName+"%")
+	}
+
+	if filter.HasMedia {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM messages m WHERE m.chat_jid = c.jid AND m.media_type != '')")
+	}
+
+	return conditions, args
+}
+
+// keysetCondition is a private helper selecting the rows after a cursor in (timestamp, id) order,
+// or the rows before it for a previous page
+func keysetCondition(timestampColumn, idColumn string, cursor *domainChatStorage.Cursor) (string, []any) {
+	operator := "<"
+	if cursor.Previous {
+		operator = ">"
+	}
+	return fmt.Sprintf("(%s, %s) %s (?, ?)", timestampColumn, idColumn, operator), []any{cursor.Timestamp, cursor.ID}
+}
+
 // DeleteChat deletes a chat and all its messages
 func (r *SQLiteRepository) DeleteChat(jid string) error {
 	tx, err := r.db.Begin()
@@ -146,7 +212,11 @@ func (r *SQLiteRepository) DeleteChat(jid string) error {
 	}
 	defer tx.Rollback()
 
-	// Delete messages first (foreign key constraint)
+	// Delete media links and messages first (foreign key constraint)
+	_, err = tx.Exec("DELETE FROM message_media WHERE chat_jid = ?", jid)
+	if err != nil {
+		return err
+	}
 	_, err = tx.Exec("DELETE FROM messages WHERE chat_jid = ?", jid)
 	if err != nil {
 		return err
@@ -183,8 +253,8 @@ func (r *SQLiteRepository) StoreMessage(message *domainChatStorage.Message) erro
 		INSERT INTO messages (
 			id, chat_jid, sender, content, timestamp, is_from_me, 
 			media_type, filename, url, media_key, file_sha256, 
-			file_enc_sha256, file_length, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			file_enc_sha256, file_length, media_path, thumbnail, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id, chat_jid) DO UPDATE SET
 			sender = excluded.sender,
 			content = excluded.content,
@@ -197,14 +267,21 @@ func (r *SQLiteRepository) StoreMessage(message *domainChatStorage.Message) erro
 			file_sha256 = excluded.file_sha256,
 			file_enc_sha256 = excluded.file_enc_sha256,
 			file_length = excluded.file_length,
+			media_path = CASE WHEN excluded.media_path != '' THEN excluded.media_path ELSE messages.media_path END,
+			thumbnail = excluded.thumbnail,
 			updated_at = excluded.updated_at
 	`
 
-	_, err := r.db.Exec(query,
-		message.ID, message.ChatJID, message.Sender, message.Content,
+	sealed, err := r.sealMessage(message)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt message %s: %w", message.ID, err)
+	}
+
+	_, err = r.db.Exec(query,
+		message.ID, message.ChatJID, message.Sender, sealed.content,
 		message.Timestamp, message.IsFromMe, message.MediaType, message.Filename,
-		message.URL, message.MediaKey, message.FileSHA256, message.FileEncSHA256,
-		message.FileLength, message.CreatedAt, message.UpdatedAt,
+		message.URL, sealed.mediaKey, sealed.fileSHA256, sealed.fileEncSHA256,
+		message.FileLength, message.MediaPath, sealed.thumbnail, message.CreatedAt, message.UpdatedAt,
 	)
 
 	return err
@@ -227,8 +304,8 @@ func (r *SQLiteRepository) StoreMessagesBatch(messages []*domainChatStorage.Mess
 		INSERT INTO messages (
 			id, chat_jid, sender, content, timestamp, is_from_me, 
 			media_type, filename, url, media_key, file_sha256, 
-			file_enc_sha256, file_length, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			file_enc_sha256, file_length, media_path, thumbnail, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id, chat_jid) DO UPDATE SET
 			sender = excluded.sender,
 			content = excluded.content,
@@ -241,6 +318,8 @@ func (r *SQLiteRepository) StoreMessagesBatch(messages []*domainChatStorage.Mess
 			file_sha256 = excluded.file_sha256,
 			file_enc_sha256 = excluded.file_enc_sha256,
 			file_length = excluded.file_length,
+			media_path = CASE WHEN excluded.media_path != '' THEN excluded.media_path ELSE messages.media_path END,
+			thumbnail = excluded.thumbnail,
 			updated_at = excluded.updated_at
 	`)
 	if err != nil {
@@ -258,11 +337,16 @@ func (r *SQLiteRepository) StoreMessagesBatch(messages []*domainChatStorage.Mess
 		message.CreatedAt = now
 		message.UpdatedAt = now
 
+		sealed, err := r.sealMessage(message)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt message %s: %w", message.ID, err)
+		}
+
 		_, err = stmt.Exec(
-			message.ID, message.ChatJID, message.Sender, message.Content,
+			message.ID, message.ChatJID, message.Sender, sealed.content,
 			message.Timestamp, message.IsFromMe, message.MediaType, message.Filename,
-			message.URL, message.MediaKey, message.FileSHA256, message.FileEncSHA256,
-			message.FileLength, message.CreatedAt, message.UpdatedAt,
+			message.URL, sealed.mediaKey, sealed.fileSHA256, sealed.fileEncSHA256,
+			message.FileLength, message.MediaPath, sealed.thumbnail, message.CreatedAt, message.UpdatedAt,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to store message %s: %w", message.ID, err)
@@ -299,13 +383,23 @@ func (r *SQLiteRepository) GetMessages(filter *domainChatStorage.MessageFilter)
 		args = append(args, *filter.IsFromMe)
 	}
 
+	order := "DESC"
+	if filter.Cursor != nil {
+		keyset, keysetArgs := keysetCondition("timestamp", "id", filter.Cursor)
+		conditions = append(conditions, keyset)
+		args = append(args, keysetArgs...)
+		if filter.Cursor.Previous {
+			order = "ASC"
+		}
+	}
+
 	query := `
 		SELECT id, chat_jid, sender, content, timestamp, is_from_me,
 			media_type, filename, url, media_key, file_sha256,
-			file_enc_sha256, file_length, created_at, updated_at
+			file_enc_sha256, file_length, media_path, thumbnail, created_at, updated_at
 		FROM messages
 		WHERE ` + strings.Join(conditions, " AND ") + `
-		ORDER BY timestamp DESC
+		ORDER BY timestamp ` + order + `, id ` + order + `
 	`
 
 	// Safely add LIMIT and OFFSET using parameterized values
@@ -317,7 +411,7 @@ func (r *SQLiteRepository) GetMessages(filter *domainChatStorage.MessageFilter)
 		query += " LIMIT ?"
 		args = append(args, filter.Limit)
 
-		if filter.Offset > 0 {
+		if filter.Offset > 0 && filter.Cursor == nil {
 			query += " OFFSET ?"
 			args = append(args, filter.Offset)
 		}
@@ -348,6 +442,11 @@ func (r *SQLiteRepository) SearchMessages(chatJID, searchText string, limit int)
 		return []*domainChatStorage.Message{}, nil
 	}
 
+	// Encrypted content can only be matched after decrypting it
+	if r.cipher != nil {
+		return r.searchEncryptedMessages(chatJID, searchText, limit)
+	}
+
 	var conditions []string
 	var args []any
 
@@ -362,7 +461,7 @@ func (r *SQLiteRepository) SearchMessages(chatJID, searchText string, limit int)
 	query := `
 		SELECT id, chat_jid, sender, content, timestamp, is_from_me,
 			media_type, filename, url, media_key, file_sha256,
-			file_enc_sha256, file_length, created_at, updated_at
+			file_enc_sha256, file_length, media_path, thumbnail, created_at, updated_at
 		FROM messages
 		WHERE ` + strings.Join(conditions, " AND ") + `
 		ORDER BY timestamp DESC
@@ -406,6 +505,13 @@ func (r *SQLiteRepository) DeleteMessage(id, chatJID string) error {
 	return err
 }
 
+// UpdateMessageMediaURL replaces the media URL of a message, for example after the phone uploaded
+// expired media again
+func (r *SQLiteRepository) UpdateMessageMediaURL(chatJID, messageID, url string) error {
+	_, err := r.db.Exec("UPDATE messages SET url = ?, updated_at = ? WHERE chat_jid = ? AND id = ?", url, time.Now(), chatJID, messageID)
+	return err
+}
+
 // getCount is a private helper for count queries
 func (r *SQLiteRepository) getCount(query string, args ...any) (int64, error) {
 	var count int64
@@ -420,9 +526,16 @@ func (r *SQLiteRepository) scanMessage(scanner interface{ Scan(...any) error })
 		&message.ID, &message.ChatJID, &message.Sender, &message.Content,
 		&message.Timestamp, &message.IsFromMe, &message.MediaType, &message.Filename,
 		&message.URL, &message.MediaKey, &message.FileSHA256, &message.FileEncSHA256,
-		&message.FileLength, &message.CreatedAt, &message.UpdatedAt,
+		&message.FileLength, &message.MediaPath, &message.Thumbnail, &message.CreatedAt, &message.UpdatedAt,
 	)
-	return message, err
+	if err != nil {
+		return message, err
+	}
+
+	if err := r.openMessage(message); err != nil {
+		logrus.Warnf("[CHAT_STORAGE] Failed to decrypt message %s: %v", message.ID, err)
+	}
+	return message, nil
 }
 
 // scanChat is a private helper for scanning chat rows
@@ -459,7 +572,16 @@ func (r *SQLiteRepository) TruncateAllChats() error {
 	}
 	defer tx.Rollback()
 
-	// Delete messages first (foreign key constraint)
+	// Delete media links and messages first (foreign key constraint)
+	_, err = tx.Exec("DELETE FROM message_media")
+	if err != nil {
+		return fmt.Errorf("failed to delete media links: %w", err)
+	}
+	// Uploads belong to the logged out account
+	_, err = tx.Exec("DELETE FROM media_uploads")
+	if err != nil {
+		return fmt.Errorf("failed to delete media uploads: %w", err)
+	}
 	_, err = tx.Exec("DELETE FROM messages")
 	if err != nil {
 		return fmt.Errorf("failed to delete messages: %w", err)
@@ -471,6 +593,24 @@ func (r *SQLiteRepository) TruncateAllChats() error {
 		return fmt.Errorf("failed to delete chats: %w", err)
 	}
 
+	// Delete call log
+	_, err = tx.Exec("DELETE FROM calls")
+	if err != nil {
+		return fmt.Errorf("failed to delete calls: %w", err)
+	}
+
+	// Delete group event history
+	_, err = tx.Exec("DELETE FROM group_events")
+	if err != nil {
+		return fmt.Errorf("failed to delete group events: %w", err)
+	}
+
+	// Delete idempotency keys
+	_, err = tx.Exec("DELETE FROM idempotency_keys")
+	if err != nil {
+		return fmt.Errorf("failed to delete idempotency keys: %w", err)
+	}
+
 	return tx.Commit()
 }
 
@@ -579,6 +719,7 @@ func (r *SQLiteRepository) CreateMessage(ctx context.Context, evt *events.Messag
 		FileSHA256:    fileSHA256,
 		FileEncSHA256: fileEncSHA256,
 		FileLength:    fileLength,
+		Thumbnail:     evt.Message.GetImageMessage().GetJPEGThumbnail(),
 	}
 
 	// Store the message
@@ -634,7 +775,10 @@ func (r *SQLiteRepository) TruncateAllDataWithLogging(logPrefix string) error {
 }
 
 // StoreSentMessageWithContext stores a message that was sent by the user with context cancellation support
-func (r *SQLiteRepository) StoreSentMessageWithContext(ctx context.Context, messageID string, senderJID string, recipientJID string, content string, timestamp time.Time) error {
+func (r *SQLiteRepository) StoreSentMessageWithContext(ctx context.Context, messageID string, senderJID string, recipientJID string, content string, timestamp time.Time) (err error) {
+	ctx, span := tracing.Start(ctx, "chatstorage.StoreSentMessage")
+	defer func() { tracing.End(span, err) }()
+
 	// Check if context is already cancelled before starting
 	select {
 	case <-ctx.Done():
@@ -702,6 +846,616 @@ func (r *SQLiteRepository) StoreSentMessageWithContext(ctx context.Context, mess
 	return r.StoreMessage(message)
 }
 
+// StoreCall creates or updates a call log entry
+func (r *SQLiteRepository) StoreCall(call *domainChatStorage.Call) error {
+	now := time.Now()
+	call.UpdatedAt = now
+
+	query := `
+		INSERT INTO calls (id, caller_jid, group_jid, is_video, is_group, status, reason, auto_rejected, started_at, ended_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			status = excluded.status,
+			reason = excluded.reason,
+			auto_rejected = excluded.auto_rejected,
+			ended_at = excluded.ended_at,
+			updated_at = excluded.updated_at
+	`
+
+	_, err := r.db.Exec(query, call.ID, call.CallerJID, call.GroupJID, call.IsVideo, call.IsGroup, call.Status,
+		call.Reason, call.AutoRejected, call.StartedAt, call.EndedAt, now, call.UpdatedAt)
+	return err
+}
+
+// GetCall retrieves a call log entry by call ID
+func (r *SQLiteRepository) GetCall(id string) (*domainChatStorage.Call, error) {
+	query := `
+		SELECT id, caller_jid, group_jid, is_video, is_group, status, reason, auto_rejected,
+			started_at, ended_at, created_at, updated_at
+		FROM calls
+		WHERE id = ?
+	`
+
+	call, err := r.scanCall(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return call, err
+}
+
+// GetCalls retrieves call log entries with filtering, newest first
+func (r *SQLiteRepository) GetCalls(filter *domainChatStorage.CallFilter) ([]*domainChatStorage.Call, error) {
+	conditions, args := r.buildCallConditions(filter)
+
+	query := `
+		SELECT id, caller_jid, group_jid, is_video, is_group, status, reason, auto_rejected,
+			started_at, ended_at, created_at, updated_at
+		FROM calls
+	`
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY started_at DESC"
+
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+
+		if filter.Offset > 0 {
+			query += " OFFSET ?"
+			args = append(args, filter.Offset)
+		}
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var calls []*domainChatStorage.Call
+	for rows.Next() {
+		call, err := r.scanCall(rows)
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, call)
+	}
+
+	return calls, rows.Err()
+}
+
+// GetCallCount returns the number of call log entries matching the filter
+func (r *SQLiteRepository) GetCallCount(filter *domainChatStorage.CallFilter) (int64, error) {
+	conditions, args := r.buildCallConditions(filter)
+
+	query := "SELECT COUNT(*) FROM calls"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	return r.getCount(query, args...)
+}
+
+// buildCallConditions is a private helper building the WHERE conditions for call log queries
+func (r *SQLiteRepository) buildCallConditions(filter *domainChatStorage.CallFilter) (conditions []string, args []any) {
+	if filter.CallerJID != "" {
+		conditions = append(conditions, "caller_jid = ?")
+		args = append(args, filter.CallerJID)
+	}
+
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+
+	return conditions, args
+}
+
+// scanCall is a private helper for scanning call rows
+func (r *SQLiteRepository) scanCall(scanner interface{ Scan(...any) error }) (*domainChatStorage.Call, error) {
+	call := &domainChatStorage.Call{}
+	var endedAt sql.NullTime
+	err := scanner.Scan(
+		&call.ID, &call.CallerJID, &call.GroupJID, &call.IsVideo, &call.IsGroup, &call.Status,
+		&call.Reason, &call.AutoRejected, &call.StartedAt, &endedAt, &call.CreatedAt, &call.UpdatedAt,
+	)
+	if endedAt.Valid {
+		call.EndedAt = &endedAt.Time
+	}
+	return call, err
+}
+
+// StoreGroupEvent appends an entry to the group event history
+func (r *SQLiteRepository) StoreGroupEvent(event *domainChatStorage.GroupEvent) error {
+	event.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO group_events (group_jid, event_type, actor_jid, old_value, new_value, participants, timestamp, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.Exec(query, event.GroupJID, event.EventType, event.ActorJID, event.OldValue, event.NewValue,
+		strings.Join(event.Participants, ","), event.Timestamp, event.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	event.ID, err = result.LastInsertId()
+	return err
+}
+
+// GetLastGroupEvent retrieves the most recent event of a type for a group
+func (r *SQLiteRepository) GetLastGroupEvent(groupJID, eventType string) (*domainChatStorage.GroupEvent, error) {
+	query := `
+		SELECT id, group_jid, event_type, actor_jid, old_value, new_value, participants, timestamp, created_at
+		FROM group_events
+		WHERE group_jid = ? AND event_type = ?
+		ORDER BY timestamp DESC, id DESC
+		LIMIT 1
+	`
+
+	event, err := r.scanGroupEvent(r.db.QueryRow(query, groupJID, eventType))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return event, err
+}
+
+// GetGroupEvents retrieves group event history with filtering, newest first
+func (r *SQLiteRepository) GetGroupEvents(filter *domainChatStorage.GroupEventFilter) ([]*domainChatStorage.GroupEvent, error) {
+	conditions, args := r.buildGroupEventConditions(filter)
+
+	query := `
+		SELECT id, group_jid, event_type, actor_jid, old_value, new_value, participants, timestamp, created_at
+		FROM group_events
+	`
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY timestamp DESC, id DESC"
+
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+
+		if filter.Offset > 0 {
+			query += " OFFSET ?"
+			args = append(args, filter.Offset)
+		}
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groupEvents []*domainChatStorage.GroupEvent
+	for rows.Next() {
+		event, err := r.scanGroupEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		groupEvents = append(groupEvents, event)
+	}
+
+	return groupEvents, rows.Err()
+}
+
+// GetGroupEventCount returns the number of group events matching the filter
+func (r *SQLiteRepository) GetGroupEventCount(filter *domainChatStorage.GroupEventFilter) (int64, error) {
+	conditions, args := r.buildGroupEventConditions(filter)
+
+	query := "SELECT COUNT(*) FROM group_events"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	return r.getCount(query, args...)
+}
+
+// buildGroupEventConditions is a private helper building the WHERE conditions for group event queries
+func (r *SQLiteRepository) buildGroupEventConditions(filter *domainChatStorage.GroupEventFilter) (conditions []string, args []any) {
+	if filter.GroupJID != "" {
+		conditions = append(conditions, "group_jid = ?")
+		args = append(args, filter.GroupJID)
+	}
+
+	if filter.EventType != "" {
+		conditions = append(conditions, "event_type = ?")
+		args = append(args, filter.EventType)
+	}
+
+	return conditions, args
+}
+
+// scanGroupEvent is a private helper for scanning group event rows
+func (r *SQLiteRepository) scanGroupEvent(scanner interface{ Scan(...any) error }) (*domainChatStorage.GroupEvent, error) {
+	event := &domainChatStorage.GroupEvent{}
+	var oldValue sql.NullString
+	var participants string
+	err := scanner.Scan(
+		&event.ID, &event.GroupJID, &event.EventType, &event.ActorJID, &oldValue, &event.NewValue,
+		&participants, &event.Timestamp, &event.CreatedAt,
+	)
+	if oldValue.Valid {
+		event.OldValue = &oldValue.String
+	}
+	if participants != "" {
+		event.Participants = strings.Split(participants, ",")
+	}
+	return event, err
+}
+
+// ClaimIdempotencyKey stores a pending idempotency key. When the key is already in use and not expired,
+// nothing is stored and the existing record is returned instead. A pending key claimed before
+// pendingBefore belongs to a request that crashed or stalled and is claimed again.
+func (r *SQLiteRepository) ClaimIdempotencyKey(record *domainChatStorage.IdempotencyKey, pendingBefore time.Time) (*domainChatStorage.IdempotencyKey, error) {
+	now := time.Now()
+	record.CreatedAt = now
+	record.Status = domainChatStorage.IdempotencyStatusPending
+
+	// Expired and abandoned keys can be reused, drop them before claiming
+	_, err := r.db.Exec("DELETE FROM idempotency_keys WHERE expires_at <= ? OR (status = ? AND created_at <= ?)",
+		now, domainChatStorage.IdempotencyStatusPending, pendingBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT OR IGNORE INTO idempotency_keys (key, operation, request_hash, status, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.Exec(query, record.Key, record.Operation, record.RequestHash, record.Status, record.CreatedAt, record.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if inserted, err := result.RowsAffected(); err != nil || inserted == 1 {
+		return nil, err
+	}
+
+	existing := &domainChatStorage.IdempotencyKey{}
+	err = r.db.QueryRow(`
+		SELECT key, operation, request_hash, status, response, created_at, expires_at
+		FROM idempotency_keys
+		WHERE key = ?
+	`, record.Key).Scan(
+		&existing.Key, &existing.Operation, &existing.RequestHash, &existing.Status,
+		&existing.Response, &existing.CreatedAt, &existing.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+// CompleteIdempotencyKey stores the response of a finished request. claimedAt is the CreatedAt of the
+// claim, a key claimed again by a retry in the meantime is left alone.
+func (r *SQLiteRepository) CompleteIdempotencyKey(key string, claimedAt time.Time, response string) error {
+	_, err := r.db.Exec("UPDATE idempotency_keys SET status = ?, response = ? WHERE key = ? AND created_at = ?",
+		domainChatStorage.IdempotencyStatusCompleted, response, key, claimedAt)
+	return err
+}
+
+// DeleteIdempotencyKey releases the claim made at claimedAt so the request can be retried
+func (r *SQLiteRepository) DeleteIdempotencyKey(key string, claimedAt time.Time) error {
+	_, err := r.db.Exec("DELETE FROM idempotency_keys WHERE key = ? AND created_at = ?", key, claimedAt)
+	return err
+}
+
+// StoreRetentionPolicy creates or replaces the retention policy of a chat
+func (r *SQLiteRepository) StoreRetentionPolicy(policy *domainChatStorage.RetentionPolicy) error {
+	policy.UpdatedAt = time.Now()
+	_, err := r.db.Exec(`
+		INSERT INTO retention_policies (chat_jid, max_age_days, max_messages, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(chat_jid) DO UPDATE SET
+			max_age_days = excluded.max_age_days,
+			max_messages = excluded.max_messages,
+			updated_at = excluded.updated_at
+	`, policy.ChatJID, policy.MaxAgeDays, policy.MaxMessages, policy.UpdatedAt)
+	return err
+}
+
+// GetRetentionPolicies returns the retention policies of all chats that have one
+func (r *SQLiteRepository) GetRetentionPolicies() ([]*domainChatStorage.RetentionPolicy, error) {
+	rows, err := r.db.Query("SELECT chat_jid, max_age_days, max_messages, updated_at FROM retention_policies ORDER BY chat_jid")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var policies []*domainChatStorage.RetentionPolicy
+	for rows.Next() {
+		policy := &domainChatStorage.RetentionPolicy{}
+		if err := rows.Scan(&policy.ChatJID, &policy.MaxAgeDays, &policy.MaxMessages, &policy.UpdatedAt); err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+
+	return policies, rows.Err()
+}
+
+// DeleteRetentionPolicy removes the policy of a chat, which then follows the global policy again
+func (r *SQLiteRepository) DeleteRetentionPolicy(chatJID string) error {
+	_, err := r.db.Exec("DELETE FROM retention_policies WHERE chat_jid = ?", chatJID)
+	return err
+}
+
+// PurgeMessages removes the messages of a chat older than before and beyond its newest keep messages,
+// together with their media links. A chat left without messages whose last message is older than
+// before is removed too. With dryRun nothing is deleted and the result describes what would be.
+// before may be in any location, it is bound in UTC like the stored timestamps.
+func (r *SQLiteRepository) PurgeMessages(chatJID string, before *time.Time, keep int, dryRun bool) (*domainChatStorage.RetentionPurge, error) {
+	purge := &domainChatStorage.RetentionPurge{ChatJID: chatJID}
+
+	var limits []string
+	args := []any{chatJID}
+	if before != nil {
+		limits = append(limits, "timestamp < ?")
+		args = append(args, *before)
+	}
+	if keep > 0 {
+		limits = append(limits, "rowid NOT IN (SELECT rowid FROM messages WHERE chat_jid = ? ORDER BY timestamp DESC, id DESC LIMIT ?)")
+		args = append(args, chatJID, keep)
+	}
+	if len(limits) == 0 {
+		return purge, nil
+	}
+	condition := "chat_jid = ? AND (" + strings.Join(limits, " OR ") + ")"
+	mediaArgs := append([]any{chatJID}, args...)
+	unreferencedArgs := append(append([]any{}, mediaArgs...), mediaArgs...)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow("SELECT COUNT(*) FROM messages WHERE "+condition, args...).Scan(&purge.Messages); err != nil {
+		return nil, fmt.Errorf("failed to count messages: %w", err)
+	}
+
+	// A media file shared with messages that are kept stays on disk
+	rows, err := tx.Query(`
+		SELECT DISTINCT path FROM message_media purged
+		WHERE chat_jid = ? AND message_id IN (SELECT id FROM messages WHERE `+condition+`)
+		AND NOT EXISTS (
+			SELECT 1 FROM message_media kept
+			WHERE kept.path = purged.path
+			AND NOT (kept.chat_jid = ? AND kept.message_id IN (SELECT id FROM messages WHERE `+condition+`))
+		)
+	`, unreferencedArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find media files: %w", err)
+	}
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		purge.MediaPaths = append(purge.MediaPaths, path)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if before != nil {
+		var total int64
+		var lastMessageTime time.Time
+		if err := tx.QueryRow("SELECT COUNT(*) FROM messages WHERE chat_jid = ?", chatJID).Scan(&total); err != nil {
+			return nil, err
+		}
+		err := tx.QueryRow("SELECT last_message_time FROM chats WHERE jid = ?", chatJID).Scan(&lastMessageTime)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		purge.ChatDeleted = err == nil && total == purge.Messages && lastMessageTime.Before(*before)
+	}
+
+	if dryRun || (purge.Messages == 0 && !purge.ChatDeleted) {
+		return purge, nil
+	}
+
+	if _, err := tx.Exec("DELETE FROM message_media WHERE chat_jid = ? AND message_id IN (SELECT id FROM messages WHERE "+condition+")", mediaArgs...); err != nil {
+		return nil, fmt.Errorf("failed to delete media links: %w", err)
+	}
+	for _, path := range purge.MediaPaths {
+		if _, err := tx.Exec("DELETE FROM media_files WHERE path = ?", path); err != nil {
+			return nil, fmt.Errorf("failed to delete media file %s: %w", path, err)
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM messages WHERE "+condition, args...); err != nil {
+		return nil, fmt.Errorf("failed to delete messages: %w", err)
+	}
+	if purge.ChatDeleted {
+		if _, err := tx.Exec("DELETE FROM chats WHERE jid = ?", chatJID); err != nil {
+			return nil, fmt.Errorf("failed to delete chat: %w", err)
+		}
+	}
+
+	return purge, tx.Commit()
+}
+
+// StoreRetentionAudit records the outcome of a retention run
+func (r *SQLiteRepository) StoreRetentionAudit(audit *domainChatStorage.RetentionAudit) error {
+	result, err := r.db.Exec(`
+		INSERT INTO retention_audit (
+			run_at, chat_jid, max_age_days, max_messages, messages_deleted, media_deleted, chat_deleted
+		) VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, audit.RunAt, audit.ChatJID, audit.MaxAgeDays, audit.MaxMessages, audit.MessagesDeleted, audit.MediaDeleted, audit.ChatDeleted)
+	if err != nil {
+		return err
+	}
+
+	audit.ID, err = result.LastInsertId()
+	return err
+}
+
+// StoreMediaFile links a downloaded media file to its message, so it is deleted with the message,
+// and records it as the local copy of the message media
+func (r *SQLiteRepository) StoreMediaFile(chatJID, messageID, path string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO message_media (path, chat_jid, message_id) VALUES (?, ?, ?)
+		ON CONFLICT(chat_jid, message_id, path) DO NOTHING
+	`, path, chatJID, messageID)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec("UPDATE messages SET media_path = ? WHERE chat_jid = ? AND id = ?", path, chatJID, messageID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// IsMediaFileTracked reports whether a media file is linked to a message
+func (r *SQLiteRepository) IsMediaFileTracked(path string) (bool, error) {
+	var tracked bool
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM message_media WHERE path = ?)", path).Scan(&tracked)
+	return tracked, err
+}
+
+// PurgeUnreferencedMedia forgets the media files that no message links to anymore, for example after
+// their chat was deleted, and returns their paths so the files can be removed
+func (r *SQLiteRepository) PurgeUnreferencedMedia(dryRun bool) ([]string, error) {
+	const unreferenced = "NOT EXISTS (SELECT 1 FROM message_media WHERE message_media.path = media_files.path)"
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT path FROM media_files WHERE " + unreferenced)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find unreferenced media: %w", err)
+	}
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if dryRun || len(paths) == 0 {
+		return paths, nil
+	}
+	if _, err := tx.Exec("DELETE FROM media_files WHERE " + unreferenced); err != nil {
+		return nil, fmt.Errorf("failed to delete unreferenced media: %w", err)
+	}
+	return paths, tx.Commit()
+}
+
+// Vacuum rebuilds the database file to return the space of deleted rows to the file system
+func (r *SQLiteRepository) Vacuum() error {
+	_, err := r.db.Exec("VACUUM")
+	return err
+}
+
+// StoreMediaContent records file as the copy of its content, replacing the previous copy
+func (r *SQLiteRepository) StoreMediaContent(file *domainChatStorage.MediaFile) error {
+	_, err := r.db.Exec(`
+		INSERT INTO media_files (sha256, path, mime_type, size) VALUES (?, ?, ?, ?)
+		ON CONFLICT(sha256) DO UPDATE SET path = excluded.path, mime_type = excluded.mime_type, size = excluded.size
+	`, r.mediaKey(file.SHA256), file.Path, file.MimeType, file.Size)
+	return err
+}
+
+// GetMediaContent returns the stored copy of the content with the hex SHA-256 sha256, nil when there is none
+func (r *SQLiteRepository) GetMediaContent(sha256 string) (*domainChatStorage.MediaFile, error) {
+	file := &domainChatStorage.MediaFile{SHA256: sha256}
+	err := r.db.QueryRow(`
+		SELECT path, mime_type, size, created_at,
+			(SELECT COUNT(*) FROM message_media WHERE message_media.path = media_files.path)
+		FROM media_files
+		WHERE sha256 = ?
+	`, r.mediaKey(sha256)).Scan(&file.Path, &file.MimeType, &file.Size, &file.CreatedAt, &file.RefCount)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return file, err
+}
+
+// StoreMediaUpload records an upload of media content so later sends of the same content can reuse it
+func (r *SQLiteRepository) StoreMediaUpload(upload *domainChatStorage.MediaUpload) error {
+	key := r.mediaKey(upload.SHA256)
+	mediaKey := upload.MediaKey
+	if r.cipher != nil {
+		var err error
+		if mediaKey, err = r.cipher.EncryptBytes(upload.MediaKey, uploadContext(key, upload.MediaType)); err != nil {
+			return err
+		}
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO media_uploads (
+			sha256, media_type, url, direct_path, media_key, file_enc_sha256, file_length, expires_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(sha256, media_type) DO UPDATE SET
+			url = excluded.url,
+			direct_path = excluded.direct_path,
+			media_key = excluded.media_key,
+			file_enc_sha256 = excluded.file_enc_sha256,
+			file_length = excluded.file_length,
+			expires_at = excluded.expires_at,
+			created_at = CURRENT_TIMESTAMP
+	`, key, upload.MediaType, upload.URL, upload.DirectPath, mediaKey, upload.FileEncSHA256, upload.FileLength, upload.ExpiresAt.UTC())
+	return err
+}
+
+// GetMediaUpload returns the upload of the content with the hex SHA-256 sha256 as mediaType, nil when
+// there is none or it expires before validAt
+func (r *SQLiteRepository) GetMediaUpload(sha256, mediaType string, validAt time.Time) (*domainChatStorage.MediaUpload, error) {
+	key := r.mediaKey(sha256)
+	upload := &domainChatStorage.MediaUpload{SHA256: sha256, MediaType: mediaType}
+	err := r.db.QueryRow(`
+		SELECT url, direct_path, media_key, file_enc_sha256, file_length, expires_at, created_at
+		FROM media_uploads
+		WHERE sha256 = ? AND media_type = ? AND expires_at > ?
+	`, key, mediaType, validAt.UTC()).Scan(
+		&upload.URL, &upload.DirectPath, &upload.MediaKey, &upload.FileEncSHA256,
+		&upload.FileLength, &upload.ExpiresAt, &upload.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if r.cipher != nil {
+		if upload.MediaKey, err = r.cipher.DecryptBytes(upload.MediaKey, uploadContext(key, mediaType)); err != nil {
+			return nil, fmt.Errorf("failed to decrypt media upload: %w", err)
+		}
+	}
+	return upload, nil
+}
+
 // _____________________________________________________________________________________________________________________
 
 // initializeSchema creates or migrates the database schema
@@ -720,7 +1474,7 @@ func (r *SQLiteRepository) InitializeSchema() error {
 		}
 	}
 
-	return nil
+	return r.loadDataKeys()
 }
 
 // getSchemaVersion returns the current schema version
@@ -816,5 +1570,191 @@ func (r *SQLiteRepository) getMigrations() []string {
 		`
 		CREATE INDEX IF NOT EXISTS idx_messages_id ON messages(id);
 		`,
+
+		// Migration 3: Call log
+		`
+		CREATE TABLE IF NOT EXISTS calls (
+			id TEXT PRIMARY KEY,
+			caller_jid TEXT NOT NULL,
+			group_jid TEXT DEFAULT '',
+			is_video BOOLEAN DEFAULT FALSE,
+			is_group BOOLEAN DEFAULT FALSE,
+			status TEXT NOT NULL,
+			reason TEXT DEFAULT '',
+			auto_rejected BOOLEAN DEFAULT FALSE,
+			started_at TIMESTAMP NOT NULL,
+			ended_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_calls_caller_jid ON calls(caller_jid);
+		CREATE INDEX IF NOT EXISTS idx_calls_started_at ON calls(started_at);
+		`,
+
+		// Migration 4: Group event history
+		`
+		CREATE TABLE IF NOT EXISTS group_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			group_jid TEXT NOT NULL,
+			event_type TEXT NOT NULL,
+			actor_jid TEXT DEFAULT '',
+			old_value TEXT,
+			new_value TEXT DEFAULT '',
+			participants TEXT DEFAULT '',
+			timestamp TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_group_events_group_jid ON group_events(group_jid, event_type, timestamp);
+		CREATE INDEX IF NOT EXISTS idx_group_events_timestamp ON group_events(timestamp);
+		`,
+
+		// Migration 5: Idempotency keys for send requests
+		`
+		CREATE TABLE IF NOT EXISTS idempotency_keys (
+			key TEXT PRIMARY KEY,
+			operation TEXT NOT NULL,
+			request_hash TEXT NOT NULL,
+			status TEXT NOT NULL,
+			response TEXT DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+		`,
+
+		// Migration 6: Keyset pagination indexes for messages and chats
+		`
+		CREATE INDEX IF NOT EXISTS idx_messages_chat_timestamp_id ON messages(chat_jid, timestamp, id);
+		CREATE INDEX IF NOT EXISTS idx_chats_last_message_jid ON chats(last_message_time, jid);
+		`,
+
+		// Migration 7: Wrapped data keys for encryption at rest
+		`
+		CREATE TABLE IF NOT EXISTS encryption_keys (
+			id TEXT PRIMARY KEY,
+			wrapped_key BLOB NOT NULL,
+			kek_id TEXT NOT NULL,
+			active BOOLEAN DEFAULT FALSE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		`,
+
+		// Migration 8: Retention policies, media files of messages and the retention audit log
+		`
+		CREATE TABLE IF NOT EXISTS retention_policies (
+			chat_jid TEXT PRIMARY KEY,
+			max_age_days INTEGER DEFAULT 0,
+			max_messages INTEGER DEFAULT 0,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS message_media (
+			path TEXT PRIMARY KEY,
+			chat_jid TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_message_media_message ON message_media(chat_jid, message_id);
+
+		CREATE TABLE IF NOT EXISTS retention_audit (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			run_at TIMESTAMP NOT NULL,
+			chat_jid TEXT DEFAULT '',
+			max_age_days INTEGER DEFAULT 0,
+			max_messages INTEGER DEFAULT 0,
+			messages_deleted INTEGER DEFAULT 0,
+			media_deleted INTEGER DEFAULT 0,
+			chat_deleted BOOLEAN DEFAULT FALSE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_retention_audit_run_at ON retention_audit(run_at);
+		`,
+
+		// Migration 9: Local path of downloaded media
+		`
+		ALTER TABLE messages ADD COLUMN media_path TEXT DEFAULT '';
+		`,
+
+		// Migration 10: Media store keyed by content, media files shared by several messages and uploads
+		// reused by sends
+		`
+		CREATE TABLE IF NOT EXISTS media_files (
+			sha256 TEXT PRIMARY KEY,
+			path TEXT NOT NULL,
+			mime_type TEXT DEFAULT '',
+			size INTEGER DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_media_files_path ON media_files(path);
+
+		CREATE TABLE IF NOT EXISTS media_uploads (
+			sha256 TEXT NOT NULL,
+			media_type TEXT NOT NULL,
+			url TEXT NOT NULL,
+			direct_path TEXT NOT NULL,
+			media_key BLOB NOT NULL,
+			file_enc_sha256 BLOB,
+			file_length INTEGER DEFAULT 0,
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (sha256, media_type)
+		);
+
+		CREATE TABLE message_media_links (
+			path TEXT NOT NULL,
+			chat_jid TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (chat_jid, message_id, path)
+		);
+
+		INSERT INTO message_media_links (path, chat_jid, message_id, created_at)
+		SELECT path, chat_jid, message_id, created_at FROM message_media;
+
+		DROP TABLE message_media;
+
+		ALTER TABLE message_media_links RENAME TO message_media;
+
+		CREATE INDEX IF NOT EXISTS idx_message_media_path ON message_media(path);
+		`,
+
+		// Migration 11: Media paths become media storage keys, relative to the statics/media folder
+		`
+		UPDATE messages SET media_path = substr(media_path, 15) WHERE media_path LIKE 'statics/media/%';
+		UPDATE message_media SET path = substr(path, 15) WHERE path LIKE 'statics/media/%';
+		UPDATE media_files SET path = substr(path, 15) WHERE path LIKE 'statics/media/%';
+		`,
+
+		// Migration 12: Times compared in queries move to UTC. They were stored in the offset of the host,
+		// as "2006-01-02 15:04:05.999999999-07:00" text, and compare as text. The fraction of a second
+		// is kept, datetime() only returns whole seconds.
+		`
+		UPDATE messages SET timestamp = datetime(timestamp) || substr(timestamp, 20, length(timestamp) - 25) || '+00:00'
+		WHERE typeof(timestamp) = 'text' AND length(timestamp) >= 25 AND substr(timestamp, -6) != '+00:00';
+		UPDATE chats SET last_message_time = datetime(last_message_time) || substr(last_message_time, 20, length(last_message_time) - 25) || '+00:00'
+		WHERE typeof(last_message_time) = 'text' AND length(last_message_time) >= 25 AND substr(last_message_time, -6) != '+00:00';
+		UPDATE group_events SET timestamp = datetime(timestamp) || substr(timestamp, 20, length(timestamp) - 25) || '+00:00'
+		WHERE typeof(timestamp) = 'text' AND length(timestamp) >= 25 AND substr(timestamp, -6) != '+00:00';
+		UPDATE calls SET started_at = datetime(started_at) || substr(started_at, 20, length(started_at) - 25) || '+00:00'
+		WHERE typeof(started_at) = 'text' AND length(started_at) >= 25 AND substr(started_at, -6) != '+00:00';
+		UPDATE calls SET ended_at = datetime(ended_at) || substr(ended_at, 20, length(ended_at) - 25) || '+00:00'
+		WHERE typeof(ended_at) = 'text' AND length(ended_at) >= 25 AND substr(ended_at, -6) != '+00:00';
+		UPDATE idempotency_keys SET expires_at = datetime(expires_at) || substr(expires_at, 20, length(expires_at) - 25) || '+00:00'
+		WHERE typeof(expires_at) = 'text' AND length(expires_at) >= 25 AND substr(expires_at, -6) != '+00:00';
+		UPDATE media_uploads SET expires_at = datetime(expires_at) || substr(expires_at, 20, length(expires_at) - 25) || '+00:00'
+		WHERE typeof(expires_at) = 'text' AND length(expires_at) >= 25 AND substr(expires_at, -6) != '+00:00';
+		UPDATE retention_audit SET run_at = datetime(run_at) || substr(run_at, 20, length(run_at) - 25) || '+00:00'
+		WHERE typeof(run_at) = 'text' AND length(run_at) >= 25 AND substr(run_at, -6) != '+00:00';
+		`,
+
+		// Migration 13: JPEG preview embedded in image messages, used when the message is quoted
+		`
+		ALTER TABLE messages ADD COLUMN thumbnail BLOB;
+		`,
 	}
 }
@@ -0,0 +1,250 @@
+package chatstorage_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"path/filepath"
+	"testing"
+	"time"
+
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/fieldcrypt"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testChatJID = "6281234567890@s.whatsapp.net"
+
+func newRepository(t *testing.T) (domainChatStorage.IChatStorageRepository, *sql.DB) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "chatstorage.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	repo := chatstorage.NewStorageRepository(db)
+	require.NoError(t, repo.InitializeSchema())
+	return repo, db
+}
+
+// inZone runs the test with zone as the local time zone
+func inZone(t *testing.T, zone *time.Location) {
+	local := time.Local
+	time.Local = zone
+	t.Cleanup(func() { time.Local = local })
+}
+
+func messageIDs(messages []*domainChatStorage.Message) []string {
+	ids := make([]string, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+	}
+	return ids
+}
+
+func TestGetMessagesKeysetAcrossOffsets(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	newYork := time.FixedZone("EST", -5*60*60)
+	inZone(t, jakarta)
+	repo, _ := newRepository(t)
+
+	// Oldest first: a at 02:00Z, b at 03:00Z, c at 04:30Z. As local text c sorts before a and b.
+	stored := []*domainChatStorage.Message{
+		{ID: "a", Timestamp: time.Date(2025, 1, 1, 9, 0, 0, 0, jakarta)},
+		{ID: "b", Timestamp: time.Date(2025, 1, 1, 10, 0, 0, 0, jakarta)},
+		{ID: "c", Timestamp: time.Date(2024, 12, 31, 23, 30, 0, 0, newYork)},
+	}
+	for _, message := range stored {
+		message.ChatJID = testChatJID
+		message.Content = "message " + message.ID
+		require.NoError(t, repo.StoreMessage(message))
+	}
+
+	tests := []struct {
+		name   string
+		cursor *domainChatStorage.Cursor
+		want   []string
+	}{
+		{name: "should list newest first", want: []string{"c", "b", "a"}},
+		{
+			name:   "should page after a cursor in another offset",
+			cursor: &domainChatStorage.Cursor{Timestamp: stored[2].Timestamp, ID: "c"},
+			want:   []string{"b", "a"},
+		},
+		{
+			name:   "should page after a utc cursor",
+			cursor: &domainChatStorage.Cursor{Timestamp: stored[1].Timestamp.UTC(), ID: "b"},
+			want:   []string{"a"},
+		},
+		{
+			name:   "should page before a cursor oldest first",
+			cursor: &domainChatStorage.Cursor{Timestamp: stored[0].Timestamp, ID: "a", Previous: true},
+			want:   []string{"b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.cursor != nil {
+				// Clients hand back the encoded token
+				decoded, err := domainChatStorage.DecodeCursor(tt.cursor.Encode())
+				require.NoError(t, err)
+				tt.cursor = decoded
+			}
+
+			messages, err := repo.GetMessages(&domainChatStorage.MessageFilter{ChatJID: testChatJID, Limit: 10, Cursor: tt.cursor})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, messageIDs(messages))
+		})
+	}
+
+	t.Run("should filter by time across offsets", func(t *testing.T) {
+		start := time.Date(2025, 1, 1, 2, 30, 0, 0, time.UTC)
+		messages, err := repo.GetMessages(&domainChatStorage.MessageFilter{ChatJID: testChatJID, StartTime: &start})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"c", "b"}, messageIDs(messages))
+	})
+}
+
+func TestGetChatsKeysetAcrossOffsets(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	inZone(t, jakarta)
+	repo, _ := newRepository(t)
+
+	chats := []*domainChatStorage.Chat{
+		{JID: "a@s.whatsapp.net", LastMessageTime: time.Date(2025, 1, 1, 10, 0, 0, 0, jakarta)},
+		{JID: "b@s.whatsapp.net", LastMessageTime: time.Date(2025, 1, 1, 4, 0, 0, 0, time.UTC)},
+	}
+	for _, chat := range chats {
+		chat.Name = chat.JID
+		require.NoError(t, repo.StoreChat(chat))
+	}
+
+	page, err := repo.GetChats(&domainChatStorage.ChatFilter{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "b@s.whatsapp.net", page[0].JID)
+
+	cursor := domainChatStorage.Cursor{Timestamp: page[0].LastMessageTime, ID: page[0].JID}
+	page, err = repo.GetChats(&domainChatStorage.ChatFilter{Limit: 1, Cursor: &cursor})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "a@s.whatsapp.net", page[0].JID)
+}
+
+func TestMigrateTimesToUTC(t *testing.T) {
+	repo, db := newRepository(t)
+
+	// Rows stored before times were bound in UTC
+	_, err := db.Exec(`INSERT INTO messages (id, chat_jid, sender, content, timestamp, media_type, filename, url) VALUES
+		('a', ?, '', 'a', '2025-01-01 09:00:00.25+07:00', '', '', ''),
+		('b', ?, '', 'b', '2025-01-01 03:00:00+00:00', '', '', ''),
+		('c', ?, '', 'c', '2024-12-31 23:30:00.5-05:00', '', '', '')`, testChatJID, testChatJID, testChatJID)
+	require.NoError(t, err)
+
+	// Roll back to schema version 11, later migrations run again
+	_, err = db.Exec("DELETE FROM schema_info WHERE version >= 12")
+	require.NoError(t, err)
+	_, err = db.Exec("ALTER TABLE messages DROP COLUMN thumbnail")
+	require.NoError(t, err)
+	require.NoError(t, repo.InitializeSchema())
+
+	rows, err := db.Query("SELECT CAST(timestamp AS TEXT) FROM messages ORDER BY id")
+	require.NoError(t, err)
+	defer rows.Close()
+	var timestamps []string
+	for rows.Next() {
+		var timestamp string
+		require.NoError(t, rows.Scan(&timestamp))
+		timestamps = append(timestamps, timestamp)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{
+		"2025-01-01 02:00:00.25+00:00",
+		"2025-01-01 03:00:00+00:00",
+		"2025-01-01 04:30:00.5+00:00",
+	}, timestamps)
+
+	messages, err := repo.GetMessages(&domainChatStorage.MessageFilter{ChatJID: testChatJID})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "b", "a"}, messageIDs(messages))
+	assert.True(t, messages[0].Timestamp.Equal(time.Date(2025, 1, 1, 4, 30, 0, 5e8, time.UTC)))
+}
+
+func TestPurgeMessagesAcrossOffsets(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	inZone(t, jakarta)
+	repo, _ := newRepository(t)
+
+	require.NoError(t, repo.StoreChat(&domainChatStorage.Chat{
+		JID:             testChatJID,
+		Name:            "chat",
+		LastMessageTime: time.Date(2025, 1, 1, 10, 0, 0, 0, jakarta),
+	}))
+	// old at 02:00Z and new at 03:00Z, stored with the +07:00 offset of the host
+	for _, message := range []*domainChatStorage.Message{
+		{ID: "old", Timestamp: time.Date(2025, 1, 1, 9, 0, 0, 0, jakarta)},
+		{ID: "new", Timestamp: time.Date(2025, 1, 1, 10, 0, 0, 0, jakarta)},
+	} {
+		message.ChatJID = testChatJID
+		message.Content = message.ID
+		require.NoError(t, repo.StoreMessage(message))
+	}
+
+	tests := []struct {
+		name   string
+		before time.Time
+		want   int64
+	}{
+		{name: "should keep messages newer than a utc cutoff", before: time.Date(2025, 1, 1, 1, 30, 0, 0, time.UTC), want: 0},
+		{name: "should purge messages older than a utc cutoff", before: time.Date(2025, 1, 1, 2, 30, 0, 0, time.UTC), want: 1},
+		{name: "should purge messages older than a cutoff in another offset", before: time.Date(2024, 12, 31, 22, 30, 0, 0, time.FixedZone("EST", -5*60*60)), want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			purge, err := repo.PurgeMessages(testChatJID, &tt.before, 0, true)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, purge.Messages)
+			assert.Equal(t, tt.want == 2, purge.ChatDeleted)
+		})
+	}
+
+	t.Run("should delete only the purged messages", func(t *testing.T) {
+		before := time.Date(2025, 1, 1, 2, 30, 0, 0, time.UTC)
+		purge, err := repo.PurgeMessages(testChatJID, &before, 0, false)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), purge.Messages)
+
+		messages, err := repo.GetMessages(&domainChatStorage.MessageFilter{ChatJID: testChatJID})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"new"}, messageIDs(messages))
+	})
+}
+
+func TestStoreMessageEncryptsThumbnail(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "chatstorage.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	keys, err := fieldcrypt.ParseKeys("k1:" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, fieldcrypt.KeySize)))
+	require.NoError(t, err)
+	keyring, err := fieldcrypt.NewKeyring(keys)
+	require.NoError(t, err)
+	repo := chatstorage.NewEncryptedStorageRepository(db, keyring)
+	require.NoError(t, repo.InitializeSchema())
+
+	thumbnail := []byte("\xff\xd8 jpeg preview")
+	require.NoError(t, repo.StoreMessage(&domainChatStorage.Message{
+		ID: "IMG", ChatJID: testChatJID, Sender: testChatJID, MediaType: "image",
+		Timestamp: time.Now(), Thumbnail: thumbnail,
+	}))
+
+	var stored []byte
+	require.NoError(t, db.QueryRow(`SELECT thumbnail FROM messages WHERE id = 'IMG'`).Scan(&stored))
+	assert.NotContains(t, string(stored), "jpeg preview", "the thumbnail is encrypted at rest")
+
+	message, err := repo.GetMessageByID("IMG")
+	require.NoError(t, err)
+	assert.Equal(t, thumbnail, message.Thumbnail)
+}
@@ -0,0 +1,141 @@
+package mediastorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	domainMediaStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/mediastorage"
+)
+
+// tempPrefix marks files that are still being written
+const tempPrefix = ".tmp-"
+
+type localStorage struct {
+	root string
+}
+
+// NewLocalStorage returns a storage keeping media as files under root
+func NewLocalStorage(root string) domainMediaStorage.IMediaStorage {
+	return &localStorage{root: filepath.Clean(root)}
+}
+
+// Put writes the file next to its final path first and renames it, so readers never see a partial file
+func (s *localStorage) Put(_ context.Context, key string, body io.Reader, _ int64, _ string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
+
+func (s *localStorage) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, domainMediaStorage.ErrNotFound
+	}
+	return file, err
+}
+
+func (s *localStorage) Stat(_ context.Context, key string) (domainMediaStorage.Object, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return domainMediaStorage.Object{}, err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.Mode().IsRegular()) {
+		return domainMediaStorage.Object{}, domainMediaStorage.ErrNotFound
+	}
+	if err != nil {
+		return domainMediaStorage.Object{}, err
+	}
+	return domainMediaStorage.Object{Key: key, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// Delete removes the file and the directories it leaves empty, up to the root
+func (s *localStorage) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for dir := filepath.Dir(path); strings.HasPrefix(dir, s.root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		// Remove fails on directories that still have files, which ends the walk up
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *localStorage) Walk(_ context.Context, fn func(object domainMediaStorage.Object) error) error {
+	return filepath.WalkDir(s.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), tempPrefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+		return fn(domainMediaStorage.Object{Key: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+	})
+}
+
+// URL returns no URL, local files are served by the application itself
+func (s *localStorage) URL(_ context.Context, _ string, _ time.Duration) (string, error) {
+	return "", nil
+}
+
+func (s *localStorage) Locate(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
+
+// path returns the file of key, rejecting keys that would leave the root
+func (s *localStorage) path(key string) (string, error) {
+	name := filepath.FromSlash(key)
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("invalid media key %q", key)
+	}
+	return filepath.Join(s.root, name), nil
+}
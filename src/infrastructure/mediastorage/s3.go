@@ -0,0 +1,75 @@
+package mediastorage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	domainMediaStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/mediastorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/s3"
+)
+
+type s3Storage struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// NewS3Storage returns a storage keeping media as objects of an S3-compatible bucket, with keys
+// under prefix
+func NewS3Storage(config s3.Config, prefix string) (domainMediaStorage.IMediaStorage, error) {
+	client, err := s3.New(config)
+	if err != nil {
+		return nil, err
+	}
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &s3Storage{client: client, bucket: config.Bucket, prefix: prefix}, nil
+}
+
+func (s *s3Storage) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	return s.client.PutObject(ctx, s.prefix+key, body, size, contentType)
+}
+
+func (s *s3Storage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	body, err := s.client.GetObject(ctx, s.prefix+key)
+	return body, notFound(err)
+}
+
+func (s *s3Storage) Stat(ctx context.Context, key string) (domainMediaStorage.Object, error) {
+	info, err := s.client.HeadObject(ctx, s.prefix+key)
+	if err != nil {
+		return domainMediaStorage.Object{}, notFound(err)
+	}
+	return domainMediaStorage.Object{Key: key, Size: info.Size, ModTime: info.LastModified}, nil
+}
+
+func (s *s3Storage) Delete(ctx context.Context, key string) error {
+	return s.client.DeleteObject(ctx, s.prefix+key)
+}
+
+func (s *s3Storage) Walk(ctx context.Context, fn func(object domainMediaStorage.Object) error) error {
+	return s.client.ListObjects(ctx, s.prefix, func(info s3.ObjectInfo) error {
+		return fn(domainMediaStorage.Object{Key: strings.TrimPrefix(info.Key, s.prefix), Size: info.Size, ModTime: info.LastModified})
+	})
+}
+
+// URL returns a presigned URL, expiry is capped at the longest validity S3 accepts
+func (s *s3Storage) URL(_ context.Context, key string, expiry time.Duration) (string, error) {
+	return s.client.PresignGetObject(s.prefix+key, min(expiry, s3.MaxPresignExpiry))
+}
+
+func (s *s3Storage) Locate(key string) string {
+	return "s3://" + s.bucket + "/" + s.prefix + key
+}
+
+func notFound(err error) error {
+	if errors.Is(err, s3.ErrNotFound) {
+		return domainMediaStorage.ErrNotFound
+	}
+	return err
+}
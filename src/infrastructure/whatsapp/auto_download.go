@@ -0,0 +1,146 @@
+package whatsapp
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/autodownload"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// autoDownloadQueueSize is the number of downloads waiting for a worker before new ones are skipped
+const autoDownloadQueueSize = 256
+
+var (
+	autoDownloadPolicy  autodownload.Policy
+	autoDownloadQueue   chan autoDownloadJob
+	autoDownloadMu      sync.RWMutex // guards autoDownloadQueue against StopAutoDownload closing it
+	autoDownloadWorkers sync.WaitGroup
+)
+
+// mediaDownload is the download of the media of one incoming message, done is closed once it finished
+type mediaDownload struct {
+	source whatsmeow.DownloadableMessage
+	done   chan struct{}
+	media  utils.ExtractedMedia
+	err    error
+}
+
+type autoDownloadJob struct {
+	ctx      context.Context
+	evt      *events.Message
+	download *mediaDownload
+}
+
+// StartAutoDownload starts the workers that download incoming media allowed by policy
+func StartAutoDownload(policy autodownload.Policy, workers int) {
+	if !policy.Enabled() || autoDownloadQueue != nil {
+		return
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	autoDownloadPolicy = policy
+	autoDownloadQueue = make(chan autoDownloadJob, autoDownloadQueueSize)
+	for i := 0; i < workers; i++ {
+		autoDownloadWorkers.Add(1)
+		go autoDownloadWorker(autoDownloadQueue)
+	}
+}
+
+// StopAutoDownload stops queueing downloads and waits until the workers finished the queued ones or
+// ctx is done
+func StopAutoDownload(ctx context.Context) error {
+	autoDownloadMu.Lock()
+	if autoDownloadQueue != nil {
+		close(autoDownloadQueue)
+		autoDownloadQueue = nil
+	}
+	autoDownloadMu.Unlock()
+
+	return utils.WaitGroupContext(ctx, &autoDownloadWorkers)
+}
+
+// queueAutoDownload queues the media of evt when the policy allows it. It returns nil when nothing
+// was queued.
+func queueAutoDownload(ctx context.Context, evt *events.Message) *mediaDownload {
+	autoDownloadMu.RLock()
+	defer autoDownloadMu.RUnlock()
+
+	if autoDownloadQueue == nil {
+		return nil
+	}
+	mediaType, media, size := messageMedia(evt.Message)
+	if media == nil || !autoDownloadPolicy.Allows(mediaType, size, evt.Info.Chat) {
+		return nil
+	}
+
+	download := &mediaDownload{source: media, done: make(chan struct{})}
+	select {
+	case autoDownloadQueue <- autoDownloadJob{ctx: ctx, evt: evt, download: download}:
+		return download
+	default:
+		log.Warnf("Auto download queue is full, skipping %s of message %s", mediaType, evt.Info.ID)
+		return nil
+	}
+}
+
+func autoDownloadWorker(queue <-chan autoDownloadJob) {
+	defer autoDownloadWorkers.Done()
+
+	for job := range queue {
+		job.download.media, job.download.err = downloadMessageMedia(job.ctx, job.evt, job.download.source)
+		if job.download.err != nil {
+			log.Warnf("Failed to auto download media of message %s: %v", job.evt.Info.ID, job.download.err)
+		} else {
+			log.Infof("Media of message %s downloaded to %s", job.evt.Info.ID, MediaLocation(job.download.media.MediaPath))
+		}
+		close(job.download.done)
+	}
+}
+
+// downloadMessageMedia returns the local copy of the media of an incoming message, see DownloadMedia
+func downloadMessageMedia(ctx context.Context, evt *events.Message, media whatsmeow.DownloadableMessage) (utils.ExtractedMedia, error) {
+	return DownloadMedia(ctx, MediaRef{ChatJID: evt.Info.Chat.String(), MessageID: evt.Info.ID, Timestamp: evt.Info.Timestamp}, media)
+}
+
+// awaitMessageMedia returns media once download finished, or downloads it itself when media was
+// not queued or the queued download failed. MediaPath holds the location of the file and MediaURL a
+// link to fetch it without API credentials.
+func awaitMessageMedia(ctx context.Context, evt *events.Message, download *mediaDownload, media whatsmeow.DownloadableMessage) (extracted utils.ExtractedMedia, err error) {
+	queued := download != nil && download.source == media
+	if queued {
+		<-download.done
+		extracted, err = download.media, download.err
+	}
+	if !queued || err != nil {
+		extracted, err = downloadMessageMedia(ctx, evt, media)
+	}
+	if err != nil {
+		return extracted, err
+	}
+	extracted.MediaURL = MediaURL(ctx, extracted.MediaPath, media.GetFileSHA256())
+	extracted.MediaPath = MediaLocation(extracted.MediaPath)
+	return extracted, nil
+}
+
+// messageMedia returns the downloadable media of msg with its chat storage media type and size
+func messageMedia(msg *waE2E.Message) (mediaType string, media whatsmeow.DownloadableMessage, size uint64) {
+	switch {
+	case msg.GetImageMessage() != nil:
+		return "image", msg.GetImageMessage(), msg.GetImageMessage().GetFileLength()
+	case msg.GetVideoMessage() != nil:
+		return "video", msg.GetVideoMessage(), msg.GetVideoMessage().GetFileLength()
+	case msg.GetAudioMessage() != nil:
+		return "audio", msg.GetAudioMessage(), msg.GetAudioMessage().GetFileLength()
+	case msg.GetDocumentMessage() != nil:
+		return "document", msg.GetDocumentMessage(), msg.GetDocumentMessage().GetFileLength()
+	case msg.GetStickerMessage() != nil:
+		return "sticker", msg.GetStickerMessage(), msg.GetStickerMessage().GetFileLength()
+	}
+	return "", nil, 0
+}
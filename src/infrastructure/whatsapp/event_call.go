@@ -0,0 +1,232 @@
+package whatsapp
+
+import (
+	"context"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/sirupsen/logrus"
+	waBinary "go.mau.fi/whatsmeow/binary"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// callerJID returns the JID of the user who started the call, without device part
+func callerJID(meta types.BasicCallMeta) types.JID {
+	if !meta.CallCreator.IsEmpty() {
+		return meta.CallCreator.ToNonAD()
+	}
+	return meta.From.ToNonAD()
+}
+
+// isVideoCallOffer reports whether a call offer node describes a video call
+func isVideoCallOffer(data *waBinary.Node) bool {
+	if data == nil {
+		return false
+	}
+	_, ok := data.GetOptionalChildByTag("video")
+	return ok
+}
+
+// handleCallOffer records an incoming call, forwards it to webhooks and auto-rejects it when configured
+func handleCallOffer(ctx context.Context, meta types.BasicCallMeta, isVideo bool, chatStorageRepo domainChatStorage.IChatStorageRepository) {
+	caller := callerJID(meta)
+	isGroup := !meta.GroupJID.IsEmpty()
+	logrus.Infof("Incoming %s call %s from %s", callMediaType(isVideo), meta.CallID, caller.String())
+
+	call := &domainChatStorage.Call{
+		ID:        meta.CallID,
+		CallerJID: caller.String(),
+		IsVideo:   isVideo,
+		IsGroup:   isGroup,
+		Status:    domainChatStorage.CallStatusOffered,
+		StartedAt: meta.Timestamp,
+	}
+	if isGroup {
+		call.GroupJID = meta.GroupJID.String()
+	}
+	storeCall(chatStorageRepo, call)
+
+	if len(config.WhatsappWebhook) > 0 {
+		forwarded := *call
+		goWebhook(func() {
+			if err := forwardCallToWebhook(ctx, "call.offer", &forwarded); err != nil {
+				logrus.Errorf("Failed to forward call offer to webhook: %v", err)
+			}
+		})
+	}
+
+	// Group calls cannot be rejected individually, they simply stop ringing
+	if !config.WhatsappAutoRejectCall || isGroup {
+		return
+	}
+
+	if err := cli.RejectCall(meta.From, meta.CallID); err != nil {
+		logrus.Errorf("Failed to reject call %s: %v", meta.CallID, err)
+		return
+	}
+	logrus.Infof("Auto-rejected call %s from %s", meta.CallID, caller.String())
+
+	now := time.Now()
+	call.Status = domainChatStorage.CallStatusMissed
+	call.Reason = "auto_rejected"
+	call.AutoRejected = true
+	call.EndedAt = &now
+	storeCall(chatStorageRepo, call)
+
+	if len(config.WhatsappWebhook) > 0 {
+		forwarded := *call
+		goWebhook(func() {
+			if err := forwardCallToWebhook(ctx, "call.missed", &forwarded); err != nil {
+				logrus.Errorf("Failed to forward missed call to webhook: %v", err)
+			}
+		})
+	}
+
+	sendCallRejectReply(ctx, caller, chatStorageRepo)
+}
+
+// handleCallStatus updates the call log when a call is accepted or rejected from another device
+func handleCallStatus(_ context.Context, meta types.BasicCallMeta, status string, chatStorageRepo domainChatStorage.IChatStorageRepository) {
+	call := getCall(chatStorageRepo, meta.CallID)
+	if call == nil || call.Status != domainChatStorage.CallStatusOffered {
+		return
+	}
+
+	call.Status = status
+	storeCall(chatStorageRepo, call)
+}
+
+// handleCallTerminate closes the call log entry and reports calls that were never answered as missed
+func handleCallTerminate(ctx context.Context, evt *events.CallTerminate, chatStorageRepo domainChatStorage.IChatStorageRepository) {
+	call := getCall(chatStorageRepo, evt.CallID)
+	if call == nil || call.EndedAt != nil {
+		return
+	}
+
+	endedAt := evt.Timestamp
+	call.EndedAt = &endedAt
+	call.Reason = evt.Reason
+
+	missed := call.Status == domainChatStorage.CallStatusOffered
+	if missed {
+		call.Status = domainChatStorage.CallStatusMissed
+	} else if call.Status == domainChatStorage.CallStatusAccepted {
+		call.Status = domainChatStorage.CallStatusEnded
+	}
+	storeCall(chatStorageRepo, call)
+
+	if missed && len(config.WhatsappWebhook) > 0 {
+		forwarded := *call
+		goWebhook(func() {
+			if err := forwardCallToWebhook(ctx, "call.missed", &forwarded); err != nil {
+				logrus.Errorf("Failed to forward missed call to webhook: %v", err)
+			}
+		})
+	}
+}
+
+// sendCallRejectReply sends the configured text to a caller whose call was auto-rejected
+func sendCallRejectReply(ctx context.Context, caller types.JID, chatStorageRepo domainChatStorage.IChatStorageRepository) {
+	if config.WhatsappAutoRejectCallMessage == "" {
+		return
+	}
+
+	response, err := cli.SendMessage(ctx, caller, &waE2E.Message{
+		Conversation: proto.String(config.WhatsappAutoRejectCallMessage),
+	})
+	if err != nil {
+		logrus.Errorf("Failed to send call reject reply to %s: %v", caller.String(), err)
+		return
+	}
+
+	if chatStorageRepo == nil {
+		return
+	}
+
+	senderJID := ""
+	if cli.Store.ID != nil {
+		senderJID = cli.Store.ID.String()
+	}
+	if err := chatStorageRepo.StoreSentMessageWithContext(ctx, response.ID, senderJID, caller.String(),
+		config.WhatsappAutoRejectCallMessage, response.Timestamp); err != nil {
+		logrus.Errorf("Failed to store call reject reply in chat storage: %v", err)
+	}
+}
+
+func getCall(chatStorageRepo domainChatStorage.IChatStorageRepository, callID string) *domainChatStorage.Call {
+	if chatStorageRepo == nil {
+		return nil
+	}
+
+	call, err := chatStorageRepo.GetCall(callID)
+	if err != nil {
+		logrus.Errorf("Failed to get call %s from call log: %v", callID, err)
+		return nil
+	}
+	return call
+}
+
+func storeCall(chatStorageRepo domainChatStorage.IChatStorageRepository, call *domainChatStorage.Call) {
+	if chatStorageRepo == nil {
+		return
+	}
+
+	if err := chatStorageRepo.StoreCall(call); err != nil {
+		logrus.Errorf("Failed to store call %s in call log: %v", call.ID, err)
+	}
+}
+
+func callMediaType(isVideo bool) string {
+	if isVideo {
+		return "video"
+	}
+	return "audio"
+}
+
+// createCallPayload creates a webhook payload for call events
+func createCallPayload(event string, call *domainChatStorage.Call) map[string]any {
+	body := make(map[string]any)
+
+	payload := make(map[string]any)
+	payload["call_id"] = call.ID
+	payload["from"] = call.CallerJID
+	payload["media"] = callMediaType(call.IsVideo)
+	payload["is_group"] = call.IsGroup
+	if call.IsGroup {
+		payload["group_id"] = call.GroupJID
+	}
+	payload["status"] = call.Status
+	payload["auto_rejected"] = call.AutoRejected
+	if call.Reason != "" {
+		payload["reason"] = call.Reason
+	}
+	payload["started_at"] = call.StartedAt.Format(time.RFC3339)
+
+	// Wrap in payload structure
+	body["payload"] = payload
+
+	// Add metadata for webhook processing
+	body["event"] = event
+	body["timestamp"] = time.Now().Format(time.RFC3339)
+
+	return body
+}
+
+// forwardCallToWebhook forwards call events to the configured webhook URLs
+func forwardCallToWebhook(ctx context.Context, event string, call *domainChatStorage.Call) error {
+	logrus.Infof("Forwarding %s event to %d configured webhook(s)", event, len(config.WhatsappWebhook))
+	payload := createCallPayload(event, call)
+
+	for _, url := range config.WhatsappWebhook {
+		if err := submitWebhook(ctx, payload, url); err != nil {
+			return err
+		}
+	}
+
+	logrus.Infof("%s event forwarded to webhook", event)
+	return nil
+}
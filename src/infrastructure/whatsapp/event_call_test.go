@@ -0,0 +1,104 @@
+package whatsapp
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/chatstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func newTestRepository(t *testing.T) domainChatStorage.IChatStorageRepository {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "chatstorage.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	repo := chatstorage.NewStorageRepository(db)
+	require.NoError(t, repo.InitializeSchema())
+	return repo
+}
+
+func callMeta(id string) types.BasicCallMeta {
+	return types.BasicCallMeta{
+		From:      types.NewADJID("6281234567890", 0, 3),
+		Timestamp: time.Date(2025, 7, 13, 10, 0, 0, 0, time.UTC),
+		CallID:    id,
+	}
+}
+
+func TestCallWebhooks(t *testing.T) {
+	received := captureWebhooks(t)
+	ctx := context.Background()
+
+	t.Run("should forward an offer and report it missed when it stops ringing", func(t *testing.T) {
+		repo := newTestRepository(t)
+		handleCallOffer(ctx, callMeta("CALL1"), true, repo)
+
+		payloads := received()
+		require.Len(t, payloads, 1)
+		assert.Equal(t, "call.offer", payloads[0]["event"])
+		assert.Equal(t, map[string]any{
+			"call_id":       "CALL1",
+			"from":          "6281234567890@s.whatsapp.net",
+			"media":         "video",
+			"is_group":      false,
+			"status":        domainChatStorage.CallStatusOffered,
+			"auto_rejected": false,
+			"started_at":    "2025-07-13T10:00:00Z",
+		}, payloads[0]["payload"], "the caller is reported without its device")
+
+		handleCallTerminate(ctx, &events.CallTerminate{BasicCallMeta: callMeta("CALL1"), Reason: "timeout"}, repo)
+		payloads = received()
+		require.Len(t, payloads, 1)
+		assert.Equal(t, "call.missed", payloads[0]["event"])
+		payload := payloads[0]["payload"].(map[string]any)
+		assert.Equal(t, domainChatStorage.CallStatusMissed, payload["status"])
+		assert.Equal(t, "timeout", payload["reason"])
+
+		call, err := repo.GetCall("CALL1")
+		require.NoError(t, err)
+		assert.Equal(t, domainChatStorage.CallStatusMissed, call.Status)
+		assert.NotNil(t, call.EndedAt)
+	})
+
+	t.Run("should not report an answered call as missed", func(t *testing.T) {
+		repo := newTestRepository(t)
+		handleCallOffer(ctx, callMeta("CALL2"), false, repo)
+		handleCallStatus(ctx, callMeta("CALL2"), domainChatStorage.CallStatusAccepted, repo)
+		handleCallTerminate(ctx, &events.CallTerminate{BasicCallMeta: callMeta("CALL2")}, repo)
+
+		payloads := received()
+		require.Len(t, payloads, 1)
+		assert.Equal(t, "call.offer", payloads[0]["event"])
+
+		call, err := repo.GetCall("CALL2")
+		require.NoError(t, err)
+		assert.Equal(t, domainChatStorage.CallStatusEnded, call.Status)
+	})
+
+	t.Run("should forward group calls without rejecting them", func(t *testing.T) {
+		autoReject := config.WhatsappAutoRejectCall
+		t.Cleanup(func() { config.WhatsappAutoRejectCall = autoReject })
+		config.WhatsappAutoRejectCall = true
+
+		meta := callMeta("CALL3")
+		meta.CallCreator = types.NewADJID("6289876543210", 0, 7)
+		meta.GroupJID = types.NewJID("120363025246125486", types.GroupServer)
+		// Rejecting would need a connected client, which the test does not have
+		handleCallOffer(ctx, meta, false, newTestRepository(t))
+
+		payloads := received()
+		require.Len(t, payloads, 1)
+		payload := payloads[0]["payload"].(map[string]any)
+		assert.Equal(t, "6289876543210@s.whatsapp.net", payload["from"], "the creator of a group call is the caller")
+		assert.Equal(t, true, payload["is_group"])
+		assert.Equal(t, "120363025246125486@g.us", payload["group_id"])
+	})
+}
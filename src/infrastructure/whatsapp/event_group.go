@@ -3,10 +3,12 @@ package whatsapp
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
 	"github.com/sirupsen/logrus"
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
@@ -96,3 +98,188 @@ func forwardGroupInfoToWebhook(ctx context.Context, evt *events.GroupInfo) error
 
 	return nil
 }
+
+// groupChange is a single metadata change carried by a group info event
+type groupChange struct {
+	field    string
+	oldValue *string
+	newValue string
+}
+
+// collectGroupChanges extracts the metadata changes carried by a group info event
+func collectGroupChanges(evt *events.GroupInfo) []groupChange {
+	var changes []groupChange
+
+	if evt.Name != nil {
+		changes = append(changes, groupChange{field: domainChatStorage.GroupEventName, newValue: evt.Name.Name})
+	}
+	if evt.Topic != nil {
+		topic := evt.Topic.Topic
+		if evt.Topic.TopicDeleted {
+			topic = ""
+		}
+		changes = append(changes, groupChange{field: domainChatStorage.GroupEventTopic, newValue: topic})
+	}
+	if evt.Locked != nil {
+		changes = append(changes, groupChange{field: domainChatStorage.GroupEventLocked, newValue: strconv.FormatBool(evt.Locked.IsLocked)})
+	}
+	if evt.Announce != nil {
+		changes = append(changes, groupChange{field: domainChatStorage.GroupEventAnnounce, newValue: strconv.FormatBool(evt.Announce.IsAnnounce)})
+	}
+	if evt.Ephemeral != nil {
+		var timer uint32
+		if evt.Ephemeral.IsEphemeral {
+			timer = evt.Ephemeral.DisappearingTimer
+		}
+		changes = append(changes, groupChange{field: domainChatStorage.GroupEventEphemeral, newValue: strconv.FormatUint(uint64(timer), 10)})
+	}
+	if evt.MembershipApprovalMode != nil {
+		changes = append(changes, groupChange{
+			field:    domainChatStorage.GroupEventMembershipApproval,
+			newValue: strconv.FormatBool(evt.MembershipApprovalMode.IsJoinApprovalRequired),
+		})
+	}
+
+	return changes
+}
+
+// groupEventActor returns the user who made a group change, if known
+func groupEventActor(evt *events.GroupInfo) string {
+	if evt.Sender == nil {
+		return ""
+	}
+	return evt.Sender.ToNonAD().String()
+}
+
+// previousGroupValue looks up the last known value of a group field from the event history
+func previousGroupValue(chatStorageRepo domainChatStorage.IChatStorageRepository, groupJID string, field string) *string {
+	last, err := chatStorageRepo.GetLastGroupEvent(groupJID, field)
+	if err != nil {
+		logrus.Errorf("Failed to get previous %s of group %s: %v", field, groupJID, err)
+		return nil
+	}
+	if last != nil {
+		return &last.NewValue
+	}
+
+	// Before the first recorded rename, the stored chat name is the best known value
+	if field == domainChatStorage.GroupEventName {
+		if chat, err := chatStorageRepo.GetChat(groupJID); err == nil && chat != nil && chat.Name != "" {
+			return &chat.Name
+		}
+	}
+
+	return nil
+}
+
+// recordGroupEvents stores the changes of a group info event in the group event history and
+// returns the metadata changes with their previous values resolved
+func recordGroupEvents(evt *events.GroupInfo, chatStorageRepo domainChatStorage.IChatStorageRepository) []groupChange {
+	changes := collectGroupChanges(evt)
+	if chatStorageRepo == nil {
+		return changes
+	}
+
+	groupJID := evt.JID.String()
+	actor := groupEventActor(evt)
+
+	for i := range changes {
+		changes[i].oldValue = previousGroupValue(chatStorageRepo, groupJID, changes[i].field)
+
+		if err := chatStorageRepo.StoreGroupEvent(&domainChatStorage.GroupEvent{
+			GroupJID:  groupJID,
+			EventType: changes[i].field,
+			ActorJID:  actor,
+			OldValue:  changes[i].oldValue,
+			NewValue:  changes[i].newValue,
+			Timestamp: evt.Timestamp,
+		}); err != nil {
+			logrus.Errorf("Failed to store %s change of group %s: %v", changes[i].field, groupJID, err)
+		}
+	}
+
+	participantActions := []struct {
+		eventType string
+		jids      []types.JID
+	}{
+		{domainChatStorage.GroupEventJoin, evt.Join},
+		{domainChatStorage.GroupEventLeave, evt.Leave},
+		{domainChatStorage.GroupEventPromote, evt.Promote},
+		{domainChatStorage.GroupEventDemote, evt.Demote},
+	}
+
+	for _, action := range participantActions {
+		if len(action.jids) == 0 {
+			continue
+		}
+
+		if err := chatStorageRepo.StoreGroupEvent(&domainChatStorage.GroupEvent{
+			GroupJID:     groupJID,
+			EventType:    action.eventType,
+			ActorJID:     actor,
+			Participants: jidsToStrings(action.jids),
+			Timestamp:    evt.Timestamp,
+		}); err != nil {
+			logrus.Errorf("Failed to store %s event of group %s: %v", action.eventType, groupJID, err)
+		}
+	}
+
+	return changes
+}
+
+// createGroupUpdatedPayload creates a webhook payload for a group metadata change
+func createGroupUpdatedPayload(evt *events.GroupInfo, change groupChange) map[string]any {
+	body := make(map[string]any)
+
+	payload := make(map[string]any)
+	payload["chat_id"] = evt.JID.String()
+	payload["field"] = change.field
+	payload["old_value"] = change.oldValue // null when the previous value is unknown
+	payload["new_value"] = change.newValue
+	payload["changed_by"] = groupEventActor(evt)
+	if evt.SenderPN != nil {
+		payload["changed_by_pn"] = evt.SenderPN.ToNonAD().String()
+	}
+
+	// Wrap in payload structure
+	body["payload"] = payload
+
+	// Add metadata for webhook processing
+	body["event"] = "group.updated"
+	body["timestamp"] = evt.Timestamp.Format(time.RFC3339)
+
+	return body
+}
+
+// forwardGroupUpdatesToWebhook forwards group metadata changes to the configured webhook URLs
+func forwardGroupUpdatesToWebhook(ctx context.Context, evt *events.GroupInfo, changes []groupChange) error {
+	for _, change := range changes {
+		payload := createGroupUpdatedPayload(evt, change)
+
+		// Collect errors from all webhook URLs instead of failing fast
+		var errors []error
+		for _, url := range config.WhatsappWebhook {
+			if err := submitWebhook(ctx, payload, url); err != nil {
+				errors = append(errors, fmt.Errorf("webhook %s failed: %w", url, err))
+			}
+		}
+
+		// If all webhooks failed, return combined error
+		if len(errors) == len(config.WhatsappWebhook) && len(errors) > 0 {
+			var errMessages []string
+			for _, err := range errors {
+				errMessages = append(errMessages, err.Error())
+			}
+			return fmt.Errorf("all webhook URLs failed: %s", strings.Join(errMessages, "; "))
+		}
+
+		// Log partial failures
+		if len(errors) > 0 {
+			logrus.Warnf("Some webhook URLs failed for group %s update: %v", change.field, errors)
+		}
+
+		logrus.Infof("Group %s update forwarded to webhook", change.field)
+	}
+
+	return nil
+}
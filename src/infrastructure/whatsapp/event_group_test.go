@@ -0,0 +1,79 @@
+package whatsapp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+func TestGroupWebhooks(t *testing.T) {
+	previousLog := log
+	log = waLog.Noop
+	t.Cleanup(func() { log = previousLog })
+
+	received := captureWebhooks(t)
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	group := types.NewJID("120363025246125486", types.GroupServer)
+	admin := types.NewADJID("6281234567890", 0, 4)
+	require.NoError(t, repo.StoreChat(&domainChatStorage.Chat{JID: group.String(), Name: "Book club", LastMessageTime: time.Now()}))
+	groupInfo := func(minute int) *events.GroupInfo {
+		return &events.GroupInfo{JID: group, Sender: &admin, Timestamp: time.Date(2025, 7, 13, 10, minute, 0, 0, time.UTC)}
+	}
+
+	renamed := groupInfo(0)
+	renamed.Name = &types.GroupName{Name: "Reading club"}
+	handleGroupInfo(ctx, renamed, repo)
+
+	payloads := received()
+	require.Len(t, payloads, 1)
+	assert.Equal(t, "group.updated", payloads[0]["event"])
+	assert.Equal(t, "2025-07-13T10:00:00Z", payloads[0]["timestamp"])
+	assert.Equal(t, map[string]any{
+		"chat_id":    group.String(),
+		"field":      domainChatStorage.GroupEventName,
+		"old_value":  "Book club",
+		"new_value":  "Reading club",
+		"changed_by": "6281234567890@s.whatsapp.net",
+	}, payloads[0]["payload"], "the first rename starts from the stored chat name")
+
+	changed := groupInfo(1)
+	changed.Name = &types.GroupName{Name: "Readers"}
+	changed.Topic = &types.GroupTopic{Topic: "Monthly picks"}
+	changed.Join = []types.JID{types.NewJID("6289876543210", types.DefaultUserServer)}
+	handleGroupInfo(ctx, changed, repo)
+
+	payloads = received()
+	require.Len(t, payloads, 3)
+	assert.Equal(t, "group.participants", payloads[0]["event"])
+	assert.Equal(t, map[string]any{
+		"chat_id": group.String(),
+		"type":    "join",
+		"jids":    []any{"6289876543210@s.whatsapp.net"},
+	}, payloads[0]["payload"])
+	name := payloads[1]["payload"].(map[string]any)
+	assert.Equal(t, "Reading club", name["old_value"], "later renames start from the recorded history")
+	assert.Equal(t, "Readers", name["new_value"])
+	topic := payloads[2]["payload"].(map[string]any)
+	assert.Equal(t, domainChatStorage.GroupEventTopic, topic["field"])
+	assert.Nil(t, topic["old_value"], "a field without history has no known previous value")
+
+	history, err := repo.GetGroupEvents(&domainChatStorage.GroupEventFilter{GroupJID: group.String(), Limit: 10})
+	require.NoError(t, err)
+	var recorded []string
+	for _, event := range history {
+		recorded = append(recorded, event.EventType)
+	}
+	assert.ElementsMatch(t, []string{"name", "name", "topic", "join"}, recorded)
+
+	handleGroupInfo(ctx, groupInfo(2), repo)
+	assert.Empty(t, received(), "events without changes are not forwarded")
+}
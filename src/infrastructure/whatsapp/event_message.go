@@ -9,8 +9,8 @@ import (
 
 	"go.mau.fi/whatsmeow/types"
 
-	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
 	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
 	"github.com/sirupsen/logrus"
@@ -24,9 +24,9 @@ func SetChatStorageRepository(repo domainChatStorage.IChatStorageRepository) {
 	chatStorageRepo = repo
 }
 
-
-// forwardMessageToWebhook is a helper function to forward message event to webhook url
-func forwardMessageToWebhook(ctx context.Context, evt *events.Message) error {
+// forwardMessageToWebhook is a helper function to forward message event to webhook url, download is
+// the auto download of the message media if one was queued
+func forwardMessageToWebhook(ctx context.Context, evt *events.Message, download *mediaDownload) error {
 	// Store the incoming message
 	if err := StoreMessage(ctx, evt); err != nil {
 		logrus.WithError(err).Error("Failed to store incoming message before forwarding to webhook")
@@ -34,7 +34,7 @@ func forwardMessageToWebhook(ctx context.Context, evt *events.Message) error {
 	}
 
 	logrus.Infof("Forwarding message event to %d configured webhook(s)", len(config.WhatsappWebhook))
-	payload, err := createMessagePayload(ctx, evt)
+	payload, err := createMessagePayload(ctx, evt, download)
 	if err != nil {
 		return err
 	}
@@ -49,7 +49,7 @@ func forwardMessageToWebhook(ctx context.Context, evt *events.Message) error {
 	return nil
 }
 
-func createMessagePayload(ctx context.Context, evt *events.Message) (map[string]any, error) {
+func createMessagePayload(ctx context.Context, evt *events.Message, download *mediaDownload) (map[string]any, error) {
 	message := utils.BuildEventMessage(evt)
 	waReaction := utils.BuildEventReaction(evt)
 	forwarded := utils.BuildForwarded(evt)
@@ -153,7 +153,7 @@ func createMessagePayload(ctx context.Context, evt *events.Message) (map[string]
 	}
 
 	if audioMedia := evt.Message.GetAudioMessage(); audioMedia != nil {
-		path, err := utils.ExtractMedia(ctx, cli, config.PathMedia, audioMedia)
+		path, err := awaitMessageMedia(ctx, evt, download, audioMedia)
 		if err != nil {
 			logrus.Errorf("Failed to download audio from %s: %v", evt.Info.SourceString(), err)
 			return nil, pkgError.WebhookError(fmt.Sprintf("Failed to download audio: %v", err))
@@ -166,7 +166,7 @@ func createMessagePayload(ctx context.Context, evt *events.Message) (map[string]
 	}
 
 	if documentMedia := evt.Message.GetDocumentMessage(); documentMedia != nil {
-		path, err := utils.ExtractMedia(ctx, cli, config.PathMedia, documentMedia)
+		path, err := awaitMessageMedia(ctx, evt, download, documentMedia)
 		if err != nil {
 			logrus.Errorf("Failed to download document from %s: %v", evt.Info.SourceString(), err)
 			return nil, pkgError.WebhookError(fmt.Sprintf("Failed to download document: %v", err))
@@ -175,7 +175,7 @@ func createMessagePayload(ctx context.Context, evt *events.Message) (map[string]
 	}
 
 	if imageMedia := evt.Message.GetImageMessage(); imageMedia != nil {
-		path, err := utils.ExtractMedia(ctx, cli, config.PathMedia, imageMedia)
+		path, err := awaitMessageMedia(ctx, evt, download, imageMedia)
 		if err != nil {
 			logrus.Errorf("Failed to download image from %s: %v", evt.Info.SourceString(), err)
 			return nil, pkgError.WebhookError(fmt.Sprintf("Failed to download image: %v", err))
@@ -200,7 +200,7 @@ func createMessagePayload(ctx context.Context, evt *events.Message) (map[string]
 	}
 
 	if stickerMedia := evt.Message.GetStickerMessage(); stickerMedia != nil {
-		path, err := utils.ExtractMedia(ctx, cli, config.PathMedia, stickerMedia)
+		path, err := awaitMessageMedia(ctx, evt, download, stickerMedia)
 		if err != nil {
 			logrus.Errorf("Failed to download sticker from %s: %v", evt.Info.SourceString(), err)
 			return nil, pkgError.WebhookError(fmt.Sprintf("Failed to download sticker: %v", err))
@@ -209,7 +209,7 @@ func createMessagePayload(ctx context.Context, evt *events.Message) (map[string]
 	}
 
 	if videoMedia := evt.Message.GetVideoMessage(); videoMedia != nil {
-		path, err := utils.ExtractMedia(ctx, cli, config.PathMedia, videoMedia)
+		path, err := awaitMessageMedia(ctx, evt, download, videoMedia)
 		if err != nil {
 			logrus.Errorf("Failed to download video from %s: %v", evt.Info.SourceString(), err)
 			return nil, pkgError.WebhookError(fmt.Sprintf("Failed to download video: %v", err))
@@ -220,7 +220,6 @@ func createMessagePayload(ctx context.Context, evt *events.Message) (map[string]
 	return body, nil
 }
 
-
 // StoreMessage stores an incoming message into the chat storage repository.
 func StoreMessage(ctx context.Context, evt *events.Message) error {
 	if evt.Message == nil {
@@ -238,6 +237,7 @@ func StoreMessage(ctx context.Context, evt *events.Message) error {
 	filename := ""
 	url := ""
 	fileLength := uint64(0)
+	var mediaKey, fileSHA256, fileEncSHA256, thumbnail []byte
 
 	// Handle different media types
 	if image := evt.Message.GetImageMessage(); image != nil {
@@ -245,36 +245,50 @@ func StoreMessage(ctx context.Context, evt *events.Message) error {
 		filename = image.GetCaption()
 		url = image.GetURL()
 		fileLength = image.GetFileLength()
+		mediaKey, fileSHA256, fileEncSHA256 = image.GetMediaKey(), image.GetFileSHA256(), image.GetFileEncSHA256()
+		thumbnail = image.GetJPEGThumbnail()
 	} else if video := evt.Message.GetVideoMessage(); video != nil {
 		mediaType = "video"
 		filename = video.GetCaption()
 		url = video.GetURL()
 		fileLength = video.GetFileLength()
+		mediaKey, fileSHA256, fileEncSHA256 = video.GetMediaKey(), video.GetFileSHA256(), video.GetFileEncSHA256()
 	} else if audio := evt.Message.GetAudioMessage(); audio != nil {
 		mediaType = "audio"
 		url = audio.GetURL()
 		fileLength = audio.GetFileLength()
+		mediaKey, fileSHA256, fileEncSHA256 = audio.GetMediaKey(), audio.GetFileSHA256(), audio.GetFileEncSHA256()
 	} else if document := evt.Message.GetDocumentMessage(); document != nil {
 		mediaType = "document"
 		filename = document.GetFileName()
 		url = document.GetURL()
 		fileLength = document.GetFileLength()
+		mediaKey, fileSHA256, fileEncSHA256 = document.GetMediaKey(), document.GetFileSHA256(), document.GetFileEncSHA256()
+	} else if sticker := evt.Message.GetStickerMessage(); sticker != nil {
+		mediaType = "sticker"
+		url = sticker.GetURL()
+		fileLength = sticker.GetFileLength()
+		mediaKey, fileSHA256, fileEncSHA256 = sticker.GetMediaKey(), sticker.GetFileSHA256(), sticker.GetFileEncSHA256()
 	}
 
 	// Create a new message object for storage
 	msg := &domainChatStorage.Message{
-		ID:        messageID,
-		ChatJID:   chatJID,
-		Sender:    senderJID,
-		Content:   content,
-		Timestamp: timestamp,
-		IsFromMe:  isFromMe,
-		MediaType: mediaType,
-		Filename:  filename,
-		URL:       url,
-		FileLength: fileLength,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:            messageID,
+		ChatJID:       chatJID,
+		Sender:        senderJID,
+		Content:       content,
+		Timestamp:     timestamp,
+		IsFromMe:      isFromMe,
+		MediaType:     mediaType,
+		Filename:      filename,
+		URL:           url,
+		MediaKey:      mediaKey,
+		FileSHA256:    fileSHA256,
+		FileEncSHA256: fileEncSHA256,
+		FileLength:    fileLength,
+		Thumbnail:     thumbnail,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
 	}
 
 	// Store the message using the chat storage repository
@@ -292,4 +306,3 @@ func StoreMessage(ctx context.Context, evt *events.Message) error {
 
 	return nil
 }
-
@@ -0,0 +1,218 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/ui/websocket"
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// Session lifecycle events delivered to webhooks and WebSocket clients
+const (
+	SessionEventConnected    = "session.connected"
+	SessionEventDisconnected = "session.disconnected"
+	SessionEventLoggedOut    = "session.logged_out"
+	SessionEventPairSuccess  = "session.pair_success"
+	SessionEventQR           = "session.qr"
+)
+
+// websocketBroadcastTimeout bounds how long a session event waits for the WebSocket hub,
+// which is not running in MCP mode
+const websocketBroadcastTimeout = 5 * time.Second
+
+// sessionEventQueueSize bounds the session events waiting for webhook delivery, further events are dropped
+const sessionEventQueueSize = 64
+
+type sessionWebhook struct {
+	ctx     context.Context
+	event   string
+	payload map[string]any
+}
+
+var (
+	sessionWebhookQueue  = make(chan sessionWebhook, sessionEventQueueSize)
+	sessionWebhookWorker sync.Once
+)
+
+// EmitSessionEvent publishes a session lifecycle event to the configured webhooks and WebSocket clients.
+// It never waits for delivery: webhooks are sent in order by one worker, WaitForWebhooks drains them.
+func EmitSessionEvent(ctx context.Context, event string, fields map[string]any) {
+	payload := createSessionPayload(event, fields)
+	logrus.Infof("Session event %s: %v", event, payload["payload"])
+
+	go broadcastSessionEvent(event, payload)
+
+	if len(config.WhatsappWebhook) == 0 {
+		return
+	}
+	sessionWebhookWorker.Do(func() { go deliverSessionWebhooks() })
+
+	webhookDeliveries.Add(1)
+	select {
+	case sessionWebhookQueue <- sessionWebhook{ctx: ctx, event: event, payload: payload}:
+	default:
+		webhookDeliveries.Done()
+		logrus.Warnf("Session event queue is full, %s event is not forwarded to webhooks", event)
+	}
+}
+
+func deliverSessionWebhooks() {
+	for job := range sessionWebhookQueue {
+		if err := forwardSessionToWebhook(job.ctx, job.event, job.payload); err != nil {
+			logrus.Errorf("Failed to forward %s event to webhook: %v", job.event, err)
+		}
+		webhookDeliveries.Done()
+	}
+}
+
+// handleSessionEvent maps whatsmeow connection events to session lifecycle events
+func handleSessionEvent(ctx context.Context, rawEvt any) {
+	switch evt := rawEvt.(type) {
+	case *events.Connected:
+		EmitSessionEvent(ctx, SessionEventConnected, sessionFields(nil))
+	case *events.Disconnected:
+		EmitSessionEvent(ctx, SessionEventDisconnected, sessionFields(map[string]any{
+			"reason": "connection_lost",
+		}))
+	case *events.KeepAliveTimeout:
+		// Only report once per outage, whatsmeow emits this on every failed ping
+		if evt.ErrorCount != 1 {
+			return
+		}
+		EmitSessionEvent(ctx, SessionEventDisconnected, sessionFields(map[string]any{
+			"reason":       "keepalive_timeout",
+			"last_success": evt.LastSuccess.Format(time.RFC3339),
+		}))
+	case *events.StreamReplaced:
+		EmitSessionEvent(ctx, SessionEventDisconnected, sessionFields(map[string]any{
+			"reason":  "stream_replaced",
+			"message": "another client connected with the same session",
+		}))
+	case *events.StreamError:
+		EmitSessionEvent(ctx, SessionEventDisconnected, sessionFields(map[string]any{
+			"reason":  "stream_error",
+			"message": evt.Code,
+		}))
+	case *events.TemporaryBan:
+		EmitSessionEvent(ctx, SessionEventDisconnected, sessionFields(map[string]any{
+			"reason":         "temporary_ban",
+			"message":        evt.String(),
+			"expire_seconds": int(evt.Expire.Seconds()),
+		}))
+	case *events.ClientOutdated:
+		EmitSessionEvent(ctx, SessionEventDisconnected, sessionFields(map[string]any{
+			"reason":  "client_outdated",
+			"message": evt.PermanentDisconnectDescription(),
+		}))
+	case *events.ConnectFailure:
+		EmitSessionEvent(ctx, SessionEventDisconnected, sessionFields(map[string]any{
+			"reason":  "connect_failure",
+			"code":    int(evt.Reason),
+			"message": evt.PermanentDisconnectDescription(),
+		}))
+	case *events.LoggedOut:
+		fields := map[string]any{
+			"reason":     "logged_out",
+			"on_connect": evt.OnConnect,
+		}
+		if evt.OnConnect {
+			fields["code"] = int(evt.Reason)
+			fields["message"] = evt.Reason.String()
+		}
+		// The fields are read now, before the session is wiped
+		EmitSessionEvent(ctx, SessionEventLoggedOut, sessionFields(fields))
+	case *events.PairSuccess:
+		EmitSessionEvent(ctx, SessionEventPairSuccess, map[string]any{
+			"jid":           evt.ID.ToNonAD().String(),
+			"lid":           evt.LID.ToNonAD().String(),
+			"business_name": evt.BusinessName,
+			"platform":      evt.Platform,
+		})
+	}
+}
+
+// sessionFields adds the current device identity to session event fields
+func sessionFields(fields map[string]any) map[string]any {
+	if fields == nil {
+		fields = make(map[string]any)
+	}
+
+	if cli != nil && cli.Store != nil && cli.Store.ID != nil {
+		fields["jid"] = cli.Store.ID.ToNonAD().String()
+		if cli.Store.PushName != "" {
+			fields["push_name"] = cli.Store.PushName
+		}
+	}
+
+	return fields
+}
+
+// createSessionPayload creates a webhook payload for session lifecycle events
+func createSessionPayload(event string, fields map[string]any) map[string]any {
+	body := make(map[string]any)
+
+	payload := make(map[string]any, len(fields))
+	for key, value := range fields {
+		payload[key] = value
+	}
+
+	// Wrap in payload structure
+	body["payload"] = payload
+
+	// Add metadata for webhook processing
+	body["event"] = event
+	body["timestamp"] = time.Now().Format(time.RFC3339)
+
+	return body
+}
+
+// broadcastSessionEvent sends a session event to WebSocket clients, e.g. "session.qr" as "SESSION_QR"
+func broadcastSessionEvent(event string, payload map[string]any) {
+	message := websocket.BroadcastMessage{
+		Code:    strings.ToUpper(strings.ReplaceAll(event, ".", "_")),
+		Message: fmt.Sprintf("Session event %s", event),
+		Result:  payload,
+	}
+
+	select {
+	case websocket.Broadcast <- message:
+	case <-time.After(websocketBroadcastTimeout):
+		logrus.Debugf("No WebSocket hub received %s event", event)
+	}
+}
+
+// forwardSessionToWebhook forwards session lifecycle events to the configured webhook URLs
+func forwardSessionToWebhook(ctx context.Context, event string, payload map[string]any) error {
+	logrus.Infof("Forwarding %s event to %d configured webhook(s)", event, len(config.WhatsappWebhook))
+
+	// Collect errors from all webhook URLs instead of failing fast
+	var errors []error
+	for _, url := range config.WhatsappWebhook {
+		if err := submitWebhook(ctx, payload, url); err != nil {
+			errors = append(errors, fmt.Errorf("webhook %s failed: %w", url, err))
+		}
+	}
+
+	// If all webhooks failed, return combined error
+	if len(errors) == len(config.WhatsappWebhook) && len(errors) > 0 {
+		var errMessages []string
+		for _, err := range errors {
+			errMessages = append(errMessages, err.Error())
+		}
+		return fmt.Errorf("all webhook URLs failed: %s", strings.Join(errMessages, "; "))
+	}
+
+	// Log partial failures
+	if len(errors) > 0 {
+		logrus.Warnf("Some webhook URLs failed for %s event: %v", event, errors)
+	}
+
+	logrus.Infof("%s event forwarded to webhook", event)
+	return nil
+}
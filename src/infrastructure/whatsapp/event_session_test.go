@@ -0,0 +1,46 @@
+package whatsapp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmitSessionEventDoesNotWaitForWebhooks(t *testing.T) {
+	var received atomic.Int32
+	first := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if received.Add(1) == 1 {
+			close(first)
+		}
+		<-release
+	}))
+	defer server.Close()
+
+	webhooks := config.WhatsappWebhook
+	t.Cleanup(func() { config.WhatsappWebhook = webhooks })
+	config.WhatsappWebhook = []string{server.URL}
+
+	started := time.Now()
+	EmitSessionEvent(context.Background(), SessionEventDisconnected, map[string]any{"reason": "stream_replaced"})
+	<-first
+	// The worker is blocked on the first event, fill the queue and overflow it
+	for i := 0; i < sessionEventQueueSize+3; i++ {
+		EmitSessionEvent(context.Background(), SessionEventConnected, nil)
+	}
+	assert.Less(t, time.Since(started), 2*time.Second, "emitting does not wait for the webhook")
+
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, WaitForWebhooks(ctx))
+	assert.Equal(t, int32(1+sessionEventQueueSize), received.Load(), "events past the queue size are dropped")
+}
@@ -3,6 +3,7 @@ package whatsapp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -15,6 +16,8 @@ import (
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
 	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
 	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/logging"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/metrics"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/ui/websocket"
 	"github.com/sirupsen/logrus"
@@ -41,6 +44,7 @@ var (
 	cli           *whatsmeow.Client
 	db            *sqlstore.Container // Add global database reference for cleanup
 	keysDB        *sqlstore.Container
+	device        *store.Device // Device loaded by LoadDevice, for commands without a client
 	log           waLog.Logger
 	historySyncID int32
 	startupTime   = time.Now().Unix()
@@ -48,8 +52,8 @@ var (
 
 // InitWaDB initializes the WhatsApp database connection
 func InitWaDB(ctx context.Context, DBURI string) *sqlstore.Container {
-	log = waLog.Stdout("Main", config.WhatsappLogLevel, true)
-	dbLog := waLog.Stdout("Database", config.WhatsappLogLevel, true)
+	log = logging.NewWhatsmeowLogger("Main", config.WhatsappLogLevel)
+	dbLog := logging.NewWhatsmeowLogger("Database", config.WhatsappLogLevel)
 
 	storeContainer, err := initDatabase(ctx, dbLog, DBURI)
 	if err != nil {
@@ -136,17 +140,38 @@ func InitWaCLI(ctx context.Context, storeContainer, keysStoreContainer *sqlstore
 	}
 
 	// Create and configure the client
-	cli = whatsmeow.NewClient(device, waLog.Stdout("Client", config.WhatsappLogLevel, true))
-	cli.EnableAutoReconnect = true
+	cli = whatsmeow.NewClient(device, logging.NewWhatsmeowLogger("Client", config.WhatsappLogLevel))
+	cli.EnableAutoReconnect = false // reconnects are handled by the supervisor, see reconnect.go
 	cli.AutoTrustIdentity = true
 
 	cli.AddEventHandler(func(rawEvt interface{}) {
 		handler(ctx, rawEvt, chatStorageRepo)
 	})
+	startReconnectSupervisor()
 
 	return cli
 }
 
+// LoadDevice opens the session in storeContainer without creating a client, for commands that only
+// read the device and its contacts from the store
+func LoadDevice(ctx context.Context, storeContainer *sqlstore.Container) error {
+	loaded, err := storeContainer.GetFirstDevice(ctx)
+	if err != nil {
+		return err
+	}
+	db = storeContainer
+	device = loaded
+	return nil
+}
+
+// GetDevice returns the device of the client, or the one of LoadDevice when no client was created
+func GetDevice() *store.Device {
+	if cli != nil {
+		return cli.Store
+	}
+	return device
+}
+
 // UpdateGlobalClient updates the global cli variable with a new client instance
 // This is needed when reinitializing the client after logout to ensure all
 // infrastructure code uses the new client instance
@@ -166,6 +191,34 @@ func GetDB() *sqlstore.Container {
 	return db
 }
 
+// Close disconnects the client and closes the WhatsApp databases
+// Disconnect closes the connection of the global client, no events arrive afterwards
+func Disconnect() {
+	if cli != nil {
+		cli.Disconnect()
+	}
+}
+
+func Close() error {
+	if cli != nil {
+		cli.Disconnect()
+	}
+
+	var errs []error
+	if keysDB != nil {
+		if err := keysDB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close keys database: %w", err))
+		}
+	}
+	if db != nil {
+		if err := db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close database: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 // GetConnectionStatus returns the current connection status of the global client
 func GetConnectionStatus() (isConnected bool, isLoggedIn bool, deviceID string) {
 	if cli == nil {
@@ -345,6 +398,10 @@ func handleRemoteLogout(ctx context.Context, chatStorageRepo domainChatStorage.I
 
 // handler is the main event handler for WhatsApp events
 func handler(ctx context.Context, rawEvt any, chatStorageRepo domainChatStorage.IChatStorageRepository) {
+	metrics.ObserveEvent(eventName(rawEvt))
+	handleSessionEvent(ctx, rawEvt)
+	handleReconnectEvent(rawEvt)
+
 	switch evt := rawEvt.(type) {
 	case *events.DeleteForMe:
 		handleDeleteForMe(ctx, evt, chatStorageRepo)
@@ -354,7 +411,10 @@ func handler(ctx context.Context, rawEvt any, chatStorageRepo domainChatStorage.
 		handlePairSuccess(ctx, evt)
 	case *events.LoggedOut:
 		handleLoggedOut(ctx, chatStorageRepo)
-	case *events.Connected, *events.PushNameSetting:
+	case *events.Connected:
+		utils.SetSessionReplaced(false)
+		handleConnectionEvents(ctx)
+	case *events.PushNameSetting:
 		handleConnectionEvents(ctx)
 	case *events.StreamReplaced:
 		handleStreamReplaced(ctx)
@@ -362,6 +422,8 @@ func handler(ctx context.Context, rawEvt any, chatStorageRepo domainChatStorage.
 		handleMessage(ctx, evt, chatStorageRepo)
 	case *events.Receipt:
 		handleReceipt(ctx, evt)
+	case *events.MediaRetry:
+		handleMediaRetry(evt)
 	case *events.Presence:
 		handlePresence(ctx, evt)
 	case *events.HistorySync:
@@ -369,7 +431,17 @@ func handler(ctx context.Context, rawEvt any, chatStorageRepo domainChatStorage.
 	case *events.AppState:
 		handleAppState(ctx, evt)
 	case *events.GroupInfo:
-		handleGroupInfo(ctx, evt)
+		handleGroupInfo(ctx, evt, chatStorageRepo)
+	case *events.CallOffer:
+		handleCallOffer(ctx, evt.BasicCallMeta, isVideoCallOffer(evt.Data), chatStorageRepo)
+	case *events.CallOfferNotice:
+		handleCallOffer(ctx, evt.BasicCallMeta, evt.Media == "video", chatStorageRepo)
+	case *events.CallAccept:
+		handleCallStatus(ctx, evt.BasicCallMeta, domainChatStorage.CallStatusAccepted, chatStorageRepo)
+	case *events.CallReject:
+		handleCallStatus(ctx, evt.BasicCallMeta, domainChatStorage.CallStatusRejected, chatStorageRepo)
+	case *events.CallTerminate:
+		handleCallTerminate(ctx, evt, chatStorageRepo)
 	}
 }
 
@@ -399,11 +471,11 @@ func handleDeleteForMe(ctx context.Context, evt *events.DeleteForMe, chatStorage
 
 	// Send webhook notification for delete event
 	if len(config.WhatsappWebhook) > 0 {
-		go func() {
+		goWebhook(func() {
 			if err := forwardDeleteToWebhook(ctx, evt, message); err != nil {
 				log.Errorf("Failed to forward delete event to webhook: %v", err)
 			}
-		}()
+		})
 	}
 }
 
@@ -453,27 +525,49 @@ func handleConnectionEvents(_ context.Context) {
 	}
 }
 
+// shutdownRequests receives a request when the process should shut down by itself
+var shutdownRequests = make(chan struct{}, 1)
+
+// ShutdownRequested is signalled when the process should shut down, e.g. after the session was
+// taken over with WhatsappExitOnStreamReplaced set
+func ShutdownRequested() <-chan struct{} {
+	return shutdownRequests
+}
+
 func handleStreamReplaced(_ context.Context) {
-	os.Exit(0)
+	// whatsmeow does not reconnect after a stream replacement, keep it that way until a takeover is requested
+	utils.SetSessionReplaced(true)
+
+	if config.WhatsappExitOnStreamReplaced {
+		logrus.Warn("[STREAM_REPLACED] Session taken over by another client, shutting down")
+		select {
+		case shutdownRequests <- struct{}{}:
+		default:
+		}
+		return
+	}
+	logrus.Warn("[STREAM_REPLACED] Session taken over by another client, use /app/takeover to reconnect")
 }
 
 func handleMessage(ctx context.Context, evt *events.Message, chatStorageRepo domainChatStorage.IChatStorageRepository) {
-	// Log message metadata
+	// Log message metadata, never the message itself
+	msgType := utils.MessageType(evt.Message)
 	metaParts := buildMessageMetaParts(evt)
-	log.Infof("Received message %s from %s (%s): %+v",
+	log.Infof("Received %s message %s from %s (%s)",
+		msgType,
 		evt.Info.ID,
 		evt.Info.SourceString(),
 		strings.Join(metaParts, ", "),
-		evt.Message,
 	)
+	metrics.ObserveReceived(msgType)
 
 	if err := StoreMessage(ctx, evt); err != nil {
 		// Log storage errors to avoid silent failures that could lead to data loss
 		log.Errorf("Failed to store incoming message %s: %v", evt.Info.ID, err)
 	}
 
-	// Handle image message if present
-	handleImageMessage(ctx, evt)
+	// Download the media in the background if the auto download policy allows it
+	download := queueAutoDownload(ctx, evt)
 
 	// Auto-mark message as read if configured
 	handleAutoMarkRead(ctx, evt)
@@ -482,7 +576,7 @@ func handleMessage(ctx context.Context, evt *events.Message, chatStorageRepo dom
 	handleAutoReply(ctx, evt, chatStorageRepo)
 
 	// Forward to webhook if configured
-	handleWebhookForward(ctx, evt)
+	handleWebhookForward(ctx, evt, download)
 }
 
 func buildMessageMetaParts(evt *events.Message) []string {
@@ -502,16 +596,6 @@ func buildMessageMetaParts(evt *events.Message) []string {
 	return metaParts
 }
 
-func handleImageMessage(ctx context.Context, evt *events.Message) {
-	if img := evt.Message.GetImageMessage(); img != nil {
-		if path, err := utils.ExtractMedia(ctx, cli, config.PathStorages, img); err != nil {
-			log.Errorf("Failed to download image: %v", err)
-		} else {
-			log.Infof("Image downloaded to %s", path)
-		}
-	}
-}
-
 func handleAutoMarkRead(_ context.Context, evt *events.Message) {
 	// Only mark read if auto-mark read is enabled and message is incoming
 	if !config.WhatsappAutoMarkRead || evt.Info.IsFromMe {
@@ -637,7 +721,7 @@ func handleAutoReply(ctx context.Context, evt *events.Message, chatStorageRepo d
 	}
 }
 
-func handleWebhookForward(ctx context.Context, evt *events.Message) {
+func handleWebhookForward(ctx context.Context, evt *events.Message, download *mediaDownload) {
 	// Skip webhook for specific protocol messages that shouldn't trigger webhooks
 	if protocolMessage := evt.Message.GetProtocolMessage(); protocolMessage != nil {
 		protocolType := protocolMessage.GetType().String()
@@ -650,11 +734,11 @@ func handleWebhookForward(ctx context.Context, evt *events.Message) {
 
 	if len(config.WhatsappWebhook) > 0 &&
 		!strings.Contains(evt.Info.SourceString(), "broadcast") {
-		go func(evt *events.Message) {
-			if err := forwardMessageToWebhook(ctx, evt); err != nil {
+		goWebhook(func() {
+			if err := forwardMessageToWebhook(ctx, evt, download); err != nil {
 				logrus.Error("Failed forward to webhook: ", err)
 			}
-		}(evt)
+		})
 	}
 }
 
@@ -672,11 +756,11 @@ func handleReceipt(ctx context.Context, evt *events.Receipt) {
 	// Forward receipt (ack) event to webhook if configured
 	// Note: Receipt events are not rate limited as they are critical for message delivery status
 	if len(config.WhatsappWebhook) > 0 && sendReceipt {
-		go func(e *events.Receipt) {
-			if err := forwardReceiptToWebhook(ctx, e); err != nil {
+		goWebhook(func() {
+			if err := forwardReceiptToWebhook(ctx, evt); err != nil {
 				logrus.Errorf("Failed to forward ack event to webhook: %v", err)
 			}
-		}(evt)
+		})
 	}
 }
 
@@ -870,6 +954,7 @@ func processConversationMessages(_ context.Context, data *waHistorySync.HistoryS
 				FileSHA256:    fileSHA256,
 				FileEncSHA256: fileEncSHA256,
 				FileLength:    fileLength,
+				Thumbnail:     msg.GetMessage().GetImageMessage().GetJPEGThumbnail(),
 			}
 
 			messageBatch = append(messageBatch, message)
@@ -936,10 +1021,11 @@ func processPushNames(_ context.Context, data *waHistorySync.HistorySync, chatSt
 	return nil
 }
 
-func handleGroupInfo(ctx context.Context, evt *events.GroupInfo) {
+func handleGroupInfo(ctx context.Context, evt *events.GroupInfo, chatStorageRepo domainChatStorage.IChatStorageRepository) {
 	// Only process events that have actual changes
 	hasChanges := len(evt.Join) > 0 || len(evt.Leave) > 0 || len(evt.Promote) > 0 || len(evt.Demote) > 0 ||
-		evt.Name != nil || evt.Topic != nil || evt.Locked != nil || evt.Announce != nil
+		evt.Name != nil || evt.Topic != nil || evt.Locked != nil || evt.Announce != nil || evt.Ephemeral != nil ||
+		evt.MembershipApprovalMode != nil
 
 	if !hasChanges {
 		return
@@ -959,12 +1045,21 @@ func handleGroupInfo(ctx context.Context, evt *events.GroupInfo) {
 		log.Infof("Group %s: %d users demoted at %s", evt.JID, len(evt.Demote), evt.Timestamp)
 	}
 
+	// Record the changes in the group event history, resolving previous values
+	changes := recordGroupEvents(evt, chatStorageRepo)
+	for _, change := range changes {
+		log.Infof("Group %s: %s changed to %q by %s at %s", evt.JID, change.field, change.newValue, groupEventActor(evt), evt.Timestamp)
+	}
+
 	// Forward group info event to webhook if configured
 	if len(config.WhatsappWebhook) > 0 {
-		go func(e *events.GroupInfo) {
-			if err := forwardGroupInfoToWebhook(ctx, e); err != nil {
+		goWebhook(func() {
+			if err := forwardGroupInfoToWebhook(ctx, evt); err != nil {
 				logrus.Errorf("Failed to forward group info event to webhook: %v", err)
 			}
-		}(evt)
+			if err := forwardGroupUpdatesToWebhook(ctx, evt, changes); err != nil {
+				logrus.Errorf("Failed to forward group update event to webhook: %v", err)
+			}
+		})
 	}
 }
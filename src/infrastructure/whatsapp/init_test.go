@@ -0,0 +1,44 @@
+package whatsapp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleStreamReplaced(t *testing.T) {
+	exit := config.WhatsappExitOnStreamReplaced
+	t.Cleanup(func() {
+		config.WhatsappExitOnStreamReplaced = exit
+		utils.SetSessionReplaced(false)
+	})
+
+	tests := []struct {
+		name         string
+		exit         bool
+		wantShutdown bool
+	}{
+		{name: "should wait for a takeover", exit: false},
+		{name: "should request a graceful shutdown", exit: true, wantShutdown: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			utils.SetSessionReplaced(false)
+			config.WhatsappExitOnStreamReplaced = tt.exit
+
+			handleStreamReplaced(context.Background())
+
+			assert.True(t, utils.IsSessionReplaced(), "the session is not reconnected by itself")
+			select {
+			case <-ShutdownRequested():
+				assert.True(t, tt.wantShutdown, "no shutdown is requested")
+			default:
+				assert.False(t, tt.wantShutdown, "a shutdown is requested")
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waMmsRetry"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// Media of older messages is removed from the WhatsApp servers after a while. The phone still has
+// it and uploads it again when it receives a media retry receipt, the new location arrives as a
+// MediaRetry event.
+
+// mediaRetryTimeout is how long the phone gets to upload the media again
+const mediaRetryTimeout = 30 * time.Second
+
+// mediaHost serves the direct paths of uploaded media
+const mediaHost = "https://mmg.whatsapp.net"
+
+var (
+	mediaRetryMu      sync.Mutex
+	mediaRetryWaiters = make(map[types.MessageID][]chan *events.MediaRetry)
+)
+
+// IsMediaExpired reports whether a download failed because the media is no longer on the WhatsApp
+// servers, so it has to be requested from the phone with RequestMediaReupload
+func IsMediaExpired(err error) bool {
+	return errors.Is(err, whatsmeow.ErrMediaDownloadFailedWith403) ||
+		errors.Is(err, whatsmeow.ErrMediaDownloadFailedWith404) ||
+		errors.Is(err, whatsmeow.ErrMediaDownloadFailedWith410)
+}
+
+// RequestMediaReupload asks the phone to upload the media of the message again and waits for the
+// upload. It returns the direct path the media can be downloaded from and its URL.
+func RequestMediaReupload(ctx context.Context, info types.MessageInfo, mediaKey []byte) (directPath, url string, err error) {
+	if cli == nil {
+		return "", "", pkgError.ErrWaCLI
+	}
+	return requestMediaReupload(ctx, info, mediaKey, cli.SendMediaRetryReceipt)
+}
+
+// requestMediaReupload sends the media retry receipt with send and waits for the MediaRetry event
+func requestMediaReupload(ctx context.Context, info types.MessageInfo, mediaKey []byte, send func(*types.MessageInfo, []byte) error) (directPath, url string, err error) {
+	retries := make(chan *events.MediaRetry, 1)
+	stop := waitMediaRetry(info.ID, retries)
+	defer stop()
+
+	if err := send(&info, mediaKey); err != nil {
+		return "", "", fmt.Errorf("failed to request media of message %s from the phone: %w", info.ID, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, mediaRetryTimeout)
+	defer cancel()
+
+	select {
+	case evt := <-retries:
+		return mediaRetryResult(evt, mediaKey)
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", "", pkgError.WaMediaRetryError(fmt.Sprintf(
+				"media of message %s expired and the phone did not upload it again within %s, make sure it is online", info.ID, mediaRetryTimeout))
+		}
+		return "", "", ctx.Err()
+	}
+}
+
+func mediaRetryResult(evt *events.MediaRetry, mediaKey []byte) (directPath, url string, err error) {
+	notification, err := whatsmeow.DecryptMediaRetryNotification(evt, mediaKey)
+	if errors.Is(err, whatsmeow.ErrMediaNotAvailableOnPhone) {
+		return "", "", pkgError.WaMediaRetryError(fmt.Sprintf(
+			"media of message %s expired and is no longer available on the phone", evt.MessageID))
+	}
+	if err != nil {
+		return "", "", pkgError.WaMediaRetryError(fmt.Sprintf("media of message %s could not be uploaded again: %v", evt.MessageID, err))
+	}
+
+	switch notification.GetResult() {
+	case waMmsRetry.MediaRetryNotification_SUCCESS:
+		if notification.GetDirectPath() == "" {
+			return "", "", pkgError.WaMediaRetryError(fmt.Sprintf("phone uploaded media of message %s again without a location", evt.MessageID))
+		}
+		return notification.GetDirectPath(), mediaHost + notification.GetDirectPath(), nil
+	case waMmsRetry.MediaRetryNotification_NOT_FOUND:
+		return "", "", pkgError.WaMediaRetryError(fmt.Sprintf(
+			"media of message %s expired and is no longer available on the phone", evt.MessageID))
+	default:
+		return "", "", pkgError.WaMediaRetryError(fmt.Sprintf(
+			"phone could not upload media of message %s again: %s", evt.MessageID, notification.GetResult()))
+	}
+}
+
+// waitMediaRetry delivers the MediaRetry event of the message to ch until stop is called
+func waitMediaRetry(messageID types.MessageID, ch chan *events.MediaRetry) (stop func()) {
+	mediaRetryMu.Lock()
+	mediaRetryWaiters[messageID] = append(mediaRetryWaiters[messageID], ch)
+	mediaRetryMu.Unlock()
+
+	return func() {
+		mediaRetryMu.Lock()
+		defer mediaRetryMu.Unlock()
+		waiters := mediaRetryWaiters[messageID]
+		for i, waiter := range waiters {
+			if waiter == ch {
+				waiters = append(waiters[:i], waiters[i+1:]...)
+				break
+			}
+		}
+		if len(waiters) == 0 {
+			delete(mediaRetryWaiters, messageID)
+		} else {
+			mediaRetryWaiters[messageID] = waiters
+		}
+	}
+}
+
+func handleMediaRetry(evt *events.MediaRetry) {
+	mediaRetryMu.Lock()
+	defer mediaRetryMu.Unlock()
+
+	waiters := mediaRetryWaiters[evt.MessageID]
+	if len(waiters) == 0 {
+		logrus.Debugf("Ignoring media retry of message %s nobody waits for", evt.MessageID)
+		return
+	}
+	for _, waiter := range waiters {
+		select {
+		case waiter <- evt:
+		default:
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package whatsapp
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mau.fi/whatsmeow/proto/waMmsRetry"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+var testMediaKey = []byte("0123456789abcdef0123456789abcdef")
+
+// mediaRetryEvent encrypts notification the way the phone answers a media retry receipt
+func mediaRetryEvent(t *testing.T, messageID string, notification *waMmsRetry.MediaRetryNotification) *events.MediaRetry {
+	plaintext, err := proto.Marshal(notification)
+	require.NoError(t, err)
+	key, err := hkdf.Key(sha256.New, testMediaKey, nil, "WhatsApp Media Retry Notification", 32)
+	require.NoError(t, err)
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	iv := make([]byte, gcm.NonceSize())
+	return &events.MediaRetry{
+		MessageID:  types.MessageID(messageID),
+		IV:         iv,
+		Ciphertext: gcm.Seal(nil, iv, plaintext, []byte(messageID)),
+	}
+}
+
+func mediaRetryWaiting(messageID types.MessageID) bool {
+	mediaRetryMu.Lock()
+	defer mediaRetryMu.Unlock()
+	_, waiting := mediaRetryWaiters[messageID]
+	return waiting
+}
+
+func TestMediaRetryResult(t *testing.T) {
+	tests := []struct {
+		name           string
+		evt            *events.MediaRetry
+		wantDirectPath string
+		wantErr        string
+	}{
+		{
+			name: "should return the new location of uploaded media",
+			evt: mediaRetryEvent(t, "MSG1", &waMmsRetry.MediaRetryNotification{
+				Result:     waMmsRetry.MediaRetryNotification_SUCCESS.Enum(),
+				DirectPath: proto.String("/v/t62.7118-24/media"),
+			}),
+			wantDirectPath: "/v/t62.7118-24/media",
+		},
+		{
+			name:    "should reject an upload without a location",
+			evt:     mediaRetryEvent(t, "MSG1", &waMmsRetry.MediaRetryNotification{Result: waMmsRetry.MediaRetryNotification_SUCCESS.Enum()}),
+			wantErr: "without a location",
+		},
+		{
+			name:    "should report media deleted from the phone",
+			evt:     mediaRetryEvent(t, "MSG1", &waMmsRetry.MediaRetryNotification{Result: waMmsRetry.MediaRetryNotification_NOT_FOUND.Enum()}),
+			wantErr: "no longer available on the phone",
+		},
+		{
+			name:    "should report an unencrypted not available error",
+			evt:     &events.MediaRetry{MessageID: "MSG1", Error: &events.MediaRetryError{Code: 2}},
+			wantErr: "no longer available on the phone",
+		},
+		{
+			name:    "should report a notification that does not decrypt",
+			evt:     &events.MediaRetry{MessageID: "MSG1", IV: make([]byte, 12), Ciphertext: []byte("garbage ciphertext")},
+			wantErr: "could not be uploaded again",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			directPath, url, err := mediaRetryResult(tt.evt, testMediaKey)
+			if tt.wantErr != "" {
+				assert.ErrorAs(t, err, new(pkgError.WaMediaRetryError))
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantDirectPath, directPath)
+			assert.Equal(t, mediaHost+tt.wantDirectPath, url)
+		})
+	}
+}
+
+func TestRequestMediaReupload(t *testing.T) {
+	info := types.MessageInfo{ID: "MSG2"}
+
+	t.Run("should return the media the phone uploaded again", func(t *testing.T) {
+		answer := func(info *types.MessageInfo, _ []byte) error {
+			evt := mediaRetryEvent(t, info.ID, &waMmsRetry.MediaRetryNotification{
+				Result:     waMmsRetry.MediaRetryNotification_SUCCESS.Enum(),
+				DirectPath: proto.String("/v/new"),
+			})
+			go handleMediaRetry(evt)
+			return nil
+		}
+		directPath, _, err := requestMediaReupload(context.Background(), info, testMediaKey, answer)
+		require.NoError(t, err)
+		assert.Equal(t, "/v/new", directPath)
+		assert.False(t, mediaRetryWaiting(info.ID), "the waiter is removed")
+	})
+
+	t.Run("should give up when the phone does not answer", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		silent := func(*types.MessageInfo, []byte) error { return nil }
+		_, _, err := requestMediaReupload(ctx, info, testMediaKey, silent)
+		assert.ErrorAs(t, err, new(pkgError.WaMediaRetryError))
+		assert.ErrorContains(t, err, "did not upload it again")
+		assert.False(t, mediaRetryWaiting(info.ID), "the waiter is removed")
+	})
+}
+
+func TestWaitMediaRetryCleansUpWaiters(t *testing.T) {
+	first := make(chan *events.MediaRetry, 1)
+	second := make(chan *events.MediaRetry, 1)
+	stopFirst := waitMediaRetry("MSG3", first)
+	stopSecond := waitMediaRetry("MSG3", second)
+
+	handleMediaRetry(&events.MediaRetry{MessageID: "MSG3"})
+	assert.Len(t, first, 1, "every waiter of the message gets the event")
+	assert.Len(t, second, 1)
+
+	stopFirst()
+	mediaRetryMu.Lock()
+	assert.Equal(t, []chan *events.MediaRetry{second}, mediaRetryWaiters["MSG3"])
+	mediaRetryMu.Unlock()
+
+	stopSecond()
+	assert.False(t, mediaRetryWaiting("MSG3"))
+
+	// An event nobody waits for is dropped
+	handleMediaRetry(&events.MediaRetry{MessageID: "MSG3"})
+	assert.Len(t, first, 1)
+}
@@ -0,0 +1,203 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	domainMediaStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/mediastorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/signedurl"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow"
+)
+
+// Media is stored once per content: the first message carrying a file downloads it into the media
+// storage under <chat>/<date>, later messages with the same FileSHA256 are linked to that copy.
+
+var mediaStorage domainMediaStorage.IMediaStorage
+
+// mediaLocks serializes the storing of the same content, so messages arriving together keep one copy
+var mediaLocks [64]sync.Mutex
+
+// SetMediaStorage sets the storage downloaded media is kept in for this package.
+func SetMediaStorage(storage domainMediaStorage.IMediaStorage) {
+	mediaStorage = storage
+}
+
+// MediaRef identifies the message a media file belongs to
+type MediaRef struct {
+	ChatJID   string
+	MessageID string
+	Timestamp time.Time
+}
+
+// key returns the media storage key of a file named name that belongs to the message
+func (ref MediaRef) key(name string) string {
+	return path.Join(utils.ExtractPhoneNumber(ref.ChatJID), ref.Timestamp.Format("2006-01-02"), path.Base(name))
+}
+
+// DownloadMedia returns the media storage key of media for the message ref in MediaPath,
+// downloading it only when no message with the same content was downloaded before
+func DownloadMedia(ctx context.Context, ref MediaRef, media whatsmeow.DownloadableMessage) (utils.ExtractedMedia, error) {
+	sum := media.GetFileSHA256()
+	if len(sum) != sha256.Size {
+		return downloadMediaFile(ctx, ref, media, "")
+	}
+
+	unlock := lockMedia(sum)
+	defer unlock()
+
+	sha := hex.EncodeToString(sum)
+	if file := storedMedia(ctx, sha); file != nil {
+		extracted := utils.DescribeMedia(media)
+		extracted.MediaPath = file.Path
+		linkMedia(ref, file.Path)
+		return extracted, nil
+	}
+	return downloadMediaFile(ctx, ref, media, sha)
+}
+
+// AddMediaFile adds the content of body as a file named name to the media store, for example an
+// imported attachment, and returns its media storage key. When the store has a copy of the same
+// content that copy is linked to the message instead.
+func AddMediaFile(ctx context.Context, ref MediaRef, name, mimeType string, body io.Reader) (string, error) {
+	// The content is staged in a temporary file, its hash is only known once it was read
+	staged, err := os.CreateTemp("", "media-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(staged.Name())
+	defer staged.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(staged, hash), body)
+	if err != nil {
+		return "", err
+	}
+	sum := hash.Sum(nil)
+
+	unlock := lockMedia(sum)
+	defer unlock()
+
+	sha := hex.EncodeToString(sum)
+	if stored := storedMedia(ctx, sha); stored != nil {
+		linkMedia(ref, stored.Path)
+		return stored.Path, nil
+	}
+
+	if _, err := staged.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	// Prefixed with the hash, a file of the same name with other content never replaces this one
+	key := ref.key(sha[:16] + "-" + path.Base(name))
+	if err := mediaStorage.Put(ctx, key, staged, size, mimeType); err != nil {
+		return "", fmt.Errorf("failed to store media: %w", err)
+	}
+
+	storeMediaContent(&domainChatStorage.MediaFile{SHA256: sha, Path: key, MimeType: mimeType, Size: size})
+	linkMedia(ref, key)
+	return key, nil
+}
+
+// MediaLocation returns where the media with the storage key lives, a file path or an s3:// URL
+func MediaLocation(key string) string {
+	return mediaStorage.Locate(key)
+}
+
+// MediaURL returns a URL the media with the storage key and content hash sha can be fetched from
+// without API credentials: a presigned URL when the storage serves files itself, otherwise a signed
+// URL of GET /media/:sha256. It is empty when neither is available.
+func MediaURL(ctx context.Context, key string, sha []byte) string {
+	url, err := mediaStorage.URL(ctx, key, config.MediaStorageURLExpiry)
+	if err != nil {
+		logrus.WithError(err).Warnf("Failed to create URL for media %s", key)
+	}
+	if url != "" || len(sha) != sha256.Size {
+		return url
+	}
+
+	mediaPath := config.AppBasePath + "/media/" + hex.EncodeToString(sha)
+	expires := time.Now().Add(config.MediaStorageURLExpiry)
+	return strings.TrimSuffix(config.AppPublicURL, "/") + signedurl.Sign([]byte(config.MediaStorageURLSecret), mediaPath, expires)
+}
+
+// downloadMediaFile downloads media into the media storage under <chat>/<date> and records it
+// under sha, an empty sha only links the file to the message
+func downloadMediaFile(ctx context.Context, ref MediaRef, media whatsmeow.DownloadableMessage, sha string) (utils.ExtractedMedia, error) {
+	data, extracted, err := utils.DownloadMediaContent(ctx, cli, media)
+	if err != nil {
+		return extracted, err
+	}
+
+	key := ref.key(utils.MediaFileName(extracted.MimeType))
+	if err := mediaStorage.Put(ctx, key, bytes.NewReader(data), int64(len(data)), extracted.MimeType); err != nil {
+		return extracted, fmt.Errorf("failed to store media: %w", err)
+	}
+	extracted.MediaPath = key
+
+	if sha != "" {
+		storeMediaContent(&domainChatStorage.MediaFile{SHA256: sha, Path: key, MimeType: extracted.MimeType, Size: int64(len(data))})
+	}
+	linkMedia(ref, key)
+	return extracted, nil
+}
+
+// storedMedia returns the stored copy of the content sha, nil when there is none or it is gone
+func storedMedia(ctx context.Context, sha string) *domainChatStorage.MediaFile {
+	if chatStorageRepo == nil {
+		return nil
+	}
+	file, err := chatStorageRepo.GetMediaContent(sha)
+	if err != nil {
+		logrus.WithError(err).Warnf("Failed to look up media %s", sha)
+		return nil
+	}
+	if file == nil {
+		return nil
+	}
+	if _, err := mediaStorage.Stat(ctx, file.Path); err != nil {
+		if !errors.Is(err, domainMediaStorage.ErrNotFound) {
+			logrus.WithError(err).Warnf("Failed to check media %s", file.Path)
+		}
+		return nil
+	}
+	return file
+}
+
+func storeMediaContent(file *domainChatStorage.MediaFile) {
+	if chatStorageRepo == nil {
+		return
+	}
+	if err := chatStorageRepo.StoreMediaContent(file); err != nil {
+		logrus.WithError(err).Warnf("Failed to record media %s", file.SHA256)
+	}
+}
+
+// linkMedia links a media file to its stored message, so the message row carries the storage key
+// and retention deletes the file once no message links to it
+func linkMedia(ref MediaRef, key string) {
+	if chatStorageRepo == nil || key == "" {
+		return
+	}
+	if err := chatStorageRepo.StoreMediaFile(ref.ChatJID, ref.MessageID, key); err != nil {
+		logrus.WithError(err).Warnf("Failed to record media file %s", key)
+	}
+}
+
+func lockMedia(sum []byte) (unlock func()) {
+	lock := &mediaLocks[int(sum[0])%len(mediaLocks)]
+	lock.Lock()
+	return lock.Unlock
+}
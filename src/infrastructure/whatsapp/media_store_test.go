@@ -0,0 +1,55 @@
+package whatsapp
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/mediastorage"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddMediaFileKeepsContentOfTheSameName(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "chatstorage.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	repo := chatstorage.NewStorageRepository(db)
+	require.NoError(t, repo.InitializeSchema())
+
+	previousRepo, previousStorage := chatStorageRepo, mediaStorage
+	t.Cleanup(func() { chatStorageRepo, mediaStorage = previousRepo, previousStorage })
+	chatStorageRepo = repo
+	SetMediaStorage(mediastorage.NewLocalStorage(t.TempDir()))
+
+	ctx := context.Background()
+	ref := func(id string) MediaRef {
+		return MediaRef{ChatJID: "6281234567890@s.whatsapp.net", MessageID: id, Timestamp: time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)}
+	}
+	read := func(key string) string {
+		body, err := mediaStorage.Open(ctx, key)
+		require.NoError(t, err)
+		defer body.Close()
+		content, err := io.ReadAll(body)
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	first, err := AddMediaFile(ctx, ref("a"), "IMG-1.jpg", "image/jpeg", strings.NewReader("first"))
+	require.NoError(t, err)
+	second, err := AddMediaFile(ctx, ref("b"), "IMG-1.jpg", "image/jpeg", strings.NewReader("second"))
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second, "a file of the same name with other content gets its own key")
+	assert.Equal(t, "first", read(first))
+	assert.Equal(t, "second", read(second))
+
+	again, err := AddMediaFile(ctx, ref("c"), "copy.jpg", "image/jpeg", strings.NewReader("first"))
+	require.NoError(t, err)
+	assert.Equal(t, first, again, "the same content is linked to the stored copy")
+}
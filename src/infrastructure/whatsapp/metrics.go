@@ -0,0 +1,51 @@
+package whatsapp
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/metrics"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+)
+
+var registerMetricsOnce sync.Once
+
+// RegisterMetrics exposes the connection state of the global client as Prometheus gauges
+func RegisterMetrics() {
+	registerMetricsOnce.Do(func() {
+		metrics.RegisterGauge("connected", "Whether the WhatsApp client is connected (1) or not (0).", func() float64 {
+			client := GetClient()
+			return boolMetric(client != nil && client.IsConnected())
+		})
+		metrics.RegisterGauge("logged_in", "Whether the WhatsApp client is logged in (1) or not (0).", func() float64 {
+			client := GetClient()
+			return boolMetric(client != nil && client.IsLoggedIn())
+		})
+		metrics.RegisterGauge("session_replaced", "Whether the session was taken over by another client (1) or not (0).", func() float64 {
+			return boolMetric(utils.IsSessionReplaced())
+		})
+		metrics.RegisterGauge("reconnect_attempts", "Failed reconnect attempts in the current outage.", func() float64 {
+			return float64(GetReconnectStatus().Attempts)
+		})
+		metrics.RegisterCounter("reconnects_total", "Successful reconnects since start.", func() float64 {
+			return float64(GetReconnectStatus().TotalReconnects)
+		})
+		metrics.RegisterGauge("auto_download_queue", "Incoming media waiting to be downloaded.", func() float64 {
+			return float64(len(autoDownloadQueue))
+		})
+	})
+}
+
+// eventName returns the whatsmeow event type name, e.g. "Message" for *events.Message
+func eventName(rawEvt any) string {
+	name := fmt.Sprintf("%T", rawEvt)
+	return strings.TrimPrefix(name[strings.LastIndex(name, ".")+1:], "*")
+}
+
+func boolMetric(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,215 @@
+package whatsapp
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// Connection supervisor states
+const (
+	ReconnectStateConnected    = "connected"
+	ReconnectStateReconnecting = "reconnecting"
+	ReconnectStateIdle         = "idle" // not logged in or session replaced, nothing to supervise
+)
+
+const (
+	reconnectBaseDelay     = 2 * time.Second
+	reconnectMaxDelay      = 5 * time.Minute
+	reconnectWatchdogEvery = 1 * time.Minute
+)
+
+// ReconnectStatus is a snapshot of the connection supervisor
+type ReconnectStatus struct {
+	State            string     `json:"state"`
+	Attempts         int        `json:"attempts"`         // failed attempts in the current outage
+	TotalReconnects  int        `json:"total_reconnects"` // successful reconnects since start
+	LastError        string     `json:"last_error,omitempty"`
+	LastDisconnectAt *time.Time `json:"last_disconnect_at,omitempty"`
+	LastConnectedAt  *time.Time `json:"last_connected_at,omitempty"`
+	NextAttemptAt    *time.Time `json:"next_attempt_at,omitempty"`
+}
+
+// reconnectSupervisor reconnects the global client after unexpected disconnections,
+// backing off exponentially with jitter between failed attempts
+type reconnectSupervisor struct {
+	mu      sync.Mutex
+	status  ReconnectStatus
+	trigger chan struct{}
+	stop    chan struct{} // closed by StopReconnect
+	start   sync.Once
+	halt    sync.Once
+}
+
+var supervisor = newReconnectSupervisor()
+
+func newReconnectSupervisor() *reconnectSupervisor {
+	return &reconnectSupervisor{
+		status:  ReconnectStatus{State: ReconnectStateIdle},
+		trigger: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+}
+
+// GetReconnectStatus returns the current state of the connection supervisor
+func GetReconnectStatus() ReconnectStatus {
+	supervisor.mu.Lock()
+	defer supervisor.mu.Unlock()
+	return supervisor.status
+}
+
+// startReconnectSupervisor starts the supervisor loop once per process
+func startReconnectSupervisor() {
+	supervisor.start.Do(func() {
+		go supervisor.run()
+	})
+}
+
+// StopReconnect stops the supervisor, a reconnect waiting for its backoff is abandoned
+func StopReconnect() {
+	supervisor.halt.Do(func() {
+		close(supervisor.stop)
+	})
+}
+
+// handleReconnectEvent feeds connection events to the supervisor
+func handleReconnectEvent(rawEvt any) {
+	switch evt := rawEvt.(type) {
+	case *events.Connected:
+		supervisor.markConnected()
+	case *events.Disconnected:
+		supervisor.markDisconnected("connection lost")
+	case *events.KeepAliveTimeout:
+		// The socket may look open while pings keep failing, force a fresh connection
+		if time.Since(evt.LastSuccess) > whatsmeow.KeepAliveMaxFailTime {
+			if client := GetClient(); client != nil {
+				client.Disconnect()
+			}
+			supervisor.markDisconnected("keepalive timeout")
+		}
+	case *events.LoggedOut, *events.StreamReplaced:
+		supervisor.markIdle()
+	}
+}
+
+func (s *reconnectSupervisor) markConnected() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.status.State == ReconnectStateReconnecting {
+		s.status.TotalReconnects++
+	}
+	s.status.State = ReconnectStateConnected
+	s.status.Attempts = 0
+	s.status.LastError = ""
+	s.status.LastConnectedAt = &now
+	s.status.NextAttemptAt = nil
+}
+
+func (s *reconnectSupervisor) markDisconnected(reason string) {
+	s.mu.Lock()
+	now := time.Now()
+	s.status.LastDisconnectAt = &now
+	s.mu.Unlock()
+
+	logrus.Warnf("[RECONNECT] Disconnected (%s), scheduling reconnect", reason)
+	s.wake()
+}
+
+func (s *reconnectSupervisor) markIdle() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status.State = ReconnectStateIdle
+	s.status.Attempts = 0
+	s.status.NextAttemptAt = nil
+}
+
+func (s *reconnectSupervisor) wake() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (s *reconnectSupervisor) run() {
+	// The watchdog catches disconnections that did not produce an event, e.g. a failed boot connect
+	watchdog := time.NewTicker(reconnectWatchdogEvery)
+	defer watchdog.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-s.trigger:
+		case <-watchdog.C:
+		}
+		s.reconnect()
+	}
+}
+
+// reconnect retries until the client is connected or there is nothing left to supervise
+func (s *reconnectSupervisor) reconnect() {
+	for {
+		client := GetClient()
+		if client == nil || client.Store.ID == nil || utils.IsSessionReplaced() {
+			s.markIdle()
+			return
+		}
+		if client.IsConnected() {
+			return
+		}
+
+		s.mu.Lock()
+		delay := backoffDelay(s.status.Attempts)
+		next := time.Now().Add(delay)
+		s.status.State = ReconnectStateReconnecting
+		s.status.NextAttemptAt = &next
+		attempt := s.status.Attempts + 1
+		s.mu.Unlock()
+
+		logrus.Infof("[RECONNECT] Attempt %d in %s", attempt, delay.Round(time.Millisecond))
+		timer := time.NewTimer(delay)
+		select {
+		case <-s.stop:
+			timer.Stop()
+			s.markIdle()
+			return
+		case <-timer.C:
+		}
+
+		// The client may have been replaced (logout, manual reconnect) while waiting
+		client = GetClient()
+		if client == nil || client.IsConnected() {
+			continue
+		}
+
+		if err := client.Connect(); err != nil {
+			logrus.Errorf("[RECONNECT] Attempt %d failed: %v", attempt, err)
+			s.mu.Lock()
+			s.status.Attempts = attempt
+			s.status.LastError = err.Error()
+			s.mu.Unlock()
+			continue
+		}
+
+		logrus.Infof("[RECONNECT] Attempt %d connected", attempt)
+		return
+	}
+}
+
+// backoffDelay returns an exponential delay for the given number of failed attempts,
+// randomized between half and the full value so clients do not retry in lockstep
+func backoffDelay(attempts int) time.Duration {
+	delay := reconnectBaseDelay << min(attempts, 16)
+	if delay > reconnectMaxDelay || delay <= 0 {
+		delay = reconnectMaxDelay
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
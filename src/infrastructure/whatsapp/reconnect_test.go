@@ -0,0 +1,87 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		want     time.Duration // the delay before jitter
+	}{
+		{name: "should start at the base delay", attempts: 0, want: reconnectBaseDelay},
+		{name: "should double per failed attempt", attempts: 1, want: 2 * reconnectBaseDelay},
+		{name: "should keep growing", attempts: 4, want: 16 * reconnectBaseDelay},
+		{name: "should cap the delay", attempts: 10, want: reconnectMaxDelay},
+		{name: "should cap instead of overflowing", attempts: 100, want: reconnectMaxDelay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				delay := backoffDelay(tt.attempts)
+				assert.GreaterOrEqual(t, delay, tt.want/2, "jitter keeps at least half the delay")
+				assert.LessOrEqual(t, delay, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconnectSupervisorStates(t *testing.T) {
+	s := newReconnectSupervisor()
+	assert.Equal(t, ReconnectStateIdle, s.status.State)
+
+	s.markConnected()
+	assert.Equal(t, ReconnectStateConnected, s.status.State)
+	assert.NotNil(t, s.status.LastConnectedAt)
+	assert.Zero(t, s.status.TotalReconnects, "the first connection is not a reconnect")
+
+	s.markDisconnected("connection lost")
+	assert.NotNil(t, s.status.LastDisconnectAt)
+	assert.Len(t, s.trigger, 1, "the supervisor is woken")
+	s.markDisconnected("connection lost")
+	assert.Len(t, s.trigger, 1, "wake ups do not pile up")
+
+	// A failed attempt as recorded by reconnect
+	next := time.Now().Add(time.Second)
+	s.status.State = ReconnectStateReconnecting
+	s.status.Attempts = 2
+	s.status.LastError = "dial failed"
+	s.status.NextAttemptAt = &next
+
+	s.markConnected()
+	assert.Equal(t, ReconnectStateConnected, s.status.State)
+	assert.Equal(t, 1, s.status.TotalReconnects)
+	assert.Zero(t, s.status.Attempts)
+	assert.Empty(t, s.status.LastError)
+	assert.Nil(t, s.status.NextAttemptAt)
+
+	s.status.Attempts = 3
+	s.status.NextAttemptAt = &next
+	s.markIdle()
+	assert.Equal(t, ReconnectStateIdle, s.status.State)
+	assert.Zero(t, s.status.Attempts)
+	assert.Nil(t, s.status.NextAttemptAt)
+	assert.Equal(t, 1, s.status.TotalReconnects, "idle keeps the history")
+}
+
+func TestReconnectSupervisorStops(t *testing.T) {
+	s := newReconnectSupervisor()
+	stopped := make(chan struct{})
+	go func() {
+		s.run()
+		close(stopped)
+	}()
+
+	close(s.stop)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the supervisor keeps running after it was stopped")
+	}
+}
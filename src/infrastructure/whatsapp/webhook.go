@@ -7,15 +7,60 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
 	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/logging"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/metrics"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/tracing"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
-	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
 )
 
-func submitWebhook(ctx context.Context, payload map[string]any, url string) error {
+// webhookDeliveries tracks webhook submissions in progress so shutdown can drain them
+var webhookDeliveries sync.WaitGroup
+
+// WaitForWebhooks blocks until in-flight webhook deliveries finish or the context is done
+func WaitForWebhooks(ctx context.Context) error {
+	return utils.WaitGroupContext(ctx, &webhookDeliveries)
+}
+
+// goWebhook runs deliver in a new goroutine. The delivery is counted before the goroutine starts, so
+// WaitForWebhooks never misses one that was already handed off.
+func goWebhook(deliver func()) {
+	webhookDeliveries.Add(1)
+	go func() {
+		defer webhookDeliveries.Done()
+		deliver()
+	}()
+}
+
+func submitWebhook(ctx context.Context, payload map[string]any, url string) (err error) {
+	started := time.Now()
+
+	spanName := "webhook"
+	event, _ := payload["event"].(string)
+	if event != "" {
+		spanName += " " + event
+	}
+	ctx, span := tracing.StartClient(ctx, spanName,
+		attribute.String("webhook.host", metrics.WebhookHost(url)),
+		attribute.String("webhook.event", event),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	// Deliveries not triggered by an API request get their own ID so retries can be correlated
+	requestID := logging.RequestID(ctx)
+	if requestID == "" {
+		requestID = logging.NewRequestID()
+		ctx = logging.WithRequestID(ctx, requestID)
+	}
+	logger := logging.FromContext(ctx).WithField("webhook_url", url)
+
 	client := &http.Client{Timeout: 10 * time.Second}
 
 	postBody, err := json.Marshal(payload)
@@ -36,29 +81,36 @@ func submitWebhook(ctx context.Context, payload map[string]any, url string) erro
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-Hub-Signature-256", fmt.Sprintf("sha256=%s", signature))
+	req.Header.Set(logging.RequestIDHeader, requestID)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
 	var attempt int
 	var maxAttempts = 5
 	var sleepDuration = 1 * time.Second
+	var lastErr error
 
 	for attempt = 0; attempt < maxAttempts; attempt++ {
 		// Create new request body for each attempt
 		req.Body = io.NopCloser(bytes.NewBuffer(postBody))
+		span.SetAttributes(attribute.Int("webhook.attempts", attempt+1))
 		resp, err := client.Do(req)
 		if err == nil {
 			defer resp.Body.Close()
 			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-				logrus.Infof("Successfully submitted webhook on attempt %d", attempt+1)
+				logger.Infof("Successfully submitted webhook on attempt %d", attempt+1)
+				metrics.ObserveWebhook(url, started, attempt, nil)
 				return nil
 			}
 			err = fmt.Errorf("webhook returned status %d", resp.StatusCode)
 		}
-		logrus.Warnf("Attempt %d to submit webhook failed: %v", attempt+1, err)
+		logger.Warnf("Attempt %d to submit webhook failed: %v", attempt+1, err)
+		lastErr = err
 		if attempt < maxAttempts-1 {
 			time.Sleep(sleepDuration)
 			sleepDuration *= 2
 		}
 	}
 
-	return pkgError.WebhookError(fmt.Sprintf("error when submit webhook after %d attempts: %v", attempt, err))
+	metrics.ObserveWebhook(url, started, attempt-1, lastErr)
+	return pkgError.WebhookError(fmt.Sprintf("error when submit webhook after %d attempts: %v", attempt, lastErr))
 }
@@ -0,0 +1,124 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWaitForWebhooksWaitsForHandedOffDeliveries(t *testing.T) {
+	release := make(chan struct{})
+	var delivered atomic.Bool
+	goWebhook(func() {
+		<-release
+		delivered.Store(true)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, WaitForWebhooks(ctx), context.DeadlineExceeded, "a delivery that has not started yet is waited for")
+
+	close(release)
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, WaitForWebhooks(ctx))
+	assert.True(t, delivered.Load())
+}
+
+// recordSpans installs a tracer provider that keeps ended spans in memory for the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestSubmitWebhookSpan(t *testing.T) {
+	recorder := recordSpans(t)
+	require.NoError(t, tracing.Setup(context.Background()))
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+
+	ctx, parent := tracing.StartServer(context.Background(), "POST /send/message")
+	require.NoError(t, submitWebhook(ctx, map[string]any{"event": "message.ack"}, server.URL))
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	span := spans[0]
+	assert.Equal(t, "webhook message.ack", span.Name())
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+	assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("webhook.host", strings.TrimPrefix(server.URL, "http://")),
+		attribute.String("webhook.event", "message.ack"),
+		attribute.Int("webhook.attempts", 1),
+	}, span.Attributes())
+	assert.Equal(t, codes.Unset, span.Status().Code)
+	assert.Contains(t, traceparent, span.SpanContext().SpanID().String(), "the receiver continues the trace of the delivery")
+
+	t.Run("should record failed deliveries", func(t *testing.T) {
+		recorder := recordSpans(t)
+		err := submitWebhook(context.Background(), map[string]any{"event": "message", "body": make(chan int)}, server.URL)
+		assert.ErrorAs(t, err, new(pkgError.WebhookError))
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "webhook message", spans[0].Name())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Contains(t, spans[0].Status().Description, "Failed to marshal body")
+	})
+}
+
+// captureWebhooks points the webhooks at a test server. The returned function waits for the
+// deliveries in flight and returns the payloads received so far.
+func captureWebhooks(t *testing.T) func() []map[string]any {
+	var mu sync.Mutex
+	var received []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		mu.Lock()
+		received = append(received, payload)
+		mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	previous := config.WhatsappWebhook
+	config.WhatsappWebhook = []string{server.URL}
+	t.Cleanup(func() { config.WhatsappWebhook = previous })
+
+	return func() []map[string]any {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		require.NoError(t, WaitForWebhooks(ctx))
+		mu.Lock()
+		defer mu.Unlock()
+		payloads := received
+		received = nil
+		return payloads
+	}
+}
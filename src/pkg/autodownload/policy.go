@@ -0,0 +1,108 @@
+// Package autodownload decides which incoming media is downloaded as soon as the message arrives.
+package autodownload
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// Media types that can be downloaded automatically, as named by the chat storage
+var MediaTypes = []string{"image", "video", "audio", "document", "sticker"}
+
+// Chat types a policy can be limited to
+const (
+	ChatTypeAll    = "all"
+	ChatTypeDirect = "direct"
+	ChatTypeGroup  = "group"
+)
+
+// Policy describes the incoming media that is downloaded automatically
+type Policy struct {
+	mediaTypes []string
+	maxSize    int64
+	chatType   string
+	allow      []string
+	deny       []string
+}
+
+// NewPolicy returns a policy downloading media of mediaTypes up to maxSize bytes from chats of
+// chatType. mediaTypes accepts "all" and "none". allow and deny hold phone numbers, group ids or full
+// JIDs; when allow is set only those chats are downloaded, deny always wins. A maxSize of 0 means
+// no limit besides the maximum download size.
+func NewPolicy(mediaTypes []string, maxSize int64, chatType string, allow, deny []string) (Policy, error) {
+	policy := Policy{maxSize: maxSize, chatType: strings.ToLower(strings.TrimSpace(chatType))}
+	if policy.chatType == "" {
+		policy.chatType = ChatTypeAll
+	}
+	if !slices.Contains([]string{ChatTypeAll, ChatTypeDirect, ChatTypeGroup}, policy.chatType) {
+		return Policy{}, fmt.Errorf("invalid auto download chat type %q, use all, direct or group", chatType)
+	}
+	if maxSize < 0 {
+		return Policy{}, fmt.Errorf("auto download max size must not be negative, got %d", maxSize)
+	}
+
+	for _, mediaType := range mediaTypes {
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		switch {
+		case mediaType == "" || mediaType == "none":
+		case mediaType == "all":
+			policy.mediaTypes = append(policy.mediaTypes, MediaTypes...)
+		case slices.Contains(MediaTypes, mediaType):
+			policy.mediaTypes = append(policy.mediaTypes, mediaType)
+		default:
+			return Policy{}, fmt.Errorf("invalid auto download media type %q, use %s, all or none", mediaType, strings.Join(MediaTypes, ", "))
+		}
+	}
+
+	policy.allow = chatList(allow)
+	policy.deny = chatList(deny)
+	return policy, nil
+}
+
+// Enabled reports whether any media is downloaded automatically
+func (p Policy) Enabled() bool {
+	return len(p.mediaTypes) > 0
+}
+
+// Allows reports whether media of mediaType and size bytes received in chat is downloaded
+func (p Policy) Allows(mediaType string, size uint64, chat types.JID) bool {
+	if !slices.Contains(p.mediaTypes, mediaType) {
+		return false
+	}
+	if p.maxSize > 0 && size > uint64(p.maxSize) {
+		return false
+	}
+
+	switch p.chatType {
+	case ChatTypeDirect:
+		if chat.Server != types.DefaultUserServer && chat.Server != types.HiddenUserServer {
+			return false
+		}
+	case ChatTypeGroup:
+		if chat.Server != types.GroupServer {
+			return false
+		}
+	}
+
+	if matchesChat(p.deny, chat) {
+		return false
+	}
+	return len(p.allow) == 0 || matchesChat(p.allow, chat)
+}
+
+func chatList(entries []string) []string {
+	var chats []string
+	for _, entry := range entries {
+		if entry = strings.TrimPrefix(strings.TrimSpace(entry), "+"); entry != "" {
+			chats = append(chats, entry)
+		}
+	}
+	return chats
+}
+
+func matchesChat(chats []string, chat types.JID) bool {
+	return slices.Contains(chats, chat.User) || slices.Contains(chats, chat.String())
+}
@@ -0,0 +1,81 @@
+package autodownload_test
+
+import (
+	"testing"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/autodownload"
+	"github.com/stretchr/testify/assert"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestNewPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		mediaTypes  []string
+		maxSize     int64
+		chatType    string
+		wantEnabled bool
+		wantErr     string
+	}{
+		{name: "should enable listed media types", mediaTypes: []string{"image", " Video "}, wantEnabled: true},
+		{name: "should enable all media types", mediaTypes: []string{"all"}, chatType: "group", wantEnabled: true},
+		{name: "should disable with none", mediaTypes: []string{"none"}},
+		{name: "should disable without media types", mediaTypes: nil},
+		{name: "should reject unknown media types", mediaTypes: []string{"gif"}, wantErr: "invalid auto download media type"},
+		{name: "should reject unknown chat types", mediaTypes: []string{"image"}, chatType: "channel", wantErr: "invalid auto download chat type"},
+		{name: "should reject negative sizes", mediaTypes: []string{"image"}, maxSize: -1, wantErr: "must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := autodownload.NewPolicy(tt.mediaTypes, tt.maxSize, tt.chatType, nil, nil)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantEnabled, policy.Enabled())
+		})
+	}
+}
+
+func TestPolicyAllows(t *testing.T) {
+	direct := types.NewJID("6281234567890", types.DefaultUserServer)
+	lid := types.NewJID("123456789012345", types.HiddenUserServer)
+	group := types.NewJID("120363025246125486", types.GroupServer)
+	status := types.NewJID("status", types.BroadcastServer)
+
+	tests := []struct {
+		name      string
+		chatType  string
+		maxSize   int64
+		allow     []string
+		deny      []string
+		mediaType string
+		size      uint64
+		chat      types.JID
+		want      bool
+	}{
+		{name: "should allow enabled media types", mediaType: "image", chat: direct, want: true},
+		{name: "should skip other media types", mediaType: "video", chat: direct, want: false},
+		{name: "should allow media up to the max size", maxSize: 1024, mediaType: "image", size: 1024, chat: direct, want: true},
+		{name: "should skip media over the max size", maxSize: 1024, mediaType: "image", size: 1025, chat: direct, want: false},
+		{name: "should allow direct chats for direct", chatType: "direct", mediaType: "image", chat: lid, want: true},
+		{name: "should skip groups for direct", chatType: "direct", mediaType: "image", chat: group, want: false},
+		{name: "should skip direct chats for group", chatType: "group", mediaType: "image", chat: direct, want: false},
+		{name: "should skip broadcasts for group", chatType: "group", mediaType: "image", chat: status, want: false},
+		{name: "should allow listed chats by phone", allow: []string{"+6281234567890"}, mediaType: "image", chat: direct, want: true},
+		{name: "should allow listed chats by jid", allow: []string{group.String()}, mediaType: "image", chat: group, want: true},
+		{name: "should skip chats missing from the allowlist", allow: []string{"6281111111111"}, mediaType: "image", chat: direct, want: false},
+		{name: "should skip denied chats", deny: []string{"120363025246125486"}, mediaType: "image", chat: group, want: false},
+		{name: "should prefer deny over allow", allow: []string{"6281234567890"}, deny: []string{"6281234567890"}, mediaType: "image", chat: direct, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := autodownload.NewPolicy([]string{"image"}, tt.maxSize, tt.chatType, tt.allow, tt.deny)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, policy.Allows(tt.mediaType, tt.size, tt.chat))
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ManifestName is the first entry of every archive and lists the files that follow it
+const ManifestName = "manifest.json"
+
+// ManifestVersion is bumped when the archive layout changes incompatibly
+const ManifestVersion = 1
+
+// Database kinds of a snapshot file
+const (
+	KindSQLite   = "sqlite"   // A standalone SQLite database file
+	KindPostgres = "postgres" // JSON lines with one table row each, see infrastructure/backup
+)
+
+// Manifest describes the content of a backup archive
+type Manifest struct {
+	Version    int       `json:"version"`
+	AppVersion string    `json:"app_version"`
+	CreatedAt  time.Time `json:"created_at"`
+	Files      []File    `json:"files"`
+}
+
+// File is one database snapshot in an archive
+type File struct {
+	Name   string `json:"name"`
+	Role   string `json:"role"` // Which database it restores: whatsapp, whatsapp_keys or chatstorage
+	Kind   string `json:"kind"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// WriteArchive writes the manifest followed by the files of dir it lists as a tar.gz. Sizes and
+// checksums of the files are filled in from disk.
+func WriteArchive(w io.Writer, dir string, manifest *Manifest) error {
+	for i := range manifest.Files {
+		size, sum, err := checksumFile(filepath.Join(dir, manifest.Files[i].Name))
+		if err != nil {
+			return err
+		}
+		manifest.Files[i].Size = size
+		manifest.Files[i].SHA256 = sum
+	}
+	manifest.Version = ManifestVersion
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+
+	header := &tar.Header{Name: ManifestName, Mode: 0600, Size: int64(len(manifestJSON)), ModTime: manifest.CreatedAt}
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := archive.Write(manifestJSON); err != nil {
+		return err
+	}
+
+	for _, file := range manifest.Files {
+		if err := addFileToArchive(archive, filepath.Join(dir, file.Name), file, manifest.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// ReadArchive extracts an archive written by WriteArchive into dir and verifies every file against
+// the checksums of the manifest
+func ReadArchive(r io.Reader, dir string) (*Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupted, err)
+	}
+	defer gz.Close()
+	archive := tar.NewReader(gz)
+
+	header, err := archive.Next()
+	if err != nil || header.Name != ManifestName {
+		return nil, fmt.Errorf("%w: manifest missing", ErrCorrupted)
+	}
+	var manifest Manifest
+	if err := json.NewDecoder(io.LimitReader(archive, 1<<20)).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("%w: invalid manifest: %v", ErrCorrupted, err)
+	}
+	if manifest.Version != ManifestVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", manifest.Version)
+	}
+
+	expected := make(map[string]File, len(manifest.Files))
+	for _, file := range manifest.Files {
+		if file.Name != filepath.Base(file.Name) || file.Name == ManifestName {
+			return nil, fmt.Errorf("%w: invalid file name %q", ErrCorrupted, file.Name)
+		}
+		expected[file.Name] = file
+	}
+
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrCorrupted, err)
+		}
+
+		file, ok := expected[header.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unexpected file %q", ErrCorrupted, header.Name)
+		}
+		if err := extractFile(archive, filepath.Join(dir, file.Name), file); err != nil {
+			return nil, err
+		}
+		delete(expected, header.Name)
+	}
+
+	for name := range expected {
+		return nil, fmt.Errorf("%w: %s is missing", ErrCorrupted, name)
+	}
+
+	return &manifest, nil
+}
+
+func addFileToArchive(archive *tar.Writer, path string, file File, modTime time.Time) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := archive.WriteHeader(&tar.Header{Name: file.Name, Mode: 0600, Size: file.Size, ModTime: modTime}); err != nil {
+		return err
+	}
+	_, err = io.Copy(archive, src)
+	return err
+}
+
+func extractFile(r io.Reader, path string, file File) error {
+	dst, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	// One byte past the manifest size is enough to tell the entry is too large, the rest is never written
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(dst, hash), io.LimitReader(r, file.Size+1))
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCorrupted, err)
+	}
+
+	if size > file.Size {
+		return fmt.Errorf("%w: %s is larger than its manifest size", ErrCorrupted, file.Name)
+	}
+	if size != file.Size || hex.EncodeToString(hash.Sum(nil)) != file.SHA256 {
+		return fmt.Errorf("%w: checksum mismatch for %s", ErrCorrupted, file.Name)
+	}
+	return nil
+}
+
+func checksumFile(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,249 @@
+// Package backup reads and writes the passphrase encrypted snapshot archives of the session and chat databases.
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Encrypted stream layout:
+//
+//	magic | scrypt log2(N), r, p | salt | nonce prefix | HMAC-SHA256 of the preceding bytes
+//	chunks of up to chunkSize bytes, each sealed with AES-256-GCM
+//
+// Chunk nonces are the prefix, a big endian chunk counter and a final-chunk flag, so chunks
+// cannot be reordered, dropped or truncated without failing authentication.
+const (
+	chunkSize       = 64 * 1024
+	saltSize        = 16
+	noncePrefixSize = 7
+	scryptLogN      = 15
+	scryptR         = 8
+	scryptP         = 1
+)
+
+var magic = []byte("GOWABAK1")
+
+var (
+	// ErrWrongPassphrase is returned when the passphrase does not match the one the backup was created with
+	ErrWrongPassphrase = errors.New("wrong backup passphrase")
+	// ErrCorrupted is returned when the encrypted data was modified or truncated
+	ErrCorrupted = errors.New("backup is corrupted or truncated")
+	// ErrNotBackup is returned when the input is not an encrypted backup
+	ErrNotBackup = errors.New("not an encrypted backup file")
+)
+
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	buf     []byte
+	closed  bool
+}
+
+// NewEncryptWriter encrypts everything written to it with a key derived from passphrase. Close must
+// be called to write the final chunk, without it the backup is unreadable.
+func NewEncryptWriter(w io.Writer, passphrase string) (io.WriteCloser, error) {
+	if passphrase == "" {
+		return nil, errors.New("backup passphrase is empty")
+	}
+
+	header := make([]byte, 0, len(magic)+3+saltSize+noncePrefixSize)
+	header = append(header, magic...)
+	header = append(header, scryptLogN, scryptR, scryptP)
+	random := make([]byte, saltSize+noncePrefixSize)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	header = append(header, random...)
+
+	aead, macKey, err := deriveKeys(passphrase, header[len(magic):len(magic)+3], random[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(header)
+	if _, err := w.Write(mac.Sum(header)); err != nil {
+		return nil, err
+	}
+
+	return &encryptWriter{
+		w:      w,
+		aead:   aead,
+		prefix: random[saltSize:],
+		buf:    make([]byte, 0, chunkSize),
+	}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("write to closed backup writer")
+	}
+
+	written := 0
+	for len(p) > 0 {
+		// A full buffer is only sealed once more data arrives, so the final chunk is never empty
+		// unless the whole stream is
+		if len(e.buf) == chunkSize {
+			if err := e.flush(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(e.buf[len(e.buf):chunkSize], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (e *encryptWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.flush(true)
+}
+
+func (e *encryptWriter) flush(last bool) error {
+	if e.counter == ^uint32(0) {
+		return errors.New("backup is too large")
+	}
+	sealed := e.aead.Seal(nil, chunkNonce(e.prefix, e.counter, last), e.buf, nil)
+	e.counter++
+	e.buf = e.buf[:0]
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+type decryptReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	plain   []byte
+	sealed  []byte
+	done    bool
+}
+
+// NewDecryptReader returns the plaintext of a stream written by NewEncryptWriter. A wrong passphrase
+// is reported immediately, corruption when the damaged chunk is read.
+func NewDecryptReader(r io.Reader, passphrase string) (io.Reader, error) {
+	headerSize := len(magic) + 3 + saltSize + noncePrefixSize
+	header := make([]byte, headerSize+sha256.Size)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrNotBackup
+		}
+		return nil, err
+	}
+	if !bytes.Equal(header[:len(magic)], magic) {
+		return nil, ErrNotBackup
+	}
+
+	params := header[len(magic) : len(magic)+3]
+	salt := header[len(magic)+3 : len(magic)+3+saltSize]
+	aead, macKey, err := deriveKeys(passphrase, params, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(header[:headerSize])
+	if !hmac.Equal(mac.Sum(nil), header[headerSize:]) {
+		return nil, ErrWrongPassphrase
+	}
+
+	return &decryptReader{
+		r:      bufio.NewReaderSize(r, chunkSize+aead.Overhead()+1),
+		aead:   aead,
+		prefix: header[len(magic)+3+saltSize : headerSize],
+		sealed: make([]byte, chunkSize+aead.Overhead()),
+	}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+func (d *decryptReader) next() error {
+	n, err := io.ReadFull(d.r, d.sealed)
+	switch {
+	case errors.Is(err, io.EOF):
+		// The final chunk is always present, even for an empty backup
+		return ErrCorrupted
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		d.done = true
+	case err != nil:
+		return err
+	default:
+		if _, peekErr := d.r.Peek(1); errors.Is(peekErr, io.EOF) {
+			d.done = true
+		}
+	}
+
+	plain, err := d.aead.Open(d.sealed[:0], chunkNonce(d.prefix, d.counter, d.done), d.sealed[:n], nil)
+	if err != nil {
+		return ErrCorrupted
+	}
+	d.counter++
+	d.plain = plain
+	return nil
+}
+
+// deriveKeys stretches the passphrase into the chunk cipher and the header MAC key
+func deriveKeys(passphrase string, params []byte, salt []byte) (cipher.AEAD, []byte, error) {
+	logN, r, p := params[0], int(params[1]), int(params[2])
+	if logN < 10 || logN > 22 || r < 1 || r > 32 || p < 1 || p > 16 {
+		return nil, nil, fmt.Errorf("%w: unsupported key derivation parameters", ErrNotBackup)
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<logN, r, p, 64)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block, err := aes.NewCipher(key[:32])
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	return aead, key[32:], nil
+}
+
+func chunkNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
@@ -0,0 +1,181 @@
+package backup_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/backup"
+	"github.com/stretchr/testify/assert"
+)
+
+func encrypt(t *testing.T, plain []byte, passphrase string) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	w, err := backup.NewEncryptWriter(&out, passphrase)
+	assert.NoError(t, err)
+	// Uneven writes exercise chunk boundaries
+	for len(plain) > 0 {
+		n := min(len(plain), 10000)
+		_, err = w.Write(plain[:n])
+		assert.NoError(t, err)
+		plain = plain[n:]
+	}
+	assert.NoError(t, w.Close())
+	return out.Bytes()
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	random := make([]byte, 3*64*1024+123)
+	_, _ = rand.Read(random)
+
+	tests := []struct {
+		name  string
+		plain []byte
+	}{
+		{name: "should round trip empty input", plain: []byte{}},
+		{name: "should round trip short input", plain: []byte("hello backup")},
+		{name: "should round trip exactly one chunk", plain: random[:64*1024]},
+		{name: "should round trip several chunks", plain: random},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed := encrypt(t, tt.plain, "correct horse")
+
+			r, err := backup.NewDecryptReader(bytes.NewReader(sealed), "correct horse")
+			assert.NoError(t, err)
+			got, err := io.ReadAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, len(tt.plain), len(got))
+			assert.True(t, bytes.Equal(tt.plain, got))
+		})
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	plain := make([]byte, 2*64*1024+5)
+	_, _ = rand.Read(plain)
+	sealed := encrypt(t, plain, "correct horse")
+	headerSize := 8 + 3 + 16 + 7 + 32
+
+	tampered := bytes.Clone(sealed)
+	tampered[headerSize+100] ^= 0x01
+
+	tests := []struct {
+		name       string
+		input      []byte
+		passphrase string
+		err        error
+	}{
+		{name: "should reject wrong passphrase", input: sealed, passphrase: "battery staple", err: backup.ErrWrongPassphrase},
+		{name: "should reject files that are not backups", input: []byte("PK\x03\x04 not a backup at all, just some bytes here"), passphrase: "correct horse", err: backup.ErrNotBackup},
+		{name: "should detect modified chunks", input: tampered, passphrase: "correct horse", err: backup.ErrCorrupted},
+		{name: "should detect truncation inside a chunk", input: sealed[:len(sealed)-10], passphrase: "correct horse", err: backup.ErrCorrupted},
+		{name: "should detect a dropped final chunk", input: sealed[:headerSize+2*(64*1024+16)], passphrase: "correct horse", err: backup.ErrCorrupted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := backup.NewDecryptReader(bytes.NewReader(tt.input), tt.passphrase)
+			if err == nil {
+				_, err = io.ReadAll(r)
+			}
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "whatsapp.db"), []byte("session"), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "chatstorage.db"), []byte("history"), 0600))
+
+	manifest := &backup.Manifest{
+		AppVersion: "v7.5.0",
+		CreatedAt:  time.Date(2025, 1, 15, 22, 30, 0, 0, time.UTC),
+		Files: []backup.File{
+			{Name: "whatsapp.db", Role: "whatsapp", Kind: backup.KindSQLite},
+			{Name: "chatstorage.db", Role: "chatstorage", Kind: backup.KindSQLite},
+		},
+	}
+
+	var archive bytes.Buffer
+	assert.NoError(t, backup.WriteArchive(&archive, src, manifest))
+	assert.Equal(t, int64(7), manifest.Files[0].Size)
+
+	dst := t.TempDir()
+	got, err := backup.ReadArchive(bytes.NewReader(archive.Bytes()), dst)
+	assert.NoError(t, err)
+	assert.Equal(t, manifest.Files, got.Files)
+
+	content, err := os.ReadFile(filepath.Join(dst, "chatstorage.db"))
+	assert.NoError(t, err)
+	assert.Equal(t, "history", string(content))
+
+	// A file that does not match its manifest checksum is rejected
+	manifest.Files[1].SHA256 = manifest.Files[0].SHA256
+	manifestJSON, _ := json.Marshal(manifest)
+	tampered := tarGz(t, map[string][]byte{
+		backup.ManifestName: manifestJSON,
+		"whatsapp.db":       []byte("session"),
+		"chatstorage.db":    []byte("history"),
+	}, backup.ManifestName, "whatsapp.db", "chatstorage.db")
+	_, err = backup.ReadArchive(bytes.NewReader(tampered), t.TempDir())
+	assert.ErrorIs(t, err, backup.ErrCorrupted)
+}
+
+func TestReadArchiveStopsAtManifestSize(t *testing.T) {
+	content := []byte("history")
+	manifest := &backup.Manifest{
+		Version: backup.ManifestVersion,
+		Files:   []backup.File{{Name: "chatstorage.db", Size: int64(len(content)), SHA256: sha256Hex(content)}},
+	}
+	manifestJSON, err := json.Marshal(manifest)
+	assert.NoError(t, err)
+
+	// The entry claims the manifest size but its tar header makes it far larger
+	oversized := append(content, bytes.Repeat([]byte{0}, 1<<20)...)
+	archive := tarGz(t, map[string][]byte{
+		backup.ManifestName: manifestJSON,
+		"chatstorage.db":    oversized,
+	}, backup.ManifestName, "chatstorage.db")
+
+	dst := t.TempDir()
+	_, err = backup.ReadArchive(bytes.NewReader(archive), dst)
+	assert.ErrorIs(t, err, backup.ErrCorrupted)
+	assert.ErrorContains(t, err, "larger than its manifest size")
+
+	info, err := os.Stat(filepath.Join(dst, "chatstorage.db"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)+1), info.Size(), "extraction stops one byte past the manifest size")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func tarGz(t *testing.T, files map[string][]byte, order ...string) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	gz := gzip.NewWriter(&out)
+	archive := tar.NewWriter(gz)
+	for _, name := range order {
+		assert.NoError(t, archive.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(files[name]))}))
+		_, err := archive.Write(files[name])
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, archive.Close())
+	assert.NoError(t, gz.Close())
+	return out.Bytes()
+}
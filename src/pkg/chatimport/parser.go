@@ -0,0 +1,226 @@
+// Package chatimport parses the transcripts produced by WhatsApp's "Export chat" on Android and iOS.
+package chatimport
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateOrder is the order of day, month and year in transcript timestamps, which follows the phone's locale
+type DateOrder string
+
+const (
+	DateOrderAuto DateOrder = ""
+	DateOrderDMY  DateOrder = "dmy"
+	DateOrderMDY  DateOrder = "mdy"
+	DateOrderYMD  DateOrder = "ymd"
+)
+
+// ErrUnrecognizedFormat is returned when no line of the input looks like a WhatsApp transcript line
+var ErrUnrecognizedFormat = errors.New("unrecognized chat export format")
+
+// Message is one message of a transcript. System notices (encryption banner, group changes) are skipped.
+type Message struct {
+	Timestamp  time.Time
+	Sender     string // Display name or phone number as written in the transcript
+	Content    string // Text, or the caption when an attachment is referenced
+	Attachment string // File name of the referenced media, empty for text messages
+	Line       int    // Line number of the message header, starting at 1
+}
+
+var (
+	// Header of every message, Android "15/01/2025, 22:30 - " and iOS "[15/01/2025, 22:30:15] ",
+	// with any of / . - as date separator, optional seconds and an optional AM/PM marker
+	headerPattern = regexp.MustCompile(`^(\[)?(\d{1,4})[./-](\d{1,2})[./-](\d{1,4})\.?,?\s+(\d{1,2})[:.](\d{2})(?:[:.](\d{2}))?\s*([aApP]\.?\s?[mM]\.?)?(\]\s*|\s+[-–]\s+)(.*)$`)
+
+	// Android "IMG-20250115-WA0001.jpg (file attached)", localized: "(file terlampir)", "(Datei angehängt)"...
+	androidAttachmentPattern = regexp.MustCompile(`^(\S.*?\.[A-Za-z0-9]{2,5}) \([^()]+\)$`)
+
+	// iOS "<attached: 00000012-PHOTO-2025-01-15-22-30-15.jpg>", localized: "<Anhang: ...>"...
+	iosAttachmentPattern = regexp.MustCompile(`^<[^:<>]+:\s*(\S.*?\.[A-Za-z0-9]{2,5})>$`)
+
+	// Invisible marks and unusual spaces WhatsApp puts around names and AM/PM markers
+	invisibleReplacer = strings.NewReplacer(
+		"\u200e", "", "\u200f", "", "\u202a", "", "\u202c", "", "\ufeff", "",
+		"\u202f", " ", "\u00a0", " ",
+	)
+)
+
+// header is a parsed but not yet dated message header
+type header struct {
+	line      int
+	date      [3]int
+	hour, min int
+	sec       int
+	meridiem  string
+	body      []string
+}
+
+// Parse reads a transcript. With DateOrderAuto the order is detected from the dates in the
+// transcript, falling back to day-month-year when every date is ambiguous. Times are
+// interpreted in loc, the timezone of the exporting phone.
+func Parse(r io.Reader, order DateOrder, loc *time.Location) ([]Message, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	headers, err := scanHeaders(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(headers) == 0 {
+		return nil, ErrUnrecognizedFormat
+	}
+
+	if order == DateOrderAuto {
+		order = detectDateOrder(headers)
+	}
+
+	messages := make([]Message, 0, len(headers))
+	for _, h := range headers {
+		timestamp, err := h.time(order, loc)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", h.line, err)
+		}
+
+		// "Name: text", anything else is a system notice
+		first := h.body[0]
+		sep := strings.Index(first, ": ")
+		if sep <= 0 {
+			continue
+		}
+
+		message := Message{
+			Timestamp: timestamp,
+			Sender:    strings.TrimSpace(first[:sep]),
+			Line:      h.line,
+		}
+
+		lines := append([]string{first[sep+2:]}, h.body[1:]...)
+		if attachment := attachmentName(lines[0]); attachment != "" {
+			message.Attachment = attachment
+			lines = lines[1:]
+		}
+		message.Content = strings.TrimRight(strings.Join(lines, "\n"), "\n ")
+
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// scanHeaders splits the transcript into message headers, attaching continuation lines to the previous one
+func scanHeaders(r io.Reader) ([]*header, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var headers []*header
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(invisibleReplacer.Replace(scanner.Text()), "\r")
+
+		match := headerPattern.FindStringSubmatch(line)
+		// iOS headers are bracketed, Android headers are not
+		if match == nil || (match[1] == "[") != strings.HasPrefix(match[9], "]") {
+			if len(headers) > 0 {
+				last := headers[len(headers)-1]
+				last.body = append(last.body, line)
+			}
+			continue
+		}
+
+		h := &header{line: lineNumber, meridiem: strings.ToLower(strings.NewReplacer(".", "", " ", "").Replace(match[8]))}
+		h.date[0], _ = strconv.Atoi(match[2])
+		h.date[1], _ = strconv.Atoi(match[3])
+		h.date[2], _ = strconv.Atoi(match[4])
+		h.hour, _ = strconv.Atoi(match[5])
+		h.min, _ = strconv.Atoi(match[6])
+		if match[7] != "" {
+			h.sec, _ = strconv.Atoi(match[7])
+		}
+		h.body = []string{match[10]}
+		headers = append(headers, h)
+	}
+
+	return headers, scanner.Err()
+}
+
+// detectDateOrder picks the order under which every date in the transcript is valid
+func detectDateOrder(headers []*header) DateOrder {
+	firstOver12, secondOver12 := false, false
+	for _, h := range headers {
+		if h.date[0] > 31 {
+			return DateOrderYMD
+		}
+		if h.date[0] > 12 {
+			firstOver12 = true
+		}
+		if h.date[1] > 12 {
+			secondOver12 = true
+		}
+	}
+
+	if secondOver12 && !firstOver12 {
+		return DateOrderMDY
+	}
+	return DateOrderDMY
+}
+
+func (h *header) time(order DateOrder, loc *time.Location) (time.Time, error) {
+	var day, month, year int
+	switch order {
+	case DateOrderMDY:
+		month, day, year = h.date[0], h.date[1], h.date[2]
+	case DateOrderYMD:
+		year, month, day = h.date[0], h.date[1], h.date[2]
+	default:
+		day, month, year = h.date[0], h.date[1], h.date[2]
+	}
+	if year < 100 {
+		year += 2000
+	}
+
+	hour := h.hour
+	switch {
+	case strings.HasPrefix(h.meridiem, "p") && hour < 12:
+		hour += 12
+	case strings.HasPrefix(h.meridiem, "a") && hour == 12:
+		hour = 0
+	}
+
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || h.min > 59 || h.sec > 59 {
+		return time.Time{}, fmt.Errorf("invalid date %02d-%02d-%04d %02d:%02d for %s order", day, month, year, hour, h.min, orderName(order))
+	}
+
+	timestamp := time.Date(year, time.Month(month), day, hour, h.min, h.sec, 0, loc)
+	if timestamp.Day() != day {
+		return time.Time{}, fmt.Errorf("invalid date %02d-%02d-%04d for %s order", day, month, year, orderName(order))
+	}
+	return timestamp, nil
+}
+
+func orderName(order DateOrder) string {
+	if order == DateOrderAuto {
+		return string(DateOrderDMY)
+	}
+	return string(order)
+}
+
+// attachmentName returns the media file referenced by the first line of a message, if any
+func attachmentName(line string) string {
+	line = strings.TrimSpace(line)
+	if match := iosAttachmentPattern.FindStringSubmatch(line); match != nil {
+		return match[1]
+	}
+	if match := androidAttachmentPattern.FindStringSubmatch(line); match != nil {
+		return match[1]
+	}
+	return ""
+}
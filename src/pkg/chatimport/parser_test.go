@@ -0,0 +1,136 @@
+package chatimport_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/chatimport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	at := func(year int, month time.Month, day, hour, min, sec int) time.Time {
+		return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		order chatimport.DateOrder
+		want  []chatimport.Message
+		err   string
+	}{
+		{
+			name: "should parse android export with day first dates",
+			input: "15/01/2025, 22:30 - Messages and calls are end-to-end encrypted.\n" +
+				"15/01/2025, 22:30 - Alice: Hello\n" +
+				"16/01/2025, 08:05 - Bob: Morning\n",
+			want: []chatimport.Message{
+				{Timestamp: at(2025, 1, 15, 22, 30, 0), Sender: "Alice", Content: "Hello", Line: 2},
+				{Timestamp: at(2025, 1, 16, 8, 5, 0), Sender: "Bob", Content: "Morning", Line: 3},
+			},
+		},
+		{
+			name: "should detect month first dates with AM/PM times",
+			input: "1/5/25, 9:15 AM - Alice: Hi\n" +
+				"1/15/25, 12:01 PM - +62 812-3456-7890: Hey\n" +
+				"1/16/25, 12:10 AM - Alice: Late\n",
+			want: []chatimport.Message{
+				{Timestamp: at(2025, 1, 5, 9, 15, 0), Sender: "Alice", Content: "Hi", Line: 1},
+				{Timestamp: at(2025, 1, 15, 12, 1, 0), Sender: "+62 812-3456-7890", Content: "Hey", Line: 2},
+				{Timestamp: at(2025, 1, 16, 0, 10, 0), Sender: "Alice", Content: "Late", Line: 3},
+			},
+		},
+		{
+			name:  "should honour explicit date order for ambiguous dates",
+			input: "02/03/2025, 10:00 - Alice: Hi\n",
+			order: chatimport.DateOrderMDY,
+			want: []chatimport.Message{
+				{Timestamp: at(2025, 2, 3, 10, 0, 0), Sender: "Alice", Content: "Hi", Line: 1},
+			},
+		},
+		{
+			name: "should parse iOS export with seconds and invisible marks",
+			input: "\ufeff[15.01.25, 22:30:15] Alice: \u200eHello\n" +
+				"[15.01.25, 10:31:02 PM] Bob: Hi\n",
+			want: []chatimport.Message{
+				{Timestamp: at(2025, 1, 15, 22, 30, 15), Sender: "Alice", Content: "Hello", Line: 1},
+				{Timestamp: at(2025, 1, 15, 22, 31, 2), Sender: "Bob", Content: "Hi", Line: 2},
+			},
+		},
+		{
+			name:  "should parse year first dates",
+			input: "2025-01-15, 22:30 - Alice: Hello\n",
+			want: []chatimport.Message{
+				{Timestamp: at(2025, 1, 15, 22, 30, 0), Sender: "Alice", Content: "Hello", Line: 1},
+			},
+		},
+		{
+			name: "should join multiline messages",
+			input: "15/01/2025, 22:30 - Alice: first line\r\n" +
+				"second line\r\n" +
+				"\r\n" +
+				"third line\r\n",
+			want: []chatimport.Message{
+				{Timestamp: at(2025, 1, 15, 22, 30, 0), Sender: "Alice", Content: "first line\nsecond line\n\nthird line", Line: 1},
+			},
+		},
+		{
+			name: "should extract android attachments with captions",
+			input: "15/01/2025, 22:30 - Alice: IMG-20250115-WA0001.jpg (file attached)\n" +
+				"look at this\n" +
+				"15/01/2025, 22:31 - Bob: PTT-20250115-WA0002.opus (file terlampir)\n" +
+				"15/01/2025, 22:32 - Bob: <Media omitted>\n",
+			want: []chatimport.Message{
+				{Timestamp: at(2025, 1, 15, 22, 30, 0), Sender: "Alice", Content: "look at this", Attachment: "IMG-20250115-WA0001.jpg", Line: 1},
+				{Timestamp: at(2025, 1, 15, 22, 31, 0), Sender: "Bob", Attachment: "PTT-20250115-WA0002.opus", Line: 3},
+				{Timestamp: at(2025, 1, 15, 22, 32, 0), Sender: "Bob", Content: "<Media omitted>", Line: 4},
+			},
+		},
+		{
+			name:  "should extract iOS attachments",
+			input: "[15/01/2025, 22:30:15] Alice: \u200e<attached: 00000012-PHOTO-2025-01-15-22-30-15.jpg>\n",
+			want: []chatimport.Message{
+				{Timestamp: at(2025, 1, 15, 22, 30, 15), Sender: "Alice", Attachment: "00000012-PHOTO-2025-01-15-22-30-15.jpg", Line: 1},
+			},
+		},
+		{
+			name:  "should not treat bracketed android lines as headers",
+			input: "15/01/2025, 22:30 - Alice: see\n[15/01/2025, 22:30 - quoted\n",
+			want: []chatimport.Message{
+				{Timestamp: at(2025, 1, 15, 22, 30, 0), Sender: "Alice", Content: "see\n[15/01/2025, 22:30 - quoted", Line: 1},
+			},
+		},
+		{
+			name:  "should reject dates invalid for the given order",
+			input: "15/01/2025, 22:30 - Alice: Hello\n",
+			order: chatimport.DateOrderMDY,
+			err:   "line 1: invalid date",
+		},
+		{
+			name:  "should reject input that is not a chat export",
+			input: "just some text\nand more\n",
+			err:   chatimport.ErrUnrecognizedFormat.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := chatimport.Parse(strings.NewReader(tt.input), tt.order, time.UTC)
+			if tt.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseUnrecognizedFormat(t *testing.T) {
+	_, err := chatimport.Parse(strings.NewReader(""), chatimport.DateOrderAuto, nil)
+	assert.True(t, errors.Is(err, chatimport.ErrUnrecognizedFormat))
+}
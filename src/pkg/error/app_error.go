@@ -100,6 +100,44 @@ func (err sessionSavedError) StatusCode() int {
 	return http.StatusInternalServerError
 }
 
+type sessionReplacedError string
+
+func throwSessionReplacedError(text string) GenericError {
+	return sessionReplacedError(text)
+}
+
+func (err sessionReplacedError) Error() string {
+	return string(err)
+}
+
+// ErrCode will return the error code based on the error data type
+func (err sessionReplacedError) ErrCode() string {
+	return "SESSION_REPLACED"
+}
+
+// StatusCode will return the HTTP status code based on the error data type
+func (err sessionReplacedError) StatusCode() int {
+	return http.StatusConflict
+}
+
+// IdempotencyConflictError is returned when an Idempotency-Key is reused for a different request,
+// or while the original request is still being processed
+type IdempotencyConflictError string
+
+func (err IdempotencyConflictError) Error() string {
+	return string(err)
+}
+
+// ErrCode will return the error code based on the error data type
+func (err IdempotencyConflictError) ErrCode() string {
+	return "IDEMPOTENCY_CONFLICT"
+}
+
+// StatusCode will return the HTTP status code based on the error data type
+func (err IdempotencyConflictError) StatusCode() int {
+	return http.StatusConflict
+}
+
 var (
 	ErrAlreadyLoggedIn = LoginError("you are already logged in.")
 	ErrNotConnected    = throwAuthError("you are not connect to services server, please reconnect")
@@ -107,4 +145,5 @@ var (
 	ErrReconnect       = throwReconnectError("reconnect error")
 	ErrQrChannel       = throwQrChannelError("QR channel error")
 	ErrSessionSaved    = throwSessionSavedError("your session have been saved, please wait to connect 2 second and refresh again")
+	ErrSessionReplaced = throwSessionReplacedError("your session was taken over by another client, use /app/takeover to reconnect")
 )
@@ -42,3 +42,37 @@ func (e ContextError) ErrCode() string {
 func (e ContextError) StatusCode() int {
 	return http.StatusRequestTimeout
 }
+
+type NotFoundError string
+
+// Error for complying the error interface
+func (e NotFoundError) Error() string {
+	return string(e)
+}
+
+// ErrCode will return the error code based on the error data type
+func (e NotFoundError) ErrCode() string {
+	return "NOT_FOUND"
+}
+
+// StatusCode will return the HTTP status code based on the error data type
+func (e NotFoundError) StatusCode() int {
+	return http.StatusNotFound
+}
+
+type ForbiddenError string
+
+// Error for complying the error interface
+func (e ForbiddenError) Error() string {
+	return string(e)
+}
+
+// ErrCode will return the error code based on the error data type
+func (e ForbiddenError) ErrCode() string {
+	return "FORBIDDEN"
+}
+
+// StatusCode will return the HTTP status code based on the error data type
+func (e ForbiddenError) StatusCode() int {
+	return http.StatusForbidden
+}
@@ -70,6 +70,23 @@ func (e WaUploadMediaError) StatusCode() int {
 	return http.StatusInternalServerError
 }
 
+type WaMediaRetryError string
+
+// Error for complying the error interface
+func (e WaMediaRetryError) Error() string {
+	return string(e)
+}
+
+// ErrCode will return the error code based on the error data type
+func (e WaMediaRetryError) ErrCode() string {
+	return "MEDIA_RETRY_ERROR"
+}
+
+// StatusCode will return the HTTP status code based on the error data type
+func (e WaMediaRetryError) StatusCode() int {
+	return http.StatusGone
+}
+
 const (
 	ErrInvalidJID        = InvalidJID("your JID is invalid")
 	ErrUserNotRegistered = InvalidJID("user is not registered")
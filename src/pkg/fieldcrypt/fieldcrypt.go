@@ -0,0 +1,323 @@
+// Package fieldcrypt encrypts single database values with AES-256-GCM data keys, which are stored
+// next to the data wrapped by key encryption keys the operator supplies from the environment or a file.
+package fieldcrypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Encrypted values carry the id of the data key they were sealed with, so values of several keys can
+// live side by side while a key is rotated:
+//
+//	text:   enc:v1:<key id>:<base64 of nonce | ciphertext>
+//	binary: 0x00 "FC1" | key id length | key id | nonce | ciphertext
+//
+// The context passed to Encrypt and Decrypt is authenticated but not stored. It binds a value to its
+// column and row, so ciphertext copied to another row does not decrypt. Empty values are left as is.
+const (
+	KeySize    = 32
+	textPrefix = "enc:v1:"
+)
+
+var binaryPrefix = []byte{0, 'F', 'C', '1'}
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+var (
+	// ErrUnknownKey is returned when a value or data key was sealed with a key that is not loaded
+	ErrUnknownKey = errors.New("encryption key is not available")
+	// ErrCorrupted is returned when an encrypted value fails authentication
+	ErrCorrupted = errors.New("encrypted value is corrupted or belongs to another row")
+)
+
+// Key is a named AES-256 key
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// ParseKeys parses key encryption keys written as id:base64 and separated by commas or new lines.
+// A single key may omit its id and is then called "default". Blank lines and lines starting with #
+// are ignored.
+func ParseKeys(spec string) ([]Key, error) {
+	var keys []Key
+	entries := strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' })
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		id, encoded, found := strings.Cut(entry, ":")
+		if !found {
+			id, encoded = "default", entry
+		}
+		if !keyIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("invalid key id %q, use up to 32 letters, digits, - or _", id)
+		}
+		secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("key %s is not valid base64: %w", id, err)
+		}
+		if len(secret) != KeySize {
+			return nil, fmt.Errorf("key %s must be %d bytes, got %d", id, KeySize, len(secret))
+		}
+		keys = append(keys, Key{ID: id, Secret: secret})
+	}
+	return keys, nil
+}
+
+// Keyring holds the key encryption keys. The first key wraps new data keys, the others are only
+// used to unwrap data keys that were wrapped before a rotation.
+type Keyring struct {
+	active string
+	aeads  map[string]cipher.AEAD
+}
+
+// NewKeyring returns a keyring for keys, the first one being the active key
+func NewKeyring(keys []Key) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no encryption key configured")
+	}
+
+	keyring := &Keyring{active: keys[0].ID, aeads: make(map[string]cipher.AEAD, len(keys))}
+	for _, key := range keys {
+		if _, exists := keyring.aeads[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate encryption key id %s", key.ID)
+		}
+		aead, err := newAEAD(key.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", key.ID, err)
+		}
+		keyring.aeads[key.ID] = aead
+	}
+	return keyring, nil
+}
+
+// ActiveID returns the id of the key new data keys are wrapped with
+func (k *Keyring) ActiveID() string {
+	return k.active
+}
+
+// Wrap seals the data key dataKeyID with the active key
+func (k *Keyring) Wrap(dataKeyID string, dataKey []byte) (wrapped []byte, keyID string) {
+	return seal(k.aeads[k.active], dataKey, wrapContext(dataKeyID)), k.active
+}
+
+// Unwrap opens a data key wrapped with keyID
+func (k *Keyring) Unwrap(dataKeyID string, keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := k.aeads[keyID]
+	if !ok {
+		return nil, fmt.Errorf("data key %s is wrapped with key %s: %w", dataKeyID, keyID, ErrUnknownKey)
+	}
+	dataKey, err := open(aead, wrapped, wrapContext(dataKeyID))
+	if err != nil {
+		return nil, fmt.Errorf("data key %s: %w", dataKeyID, err)
+	}
+	return dataKey, nil
+}
+
+// NewDataKey generates a random data key and an id for it
+func NewDataKey() (id string, key []byte, err error) {
+	random := make([]byte, 4+KeySize)
+	if _, err := rand.Read(random); err != nil {
+		return "", nil, err
+	}
+	return hex.EncodeToString(random[:4]), random[4:], nil
+}
+
+// Cipher encrypts values with the active data key and decrypts values sealed with any loaded data key
+type Cipher struct {
+	mu     sync.RWMutex
+	active string
+	aeads  map[string]cipher.AEAD
+}
+
+// NewCipher returns a cipher without keys, see AddKey
+func NewCipher() *Cipher {
+	return &Cipher{aeads: make(map[string]cipher.AEAD)}
+}
+
+// AddKey loads a data key, the active one is used to encrypt
+func (c *Cipher) AddKey(id string, key []byte, active bool) error {
+	if !keyIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid data key id %q", id)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return fmt.Errorf("data key %s: %w", id, err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.aeads[id] = aead
+	if active {
+		c.active = id
+	}
+	return nil
+}
+
+// ActiveID returns the id of the data key values are encrypted with
+func (c *Cipher) ActiveID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.active
+}
+
+// EncryptString encrypts value for context
+func (c *Cipher) EncryptString(value string, context string) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+	id, aead, err := c.activeAEAD()
+	if err != nil {
+		return "", err
+	}
+	sealed := seal(aead, []byte(value), []byte(context))
+	return textPrefix + id + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptString decrypts a value encrypted by EncryptString. Values without the encryption prefix
+// are plaintext written before encryption was enabled and are returned unchanged.
+func (c *Cipher) DecryptString(value string, context string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	id, encoded, _ := strings.Cut(strings.TrimPrefix(value, textPrefix), ":")
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrCorrupted
+	}
+	plain, err := c.open(id, sealed, context)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// EncryptBytes encrypts value for context
+func (c *Cipher) EncryptBytes(value []byte, context string) ([]byte, error) {
+	if len(value) == 0 {
+		return value, nil
+	}
+	id, aead, err := c.activeAEAD()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(binaryPrefix)+1+len(id)+aead.NonceSize()+len(value)+aead.Overhead())
+	out = append(out, binaryPrefix...)
+	out = append(out, byte(len(id)))
+	out = append(out, id...)
+	return append(out, seal(aead, value, []byte(context))...), nil
+}
+
+// DecryptBytes decrypts a value encrypted by EncryptBytes, other values are returned unchanged
+func (c *Cipher) DecryptBytes(value []byte, context string) ([]byte, error) {
+	if !IsEncryptedBytes(value) {
+		return value, nil
+	}
+	rest := value[len(binaryPrefix):]
+	idLength := int(rest[0])
+	if len(rest) < 1+idLength {
+		return nil, ErrCorrupted
+	}
+	return c.open(string(rest[1:1+idLength]), rest[1+idLength:], context)
+}
+
+// IsEncrypted reports whether value was produced by EncryptString
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, textPrefix)
+}
+
+// IsEncryptedBytes reports whether value was produced by EncryptBytes
+func IsEncryptedBytes(value []byte) bool {
+	return len(value) > len(binaryPrefix) && bytes.HasPrefix(value, binaryPrefix)
+}
+
+// BlindIndex hashes values with a secret key, so a column can be searched for a value without
+// storing the value itself
+type BlindIndex struct {
+	key []byte
+}
+
+// NewBlindIndex returns a blind index keyed by key, purpose separates the indexes of different
+// columns sharing a key
+func NewBlindIndex(key []byte, purpose string) *BlindIndex {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("blind index " + purpose))
+	return &BlindIndex{key: mac.Sum(nil)}
+}
+
+// Sum returns the hex index of value
+func (b *BlindIndex) Sum(value []byte) string {
+	mac := hmac.New(sha256.New, b.key)
+	mac.Write(value)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (c *Cipher) activeAEAD() (string, cipher.AEAD, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	aead, ok := c.aeads[c.active]
+	if !ok {
+		return "", nil, fmt.Errorf("no active data key: %w", ErrUnknownKey)
+	}
+	return c.active, aead, nil
+}
+
+func (c *Cipher) open(id string, sealed []byte, context string) ([]byte, error) {
+	c.mu.RLock()
+	aead, ok := c.aeads[id]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("data key %s: %w", id, ErrUnknownKey)
+	}
+	return open(aead, sealed, []byte(context))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal returns a random nonce followed by the ciphertext
+func seal(aead cipher.AEAD, plain []byte, context []byte) []byte {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		// crypto/rand never fails on supported platforms
+		panic(err)
+	}
+	return aead.Seal(nonce, nonce, plain, context)
+}
+
+func open(aead cipher.AEAD, sealed []byte, context []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrCorrupted
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], context)
+	if err != nil {
+		return nil, ErrCorrupted
+	}
+	return plain, nil
+}
+
+func wrapContext(dataKeyID string) []byte {
+	return []byte("data key " + dataKeyID)
+}
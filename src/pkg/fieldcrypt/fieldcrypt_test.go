@@ -0,0 +1,207 @@
+package fieldcrypt_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/fieldcrypt"
+	"github.com/stretchr/testify/assert"
+)
+
+func testKey(fill byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{fill}, fieldcrypt.KeySize))
+}
+
+func newCipher(t *testing.T, ids ...string) *fieldcrypt.Cipher {
+	t.Helper()
+	c := fieldcrypt.NewCipher()
+	for i, id := range ids {
+		assert.NoError(t, c.AddKey(id, bytes.Repeat([]byte{byte(i + 1)}, fieldcrypt.KeySize), i == len(ids)-1))
+	}
+	return c
+}
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantIDs []string
+		wantErr string
+	}{
+		{name: "should return no keys for empty input", spec: "  \n"},
+		{name: "should name a single key without id default", spec: testKey(1), wantIDs: []string{"default"}},
+		{name: "should keep comma separated order", spec: "new:" + testKey(1) + ",old:" + testKey(2), wantIDs: []string{"new", "old"}},
+		{name: "should skip comments and blank lines", spec: "# rotated 2026\n\nnew:" + testKey(1) + "\r\nold:" + testKey(2) + "\n", wantIDs: []string{"new", "old"}},
+		{name: "should reject invalid base64", spec: "a:not-base64!", wantErr: "not valid base64"},
+		{name: "should reject short keys", spec: "a:" + base64.StdEncoding.EncodeToString([]byte("short")), wantErr: "must be 32 bytes"},
+		{name: "should reject invalid ids", spec: "bad id:" + testKey(1), wantErr: "invalid key id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := fieldcrypt.ParseKeys(tt.spec)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			var ids []string
+			for _, key := range keys {
+				ids = append(ids, key.ID)
+			}
+			assert.Equal(t, tt.wantIDs, ids)
+		})
+	}
+}
+
+func TestCipherRoundTrip(t *testing.T) {
+	c := newCipher(t, "k1")
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "should leave empty values empty", value: ""},
+		{name: "should round trip text", value: "hello, encrypted world"},
+		{name: "should round trip unicode", value: "sampai jumpa \U0001F44B"},
+		{name: "should round trip text that looks encrypted", value: "enc:v1:k1:AAAA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := c.EncryptString(tt.value, "messages.content")
+			assert.NoError(t, err)
+			if tt.value != "" {
+				assert.True(t, fieldcrypt.IsEncrypted(sealed))
+				assert.NotContains(t, sealed, tt.value)
+			}
+			opened, err := c.DecryptString(sealed, "messages.content")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.value, opened)
+
+			sealedBytes, err := c.EncryptBytes([]byte(tt.value), "messages.media_key")
+			assert.NoError(t, err)
+			openedBytes, err := c.DecryptBytes(sealedBytes, "messages.media_key")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.value, string(openedBytes))
+		})
+	}
+}
+
+func TestCipherDecrypt(t *testing.T) {
+	c := newCipher(t, "k1")
+	sealed, err := c.EncryptString("secret", "row-1")
+	assert.NoError(t, err)
+	sealedBytes, err := c.EncryptBytes([]byte("secret"), "row-1")
+	assert.NoError(t, err)
+	tampered := []byte(sealed)
+	tampered[len(tampered)/2+5] ^= 1
+
+	tests := []struct {
+		name    string
+		cipher  *fieldcrypt.Cipher
+		value   string
+		context string
+		want    string
+		wantErr error
+	}{
+		{name: "should pass plaintext through", cipher: c, value: "written before encryption", context: "row-1", want: "written before encryption"},
+		{name: "should reject another row", cipher: c, value: sealed, context: "row-2", wantErr: fieldcrypt.ErrCorrupted},
+		{name: "should reject modified ciphertext", cipher: c, value: string(tampered), context: "row-1", wantErr: fieldcrypt.ErrCorrupted},
+		{name: "should reject unknown data keys", cipher: newCipher(t, "k2"), value: sealed, context: "row-1", wantErr: fieldcrypt.ErrUnknownKey},
+		{name: "should decrypt with a retired key after rotation", cipher: newCipher(t, "k1", "k2"), value: sealed, context: "row-1", want: "secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cipher.DecryptString(tt.value, tt.context)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("should decrypt binary values sealed with a retired key", func(t *testing.T) {
+		rotated := newCipher(t, "k1", "k2")
+		assert.Equal(t, "k2", rotated.ActiveID())
+		got, err := rotated.DecryptBytes(sealedBytes, "row-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", string(got))
+
+		resealed, err := rotated.EncryptString("secret", "row-1")
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(resealed, "enc:v1:k2:"))
+	})
+}
+
+func TestKeyringWrap(t *testing.T) {
+	oldKeys, err := fieldcrypt.ParseKeys("old:" + testKey(1))
+	assert.NoError(t, err)
+	rotatedKeys, err := fieldcrypt.ParseKeys("new:" + testKey(2) + ",old:" + testKey(1))
+	assert.NoError(t, err)
+	oldKeyring, err := fieldcrypt.NewKeyring(oldKeys)
+	assert.NoError(t, err)
+	rotatedKeyring, err := fieldcrypt.NewKeyring(rotatedKeys)
+	assert.NoError(t, err)
+
+	id, dataKey, err := fieldcrypt.NewDataKey()
+	assert.NoError(t, err)
+	wrapped, kekID := oldKeyring.Wrap(id, dataKey)
+	assert.Equal(t, "old", kekID)
+
+	tests := []struct {
+		name      string
+		keyring   *fieldcrypt.Keyring
+		dataKeyID string
+		kekID     string
+		wantErr   error
+	}{
+		{name: "should unwrap with the same key", keyring: oldKeyring, dataKeyID: id, kekID: "old"},
+		{name: "should unwrap with a retired key after rotation", keyring: rotatedKeyring, dataKeyID: id, kekID: "old"},
+		{name: "should reject a missing key", keyring: rotatedKeyring, dataKeyID: id, kekID: "gone", wantErr: fieldcrypt.ErrUnknownKey},
+		{name: "should reject a swapped data key id", keyring: oldKeyring, dataKeyID: "other", kekID: "old", wantErr: fieldcrypt.ErrCorrupted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.keyring.Unwrap(tt.dataKeyID, tt.kekID, wrapped)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, dataKey, got)
+		})
+	}
+
+	t.Run("should reject duplicate key ids", func(t *testing.T) {
+		_, err := fieldcrypt.NewKeyring(append(oldKeys, oldKeys...))
+		assert.ErrorContains(t, err, "duplicate")
+	})
+}
+
+func TestBlindIndex(t *testing.T) {
+	index := fieldcrypt.NewBlindIndex(bytes.Repeat([]byte{1}, fieldcrypt.KeySize), "media")
+	value := []byte("file hash")
+
+	t.Run("should return the same index for the same value", func(t *testing.T) {
+		assert.Equal(t, index.Sum(value), index.Sum(value))
+		assert.Len(t, index.Sum(value), 64)
+	})
+	t.Run("should not reveal the value", func(t *testing.T) {
+		assert.NotContains(t, index.Sum(value), hex.EncodeToString(value))
+	})
+	t.Run("should differ between keys and purposes", func(t *testing.T) {
+		otherKey := fieldcrypt.NewBlindIndex(bytes.Repeat([]byte{2}, fieldcrypt.KeySize), "media")
+		otherPurpose := fieldcrypt.NewBlindIndex(bytes.Repeat([]byte{1}, fieldcrypt.KeySize), "uploads")
+		assert.NotEqual(t, index.Sum(value), otherKey.Sum(value))
+		assert.NotEqual(t, index.Sum(value), otherPurpose.Sum(value))
+	})
+}
@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Supported log output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// RequestIDHeader carries the request ID on REST/MCP requests, responses and webhook deliveries
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// requestIDPattern limits client supplied request IDs to values that are safe to log
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// Setup applies the configured log format and redaction to the global logger
+func Setup() error {
+	if err := ConfigureRedaction(config.AppLogRedact); err != nil {
+		return err
+	}
+
+	var formatter logrus.Formatter
+	switch strings.ToLower(config.AppLogFormat) {
+	case "", FormatText:
+		formatter = &logrus.TextFormatter{}
+	case FormatJSON:
+		formatter = &logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano}
+	default:
+		return fmt.Errorf("unknown log format %q, expected %s or %s", config.AppLogFormat, FormatText, FormatJSON)
+	}
+
+	if Redacting() {
+		formatter = &redactingFormatter{next: formatter}
+	}
+	logrus.SetFormatter(formatter)
+	return nil
+}
+
+// IsJSON reports whether logs are written as JSON
+func IsJSON() bool {
+	return strings.EqualFold(config.AppLogFormat, FormatJSON)
+}
+
+// NewRequestID generates a new request ID
+func NewRequestID() string {
+	return uuid.NewString()
+}
+
+// RequestIDFrom returns the client supplied request ID when it is safe to log, or a new one
+func RequestIDFrom(header string) string {
+	if requestIDPattern.MatchString(header) {
+		return header
+	}
+	return NewRequestID()
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID carried by ctx, or an empty string
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// FromContext returns a logger that tags every entry with the request and trace IDs carried by ctx
+func FromContext(ctx context.Context) *logrus.Entry {
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	if ctx == nil {
+		return entry
+	}
+	if requestID := RequestID(ctx); requestID != "" {
+		entry = entry.WithField("request_id", requestID)
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		entry = entry.WithField("trace_id", spanContext.TraceID().String())
+	}
+	return entry
+}
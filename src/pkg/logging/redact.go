@@ -0,0 +1,124 @@
+package logging
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Redaction categories accepted by --log-redact
+const (
+	RedactContent  = "content"   // message text, captions and push names
+	RedactPhone    = "phone"     // phone numbers in user JIDs, phone fields and +-prefixed numbers
+	RedactMediaKey = "media_key" // media keys, file hashes, direct paths and media download URLs
+	RedactAll      = "all"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	redactContent  bool
+	redactPhone    bool
+	redactMediaKey bool
+
+	// Values keyed by a sensitive field, in proto text (key:"v"), JSON ("key":"v") and fmt (key: v) forms
+	contentPattern  = regexp.MustCompile(`(?i)("?\b(?:conversation|text|caption|body|content|selected_?display_?text|push_?name)"?\s*[:=]\s*)("(?:[^"\\]|\\.)*"|[^,}\]\n]+)`)
+	mediaKeyPattern = regexp.MustCompile(`(?i)("?\b(?:media_?key|file_?enc_?sha256|file_?sha256|direct_?path|jpeg_?thumbnail|thumbnail_?(?:enc_?)?sha256|thumbnail_?direct_?path)"?\s*[:=]\s*)("(?:[^"\\]|\\.)*"|\[[^\]]*\]|[^\s,}\]]+)`)
+	mediaURLPattern = regexp.MustCompile(`https?://[a-z0-9.-]*\.whatsapp\.net/[^\s"']*`)
+	// Digit runs are only phone numbers next to a leading +, a phone key or a user JID server, so
+	// timestamps, sizes and numeric message IDs are left alone
+	phonePattern = regexp.MustCompile(`(?i)(\+|"?\bphone(?:_?number)?"?\s*[:=]\s*"?)?\b(\d{10,15})((?:[.:]\d+)*@s\.whatsapp\.net)?\b`)
+)
+
+// ConfigureRedaction enables the given redaction categories and disables the others
+func ConfigureRedaction(categories []string) error {
+	redactContent, redactPhone, redactMediaKey = false, false, false
+
+	for _, category := range categories {
+		switch strings.ToLower(strings.TrimSpace(category)) {
+		case "":
+		case RedactContent:
+			redactContent = true
+		case RedactPhone:
+			redactPhone = true
+		case RedactMediaKey:
+			redactMediaKey = true
+		case RedactAll:
+			redactContent, redactPhone, redactMediaKey = true, true, true
+		default:
+			return fmt.Errorf("unknown log redaction %q, expected %s, %s, %s or %s",
+				category, RedactContent, RedactPhone, RedactMediaKey, RedactAll)
+		}
+	}
+	return nil
+}
+
+// Redacting reports whether any redaction category is enabled
+func Redacting() bool {
+	return redactContent || redactPhone || redactMediaKey
+}
+
+// Redact masks the enabled categories of sensitive data in s
+func Redact(s string) string {
+	if redactMediaKey {
+		s = mediaKeyPattern.ReplaceAllString(s, "${1}"+redacted)
+		s = mediaURLPattern.ReplaceAllString(s, redacted)
+	}
+	if redactContent {
+		s = contentPattern.ReplaceAllString(s, "${1}"+redacted)
+	}
+	if redactPhone {
+		s = redactPhones(s)
+	}
+	return s
+}
+
+// redactPhones masks the digit runs of phonePattern matches that have a phone context
+func redactPhones(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range phonePattern.FindAllStringSubmatchIndex(s, -1) {
+		if m[2] < 0 && m[6] < 0 {
+			continue
+		}
+		b.WriteString(s[last:m[4]])
+		b.WriteString(maskPhone(s[m[4]:m[5]]))
+		last = m[5]
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// maskPhone keeps the country prefix and last two digits, e.g. "6281234567890" becomes "62*********90"
+func maskPhone(phone string) string {
+	return phone[:2] + strings.Repeat("*", len(phone)-4) + phone[len(phone)-2:]
+}
+
+// redactingFormatter masks sensitive data in the message and string fields before formatting
+type redactingFormatter struct {
+	next logrus.Formatter
+}
+
+func (f *redactingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	clone := *entry
+	clone.Message = Redact(entry.Message)
+	clone.Data = make(logrus.Fields, len(entry.Data))
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			clone.Data[key] = Redact(v)
+		case error:
+			clone.Data[key] = Redact(v.Error())
+		case fmt.Stringer:
+			clone.Data[key] = Redact(v.String())
+		default:
+			clone.Data[key] = value
+		}
+	}
+	return f.next.Format(&clone)
+}
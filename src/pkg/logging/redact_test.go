@@ -0,0 +1,143 @@
+package logging_test
+
+import (
+	"testing"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name       string
+		categories []string
+		input      string
+		want       string
+	}{
+		{
+			name:       "should leave input untouched when redaction is disabled",
+			categories: nil,
+			input:      `Received message from 6281234567890@s.whatsapp.net`,
+			want:       `Received message from 6281234567890@s.whatsapp.net`,
+		},
+		{
+			name:       "should mask phone numbers in JIDs",
+			categories: []string{"phone"},
+			input:      `Received message from 6281234567890@s.whatsapp.net`,
+			want:       `Received message from 62*********90@s.whatsapp.net`,
+		},
+		{
+			name:       "should mask phone numbers with plus sign",
+			categories: []string{"phone"},
+			input:      `calling +6281234567890`,
+			want:       `calling +62*********90`,
+		},
+		{
+			name:       "should not mask short numbers",
+			categories: []string{"phone"},
+			input:      `attempt 3 of 5, size 204800`,
+			want:       `attempt 3 of 5, size 204800`,
+		},
+		{
+			name:       "should mask phone numbers in device JIDs",
+			categories: []string{"phone"},
+			input:      `connected as 6281234567890:12@s.whatsapp.net`,
+			want:       `connected as 62*********90:12@s.whatsapp.net`,
+		},
+		{
+			name:       "should mask phone fields",
+			categories: []string{"phone"},
+			input:      `{"phone":"6281234567890","message":"hi"} phone=6289876543210`,
+			want:       `{"phone":"62*********90","message":"hi"} phone=62*********10`,
+		},
+		{
+			name:       "should not mask timestamps",
+			categories: []string{"phone"},
+			input:      `{"timestamp":1735689600,"sent_at":1735689600123} at 1735689600`,
+			want:       `{"timestamp":1735689600,"sent_at":1735689600123} at 1735689600`,
+		},
+		{
+			name:       "should not mask numeric message and group IDs",
+			categories: []string{"phone"},
+			input:      `Message 3EB0C127D7BACC83D6A3 id 120363025246125486 in 120363025246125486@g.us, server id 1234567890123`,
+			want:       `Message 3EB0C127D7BACC83D6A3 id 120363025246125486 in 120363025246125486@g.us, server id 1234567890123`,
+		},
+		{
+			name:       "should not mask hidden user IDs",
+			categories: []string{"phone"},
+			input:      `sender 123456789012345@lid`,
+			want:       `sender 123456789012345@lid`,
+		},
+		{
+			name:       "should mask message content in proto text",
+			categories: []string{"content"},
+			input:      `extendedTextMessage:{text:"see you at 8" contextInfo:{}}`,
+			want:       `extendedTextMessage:{text:[REDACTED] contextInfo:{}}`,
+		},
+		{
+			name:       "should mask message content in JSON",
+			categories: []string{"content"},
+			input:      `{"conversation":"hello \"there\"","id":"ABC"}`,
+			want:       `{"conversation":[REDACTED],"id":"ABC"}`,
+		},
+		{
+			name:       "should mask push names",
+			categories: []string{"content"},
+			input:      `pushname: John Doe, timestamp: 2025-01-01`,
+			want:       `pushname: [REDACTED], timestamp: 2025-01-01`,
+		},
+		{
+			name:       "should mask media keys and download URLs",
+			categories: []string{"media_key"},
+			input:      `imageMessage:{url:"https://mmg.whatsapp.net/v/t62/abc?oh=1" mediaKey:"\x12\x34" fileEncSHA256:"\xab"}`,
+			want:       `imageMessage:{url:"[REDACTED]" mediaKey:[REDACTED] fileEncSHA256:[REDACTED]}`,
+		},
+		{
+			name:       "should apply every category with all",
+			categories: []string{"all"},
+			input:      `conversation:"call 6281234567890" from 6281234567890@s.whatsapp.net`,
+			want:       `conversation:[REDACTED] from 62*********90@s.whatsapp.net`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, logging.ConfigureRedaction(tt.categories))
+			t.Cleanup(func() { _ = logging.ConfigureRedaction(nil) })
+
+			assert.Equal(t, tt.want, logging.Redact(tt.input))
+		})
+	}
+}
+
+func TestConfigureRedaction(t *testing.T) {
+	tests := []struct {
+		name       string
+		categories []string
+		wantErr    bool
+	}{
+		{name: "should accept known categories", categories: []string{"content", "Phone", " media_key "}},
+		{name: "should accept all", categories: []string{"all"}},
+		{name: "should accept empty values", categories: []string{""}},
+		{name: "should reject unknown category", categories: []string{"email"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := logging.ConfigureRedaction(tt.categories)
+			t.Cleanup(func() { _ = logging.ConfigureRedaction(nil) })
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRequestIDFrom(t *testing.T) {
+	assert.Equal(t, "req-123", logging.RequestIDFrom("req-123"))
+	assert.NotEqual(t, "bad id\nnext line", logging.RequestIDFrom("bad id\nnext line"))
+	assert.NotEmpty(t, logging.RequestIDFrom(""))
+}
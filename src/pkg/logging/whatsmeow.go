@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+var whatsmeowLevels = map[string]int{
+	"DEBUG": 0,
+	"INFO":  1,
+	"WARN":  2,
+	"ERROR": 3,
+}
+
+// NewWhatsmeowLogger returns a whatsmeow logger for the module. It writes through logrus when
+// JSON output or redaction is enabled so those apply to whatsmeow logs too.
+func NewWhatsmeowLogger(module string, minLevel string) waLog.Logger {
+	if !IsJSON() && !Redacting() {
+		return waLog.Stdout(module, minLevel, true)
+	}
+	return &whatsmeowLogger{module: module, minLevel: whatsmeowLevels[strings.ToUpper(minLevel)]}
+}
+
+// whatsmeowLogger adapts waLog.Logger to logrus, filtering by the whatsmeow log level
+type whatsmeowLogger struct {
+	module   string
+	minLevel int
+}
+
+func (l *whatsmeowLogger) log(level string, msg string, args []any) {
+	if whatsmeowLevels[level] < l.minLevel {
+		return
+	}
+
+	entry := logrus.WithField("module", l.module)
+	message := fmt.Sprintf(msg, args...)
+	switch level {
+	case "ERROR":
+		entry.Error(message)
+	case "WARN":
+		entry.Warn(message)
+	case "INFO":
+		entry.Info(message)
+	default:
+		entry.Debug(message)
+	}
+}
+
+func (l *whatsmeowLogger) Errorf(msg string, args ...any) { l.log("ERROR", msg, args) }
+func (l *whatsmeowLogger) Warnf(msg string, args ...any)  { l.log("WARN", msg, args) }
+func (l *whatsmeowLogger) Infof(msg string, args ...any)  { l.log("INFO", msg, args) }
+func (l *whatsmeowLogger) Debugf(msg string, args ...any) { l.log("DEBUG", msg, args) }
+
+func (l *whatsmeowLogger) Sub(module string) waLog.Logger {
+	return &whatsmeowLogger{module: fmt.Sprintf("%s/%s", l.module, module), minLevel: l.minLevel}
+}
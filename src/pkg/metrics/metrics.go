@@ -0,0 +1,180 @@
+package metrics
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "whatsapp"
+
+// Outcome labels shared by counters
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+var (
+	messagesSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_sent_total",
+		Help:      "Messages sent through the API, by message type and outcome.",
+	}, []string{"type", "status"})
+
+	sendDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "send_duration_seconds",
+		Help:      "Time taken to send a message to WhatsApp, by message type.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"type"})
+
+	messagesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_received_total",
+		Help:      "Messages received from WhatsApp, by message type.",
+	}, []string{"type"})
+
+	events = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_total",
+		Help:      "WhatsApp events handled, by event type.",
+	}, []string{"event"})
+
+	webhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook deliveries, by destination host and outcome.",
+	}, []string{"host", "status"})
+
+	webhookDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "webhook_delivery_duration_seconds",
+		Help:      "Time taken to deliver a webhook including retries, by destination host.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"host"})
+
+	webhookRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_retries_total",
+		Help:      "Webhook delivery retries, by destination host.",
+	}, []string{"host"})
+
+	storageQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "chatstorage_query_duration_seconds",
+		Help:      "Chat storage query latency, by SQL operation.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation"})
+
+	mcpToolCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mcp_tool_calls_total",
+		Help:      "MCP tool invocations, by tool name and outcome.",
+	}, []string{"tool", "status"})
+
+	mcpToolDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mcp_tool_duration_seconds",
+		Help:      "MCP tool invocation latency, by tool name.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"tool"})
+)
+
+func status(err error) string {
+	if err != nil {
+		return StatusFailure
+	}
+	return StatusSuccess
+}
+
+// ObserveSend records the outcome and latency of an outgoing message
+func ObserveSend(msgType string, started time.Time, err error) {
+	messagesSent.WithLabelValues(msgType, status(err)).Inc()
+	sendDuration.WithLabelValues(msgType).Observe(time.Since(started).Seconds())
+}
+
+// ObserveReceived records an incoming message
+func ObserveReceived(msgType string) {
+	messagesReceived.WithLabelValues(msgType).Inc()
+}
+
+// ObserveEvent records a handled WhatsApp event
+func ObserveEvent(event string) {
+	events.WithLabelValues(event).Inc()
+}
+
+// ObserveWebhook records a webhook delivery, retries are the attempts made after the first one
+func ObserveWebhook(webhookURL string, started time.Time, retries int, err error) {
+	host := WebhookHost(webhookURL)
+	webhookDeliveries.WithLabelValues(host, status(err)).Inc()
+	webhookDuration.WithLabelValues(host).Observe(time.Since(started).Seconds())
+	if retries > 0 {
+		webhookRetries.WithLabelValues(host).Add(float64(retries))
+	}
+}
+
+// WebhookHost returns the host of a webhook URL. Paths, query strings and credentials often carry
+// tokens, so they are kept out of labels and span attributes.
+func WebhookHost(webhookURL string) string {
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || parsed.Host == "" {
+		return "invalid"
+	}
+	return parsed.Host
+}
+
+// ObserveQuery records the latency of a chat storage statement, labelled by its SQL verb
+func ObserveQuery(query string, started time.Time) {
+	storageQueryDuration.WithLabelValues(QueryOperation(query)).Observe(time.Since(started).Seconds())
+}
+
+// QueryOperation returns the lower-cased first keyword of a SQL statement, e.g. "select"
+func QueryOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "unknown"
+	}
+	return strings.ToLower(fields[0])
+}
+
+// RegisterGauge registers a gauge whose value is read from fn on every scrape
+func RegisterGauge(name, help string, fn func() float64) {
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn))
+}
+
+// RegisterCounter registers a counter whose value is read from fn on every scrape
+func RegisterCounter(name, help string, fn func() float64) {
+	prometheus.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn))
+}
+
+// ToolMiddleware counts MCP tool invocations and their latency
+func ToolMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		started := time.Now()
+		result, err := next(ctx, request)
+
+		outcome := status(err)
+		if result != nil && result.IsError {
+			outcome = StatusFailure
+		}
+
+		tool := request.Params.Name
+		mcpToolCalls.WithLabelValues(tool, outcome).Inc()
+		mcpToolDuration.WithLabelValues(tool).Observe(time.Since(started).Seconds())
+		return result, err
+	}
+}
@@ -0,0 +1,255 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MaxPresignExpiry is the longest validity S3 accepts for a presigned URL
+const MaxPresignExpiry = 7 * 24 * time.Hour
+
+// ErrNotFound is returned when an object does not exist
+var ErrNotFound = errors.New("s3: object not found")
+
+// Error is an error response of the storage service
+type Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3: request failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("s3: %s: %s", e.Code, e.Message)
+}
+
+// Config describes the bucket a client works on
+type Config struct {
+	Endpoint   string // Base URL of the service, https://s3.<region>.amazonaws.com when empty
+	Region     string // us-east-1 when empty
+	Bucket     string
+	AccessKey  string
+	SecretKey  string
+	PathStyle  bool          // Address the bucket as the first path segment instead of a subdomain, needed by MinIO
+	Timeout    time.Duration // Deadline of a request, body included, when HTTPClient is nil. 5 minutes when zero
+	HTTPClient *http.Client  // A client with Timeout when nil
+}
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+	ContentType  string
+}
+
+// Client performs object operations on one bucket
+type Client struct {
+	endpoint  *url.URL
+	bucket    string
+	pathStyle bool
+	signer    Signer
+	http      *http.Client
+}
+
+// New returns a client for the bucket of cfg
+func New(cfg Config) (*Client, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("s3: bucket is required")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("s3: access key and secret key are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("s3: invalid endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Minute
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: cfg.Timeout}
+	}
+
+	return &Client{
+		endpoint:  endpoint,
+		bucket:    cfg.Bucket,
+		pathStyle: cfg.PathStyle,
+		signer:    Signer{AccessKey: cfg.AccessKey, SecretKey: cfg.SecretKey, Region: cfg.Region},
+		http:      cfg.HTTPClient,
+	}, nil
+}
+
+// PutObject uploads size bytes of body as key. The payload is streamed unsigned, so the endpoint
+// should use TLS outside of local testing.
+func (c *Client) PutObject(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := c.do(ctx, http.MethodPut, key, nil, header, body, size)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// GetObject returns the content of key, the caller closes it
+func (c *Client) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, http.MethodGet, key, nil, nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// HeadObject returns the size, modification time and content type of key
+func (c *Client) HeadObject(ctx context.Context, key string) (ObjectInfo, error) {
+	resp, err := c.do(ctx, http.MethodHead, key, nil, nil, nil, 0)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	resp.Body.Close()
+
+	info := ObjectInfo{Key: key, Size: resp.ContentLength, ContentType: resp.Header.Get("Content-Type")}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.LastModified = modified
+	}
+	return info, nil
+}
+
+// DeleteObject deletes key, deleting a missing object succeeds
+func (c *Client) DeleteObject(ctx context.Context, key string) error {
+	resp, err := c.do(ctx, http.MethodDelete, key, nil, nil, nil, 0)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// ListObjects calls fn for every object whose key starts with prefix, in key order
+func (c *Client) ListObjects(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	var result struct {
+		Contents []struct {
+			Key          string    `xml:"Key"`
+			Size         int64     `xml:"Size"`
+			LastModified time.Time `xml:"LastModified"`
+		} `xml:"Contents"`
+		IsTruncated           bool   `xml:"IsTruncated"`
+		NextContinuationToken string `xml:"NextContinuationToken"`
+	}
+
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := c.do(ctx, http.MethodGet, "", query, nil, nil, 0)
+		if err != nil {
+			return err
+		}
+		result.Contents = nil
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("s3: failed to parse object list: %w", err)
+		}
+
+		for _, object := range result.Contents {
+			if err := fn(ObjectInfo{Key: object.Key, Size: object.Size, LastModified: object.LastModified}); err != nil {
+				return err
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// PresignGetObject returns a URL that downloads key without credentials until expiry has passed
+func (c *Client) PresignGetObject(key string, expiry time.Duration) (string, error) {
+	if expiry <= 0 || expiry > MaxPresignExpiry {
+		return "", fmt.Errorf("s3: presign expiry must be between 1s and %s", MaxPresignExpiry)
+	}
+	return c.signer.Presign(c.objectURL(key, nil), expiry, time.Now()).String(), nil
+}
+
+// objectURL returns the URL of key, or of the bucket when key is empty
+func (c *Client) objectURL(key string, query url.Values) *url.URL {
+	u := *c.endpoint
+	path := u.Path
+	if c.pathStyle {
+		path += "/" + c.bucket
+	} else {
+		u.Host = c.bucket + "." + u.Host
+	}
+	if key != "" || !c.pathStyle {
+		path += "/" + key
+	}
+
+	u.Path = path
+	u.RawPath = uriEncode(path, false)
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+func (c *Client) do(ctx context.Context, method, key string, query url.Values, header http.Header, body io.Reader, size int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.objectURL(key, query).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	payloadHash := EmptyPayload
+	if body != nil {
+		req.ContentLength = size
+		payloadHash = UnsignedPayload
+	}
+	c.signer.Sign(req, payloadHash, time.Now())
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	if content, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10)); err == nil && len(content) > 0 {
+		_ = xml.Unmarshal(content, apiErr)
+	}
+	if resp.StatusCode == http.StatusNotFound && (apiErr.Code == "" || apiErr.Code == "NoSuchKey") {
+		return nil, ErrNotFound
+	}
+	return nil, apiErr
+}
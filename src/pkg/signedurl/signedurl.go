@@ -0,0 +1,57 @@
+// Package signedurl grants time-limited access to a URL path with an HMAC-SHA256 signature, so a link
+// can be handed to a client that has no credentials for the API, such as a webhook consumer.
+package signedurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Query parameters of a signed URL
+const (
+	ExpiresParam   = "expires"
+	SignatureParam = "signature"
+)
+
+var (
+	ErrMissing   = errors.New("signedurl: url is not signed")
+	ErrExpired   = errors.New("signedurl: url has expired")
+	ErrSignature = errors.New("signedurl: invalid signature")
+)
+
+// Sign returns path with the query parameters that grant access to it until expires
+func Sign(secret []byte, path string, expires time.Time) string {
+	unix := strconv.FormatInt(expires.Unix(), 10)
+	query := url.Values{ExpiresParam: {unix}, SignatureParam: {signature(secret, path, unix)}}
+	return path + "?" + query.Encode()
+}
+
+// Verify checks that query holds a signature of path made with secret that has not expired at now
+func Verify(secret []byte, path string, query url.Values, now time.Time) error {
+	unix, sig := query.Get(ExpiresParam), query.Get(SignatureParam)
+	if unix == "" || sig == "" {
+		return ErrMissing
+	}
+	expires, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return ErrSignature
+	}
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, path, unix))) {
+		return ErrSignature
+	}
+	if now.Unix() > expires {
+		return ErrExpired
+	}
+	return nil
+}
+
+func signature(secret []byte, path, expires string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(path + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,66 @@
+package signedurl_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/signedurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Date(2025, 7, 13, 10, 0, 0, 0, time.UTC)
+	signed := signedurl.Sign(secret, "/media/abc", now.Add(time.Hour))
+
+	tests := []struct {
+		name    string
+		path    string
+		query   func(url.Values)
+		now     time.Time
+		wantErr error
+	}{
+		{name: "should accept a valid signature", path: "/media/abc", now: now},
+		{name: "should accept until the expiry", path: "/media/abc", now: now.Add(time.Hour)},
+		{name: "should reject an expired url", path: "/media/abc", now: now.Add(time.Hour + time.Second), wantErr: signedurl.ErrExpired},
+		{name: "should reject another path", path: "/media/abd", now: now, wantErr: signedurl.ErrSignature},
+		{name: "should reject a changed expiry", path: "/media/abc", now: now, query: func(q url.Values) {
+			q.Set(signedurl.ExpiresParam, "99999999999")
+		}, wantErr: signedurl.ErrSignature},
+		{name: "should reject a tampered signature", path: "/media/abc", now: now, query: func(q url.Values) {
+			q.Set(signedurl.SignatureParam, strings.Repeat("0", 64))
+		}, wantErr: signedurl.ErrSignature},
+		{name: "should reject an unsigned url", path: "/media/abc", now: now, query: func(q url.Values) {
+			q.Del(signedurl.SignatureParam)
+		}, wantErr: signedurl.ErrMissing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(signed)
+			require.NoError(t, err)
+			query := u.Query()
+			if tt.query != nil {
+				tt.query(query)
+			}
+
+			err = signedurl.Verify(secret, tt.path, query, tt.now)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestVerifyOtherSecret(t *testing.T) {
+	now := time.Now()
+	u, err := url.Parse(signedurl.Sign([]byte("secret"), "/media/abc", now.Add(time.Minute)))
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, signedurl.Verify([]byte("other"), "/media/abc", u.Query(), now), signedurl.ErrSignature)
+}
@@ -0,0 +1,103 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/tracing"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider that keeps ended spans in memory for the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestStart(t *testing.T) {
+	recorder := recordSpans(t)
+
+	ctx, parent := tracing.StartServer(context.Background(), "POST /send/message", attribute.String("http.route", "/send/message"))
+	_, child := tracing.Start(ctx, "whatsapp.send_message", attribute.String("message.type", "text"))
+	tracing.End(child, nil)
+	_, client := tracing.StartClient(ctx, "webhook message")
+	tracing.End(client, nil)
+	tracing.End(parent, nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "whatsapp.send_message", spans[0].Name())
+	assert.Equal(t, trace.SpanKindInternal, spans[0].SpanKind())
+	assert.Equal(t, []attribute.KeyValue{attribute.String("message.type", "text")}, spans[0].Attributes())
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID(), "spans are children of the span in ctx")
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Empty(t, spans[0].Events())
+
+	assert.Equal(t, "webhook message", spans[1].Name())
+	assert.Equal(t, trace.SpanKindClient, spans[1].SpanKind())
+
+	assert.Equal(t, "POST /send/message", spans[2].Name())
+	assert.Equal(t, trace.SpanKindServer, spans[2].SpanKind())
+	assert.Equal(t, []attribute.KeyValue{attribute.String("http.route", "/send/message")}, spans[2].Attributes())
+}
+
+func TestEndRecordsErrors(t *testing.T) {
+	recorder := recordSpans(t)
+
+	_, span := tracing.Start(context.Background(), "send.download_image")
+	tracing.End(span, errors.New("download failed"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "download failed", spans[0].Status().Description)
+	require.Len(t, spans[0].Events(), 1)
+	assert.Equal(t, "exception", spans[0].Events()[0].Name)
+	assert.Contains(t, spans[0].Events()[0].Attributes, attribute.String("exception.message", "download failed"))
+}
+
+func TestToolMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		result     *mcp.CallToolResult
+		err        error
+		wantStatus codes.Code
+	}{
+		{name: "should leave successful tools unset", result: mcp.NewToolResultText("sent"), wantStatus: codes.Unset},
+		{name: "should mark error results", result: mcp.NewToolResultError("invalid phone"), wantStatus: codes.Error},
+		{name: "should record tool errors", err: errors.New("not logged in"), wantStatus: codes.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := recordSpans(t)
+			handler := tracing.ToolMiddleware(func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				assert.True(t, trace.SpanFromContext(ctx).SpanContext().IsValid(), "the tool runs inside the span")
+				return tt.result, tt.err
+			})
+
+			var request mcp.CallToolRequest
+			request.Params.Name = "whatsapp_send_text"
+			_, err := handler(context.Background(), request)
+			assert.Equal(t, tt.err, err)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			assert.Equal(t, "mcp.tool whatsapp_send_text", spans[0].Name())
+			assert.Equal(t, []attribute.KeyValue{attribute.String("mcp.tool.name", "whatsapp_send_text")}, spans[0].Attributes())
+			assert.Equal(t, tt.wantStatus, spans[0].Status().Code)
+		})
+	}
+}
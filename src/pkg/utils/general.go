@@ -2,8 +2,9 @@ package utils
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"image"
 	_ "image/gif"  // Register GIF format
 	_ "image/jpeg" // For JPEG encoding
 	_ "image/png"  // For PNG encoding
@@ -16,6 +17,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
@@ -73,12 +75,13 @@ func StrToFloat64(text string) float64 {
 }
 
 type Metadata struct {
-	Title       string
-	Description string
-	Image       string
-	ImageThumb  []byte
-	Height      *uint32
-	Width       *uint32
+	Title        string
+	Description  string
+	Image        string
+	ImageThumb   []byte // Inline JPEG thumbnail of the preview image
+	ImagePreview []byte // Larger JPEG of the preview image, Height and Width are its dimensions
+	Height       *uint32
+	Width        *uint32
 }
 
 func GetMetaDataFromURL(urlStr string) (meta Metadata, err error) {
@@ -183,17 +186,15 @@ func GetMetaDataFromURL(urlStr string) (meta Metadata, err error) {
 					} else if len(imageData) == 0 {
 						logrus.Warn("Downloaded image data is empty")
 					} else {
-						meta.ImageThumb = imageData
-
-						// Validate image by decoding it
-						imageReader := bytes.NewReader(imageData)
-						img, _, err := image.Decode(imageReader)
+						// Re-encode the image as upright JPEG thumbnails without metadata
+						preview, err := PrepareLinkPreview(imageData)
 						if err != nil {
 							logrus.Warnf("Failed to decode image: %v", err)
 						} else {
-							bounds := img.Bounds()
-							width := uint32(bounds.Max.X - bounds.Min.X)
-							height := uint32(bounds.Max.Y - bounds.Min.Y)
+							meta.ImageThumb = preview.Thumbnail
+							meta.ImagePreview = preview.Image
+							width := uint32(preview.Width)
+							height := uint32(preview.Height)
 
 							// Check if image is square (1:1 ratio)
 							if width == height && width <= 200 {
@@ -232,8 +233,49 @@ func ContainsMention(message string) []string {
 	return phoneNumbers
 }
 
-func DownloadImageFromURL(url string) ([]byte, string, error) {
-	client := &http.Client{
+// ErrFileTooLarge is returned by CopyLimited when the copied content exceeds the limit
+var ErrFileTooLarge = errors.New("file exceeds the maximum allowed size")
+
+// CopyLimited copies src to dst like io.Copy, but stops with ErrFileTooLarge as soon as more than limit
+// bytes were read, so content of unknown size never grows past the limit.
+func CopyLimited(dst io.Writer, src io.Reader, limit int64) (int64, error) {
+	reader := io.LimitReader(src, limit)
+	if limit < math.MaxInt64 {
+		reader = io.LimitReader(src, limit+1)
+	}
+	n, err := io.Copy(dst, reader)
+	if err != nil {
+		return n, err
+	}
+	if n > limit {
+		return n, fmt.Errorf("%w of %d bytes", ErrFileTooLarge, limit)
+	}
+	return n, nil
+}
+
+// downloadToFile streams a download into a new file in dir named after the URL extension, the file is
+// removed again when download fails. The caller removes the returned path once it is done with it.
+func downloadToFile(rawURL, dir string, download func(w io.Writer) (string, error)) (path, fileName string, err error) {
+	extension := filepath.Ext(strings.Split(rawURL, "?")[0])
+	file, err := os.CreateTemp(dir, "download-*"+extension)
+	if err != nil {
+		return "", "", err
+	}
+	defer func() {
+		if errClose := file.Close(); err == nil {
+			err = errClose
+		}
+		if err != nil {
+			os.Remove(file.Name())
+		}
+	}()
+
+	fileName, err = download(file)
+	return file.Name(), fileName, err
+}
+
+func newDownloadClient() *http.Client {
+	return &http.Client{
 		Timeout: 30 * time.Second,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			if len(via) >= 10 {
@@ -242,26 +284,36 @@ func DownloadImageFromURL(url string) ([]byte, string, error) {
 			return nil
 		},
 	}
-	response, err := client.Get(url)
+}
+
+func DownloadImageFromURL(url string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	fileName, err := downloadImage(url, &buf)
 	if err != nil {
 		return nil, "", err
 	}
+	return buf.Bytes(), fileName, nil
+}
+
+func downloadImage(url string, w io.Writer) (string, error) {
+	response, err := newDownloadClient().Get(url)
+	if err != nil {
+		return "", err
+	}
 	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
-		return nil, "", fmt.Errorf("HTTP request failed with status: %s", response.Status)
+		return "", fmt.Errorf("HTTP request failed with status: %s", response.Status)
 	}
 
 	contentType := response.Header.Get("Content-Type")
 	if !strings.HasPrefix(contentType, "image/") {
-		return nil, "", fmt.Errorf("invalid content type: %s", contentType)
+		return "", fmt.Errorf("invalid content type: %s", contentType)
 	}
 	// Check content length if available
 	if contentLength := response.ContentLength; contentLength > int64(config.WhatsappSettingMaxImageSize) {
-		return nil, "", fmt.Errorf("image size %d exceeds maximum allowed size %d", contentLength, config.WhatsappSettingMaxImageSize)
+		return "", fmt.Errorf("image size %d exceeds maximum allowed size %d", contentLength, config.WhatsappSettingMaxImageSize)
 	}
-	// Limit the size from config
-	reader := io.LimitReader(response.Body, int64(config.WhatsappSettingMaxImageSize))
 	// Extract the file name from the URL and remove query parameters if present
 	segments := strings.Split(url, "/")
 	fileName := segments[len(segments)-1]
@@ -275,13 +327,13 @@ func DownloadImageFromURL(url string) ([]byte, string, error) {
 	}
 	extension := strings.ToLower(filepath.Ext(fileName))
 	if !allowedExtensions[extension] {
-		return nil, "", fmt.Errorf("unsupported file type: %s", extension)
+		return "", fmt.Errorf("unsupported file type: %s", extension)
 	}
-	imageData, err := io.ReadAll(reader)
-	if err != nil {
-		return nil, "", err
+	// Limit the size from config, the server may not announce it
+	if _, err := CopyLimited(w, response.Body, config.WhatsappSettingMaxImageSize); err != nil {
+		return "", err
 	}
-	return imageData, fileName, nil
+	return fileName, nil
 }
 
 // DownloadAudioFromURL downloads an audio file from the provided URL and returns the bytes and sanitized filename.
@@ -289,24 +341,31 @@ func DownloadImageFromURL(url string) ([]byte, string, error) {
 // WhatsappSettingMaxDownloadSize limit to avoid memory exhaustion. Only the MIME types defined in audio validation
 // are allowed to ensure WhatsApp compatibility.
 func DownloadAudioFromURL(audioURL string) ([]byte, string, error) {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if len(via) >= 10 {
-				return fmt.Errorf("too many redirects")
-			}
-			return nil
-		},
+	var buf bytes.Buffer
+	fileName, err := downloadAudio(audioURL, &buf)
+	if err != nil {
+		return nil, "", err
 	}
+	return buf.Bytes(), fileName, nil
+}
 
-	resp, err := client.Get(audioURL)
+// DownloadAudioToFile downloads an audio file like DownloadAudioFromURL, but streams it into a new file in dir
+// instead of memory. It returns the path of that file, which the caller removes, and the sanitized filename.
+func DownloadAudioToFile(audioURL, dir string) (path, fileName string, err error) {
+	return downloadToFile(audioURL, dir, func(w io.Writer) (string, error) {
+		return downloadAudio(audioURL, w)
+	})
+}
+
+func downloadAudio(audioURL string, w io.Writer) (string, error) {
+	resp, err := newDownloadClient().Get(audioURL)
 	if err != nil {
-		return nil, "", err
+		return "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, "", fmt.Errorf("HTTP request failed with status: %s", resp.Status)
+		return "", fmt.Errorf("HTTP request failed with status: %s", resp.Status)
 	}
 
 	// Extract only the MIME type portion (ignore parameters like charset)
@@ -333,29 +392,18 @@ func DownloadAudioFromURL(audioURL string) ([]byte, string, error) {
 	}
 
 	if !allowedMimes[contentType] {
-		return nil, "", fmt.Errorf("invalid content type: %s", contentType)
+		return "", fmt.Errorf("invalid content type: %s", contentType)
 	}
 
 	// Validate content length when it is provided by the server.
 	maxSize := config.WhatsappSettingMaxDownloadSize
 	if resp.ContentLength > 0 && resp.ContentLength > maxSize {
-		return nil, "", fmt.Errorf("audio size %d exceeds maximum allowed size %d", resp.ContentLength, maxSize)
-	}
-
-	// Guard against servers that do not set Content-Length by reading at most (maxSize+1) bytes
-	// and erroring if the limit is exceeded.
-	limit := maxSize
-	if limit < math.MaxInt64 {
-		limit++
+		return "", fmt.Errorf("audio size %d exceeds maximum allowed size %d", resp.ContentLength, maxSize)
 	}
 
-	limitedReader := &io.LimitedReader{R: resp.Body, N: limit}
-	audioData, err := io.ReadAll(limitedReader)
-	if err != nil {
-		return nil, "", err
-	}
-	if int64(len(audioData)) > maxSize {
-		return nil, "", fmt.Errorf("downloaded audio size of %d bytes exceeds the maximum allowed size of %d bytes", len(audioData), maxSize)
+	// Guard against servers that do not set Content-Length, the copy fails once the limit is exceeded.
+	if _, err := CopyLimited(w, resp.Body, maxSize); err != nil {
+		return "", fmt.Errorf("failed to download audio: %w", err)
 	}
 
 	// Derive filename from URL path (strip query parameters if present)
@@ -366,31 +414,38 @@ func DownloadAudioFromURL(audioURL string) ([]byte, string, error) {
 		fileName = fmt.Sprintf("audio_%d", time.Now().Unix())
 	}
 
-	return audioData, fileName, nil
+	return fileName, nil
 }
 
 // DownloadVideoFromURL downloads a video file from the provided URL and returns the bytes and sanitized filename.
 // It validates that the content-type returned by the server is one of the supported WhatsApp video formats and
 // that the size does not exceed WhatsappSettingMaxDownloadSize to avoid memory exhaustion.
 func DownloadVideoFromURL(videoURL string) ([]byte, string, error) {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if len(via) >= 10 {
-				return fmt.Errorf("too many redirects")
-			}
-			return nil
-		},
+	var buf bytes.Buffer
+	fileName, err := downloadVideo(videoURL, &buf)
+	if err != nil {
+		return nil, "", err
 	}
+	return buf.Bytes(), fileName, nil
+}
 
-	resp, err := client.Get(videoURL)
+// DownloadVideoToFile downloads a video file like DownloadVideoFromURL, but streams it into a new file in dir
+// instead of memory. It returns the path of that file, which the caller removes, and the sanitized filename.
+func DownloadVideoToFile(videoURL, dir string) (path, fileName string, err error) {
+	return downloadToFile(videoURL, dir, func(w io.Writer) (string, error) {
+		return downloadVideo(videoURL, w)
+	})
+}
+
+func downloadVideo(videoURL string, w io.Writer) (string, error) {
+	resp, err := newDownloadClient().Get(videoURL)
 	if err != nil {
-		return nil, "", err
+		return "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, "", fmt.Errorf("HTTP request failed with status: %s", resp.Status)
+		return "", fmt.Errorf("HTTP request failed with status: %s", resp.Status)
 	}
 
 	// Extract MIME type without parameters
@@ -404,28 +459,18 @@ func DownloadVideoFromURL(videoURL string) ([]byte, string, error) {
 	}
 
 	if !allowedMimes[contentType] {
-		return nil, "", fmt.Errorf("invalid content type: %s", contentType)
+		return "", fmt.Errorf("invalid content type: %s", contentType)
 	}
 
 	// Validate content length if provided
 	maxSize := config.WhatsappSettingMaxDownloadSize
 	if resp.ContentLength > 0 && resp.ContentLength > maxSize {
-		return nil, "", fmt.Errorf("video size %d exceeds maximum allowed size %d", resp.ContentLength, maxSize)
+		return "", fmt.Errorf("video size %d exceeds maximum allowed size %d", resp.ContentLength, maxSize)
 	}
 
-	// Guard against unknown Content-Length by limiting reader
-	limit := maxSize
-	if limit < math.MaxInt64 {
-		limit++
-	}
-
-	limitedReader := &io.LimitedReader{R: resp.Body, N: limit}
-	videoData, err := io.ReadAll(limitedReader)
-	if err != nil {
-		return nil, "", err
-	}
-	if int64(len(videoData)) > maxSize {
-		return nil, "", fmt.Errorf("downloaded video size of %d bytes exceeds the maximum allowed size of %d bytes", len(videoData), maxSize)
+	// Guard against unknown Content-Length, the copy fails once the limit is exceeded
+	if _, err := CopyLimited(w, resp.Body, maxSize); err != nil {
+		return "", fmt.Errorf("failed to download video: %w", err)
 	}
 
 	// Derive filename from URL path
@@ -436,7 +481,21 @@ func DownloadVideoFromURL(videoURL string) ([]byte, string, error) {
 		fileName = fmt.Sprintf("video_%d.mp4", time.Now().Unix())
 	}
 
-	return videoData, fileName, nil
+	return fileName, nil
+}
+
+// AlbumMediaTypeFromURL returns the media type of an album item URL from its extension, image or
+// video, or an empty string when the extension is not one DownloadImageFromURL or
+// DownloadVideoToFile handles
+func AlbumMediaTypeFromURL(mediaURL string) string {
+	switch strings.ToLower(filepath.Ext(strings.Split(mediaURL, "?")[0])) {
+	case ".jpg", ".jpeg", ".png", ".webp":
+		return "image"
+	case ".mp4", ".mkv", ".avi":
+		return "video"
+	default:
+		return ""
+	}
 }
 
 // FormatBusinessHourTime converts numeric time format (e.g., 600, 1200) to HH:MM format (e.g., "06:00", "12:00")
@@ -472,3 +531,19 @@ func FormatBusinessHourTime(timeValue any) string {
 
 	return fmt.Sprintf("%02d:%02d", hours, minutes)
 }
+
+// WaitGroupContext blocks until wg is done or ctx is done, it returns the error of ctx in the latter case
+func WaitGroupContext(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
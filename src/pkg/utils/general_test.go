@@ -604,6 +604,57 @@ func (suite *UtilsTestSuite) TestDownloadVideoFromURL() {
 	assert.Contains(suite.T(), err.Error(), "too many redirects")
 }
 
+func (suite *UtilsTestSuite) TestCopyLimited() {
+	var buf strings.Builder
+	n, err := utils.CopyLimited(&buf, strings.NewReader("12345"), 5)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(5), n)
+	assert.Equal(suite.T(), "12345", buf.String())
+
+	buf.Reset()
+	_, err = utils.CopyLimited(&buf, strings.NewReader("123456789"), 5)
+	assert.ErrorIs(suite.T(), err, utils.ErrFileTooLarge)
+	assert.Equal(suite.T(), "123456", buf.String(), "copying stops right after the limit")
+}
+
+func (suite *UtilsTestSuite) TestDownloadVideoToFile() {
+	origMaxSize := config.WhatsappSettingMaxDownloadSize
+	config.WhatsappSettingMaxDownloadSize = 16
+	defer func() {
+		config.WhatsappSettingMaxDownloadSize = origMaxSize
+	}()
+
+	// Chunked responses do not announce their size, so only the copy can catch them
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "video/mp4")
+		if r.URL.Path == "/large.mp4" {
+			for i := 0; i < 4; i++ {
+				w.Write([]byte("chunk of video data"))
+				w.(http.Flusher).Flush()
+			}
+			return
+		}
+		w.Write([]byte("video data"))
+	}))
+	defer server.Close()
+
+	dir := suite.T().TempDir()
+	path, fileName, err := utils.DownloadVideoToFile(server.URL+"/test.mp4?v=1", dir)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "test.mp4", fileName)
+	assert.Equal(suite.T(), ".mp4", filepath.Ext(path))
+	content, err := os.ReadFile(path)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "video data", string(content))
+
+	_, _, err = utils.DownloadVideoToFile(server.URL+"/large.mp4", dir)
+	assert.ErrorIs(suite.T(), err, utils.ErrFileTooLarge)
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), entries, 1, "the failed download is removed")
+}
+
 func TestUtilsTestSuite(t *testing.T) {
 	suite.Run(t, new(UtilsTestSuite))
 }
@@ -123,6 +123,23 @@ func compressToJPEGWithDepth(img image.Image, quality int, depth int) (*bytes.Bu
 	return &buf, nil
 }
 
+// GenerateJPEGThumbnail decodes an image and returns an upright JPEG thumbnail scaled to the given
+// width, suitable for the JPEGThumbnail field of WhatsApp media messages
+func GenerateJPEGThumbnail(data []byte, width int) ([]byte, error) {
+	img, err := DecodeImage(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	thumbnail := imaging.Resize(img, width, 0, imaging.Lanczos)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: GroupPhotoQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
 // ValidateGroupPhotoFormat checks if the uploaded file is a supported image format
 func ValidateGroupPhotoFormat(file *multipart.FileHeader) error {
 	if file == nil {
@@ -0,0 +1,73 @@
+package utils_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestGenerateJPEGThumbnail(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		width      int
+		wantWidth  int
+		wantHeight int
+		wantErr    bool
+	}{
+		{
+			name:       "should scale landscape image keeping aspect ratio",
+			data:       encodePNG(t, 400, 200),
+			width:      100,
+			wantWidth:  100,
+			wantHeight: 50,
+		},
+		{
+			name:       "should scale portrait image keeping aspect ratio",
+			data:       encodePNG(t, 200, 400),
+			width:      100,
+			wantWidth:  100,
+			wantHeight: 200,
+		},
+		{
+			name:    "should fail on non image data",
+			data:    []byte("not an image"),
+			width:   100,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thumbnail, err := utils.GenerateJPEGThumbnail(tt.data, tt.width)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			decoded, err := jpeg.Decode(bytes.NewReader(thumbnail))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantWidth, decoded.Bounds().Dx())
+			assert.Equal(t, tt.wantHeight, decoded.Bounds().Dy())
+		})
+	}
+}
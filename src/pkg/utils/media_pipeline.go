@@ -0,0 +1,257 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"strings"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	"github.com/disintegration/imaging"
+)
+
+// Images are sent the way the official clients send them: decoded, turned upright by their EXIF
+// orientation and encoded again as JPEG, which drops EXIF, XMP and other metadata such as the
+// location a photo was taken at. Everything runs in Go, ffmpeg is only needed for videos.
+
+const (
+	// ThumbnailDimension is the longest side of the inline JPEGThumbnail of a message
+	ThumbnailDimension = 100
+	// LinkPreviewDimension is the longest side of the preview image uploaded with a link
+	LinkPreviewDimension = 720
+	// CompressedImageDimension is the longest side of images sent with compress, like the standard
+	// quality of the official clients
+	CompressedImageDimension = 1600
+	// MaxImagePixels is the largest image decoded, it keeps a small file that claims huge
+	// dimensions from exhausting memory
+	MaxImagePixels = 50_000_000
+
+	imageQuality           = 90
+	compressedImageQuality = 75
+	thumbnailQuality       = 80
+	minImageQuality        = 40
+
+	// pdfScanLimit is how much of a PDF is searched for an image to use as its thumbnail
+	pdfScanLimit = 8 << 20
+)
+
+// ErrImageTooLarge is returned for images with more than MaxImagePixels pixels
+var ErrImageTooLarge = errors.New("image dimensions are too large")
+
+// ImageOptions tunes PrepareImage
+type ImageOptions struct {
+	MaxDimension int   // The longest side is scaled down to this many pixels, the size is kept when zero
+	Quality      int   // JPEG quality the encoding starts with
+	MaxSize      int64 // The quality and then the dimensions are lowered until the JPEG fits, unlimited when zero
+}
+
+// SendImageOptions returns the options of an image send, compress trades quality for a smaller file
+func SendImageOptions(compress bool) ImageOptions {
+	if compress {
+		return ImageOptions{MaxDimension: CompressedImageDimension, Quality: compressedImageQuality, MaxSize: config.WhatsappSettingMaxImageSize}
+	}
+	return ImageOptions{Quality: imageQuality, MaxSize: config.WhatsappSettingMaxImageSize}
+}
+
+// PreparedImage is an image ready to be sent
+type PreparedImage struct {
+	JPEG      []byte // Upright JPEG without metadata
+	Width     int
+	Height    int
+	Thumbnail []byte // JPEG for the JPEGThumbnail field
+}
+
+// PrepareImage decodes a JPEG, PNG, GIF or WebP image, turns it upright and encodes it again as a JPEG
+// without metadata that fits the options, together with its thumbnail
+func PrepareImage(r io.ReadSeeker, opts ImageOptions) (*PreparedImage, error) {
+	img, err := DecodeImage(r)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxDimension > 0 {
+		img = fitImage(img, opts.MaxDimension)
+	}
+
+	data, img, err := EncodeJPEG(img, opts.Quality, opts.MaxSize)
+	if err != nil {
+		return nil, err
+	}
+	thumbnail, err := Thumbnail(img)
+	if err != nil {
+		return nil, err
+	}
+	return &PreparedImage{
+		JPEG:      data,
+		Width:     img.Bounds().Dx(),
+		Height:    img.Bounds().Dy(),
+		Thumbnail: thumbnail,
+	}, nil
+}
+
+// DecodeImage decodes an image after checking its dimensions against MaxImagePixels and applies its
+// EXIF orientation. Transparent areas are flattened onto white, JPEG has no alpha channel.
+func DecodeImage(r io.ReadSeeker) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if cfg.Width*cfg.Height > MaxImagePixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, cfg.Width, cfg.Height)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	img, err := imaging.Decode(r, imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && !opaque.Opaque() {
+		background := imaging.New(img.Bounds().Dx(), img.Bounds().Dy(), color.White)
+		img = imaging.Overlay(background, img, image.Pt(0, 0), 1)
+	}
+	return img, nil
+}
+
+// EncodeJPEG encodes img as JPEG with quality. When the result is larger than maxSize the quality is
+// lowered down to a floor, then the image is scaled down until it fits. It returns the JPEG and the
+// image it encodes, which is smaller than img when it had to be scaled.
+func EncodeJPEG(img image.Image, quality int, maxSize int64) ([]byte, image.Image, error) {
+	var buf bytes.Buffer
+	for {
+		buf.Reset()
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode JPEG: %w", err)
+		}
+		if maxSize <= 0 || int64(buf.Len()) <= maxSize {
+			return buf.Bytes(), img, nil
+		}
+
+		if quality > minImageQuality {
+			quality = max(quality-10, minImageQuality)
+			continue
+		}
+		longest := max(img.Bounds().Dx(), img.Bounds().Dy())
+		if longest <= ThumbnailDimension {
+			return nil, nil, fmt.Errorf("image cannot be compressed below %d bytes", maxSize)
+		}
+		img = fitImage(img, longest*4/5)
+	}
+}
+
+// Thumbnail returns img scaled to fit ThumbnailDimension as JPEG, for the JPEGThumbnail field
+func Thumbnail(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, fitImage(img, ThumbnailDimension), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// LinkPreview is the preview image of a link
+type LinkPreview struct {
+	Thumbnail []byte // JPEG for the JPEGThumbnail field
+	Image     []byte // Larger JPEG uploaded as the high quality thumbnail
+	Width     int    // Dimensions of Image
+	Height    int
+}
+
+// PrepareLinkPreview turns the image a page links as its preview, for example og:image, into the
+// thumbnails of a link message
+func PrepareLinkPreview(data []byte) (*LinkPreview, error) {
+	img, err := DecodeImage(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	preview, img, err := EncodeJPEG(fitImage(img, LinkPreviewDimension), thumbnailQuality, 0)
+	if err != nil {
+		return nil, err
+	}
+	thumbnail, err := Thumbnail(img)
+	if err != nil {
+		return nil, err
+	}
+	return &LinkPreview{Thumbnail: thumbnail, Image: preview, Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}, nil
+}
+
+// DocumentThumbnail is the preview of a document
+type DocumentThumbnail struct {
+	JPEG   []byte
+	Width  int
+	Height int
+}
+
+// GenerateDocumentThumbnail renders the thumbnail of a document with the MIME type: images are scaled
+// down and PDFs show the first JPEG embedded in them, for scanned documents the first page. It returns
+// nil for other documents and for PDFs without such an image. r is left at an unspecified position.
+func GenerateDocumentThumbnail(r io.ReadSeeker, mimeType string) (*DocumentThumbnail, error) {
+	var (
+		img image.Image
+		err error
+	)
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		img, err = DecodeImage(r)
+	case mimeType == "application/pdf":
+		img, err = pdfFirstImage(r)
+	}
+	if err != nil || img == nil {
+		return nil, err
+	}
+
+	thumbnail, err := Thumbnail(img)
+	if err != nil {
+		return nil, err
+	}
+	size := fitImage(img, ThumbnailDimension).Bounds()
+	return &DocumentThumbnail{JPEG: thumbnail, Width: size.Dx(), Height: size.Dy()}, nil
+}
+
+// pdfFirstImage returns the first JPEG image (a DCTDecode stream) in the start of a PDF that is at
+// least ThumbnailDimension in both directions, which skips logos and icons. Rendering the page
+// itself would need a PDF renderer.
+func pdfFirstImage(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(io.LimitReader(r, pdfScanLimit))
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		i := bytes.Index(data, []byte("/DCTDecode"))
+		if i < 0 {
+			return nil, nil
+		}
+		data = data[i+len("/DCTDecode"):]
+
+		start := bytes.Index(data, []byte("stream"))
+		if start < 0 {
+			return nil, nil
+		}
+		stream := bytes.TrimLeft(data[start+len("stream"):], "\r\n")
+		end := bytes.Index(stream, []byte("endstream"))
+		if end < 0 {
+			return nil, nil
+		}
+
+		// Streams with more filters than DCTDecode fail to decode and are skipped
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(stream[:end]))
+		if err != nil || cfg.Width < ThumbnailDimension || cfg.Height < ThumbnailDimension || cfg.Width*cfg.Height > MaxImagePixels {
+			continue
+		}
+		if img, err := jpeg.Decode(bytes.NewReader(stream[:end])); err == nil {
+			return img, nil
+		}
+	}
+}
+
+// fitImage scales img down so its longest side is at most dimension, smaller images are kept
+func fitImage(img image.Image, dimension int) image.Image {
+	if img.Bounds().Dx() <= dimension && img.Bounds().Dy() <= dimension {
+		return img
+	}
+	return imaging.Fit(img, dimension, dimension, imaging.Lanczos)
+}
@@ -0,0 +1,210 @@
+package utils_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"testing"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}))
+	return buf.Bytes()
+}
+
+// noise returns an image that compresses badly
+func noise(width, height int) image.Image {
+	random := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	random.Read(img.Pix)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+	return img
+}
+
+// withOrientation inserts an EXIF segment with the orientation tag after the SOI marker of a JPEG
+func withOrientation(data []byte, orientation uint16) []byte {
+	exif := []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01")
+	exif = binary.BigEndian.AppendUint16(exif, orientation)
+	exif = append(exif, 0, 0, 0, 0, 0, 0)
+
+	segment := []byte{0xff, 0xe1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(exif)+2))
+	segment = append(segment, exif...)
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+// pngWithSize returns a PNG whose header claims the dimensions, without pixel data to back them
+func pngWithSize(t *testing.T, width, height uint32) []byte {
+	data := encodePNG(t, 1, 1)
+	// The IHDR chunk follows the 8 byte signature: length, type, width, height, ..., CRC
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestPrepareImage(t *testing.T) {
+	transparent := image.NewNRGBA(image.Rect(0, 0, 50, 50))
+	var transparentPNG bytes.Buffer
+	require.NoError(t, png.Encode(&transparentPNG, transparent))
+
+	tests := []struct {
+		name       string
+		data       []byte
+		opts       utils.ImageOptions
+		wantWidth  int
+		wantHeight int
+		check      func(t *testing.T, img image.Image)
+		wantErr    error
+	}{
+		{
+			name:       "should keep the size without limits",
+			data:       encodePNG(t, 400, 200),
+			opts:       utils.ImageOptions{Quality: 90},
+			wantWidth:  400,
+			wantHeight: 200,
+		},
+		{
+			name:       "should scale down to the max dimension",
+			data:       encodePNG(t, 200, 400),
+			opts:       utils.ImageOptions{MaxDimension: 100, Quality: 90},
+			wantWidth:  50,
+			wantHeight: 100,
+		},
+		{
+			name:       "should turn the image upright by its exif orientation",
+			data:       withOrientation(encodeJPEG(t, noise(40, 20)), 6),
+			opts:       utils.ImageOptions{Quality: 90},
+			wantWidth:  20,
+			wantHeight: 40,
+		},
+		{
+			name:       "should flatten transparency onto white",
+			data:       transparentPNG.Bytes(),
+			opts:       utils.ImageOptions{Quality: 90},
+			wantWidth:  50,
+			wantHeight: 50,
+			check: func(t *testing.T, img image.Image) {
+				r, g, b, _ := img.At(25, 25).RGBA()
+				assert.Greater(t, r>>8, uint32(250))
+				assert.Greater(t, g>>8, uint32(250))
+				assert.Greater(t, b>>8, uint32(250))
+			},
+		},
+		{
+			name:    "should reject images with too many pixels",
+			data:    pngWithSize(t, 10000, 10000),
+			opts:    utils.ImageOptions{Quality: 90},
+			wantErr: utils.ErrImageTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prepared, err := utils.PrepareImage(bytes.NewReader(tt.data), tt.opts)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.NotContains(t, string(prepared.JPEG), "Exif", "metadata is stripped")
+			img, err := jpeg.Decode(bytes.NewReader(prepared.JPEG))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantWidth, img.Bounds().Dx())
+			assert.Equal(t, tt.wantHeight, img.Bounds().Dy())
+			assert.Equal(t, tt.wantWidth, prepared.Width)
+			assert.Equal(t, tt.wantHeight, prepared.Height)
+			if tt.check != nil {
+				tt.check(t, img)
+			}
+
+			thumbnail, err := jpeg.Decode(bytes.NewReader(prepared.Thumbnail))
+			require.NoError(t, err)
+			assert.LessOrEqual(t, max(thumbnail.Bounds().Dx(), thumbnail.Bounds().Dy()), utils.ThumbnailDimension)
+		})
+	}
+}
+
+func TestPrepareImageMaxSize(t *testing.T) {
+	data := encodeJPEG(t, noise(600, 400))
+	const maxSize = 20000
+	require.Greater(t, len(data), maxSize)
+
+	prepared, err := utils.PrepareImage(bytes.NewReader(data), utils.ImageOptions{Quality: 90, MaxSize: maxSize})
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(prepared.JPEG), maxSize)
+	assert.Less(t, prepared.Width, 600, "noise only fits once scaled down")
+	assert.InDelta(t, 1.5, float64(prepared.Width)/float64(prepared.Height), 0.05)
+}
+
+func TestPrepareLinkPreview(t *testing.T) {
+	preview, err := utils.PrepareLinkPreview(encodePNG(t, 1440, 720))
+	require.NoError(t, err)
+	assert.Equal(t, utils.LinkPreviewDimension, preview.Width)
+	assert.Equal(t, utils.LinkPreviewDimension/2, preview.Height)
+
+	thumbnail, err := jpeg.Decode(bytes.NewReader(preview.Thumbnail))
+	require.NoError(t, err)
+	assert.Equal(t, utils.ThumbnailDimension, thumbnail.Bounds().Dx())
+
+	_, err = utils.PrepareLinkPreview([]byte("<html>"))
+	assert.Error(t, err)
+}
+
+func TestGenerateDocumentThumbnail(t *testing.T) {
+	pdfImage := func(img image.Image) string {
+		return "<< /Type /XObject /Subtype /Image /Filter /DCTDecode >>\nstream\r\n" + string(encodeJPEG(t, img)) + "\nendstream\nendobj\n"
+	}
+	page := image.NewRGBA(image.Rect(0, 0, 200, 400))
+	for i := range page.Pix {
+		page.Pix[i] = 200
+	}
+	pdf := "%PDF-1.4\n1 0 obj\n" + pdfImage(noise(20, 20)) +
+		"2 0 obj\n<< /Filter [/FlateDecode /DCTDecode] >>\nstream\nnot a jpeg\nendstream\nendobj\n" +
+		"3 0 obj\n" + pdfImage(page) + "%%EOF\n"
+
+	tests := []struct {
+		name       string
+		data       []byte
+		mimeType   string
+		wantNil    bool
+		wantWidth  int
+		wantHeight int
+	}{
+		{name: "should use the first page sized image of a pdf", data: []byte(pdf), mimeType: "application/pdf", wantWidth: 50, wantHeight: 100},
+		{name: "should scale down image documents", data: encodePNG(t, 400, 200), mimeType: "image/png", wantWidth: 100, wantHeight: 50},
+		{name: "should skip pdfs without images", data: []byte("%PDF-1.4\n%%EOF\n"), mimeType: "application/pdf", wantNil: true},
+		{name: "should skip other documents", data: []byte("PK\x03\x04"), mimeType: "application/zip", wantNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thumbnail, err := utils.GenerateDocumentThumbnail(bytes.NewReader(tt.data), tt.mimeType)
+			require.NoError(t, err)
+			if tt.wantNil {
+				assert.Nil(t, thumbnail)
+				return
+			}
+
+			require.NotNil(t, thumbnail)
+			img, err := jpeg.Decode(bytes.NewReader(thumbnail.JPEG))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantWidth, img.Bounds().Dx())
+			assert.Equal(t, tt.wantHeight, img.Bounds().Dy())
+			assert.Equal(t, tt.wantWidth, thumbnail.Width)
+			assert.Equal(t, tt.wantHeight, thumbnail.Height)
+		})
+	}
+}
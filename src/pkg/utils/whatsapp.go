@@ -7,9 +7,12 @@ import (
 	"encoding/hex"
 	"fmt"
 	"mime"
+	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -240,6 +243,36 @@ func ExtractMediaInfo(msg *waE2E.Message) (mediaType string, filename string, ur
 	return "", "", "", nil, nil, nil, 0
 }
 
+// MessageType returns a short name for the kind of content in a WhatsApp message, e.g. "text" or "image"
+func MessageType(msg *waE2E.Message) string {
+	if msg == nil {
+		return "unknown"
+	}
+
+	if mediaType, _, _, _, _, _, _ := ExtractMediaInfo(msg); mediaType != "" {
+		return mediaType
+	}
+
+	switch {
+	case msg.GetConversation() != "" || msg.GetExtendedTextMessage() != nil:
+		return "text"
+	case msg.GetReactionMessage() != nil:
+		return "reaction"
+	case msg.GetContactMessage() != nil || msg.GetContactsArrayMessage() != nil:
+		return "contact"
+	case msg.GetLocationMessage() != nil || msg.GetLiveLocationMessage() != nil:
+		return "location"
+	case msg.GetPollCreationMessage() != nil || msg.GetPollCreationMessageV3() != nil:
+		return "poll"
+	case msg.GetAlbumMessage() != nil:
+		return "album"
+	case msg.GetProtocolMessage() != nil:
+		return "protocol"
+	}
+
+	return "other"
+}
+
 // ExtractEphemeralExpiration extracts ephemeral expiration from a WhatsApp message
 func ExtractEphemeralExpiration(msg *waE2E.Message) uint32 {
 	logrus.Debug("ExtractEphemeralExpiration: Starting extraction process")
@@ -468,28 +501,13 @@ func FormatJID(jid string) types.JID {
 // ExtractedMedia represents extracted media information
 type ExtractedMedia struct {
 	MediaPath string `json:"media_path"`
+	MediaURL  string `json:"media_url,omitempty"` // Time-limited link to the media, set for webhook payloads
 	MimeType  string `json:"mime_type"`
 	Caption   string `json:"caption"`
 }
 
-// ExtractMedia is a helper function to extract media from whatsapp
-func ExtractMedia(ctx context.Context, client *whatsmeow.Client, storageLocation string, mediaFile whatsmeow.DownloadableMessage) (extractedMedia ExtractedMedia, err error) {
-	if mediaFile == nil {
-		logrus.Info("Skip download because data is nil")
-		return extractedMedia, nil
-	}
-
-	data, err := client.Download(ctx, mediaFile)
-	if err != nil {
-		return extractedMedia, err
-	}
-
-	// Validate file size before writing to disk
-	maxFileSize := config.WhatsappSettingMaxDownloadSize
-	if int64(len(data)) > maxFileSize {
-		return extractedMedia, fmt.Errorf("file size exceeds the maximum limit of %d bytes", maxFileSize)
-	}
-
+// DescribeMedia returns the mime type and caption of a media message, without a media path
+func DescribeMedia(mediaFile whatsmeow.DownloadableMessage) (extractedMedia ExtractedMedia) {
 	switch media := mediaFile.(type) {
 	case *waE2E.ImageMessage:
 		extractedMedia.MimeType = media.GetMimetype()
@@ -505,15 +523,35 @@ func ExtractMedia(ctx context.Context, client *whatsmeow.Client, storageLocation
 		extractedMedia.MimeType = media.GetMimetype()
 		extractedMedia.Caption = media.GetCaption()
 	}
+	return extractedMedia
+}
 
-	var extension string
-	if ext, err := mime.ExtensionsByType(extractedMedia.MimeType); err == nil && len(ext) > 0 {
-		extension = ext[0]
-	} else if parts := strings.Split(extractedMedia.MimeType, "/"); len(parts) > 1 {
-		extension = "." + parts[len(parts)-1]
+// MediaURLExpiry returns when a WhatsApp media URL stops working, read from its oe parameter
+func MediaURLExpiry(mediaURL string) (time.Time, bool) {
+	parsed, err := url.Parse(mediaURL)
+	if err != nil {
+		return time.Time{}, false
+	}
+	expiry, err := strconv.ParseInt(parsed.Query().Get("oe"), 16, 64)
+	if err != nil || expiry <= 0 {
+		return time.Time{}, false
 	}
+	return time.Unix(expiry, 0), true
+}
 
-	extractedMedia.MediaPath = fmt.Sprintf("%s/%d-%s%s", storageLocation, time.Now().Unix(), uuid.NewString(), extension)
+// ExtractMedia is a helper function to extract media from whatsapp
+func ExtractMedia(ctx context.Context, client *whatsmeow.Client, storageLocation string, mediaFile whatsmeow.DownloadableMessage) (extractedMedia ExtractedMedia, err error) {
+	if mediaFile == nil {
+		logrus.Info("Skip download because data is nil")
+		return extractedMedia, nil
+	}
+
+	data, extractedMedia, err := DownloadMediaContent(ctx, client, mediaFile)
+	if err != nil {
+		return extractedMedia, err
+	}
+
+	extractedMedia.MediaPath = fmt.Sprintf("%s/%s", storageLocation, MediaFileName(extractedMedia.MimeType))
 	err = os.WriteFile(extractedMedia.MediaPath, data, 0600)
 	if err != nil {
 		return extractedMedia, err
@@ -521,6 +559,33 @@ func ExtractMedia(ctx context.Context, client *whatsmeow.Client, storageLocation
 	return extractedMedia, nil
 }
 
+// DownloadMediaContent downloads and decrypts mediaFile and describes it, MediaPath is left empty
+func DownloadMediaContent(ctx context.Context, client *whatsmeow.Client, mediaFile whatsmeow.DownloadableMessage) (data []byte, extractedMedia ExtractedMedia, err error) {
+	data, err = client.Download(ctx, mediaFile)
+	if err != nil {
+		return nil, extractedMedia, err
+	}
+
+	// Validate file size before storing it
+	maxFileSize := config.WhatsappSettingMaxDownloadSize
+	if int64(len(data)) > maxFileSize {
+		return nil, extractedMedia, fmt.Errorf("file size exceeds the maximum limit of %d bytes", maxFileSize)
+	}
+
+	return data, DescribeMedia(mediaFile), nil
+}
+
+// MediaFileName returns a unique name for a downloaded file of mimeType
+func MediaFileName(mimeType string) string {
+	var extension string
+	if ext, err := mime.ExtensionsByType(mimeType); err == nil && len(ext) > 0 {
+		extension = ext[0]
+	} else if parts := strings.Split(mimeType, "/"); len(parts) > 1 {
+		extension = "." + parts[len(parts)-1]
+	}
+	return fmt.Sprintf("%d-%s%s", time.Now().Unix(), uuid.NewString(), extension)
+}
+
 // SanitizePhone sanitizes phone number by adding appropriate WhatsApp suffix
 const maxPhoneNumberLength = 15 // Maximum digits in a phone number
 
@@ -565,11 +630,27 @@ func ValidateJidWithLogin(client *whatsmeow.Client, jid string) (types.JID, erro
 	return ParseJID(jid)
 }
 
+// sessionReplaced is set while another client holds this session (stream replaced)
+var sessionReplaced atomic.Bool
+
+// SetSessionReplaced marks whether the session was taken over by another client
+func SetSessionReplaced(replaced bool) {
+	sessionReplaced.Store(replaced)
+}
+
+// IsSessionReplaced reports whether the session was taken over by another client
+func IsSessionReplaced() bool {
+	return sessionReplaced.Load()
+}
+
 // MustLogin ensures the WhatsApp client is logged in
 func MustLogin(client *whatsmeow.Client) {
 	if client == nil {
 		panic(pkgError.InternalServerError("Whatsapp client is not initialized"))
 	}
+	if IsSessionReplaced() {
+		panic(pkgError.ErrSessionReplaced)
+	}
 	if !client.IsConnected() {
 		panic(pkgError.ErrNotConnected)
 	} else if !client.IsLoggedIn() {
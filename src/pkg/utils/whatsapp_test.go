@@ -0,0 +1,90 @@
+package utils_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMessageType(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *waE2E.Message
+		want string
+	}{
+		{
+			name: "should detect plain text",
+			msg:  &waE2E.Message{Conversation: proto.String("hello")},
+			want: "text",
+		},
+		{
+			name: "should detect extended text",
+			msg:  &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{Text: proto.String("hello")}},
+			want: "text",
+		},
+		{
+			name: "should detect image",
+			msg:  &waE2E.Message{ImageMessage: &waE2E.ImageMessage{}},
+			want: "image",
+		},
+		{
+			name: "should detect location",
+			msg:  &waE2E.Message{LocationMessage: &waE2E.LocationMessage{}},
+			want: "location",
+		},
+		{
+			name: "should detect poll",
+			msg:  &waE2E.Message{PollCreationMessageV3: &waE2E.PollCreationMessage{}},
+			want: "poll",
+		},
+		{
+			name: "should fall back to other",
+			msg:  &waE2E.Message{},
+			want: "other",
+		},
+		{
+			name: "should handle nil message",
+			msg:  nil,
+			want: "unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, utils.MessageType(tt.msg))
+		})
+	}
+}
+
+func TestMediaURLExpiry(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		want   time.Time
+		wantOK bool
+	}{
+		{
+			name:   "should read the hex oe parameter",
+			url:    "https://mmg.whatsapp.net/v/t62.7118-24/12345_n.enc?ccb=11-4&oh=01_Q5Aa&oe=68A1B2C3&_nc_sid=5e03e0",
+			want:   time.Unix(0x68A1B2C3, 0),
+			wantOK: true,
+		},
+		{name: "should report urls without oe", url: "https://mmg.whatsapp.net/v/t62.7118-24/12345_n.enc?ccb=11-4"},
+		{name: "should report invalid oe values", url: "https://mmg.whatsapp.net/x.enc?oe=zz"},
+		{name: "should report empty urls", url: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := utils.MediaURLExpiry(tt.url)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.True(t, tt.want.Equal(got))
+			}
+		})
+	}
+}
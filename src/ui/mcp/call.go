@@ -0,0 +1,84 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	domainCall "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/call"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type CallHandler struct {
+	callService domainCall.ICallUsecase
+}
+
+func InitMcpCall(callService domainCall.ICallUsecase) *CallHandler {
+	return &CallHandler{
+		callService: callService,
+	}
+}
+
+func (c *CallHandler) AddCallTools(mcpServer *server.MCPServer) {
+	mcpServer.AddTool(c.toolGetCallLog(), c.handleGetCallLog)
+}
+
+func (c *CallHandler) toolGetCallLog() mcp.Tool {
+	return mcp.NewTool("whatsapp_get_call_log",
+		mcp.WithDescription("Get the log of incoming WhatsApp calls, newest first."),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of calls to return (default: 25)"),
+		),
+		mcp.WithString("status",
+			mcp.Description("Only return calls with this status: offered, accepted, rejected, missed or ended"),
+		),
+		mcp.WithString("phone",
+			mcp.Description("Only return calls from this phone number"),
+		),
+	)
+}
+
+func (c *CallHandler) handleGetCallLog(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	limit := 25
+	if l, ok := request.GetArguments()["limit"].(float64); ok {
+		limit = int(l)
+	}
+	status, _ := request.GetArguments()["status"].(string)
+	phone, _ := request.GetArguments()["phone"].(string)
+	utils.SanitizePhone(&phone)
+
+	response, err := c.callService.ListCalls(ctx, domainCall.ListCallsRequest{
+		Limit:     limit,
+		Status:    status,
+		CallerJID: phone,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get call log: %w", err)
+	}
+
+	result := fmt.Sprintf("Found %d calls:\n\n", len(response.Data))
+	for i, call := range response.Data {
+		media := "Voice"
+		if call.IsVideo {
+			media = "Video"
+		}
+
+		result += fmt.Sprintf("%d. %s call from %s (%s)\n", i+1, media, call.CallerJID, call.Status)
+		result += fmt.Sprintf("   ID: %s\n", call.ID)
+		if call.IsGroup {
+			result += fmt.Sprintf("   Group: %s\n", call.GroupJID)
+		}
+		result += fmt.Sprintf("   Started: %s\n", call.StartedAt)
+		if call.EndedAt != "" {
+			result += fmt.Sprintf("   Ended: %s\n", call.EndedAt)
+		}
+		if call.AutoRejected {
+			result += "   Auto-rejected\n"
+		}
+		result += "\n"
+	}
+	result += fmt.Sprintf("Total: %d calls (showing %d)\n", response.Pagination.Total, len(response.Data))
+
+	return mcp.NewToolResultText(result), nil
+}
@@ -33,6 +33,9 @@ func (c *ChatHandler) toolGetList() mcp.Tool {
 		mcp.WithNumber("limit",
 			mcp.Description("Maximum number of chats to return (default: 50)"),
 		),
+		mcp.WithString("cursor",
+			mcp.Description("Cursor from a previous result to fetch the next or previous page (optional)"),
+		),
 	)
 }
 
@@ -42,10 +45,13 @@ func (c *ChatHandler) handleGetList(ctx context.Context, request mcp.CallToolReq
 		limit = int(l)
 	}
 
+	cursor, _ := request.GetArguments()["cursor"].(string)
+
 	// Call actual service
 	response, err := c.chatService.ListChats(ctx, domainChat.ListChatsRequest{
 		Limit:  limit,
 		Offset: 0,
+		Cursor: cursor,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get chat list: %w", err)
@@ -91,6 +97,7 @@ func (c *ChatHandler) handleGetList(ctx context.Context, request mcp.CallToolReq
 	}
 	
 	result += fmt.Sprintf("\nTotal: %d chats (showing %d)\n", response.Pagination.Total, len(response.Data))
+	result += formatCursors(response.Pagination)
 
 	return mcp.NewToolResultText(result), nil
 }
@@ -191,6 +198,9 @@ func (c *ChatHandler) toolGetMessages() mcp.Tool {
 		mcp.WithNumber("limit",
 			mcp.Description("Number of messages to retrieve (default: 10)"),
 		),
+		mcp.WithString("cursor",
+			mcp.Description("Cursor from a previous result to fetch older (next) or newer (previous) messages (optional)"),
+		),
 	)
 }
 
@@ -201,10 +211,13 @@ func (c *ChatHandler) handleGetMessages(ctx context.Context, request mcp.CallToo
 		limit = int(l)
 	}
 
+	cursor, _ := request.GetArguments()["cursor"].(string)
+
 	response, err := c.chatService.GetChatMessages(ctx, domainChat.GetChatMessagesRequest{
 		ChatJID: phone,
 		Limit:   limit,
 		Offset:  0,
+		Cursor:  cursor,
 	})
 	
 	if err != nil {
@@ -232,5 +245,19 @@ func (c *ChatHandler) handleGetMessages(ctx context.Context, request mcp.CallToo
 		}
 	}
 	
+	result += formatCursors(response.Pagination)
+
 	return mcp.NewToolResultText(result), nil
+}
+
+// formatCursors lists the cursors to pass back for the surrounding pages, if any
+func formatCursors(pagination domainChat.PaginationResponse) string {
+	result := ""
+	if pagination.NextCursor != "" {
+		result += fmt.Sprintf("Next page cursor: %s\n", pagination.NextCursor)
+	}
+	if pagination.PrevCursor != "" {
+		result += fmt.Sprintf("Previous page cursor: %s\n", pagination.PrevCursor)
+	}
+	return result
 }
\ No newline at end of file
@@ -296,6 +296,9 @@ func (m *MessageHandler) handleDownloadMedia(ctx context.Context, request mcp.Ca
 
 	result := fmt.Sprintf("Media downloaded successfully:\nType: %s\nFilename: %s\nPath: %s\nSize: %d bytes", 
 		response.MediaType, response.Filename, response.FilePath, response.FileSize)
+	if response.URL != "" {
+		result += fmt.Sprintf("\nURL: %s", response.URL)
+	}
 	
 	return mcp.NewToolResultText(result), nil
 }
\ No newline at end of file
@@ -22,22 +22,38 @@ func InitMcpSend(sendService domainSend.ISendUsecase) *SendHandler {
 
 func (s *SendHandler) AddSendTools(mcpServer *server.MCPServer) {
 	// Text and basic media
-	mcpServer.AddTool(s.toolSendText(), s.handleSendText)
-	mcpServer.AddTool(s.toolSendImage(), s.handleSendImage)
+	mcpServer.AddTool(withIdempotencyKey(s.toolSendText(), s.handleSendText))
+	mcpServer.AddTool(withIdempotencyKey(s.toolSendImage(), s.handleSendImage))
 	
 	// Advanced multimedia
-	mcpServer.AddTool(s.toolSendAudio(), s.handleSendAudio)
-	mcpServer.AddTool(s.toolSendVideo(), s.handleSendVideo)
-	mcpServer.AddTool(s.toolSendFile(), s.handleSendFile)
+	mcpServer.AddTool(withIdempotencyKey(s.toolSendAudio(), s.handleSendAudio))
+	mcpServer.AddTool(withIdempotencyKey(s.toolSendVideo(), s.handleSendVideo))
+	mcpServer.AddTool(withIdempotencyKey(s.toolSendFile(), s.handleSendFile))
+	mcpServer.AddTool(withIdempotencyKey(s.toolSendAlbum(), s.handleSendAlbum))
 	
 	// Interactions
-	mcpServer.AddTool(s.toolSendContact(), s.handleSendContact)
-	mcpServer.AddTool(s.toolSendLink(), s.handleSendLink)
-	mcpServer.AddTool(s.toolSendLocation(), s.handleSendLocation)
-	mcpServer.AddTool(s.toolSendPoll(), s.handleSendPoll)
+	mcpServer.AddTool(withIdempotencyKey(s.toolSendContact(), s.handleSendContact))
+	mcpServer.AddTool(withIdempotencyKey(s.toolSendLink(), s.handleSendLink))
+	mcpServer.AddTool(withIdempotencyKey(s.toolSendLocation(), s.handleSendLocation))
+	mcpServer.AddTool(withIdempotencyKey(s.toolSendPoll(), s.handleSendPoll))
 	
 	// Presence
-	mcpServer.AddTool(s.toolSendPresence(), s.handleSendPresence)
+	mcpServer.AddTool(withIdempotencyKey(s.toolSendPresence(), s.handleSendPresence))
+}
+
+// withIdempotencyKey adds the optional idempotency_key argument to a send tool and carries it to the
+// send usecase, so a retried tool call returns the original result instead of sending again
+func withIdempotencyKey(tool mcp.Tool, handler server.ToolHandlerFunc) (mcp.Tool, server.ToolHandlerFunc) {
+	mcp.WithString("idempotency_key",
+		mcp.Description("Unique key for this send, retries with the same key return the original result instead of sending again (optional)"),
+	)(&tool)
+
+	return tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if key, ok := request.GetArguments()["idempotency_key"].(string); ok && key != "" {
+			ctx = domainSend.WithIdempotencyKey(ctx, key)
+		}
+		return handler(ctx, request)
+	}
 }
 
 func (s *SendHandler) toolSendText() mcp.Tool {
@@ -85,11 +101,11 @@ func (s *SendHandler) handleSendText(ctx context.Context, request mcp.CallToolRe
 
 	res, err := s.sendService.SendText(ctx, domainSend.MessageRequest{
 		BaseRequest: domainSend.BaseRequest{
-			Phone:       phone,
-			IsForwarded: isForwarded,
+			Phone:          phone,
+			IsForwarded:    isForwarded,
+			ReplyMessageID: &replyMessageId,
 		},
-		Message:        message,
-		ReplyMessageID: &replyMessageId,
+		Message: message,
 	})
 
 	if err != nil {
@@ -117,6 +133,9 @@ func (s *SendHandler) toolSendContact() mcp.Tool {
 		mcp.WithBoolean("is_forwarded",
 			mcp.Description("Whether this message is being forwarded (default: false)"),
 		),
+		mcp.WithString("reply_message_id",
+			mcp.Description("Message ID to reply to (optional)"),
+		),
 	)
 
 	return sendContactTool
@@ -143,10 +162,16 @@ func (s *SendHandler) handleSendContact(ctx context.Context, request mcp.CallToo
 		isForwarded = false
 	}
 
+	replyMessageId, ok := request.GetArguments()["reply_message_id"].(string)
+	if !ok {
+		replyMessageId = ""
+	}
+
 	res, err := s.sendService.SendContact(ctx, domainSend.ContactRequest{
 		BaseRequest: domainSend.BaseRequest{
-			Phone:       phone,
-			IsForwarded: isForwarded,
+			Phone:          phone,
+			IsForwarded:    isForwarded,
+			ReplyMessageID: &replyMessageId,
 		},
 		ContactName:  contactName,
 		ContactPhone: contactPhone,
@@ -177,6 +202,9 @@ func (s *SendHandler) toolSendLink() mcp.Tool {
 		mcp.WithBoolean("is_forwarded",
 			mcp.Description("Whether this message is being forwarded (default: false)"),
 		),
+		mcp.WithString("reply_message_id",
+			mcp.Description("Message ID to reply to (optional)"),
+		),
 	)
 
 	return sendLinkTool
@@ -203,10 +231,16 @@ func (s *SendHandler) handleSendLink(ctx context.Context, request mcp.CallToolRe
 		isForwarded = false
 	}
 
+	replyMessageId, ok := request.GetArguments()["reply_message_id"].(string)
+	if !ok {
+		replyMessageId = ""
+	}
+
 	res, err := s.sendService.SendLink(ctx, domainSend.LinkRequest{
 		BaseRequest: domainSend.BaseRequest{
-			Phone:       phone,
-			IsForwarded: isForwarded,
+			Phone:          phone,
+			IsForwarded:    isForwarded,
+			ReplyMessageID: &replyMessageId,
 		},
 		Link:    link,
 		Caption: caption,
@@ -237,6 +271,9 @@ func (s *SendHandler) toolSendLocation() mcp.Tool {
 		mcp.WithBoolean("is_forwarded",
 			mcp.Description("Whether this message is being forwarded (default: false)"),
 		),
+		mcp.WithString("reply_message_id",
+			mcp.Description("Message ID to reply to (optional)"),
+		),
 	)
 
 	return sendLocationTool
@@ -263,10 +300,16 @@ func (s *SendHandler) handleSendLocation(ctx context.Context, request mcp.CallTo
 		isForwarded = false
 	}
 
+	replyMessageId, ok := request.GetArguments()["reply_message_id"].(string)
+	if !ok {
+		replyMessageId = ""
+	}
+
 	res, err := s.sendService.SendLocation(ctx, domainSend.LocationRequest{
 		BaseRequest: domainSend.BaseRequest{
-			Phone:       phone,
-			IsForwarded: isForwarded,
+			Phone:          phone,
+			IsForwarded:    isForwarded,
+			ReplyMessageID: &replyMessageId,
 		},
 		Latitude:  latitude,
 		Longitude: longitude,
@@ -301,6 +344,9 @@ func (s *SendHandler) toolSendImage() mcp.Tool {
 		mcp.WithBoolean("is_forwarded",
 			mcp.Description("Whether this message is being forwarded (default: false)"),
 		),
+		mcp.WithString("reply_message_id",
+			mcp.Description("Message ID to reply to (optional)"),
+		),
 	)
 
 	return sendImageTool
@@ -337,11 +383,17 @@ func (s *SendHandler) handleSendImage(ctx context.Context, request mcp.CallToolR
 		isForwarded = false
 	}
 
+	replyMessageId, ok := request.GetArguments()["reply_message_id"].(string)
+	if !ok {
+		replyMessageId = ""
+	}
+
 	// Create image request
 	imageRequest := domainSend.ImageRequest{
 		BaseRequest: domainSend.BaseRequest{
-			Phone:       phone,
-			IsForwarded: isForwarded,
+			Phone:          phone,
+			IsForwarded:    isForwarded,
+			ReplyMessageID: &replyMessageId,
 		},
 		Caption:  caption,
 		ViewOnce: viewOnce,
@@ -375,6 +427,9 @@ func (s *SendHandler) toolSendAudio() mcp.Tool {
 		mcp.WithBoolean("is_forwarded",
 			mcp.Description("Whether this message is being forwarded (default: false)"),
 		),
+		mcp.WithString("reply_message_id",
+			mcp.Description("Message ID to reply to (optional)"),
+		),
 	)
 }
 
@@ -394,10 +449,16 @@ func (s *SendHandler) handleSendAudio(ctx context.Context, request mcp.CallToolR
 		isForwarded = false
 	}
 
+	replyMessageId, ok := request.GetArguments()["reply_message_id"].(string)
+	if !ok {
+		replyMessageId = ""
+	}
+
 	res, err := s.sendService.SendAudio(ctx, domainSend.AudioRequest{
 		BaseRequest: domainSend.BaseRequest{
-			Phone:       phone,
-			IsForwarded: isForwarded,
+			Phone:          phone,
+			IsForwarded:    isForwarded,
+			ReplyMessageID: &replyMessageId,
 		},
 		AudioURL: &audioURL,
 	})
@@ -432,6 +493,9 @@ func (s *SendHandler) toolSendVideo() mcp.Tool {
 		mcp.WithBoolean("is_forwarded",
 			mcp.Description("Whether this message is being forwarded (default: false)"),
 		),
+		mcp.WithString("reply_message_id",
+			mcp.Description("Message ID to reply to (optional)"),
+		),
 	)
 }
 
@@ -466,10 +530,16 @@ func (s *SendHandler) handleSendVideo(ctx context.Context, request mcp.CallToolR
 		isForwarded = false
 	}
 
+	replyMessageId, ok := request.GetArguments()["reply_message_id"].(string)
+	if !ok {
+		replyMessageId = ""
+	}
+
 	res, err := s.sendService.SendVideo(ctx, domainSend.VideoRequest{
 		BaseRequest: domainSend.BaseRequest{
-			Phone:       phone,
-			IsForwarded: isForwarded,
+			Phone:          phone,
+			IsForwarded:    isForwarded,
+			ReplyMessageID: &replyMessageId,
 		},
 		Caption:  caption,
 		ViewOnce: viewOnce,
@@ -511,6 +581,109 @@ func (s *SendHandler) handleSendFile(ctx context.Context, request mcp.CallToolRe
 	return mcp.NewToolResultText("File sending via URL requires file upload capability not available in MCP context. Use image, audio, or video tools for media files with URLs."), nil
 }
 
+func (s *SendHandler) toolSendAlbum() mcp.Tool {
+	return mcp.NewTool("whatsapp_send_album",
+		mcp.WithDescription("Send multiple images and videos grouped as a single album to a WhatsApp contact or group."),
+		mcp.WithString("phone",
+			mcp.Required(),
+			mcp.Description("Phone number or group ID to send album to"),
+		),
+		mcp.WithArray("items",
+			mcp.Required(),
+			mcp.Description("Array of 2 to 30 album items, each an object with 'url' (image or video URL) and optional 'caption'"),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"url":     map[string]any{"type": "string"},
+					"caption": map[string]any{"type": "string"},
+				},
+				"required": []string{"url"},
+			}),
+		),
+		mcp.WithBoolean("compress",
+			mcp.Description("Whether to compress the images and videos (default: true)"),
+		),
+		mcp.WithBoolean("is_forwarded",
+			mcp.Description("Whether this message is being forwarded (default: false)"),
+		),
+		mcp.WithString("reply_message_id",
+			mcp.Description("Message ID to reply to (optional)"),
+		),
+	)
+}
+
+func (s *SendHandler) handleSendAlbum(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	phone, ok := request.GetArguments()["phone"].(string)
+	if !ok {
+		return nil, errors.New("phone must be a string")
+	}
+
+	itemsRaw, ok := request.GetArguments()["items"].([]interface{})
+	if !ok {
+		return nil, errors.New("items must be an array")
+	}
+
+	items := make([]domainSend.AlbumItem, len(itemsRaw))
+	for i, raw := range itemsRaw {
+		itemMap, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("item at index %d must be an object", i)
+		}
+
+		itemURL, ok := itemMap["url"].(string)
+		if !ok {
+			return nil, fmt.Errorf("item at index %d must have a string url", i)
+		}
+
+		caption, ok := itemMap["caption"].(string)
+		if !ok {
+			caption = ""
+		}
+
+		items[i] = domainSend.AlbumItem{URL: &itemURL, Caption: caption}
+	}
+
+	compress, ok := request.GetArguments()["compress"].(bool)
+	if !ok {
+		compress = true
+	}
+
+	isForwarded, ok := request.GetArguments()["is_forwarded"].(bool)
+	if !ok {
+		isForwarded = false
+	}
+
+	replyMessageId, ok := request.GetArguments()["reply_message_id"].(string)
+	if !ok {
+		replyMessageId = ""
+	}
+
+	res, err := s.sendService.SendAlbum(ctx, domainSend.AlbumRequest{
+		BaseRequest: domainSend.BaseRequest{
+			Phone:          phone,
+			IsForwarded:    isForwarded,
+			ReplyMessageID: &replyMessageId,
+		},
+		Items:    items,
+		Compress: compress,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := fmt.Sprintf("%s\n", res.Status)
+	for _, item := range res.Items {
+		if item.Error != "" {
+			result += fmt.Sprintf("- Item %d (%s): failed: %s\n", item.Index, item.MediaType, item.Error)
+		} else {
+			result += fmt.Sprintf("- Item %d (%s): sent with ID %s\n", item.Index, item.MediaType, item.MessageID)
+		}
+	}
+
+	return mcp.NewToolResultText(result), nil
+}
+
 func (s *SendHandler) toolSendPoll() mcp.Tool {
 	return mcp.NewTool("whatsapp_send_poll",
 		mcp.WithDescription("Send a poll to a WhatsApp contact or group."),
@@ -532,6 +705,9 @@ func (s *SendHandler) toolSendPoll() mcp.Tool {
 		mcp.WithBoolean("is_forwarded",
 			mcp.Description("Whether this message is being forwarded (default: false)"),
 		),
+		mcp.WithString("reply_message_id",
+			mcp.Description("Message ID to reply to (optional)"),
+		),
 	)
 }
 
@@ -570,10 +746,16 @@ func (s *SendHandler) handleSendPoll(ctx context.Context, request mcp.CallToolRe
 		isForwarded = false
 	}
 
+	replyMessageId, ok := request.GetArguments()["reply_message_id"].(string)
+	if !ok {
+		replyMessageId = ""
+	}
+
 	res, err := s.sendService.SendPoll(ctx, domainSend.PollRequest{
 		BaseRequest: domainSend.BaseRequest{
-			Phone:       phone,
-			IsForwarded: isForwarded,
+			Phone:          phone,
+			IsForwarded:    isForwarded,
+			ReplyMessageID: &replyMessageId,
 		},
 		Question:  question,
 		Options:   options,
@@ -276,7 +276,7 @@ func (u *UserHandler) handleMyListNewsletter(ctx context.Context, request mcp.Ca
 	result := fmt.Sprintf("My Newsletters (%d):\n", len(response.Data))
 	for _, newsletter := range response.Data {
 		result += fmt.Sprintf("- ID: %s\n", newsletter.ID.String())
-		result += fmt.Sprintf("  State: %d\n", newsletter.State)
+		result += fmt.Sprintf("  State: %s\n", newsletter.State.Type)
 		result += fmt.Sprintf("  Created: %s\n", newsletter.ThreadMeta.CreationTime.Format("2006-01-02 15:04:05"))
 	}
 	return mcp.NewToolResultText(result), nil
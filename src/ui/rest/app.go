@@ -20,6 +20,7 @@ func InitRestApp(app fiber.Router, service domainApp.IAppUsecase) App {
 	app.Get("/app/login-with-code", rest.LoginWithCode)
 	app.Get("/app/logout", rest.Logout)
 	app.Get("/app/reconnect", rest.Reconnect)
+	app.Get("/app/takeover", rest.Takeover)
 	app.Get("/app/devices", rest.Devices)
 	app.Get("/app/status", rest.ConnectionStatus)
 
@@ -79,6 +80,18 @@ func (handler *App) Reconnect(c *fiber.Ctx) error {
 	})
 }
 
+func (handler *App) Takeover(c *fiber.Ctx) error {
+	err := handler.Service.Takeover(c.UserContext())
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: "Session takeover success",
+		Results: nil,
+	})
+}
+
 func (handler *App) Devices(c *fiber.Ctx) error {
 	devices, err := handler.Service.FetchDevices(c.UserContext())
 	utils.PanicIfNeeded(err)
@@ -102,6 +115,8 @@ func (handler *App) ConnectionStatus(c *fiber.Ctx) error {
 			"is_connected": isConnected,
 			"is_logged_in": isLoggedIn,
 			"device_id":    deviceID,
+			"is_replaced":  utils.IsSessionReplaced(),
+			"reconnect":    whatsapp.GetReconnectStatus(),
 		},
 	})
 }
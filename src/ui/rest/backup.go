@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainBackup "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/backup"
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Backup struct {
+	Service domainBackup.IBackupUsecase
+}
+
+func InitRestBackup(app fiber.Router, service domainBackup.IBackupUsecase) Backup {
+	rest := Backup{Service: service}
+
+	app.Post("/app/backup", rest.CreateBackup)
+	app.Get("/app/backups", rest.ListBackups)
+	app.Get("/app/backups/:filename", rest.DownloadBackup)
+
+	return rest
+}
+
+func (controller *Backup) CreateBackup(c *fiber.Ctx) error {
+	response, err := controller.Service.CreateBackup(c.UserContext(), domainBackup.CreateBackupRequest{})
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: fmt.Sprintf("Backup %s created", response.Filename),
+		Results: response,
+	})
+}
+
+func (controller *Backup) ListBackups(c *fiber.Ctx) error {
+	response, err := controller.Service.ListBackups(c.UserContext())
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: "Success get backups",
+		Results: response,
+	})
+}
+
+func (controller *Backup) DownloadBackup(c *fiber.Ctx) error {
+	// Backups hold the whole session, only the configured users may take them off the server
+	username, _ := c.Locals("username").(string)
+	if username == "" || !slices.Contains(config.AppBackupDownloadUsers, username) {
+		panic(pkgError.ForbiddenError("backup downloads are not enabled for this user, see --backup-download-users"))
+	}
+
+	backups, err := controller.Service.ListBackups(c.UserContext())
+	utils.PanicIfNeeded(err)
+
+	// Only files listed in the backup directory can be downloaded
+	filename := c.Params("filename")
+	for _, backup := range backups {
+		if backup.Filename == filename {
+			return c.Download(backup.Path, backup.Filename)
+		}
+	}
+
+	return c.Status(fiber.StatusNotFound).JSON(utils.ResponseData{
+		Status:  404,
+		Code:    "NOT_FOUND",
+		Message: fmt.Sprintf("backup %s not found", filename),
+		Results: nil,
+	})
+}
@@ -1,6 +1,10 @@
 package rest
 
 import (
+	"fmt"
+	"io"
+	"os"
+
 	domainChat "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chat"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
 	"github.com/gofiber/fiber/v2"
@@ -16,6 +20,8 @@ func InitRestChat(app fiber.Router, service domainChat.IChatUsecase) Chat {
 	// Chat endpoints
 	app.Get("/chats", rest.ListChats)
 	app.Get("/chat/:chat_jid/messages", rest.GetChatMessages)
+	app.Get("/chat/:chat_jid/export", rest.ExportChat)
+	app.Post("/chat/:chat_jid/import", rest.ImportChat)
 	app.Post("/chat/:chat_jid/pin", rest.PinChat)
 
 	return rest
@@ -27,6 +33,7 @@ func (controller *Chat) ListChats(c *fiber.Ctx) error {
 	// Parse query parameters
 	request.Limit = c.QueryInt("limit", 25)
 	request.Offset = c.QueryInt("offset", 0)
+	request.Cursor = c.Query("cursor", "")
 	request.Search = c.Query("search", "")
 	request.HasMedia = c.QueryBool("has_media", false)
 
@@ -50,6 +57,7 @@ func (controller *Chat) GetChatMessages(c *fiber.Ctx) error {
 	// Parse query parameters
 	request.Limit = c.QueryInt("limit", 50)
 	request.Offset = c.QueryInt("offset", 0)
+	request.Cursor = c.Query("cursor", "")
 	request.MediaOnly = c.QueryBool("media_only", false)
 	request.Search = c.Query("search", "")
 
@@ -78,6 +86,40 @@ func (controller *Chat) GetChatMessages(c *fiber.Ctx) error {
 	})
 }
 
+func (controller *Chat) ImportChat(c *fiber.Ctx) error {
+	var request domainChat.ImportChatRequest
+
+	err := c.BodyParser(&request)
+	utils.PanicIfNeeded(err)
+
+	request.ChatJID = c.Params("chat_jid")
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(utils.ResponseData{
+			Status:  400,
+			Code:    "BAD_REQUEST",
+			Message: "file is required, upload the .txt or .zip created by Export chat",
+			Results: nil,
+		})
+	}
+	request.Filename = fileHeader.Filename
+
+	file, err := fileHeader.Open()
+	utils.PanicIfNeeded(err)
+	defer file.Close()
+
+	response, err := controller.Service.ImportChat(c.UserContext(), request, file, fileHeader.Size)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: fmt.Sprintf("Imported %d messages into %s", response.MessagesImported, response.ChatJID),
+		Results: response,
+	})
+}
+
 func (controller *Chat) PinChat(c *fiber.Ctx) error {
 	var request domainChat.PinChatRequest
 
@@ -104,3 +146,43 @@ func (controller *Chat) PinChat(c *fiber.Ctx) error {
 		Results: response,
 	})
 }
+
+func (controller *Chat) ExportChat(c *fiber.Ctx) error {
+	var request domainChat.ExportChatRequest
+
+	request.ChatJID = c.Params("chat_jid")
+	request.Format = c.Query("format", domainChat.ExportFormatJSON)
+	request.IncludeMedia = c.QueryBool("include_media", false)
+
+	// Export into a temporary file first, so failures are still reported as an error response
+	file, err := os.CreateTemp("", "chat-export-*")
+	utils.PanicIfNeeded(err)
+	export := &tempExport{File: file}
+
+	response, err := controller.Service.ExportChat(c.UserContext(), request, file)
+	var size int64
+	if err == nil {
+		size, err = file.Seek(0, io.SeekCurrent)
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		export.Close()
+	}
+	utils.PanicIfNeeded(err)
+
+	c.Set(fiber.HeaderContentType, response.ContentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, response.Filename))
+	return c.SendStream(export, int(size))
+}
+
+// tempExport removes the temporary export file once the response body has been sent
+type tempExport struct {
+	*os.File
+}
+
+func (e *tempExport) Close() error {
+	e.File.Close()
+	return os.Remove(e.File.Name())
+}
@@ -35,6 +35,7 @@ func InitRestGroup(app fiber.Router, service domainGroup.IGroupUsecase) Group {
 	app.Post("/group/announce", rest.SetGroupAnnounce)
 	app.Post("/group/topic", rest.SetGroupTopic)
 	app.Get("/group/invite-link", rest.GetGroupInviteLink)
+	app.Get("/group/events", rest.GroupEvents)
 	return rest
 }
 
@@ -358,3 +359,21 @@ func (controller *Group) GetGroupInviteLink(c *fiber.Ctx) error {
 		Results: response,
 	})
 }
+
+func (controller *Group) GroupEvents(c *fiber.Ctx) error {
+	var request domainGroup.GroupEventsRequest
+	err := c.QueryParser(&request)
+	utils.PanicIfNeeded(err)
+
+	utils.SanitizePhone(&request.GroupID)
+
+	response, err := controller.Service.GroupEvents(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: "Success get group events",
+		Results: response,
+	})
+}
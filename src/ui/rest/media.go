@@ -0,0 +1,80 @@
+package rest
+
+import (
+	"fmt"
+	"io"
+
+	domainMedia "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/media"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+type Media struct {
+	Service domainMedia.IMediaUsecase
+}
+
+func InitRestMedia(app fiber.Router, service domainMedia.IMediaUsecase) Media {
+	rest := Media{Service: service}
+
+	app.Get("/media/:sha256", rest.GetMedia)
+
+	return rest
+}
+
+func (controller *Media) GetMedia(c *fiber.Ctx) error {
+	request := domainMedia.GetMediaRequest{SHA256: c.Params("sha256")}
+	response, err := controller.Service.GetMedia(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	// Storages that serve files themselves, such as S3, hand out a presigned URL instead
+	if response.URL != "" {
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		return c.Redirect(response.URL, fiber.StatusFound)
+	}
+
+	response, content, err := controller.Service.OpenMedia(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	// Content is addressed by its hash, so it never changes under the same URL
+	c.Set(fiber.HeaderCacheControl, "private, max-age=31536000, immutable")
+	c.Set(fiber.HeaderETag, `"`+response.SHA256+`"`)
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	if response.MimeType != "" {
+		c.Set(fiber.HeaderContentType, response.MimeType)
+	}
+
+	byteRange := c.Get(fiber.HeaderRange)
+	if byteRange == "" {
+		return c.SendStream(content, int(response.Size))
+	}
+
+	start, end, err := fasthttp.ParseByteRange([]byte(byteRange), int(response.Size))
+	if err != nil {
+		content.Close()
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", response.Size))
+		return c.SendStatus(fiber.StatusRequestedRangeNotSatisfiable)
+	}
+	if err := skipBytes(content, int64(start)); err != nil {
+		content.Close()
+		return err
+	}
+
+	length := end - start + 1
+	c.Status(fiber.StatusPartialContent)
+	c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", start, end, response.Size))
+	return c.SendStream(struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(content, int64(length)), content}, length)
+}
+
+// skipBytes moves content forward by n bytes, seeking when the storage allows it
+func skipBytes(content io.Reader, n int64) error {
+	if seeker, ok := content.(io.Seeker); ok {
+		_, err := seeker.Seek(n, io.SeekStart)
+		return err
+	}
+	_, err := io.CopyN(io.Discard, content, n)
+	return err
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// BodyLimit rejects request bodies larger than limit bytes. Request bodies are streamed, so fiber only
+// reads them when the handler asks for the form: a declared Content-Length is checked up front and
+// a chunked body is spooled to a temporary file that fails as soon as it grows past the limit.
+func BodyLimit(limit int64) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := c.Request()
+		if int64(req.Header.ContentLength()) > limit {
+			return rejectTooLarge(c)
+		}
+		if req.Header.ContentLength() != -1 || !req.IsBodyStream() {
+			return c.Next()
+		}
+
+		spool, err := os.CreateTemp("", "request-*")
+		if err != nil {
+			return err
+		}
+		// Removed once the request is handled, whichever way it ends
+		defer os.Remove(spool.Name())
+
+		size, err := utils.CopyLimited(spool, req.BodyStream(), limit)
+		if err == nil {
+			_, err = spool.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			spool.Close()
+			if errors.Is(err, utils.ErrFileTooLarge) {
+				return rejectTooLarge(c)
+			}
+			return err
+		}
+
+		// The request closes the spool file together with its body
+		req.SetBodyStream(spool, int(size))
+		return c.Next()
+	}
+}
+
+// rejectTooLarge answers with 413 and closes the connection, the rest of the body is never read
+func rejectTooLarge(c *fiber.Ctx) error {
+	c.Context().SetConnectionClose()
+	return fiber.ErrRequestEntityTooLarge
+}
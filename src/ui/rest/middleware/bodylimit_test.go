@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chunkedRequest sends body without a Content-Length, like a client streaming an upload
+func chunkedRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/upload", io.MultiReader(strings.NewReader(body)))
+	req.ContentLength = -1
+	req.TransferEncoding = []string{"chunked"}
+	return req
+}
+
+func TestBodyLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		request    func() *http.Request
+		failNext   bool
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "should pass a chunked body within the limit",
+			request:    func() *http.Request { return chunkedRequest("small body") },
+			wantStatus: http.StatusOK,
+			wantBody:   "small body",
+		},
+		{
+			name:       "should reject a chunked body over the limit",
+			request:    func() *http.Request { return chunkedRequest(strings.Repeat("x", 64)) },
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "should reject a declared length over the limit",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(strings.Repeat("x", 64)))
+			},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "should clean up when the handler fails",
+			request:    func() *http.Request { return chunkedRequest("small body") },
+			failNext:   true,
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spoolDir := t.TempDir()
+			t.Setenv("TMPDIR", spoolDir)
+
+			app := fiber.New(fiber.Config{StreamRequestBody: true, DisablePreParseMultipartForm: true})
+			app.Use(BodyLimit(32))
+			app.Post("/upload", func(c *fiber.Ctx) error {
+				if tt.failNext {
+					return errors.New("handler failed")
+				}
+				body, err := io.ReadAll(c.Request().BodyStream())
+				if err != nil {
+					return err
+				}
+				return c.Send(body)
+			})
+
+			resp, err := app.Test(tt.request(), -1)
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody, string(body))
+			}
+
+			spooled, err := os.ReadDir(spoolDir)
+			require.NoError(t, err)
+			assert.Empty(t, spooled, "the spool file is removed")
+		})
+	}
+}
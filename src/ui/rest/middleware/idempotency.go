@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	domainSend "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/send"
+	"github.com/gofiber/fiber/v2"
+)
+
+// IdempotencyKey carries the Idempotency-Key header through the user context, so retried
+// send requests return the original response instead of sending again. Keys are scoped to the basic
+// auth user.
+func IdempotencyKey() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if key := c.Get(domainSend.IdempotencyKeyHeader); key != "" {
+			ctx := domainSend.WithIdempotencyKey(c.UserContext(), key)
+			if username, ok := c.Locals("username").(string); ok && username != "" {
+				ctx = domainSend.WithIdempotencyScope(ctx, username)
+			}
+			c.SetUserContext(ctx)
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/logging"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// RequestID tags each request with an ID, taken from the X-Request-ID header when present,
+// and carries it through the user context so usecase and webhook logs can be correlated
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestID := logging.RequestIDFrom(c.Get(logging.RequestIDHeader))
+		c.Set(logging.RequestIDHeader, requestID)
+		c.SetUserContext(logging.WithRequestID(c.UserContext(), requestID))
+
+		return c.Next()
+	}
+}
+
+// AccessLog writes one structured log entry per request, used instead of the text access log in JSON mode
+func AccessLog() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		started := time.Now()
+		err := c.Next()
+
+		logging.FromContext(c.UserContext()).WithFields(logrus.Fields{
+			"method":     c.Method(),
+			"path":       c.Path(),
+			"status":     c.Response().StatusCode(),
+			"latency_ms": time.Since(started).Milliseconds(),
+			"ip":         c.IP(),
+		}).Info("request completed")
+
+		return err
+	}
+}
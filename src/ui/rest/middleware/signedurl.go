@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/signedurl"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SignedURL reports whether the request fetches a path with a valid, unexpired signature made with
+// the media URL secret. Such requests are let through without basic auth.
+func SignedURL(c *fiber.Ctx) bool {
+	if config.MediaStorageURLSecret == "" || (c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead) {
+		return false
+	}
+	query := url.Values{
+		signedurl.ExpiresParam:   {c.Query(signedurl.ExpiresParam)},
+		signedurl.SignatureParam: {c.Query(signedurl.SignatureParam)},
+	}
+	return signedurl.Verify([]byte(config.MediaStorageURLSecret), c.Path(), query, time.Now()) == nil
+}
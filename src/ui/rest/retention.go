@@ -0,0 +1,84 @@
+package rest
+
+import (
+	"fmt"
+
+	domainRetention "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/retention"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Retention struct {
+	Service domainRetention.IRetentionUsecase
+}
+
+func InitRestRetention(app fiber.Router, service domainRetention.IRetentionUsecase) Retention {
+	rest := Retention{Service: service}
+
+	app.Get("/app/retention", rest.ListPolicies)
+	app.Get("/app/retention/report", rest.Report)
+	app.Put("/chat/:chat_jid/retention", rest.SetPolicy)
+	app.Delete("/chat/:chat_jid/retention", rest.DeletePolicy)
+
+	return rest
+}
+
+func (controller *Retention) ListPolicies(c *fiber.Ctx) error {
+	response, err := controller.Service.ListPolicies(c.UserContext())
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: "Success get retention policies",
+		Results: response,
+	})
+}
+
+func (controller *Retention) Report(c *fiber.Ctx) error {
+	response, err := controller.Service.Report(c.UserContext())
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: fmt.Sprintf("Retention would delete %d messages and %d media files", response.MessagesDeleted, response.MediaDeleted),
+		Results: response,
+	})
+}
+
+func (controller *Retention) SetPolicy(c *fiber.Ctx) error {
+	var request domainRetention.SetPolicyRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(400).JSON(utils.ResponseData{
+			Status:  400,
+			Code:    "BAD_REQUEST",
+			Message: "Invalid request body",
+			Results: nil,
+		})
+	}
+	request.ChatJID = c.Params("chat_jid")
+
+	response, err := controller.Service.SetPolicy(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: fmt.Sprintf("Retention policy of %s set", response.ChatJID),
+		Results: response,
+	})
+}
+
+func (controller *Retention) DeletePolicy(c *fiber.Ctx) error {
+	chatJID := c.Params("chat_jid")
+	err := controller.Service.DeletePolicy(c.UserContext(), chatJID)
+	utils.PanicIfNeeded(err)
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: fmt.Sprintf("Retention policy of %s deleted, the global policy applies", chatJID),
+		Results: nil,
+	})
+}
@@ -3,6 +3,7 @@ package rest
 import (
 	domainSend "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/send"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/ui/rest/middleware"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -12,10 +13,12 @@ type Send struct {
 
 func InitRestSend(app fiber.Router, service domainSend.ISendUsecase) Send {
 	rest := Send{Service: service}
+	app.Use("/send", middleware.IdempotencyKey())
 	app.Post("/send/message", rest.SendText)
 	app.Post("/send/image", rest.SendImage)
 	app.Post("/send/file", rest.SendFile)
 	app.Post("/send/video", rest.SendVideo)
+	app.Post("/send/album", rest.SendAlbum)
 	app.Post("/send/contact", rest.SendContact)
 	app.Post("/send/link", rest.SendLink)
 	app.Post("/send/location", rest.SendLocation)
@@ -114,6 +117,49 @@ func (controller *Send) SendVideo(c *fiber.Ctx) error {
 	})
 }
 
+func (controller *Send) SendAlbum(c *fiber.Ctx) error {
+	var request domainSend.AlbumRequest
+	request.Compress = true
+
+	err := c.BodyParser(&request)
+	utils.PanicIfNeeded(err)
+
+	// Multipart requests upload the items as repeated "files" fields,
+	// with optional "captions" fields matched by position
+	if form, errForm := c.MultipartForm(); errForm == nil {
+		captions := form.Value["captions"]
+		for i, file := range form.File["files"] {
+			item := domainSend.AlbumItem{File: file}
+			if i < len(captions) {
+				item.Caption = captions[i]
+			}
+			request.Items = append(request.Items, item)
+		}
+	}
+
+	utils.SanitizePhone(&request.Phone)
+
+	response, err := controller.Service.SendAlbum(c.UserContext(), request)
+	utils.PanicIfNeeded(err)
+
+	// Some items failed, the response lists the error of each one
+	if response.Partial {
+		return c.Status(fiber.StatusMultiStatus).JSON(utils.ResponseData{
+			Status:  fiber.StatusMultiStatus,
+			Code:    "PARTIAL_SUCCESS",
+			Message: response.Status,
+			Results: response,
+		})
+	}
+
+	return c.JSON(utils.ResponseData{
+		Status:  200,
+		Code:    "SUCCESS",
+		Message: response.Status,
+		Results: response,
+	})
+}
+
 func (controller *Send) SendContact(c *fiber.Ctx) error {
 	var request domainSend.ContactRequest
 	err := c.BodyParser(&request)
@@ -0,0 +1,22 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"go.mau.fi/whatsmeow"
+)
+
+func TestReconnectRefusesReplacedSession(t *testing.T) {
+	t.Cleanup(func() { utils.SetSessionReplaced(false) })
+	utils.SetSessionReplaced(true)
+
+	service := &serviceApp{}
+	assert.ErrorIs(t, service.Reconnect(context.Background()), pkgError.ErrSessionReplaced, "only a takeover brings a replaced session back")
+	assert.PanicsWithValue(t, pkgError.ErrSessionReplaced, func() { utils.MustLogin(&whatsmeow.Client{}) }, "sends are refused")
+	assert.ErrorIs(t, service.Takeover(context.Background()), pkgError.ErrWaCLI, "a takeover needs a client")
+	assert.True(t, utils.IsSessionReplaced(), "a failed takeover keeps the session replaced")
+}
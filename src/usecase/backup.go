@@ -0,0 +1,264 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainBackup "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/backup"
+	infraBackup "github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/backup"
+	pkgBackup "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/backup"
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/logging"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/tracing"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/validations"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	backupFilePrefix = "whatsapp-backup-"
+	backupFileSuffix = ".enc"
+	backupTimeLayout = "20060102-150405"
+)
+
+// backupMutex keeps scheduled and requested backups from running at the same time
+var backupMutex sync.Mutex
+
+type serviceBackup struct{}
+
+func NewBackupService() domainBackup.IBackupUsecase {
+	return &serviceBackup{}
+}
+
+// CreateBackup snapshots the session and chat databases while they stay in use and writes them as one
+// encrypted file, by default into the backup directory where only the newest backups are kept
+func (service serviceBackup) CreateBackup(ctx context.Context, request domainBackup.CreateBackupRequest) (response domainBackup.BackupInfo, err error) {
+	if err = validations.ValidateBackupPassphrase(ctx, config.AppBackupPassphrase); err != nil {
+		return response, err
+	}
+
+	backupMutex.Lock()
+	defer backupMutex.Unlock()
+
+	ctx, span := tracing.Start(ctx, "backup.Create")
+	defer func() { tracing.End(span, err) }()
+
+	workDir, err := os.MkdirTemp("", "whatsapp-backup-*")
+	if err != nil {
+		return response, err
+	}
+	defer os.RemoveAll(workDir)
+
+	createdAt := time.Now().UTC()
+	manifest := &pkgBackup.Manifest{AppVersion: config.AppVersion, CreatedAt: createdAt}
+	for _, database := range backupDatabases() {
+		file, err := infraBackup.Snapshot(ctx, database, workDir)
+		if err != nil {
+			return response, err
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	output := request.Output
+	if output == "" {
+		if err = os.MkdirAll(config.AppBackupDir, 0700); err != nil {
+			return response, fmt.Errorf("failed to create backup directory: %w", err)
+		}
+		output = filepath.Join(config.AppBackupDir, backupFilePrefix+createdAt.Format(backupTimeLayout)+backupFileSuffix)
+	}
+
+	// Write next to the destination and rename, so a failed backup never leaves a partial file behind
+	staged, err := os.CreateTemp(filepath.Dir(output), ".backup-*")
+	if err != nil {
+		return response, err
+	}
+	defer os.Remove(staged.Name())
+
+	encrypted, err := pkgBackup.NewEncryptWriter(staged, config.AppBackupPassphrase)
+	if err == nil {
+		err = pkgBackup.WriteArchive(encrypted, workDir, manifest)
+	}
+	if err == nil {
+		err = encrypted.Close()
+	}
+	if err == nil {
+		err = staged.Sync()
+	}
+	if closeErr := staged.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(staged.Name(), output)
+	}
+	if err != nil {
+		return response, fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	info, err := os.Stat(output)
+	if err != nil {
+		return response, err
+	}
+	response = domainBackup.BackupInfo{
+		Filename:  filepath.Base(output),
+		Path:      output,
+		Size:      info.Size(),
+		CreatedAt: createdAt,
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"filename": response.Filename,
+		"size":     response.Size,
+	}).Info("Backup created")
+
+	if request.Output == "" {
+		service.rotateBackups(ctx)
+	}
+	return response, nil
+}
+
+// ListBackups returns the backups in the backup directory, newest first
+func (service serviceBackup) ListBackups(_ context.Context) (response []domainBackup.BackupInfo, err error) {
+	entries, err := os.ReadDir(config.AppBackupDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []domainBackup.BackupInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response = []domainBackup.BackupInfo{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, backupFilePrefix) || !strings.HasSuffix(name, backupFileSuffix) {
+			continue
+		}
+		createdAt, err := time.Parse(backupTimeLayout, strings.TrimSuffix(strings.TrimPrefix(name, backupFilePrefix), backupFileSuffix))
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		response = append(response, domainBackup.BackupInfo{
+			Filename:  name,
+			Path:      filepath.Join(config.AppBackupDir, name),
+			Size:      info.Size(),
+			CreatedAt: createdAt,
+		})
+	}
+
+	sort.Slice(response, func(i, j int) bool { return response[i].CreatedAt.After(response[j].CreatedAt) })
+	return response, nil
+}
+
+// RestoreBackup decrypts a backup and verifies every snapshot in it before any database is touched.
+// Unless only verification is requested the databases are then replaced, they must not be in use.
+func (service serviceBackup) RestoreBackup(ctx context.Context, request domainBackup.RestoreBackupRequest) (response domainBackup.RestoreBackupResponse, err error) {
+	if err = validations.ValidateRestoreBackup(ctx, request); err != nil {
+		return response, err
+	}
+
+	file, err := os.Open(request.Path)
+	if err != nil {
+		return response, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer file.Close()
+
+	workDir, err := os.MkdirTemp("", "whatsapp-restore-*")
+	if err != nil {
+		return response, err
+	}
+	defer os.RemoveAll(workDir)
+
+	decrypted, err := pkgBackup.NewDecryptReader(file, request.Passphrase)
+	if err != nil {
+		return response, backupError(err)
+	}
+	manifest, err := pkgBackup.ReadArchive(decrypted, workDir)
+	if err != nil {
+		return response, backupError(err)
+	}
+
+	response.AppVersion = manifest.AppVersion
+	response.CreatedAt = manifest.CreatedAt
+	for _, snapshot := range manifest.Files {
+		if err = infraBackup.Verify(ctx, snapshot, workDir); err != nil {
+			return response, backupError(err)
+		}
+		response.Files = append(response.Files, domainBackup.RestoredFile{Role: snapshot.Role, Kind: snapshot.Kind, Size: snapshot.Size})
+	}
+	if request.VerifyOnly {
+		return response, nil
+	}
+
+	databases := make(map[string]infraBackup.Database)
+	for _, database := range backupDatabases() {
+		databases[database.Role] = database
+	}
+	for _, snapshot := range manifest.Files {
+		database, ok := databases[snapshot.Role]
+		if !ok {
+			logging.FromContext(ctx).Warnf("Skipping %s snapshot, that database is not configured", snapshot.Role)
+			continue
+		}
+		if err = infraBackup.Restore(ctx, database, snapshot, workDir); err != nil {
+			return response, err
+		}
+	}
+	response.Restored = true
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"path":       request.Path,
+		"created_at": manifest.CreatedAt,
+	}).Info("Backup restored")
+	return response, nil
+}
+
+// rotateBackups deletes the oldest backups beyond config.AppBackupKeep
+func (service serviceBackup) rotateBackups(ctx context.Context) {
+	backups, err := service.ListBackups(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Warnf("Failed to list backups for rotation: %v", err)
+		return
+	}
+	if config.AppBackupKeep <= 0 || len(backups) <= config.AppBackupKeep {
+		return
+	}
+
+	for _, backup := range backups[config.AppBackupKeep:] {
+		if err := os.Remove(backup.Path); err != nil {
+			logging.FromContext(ctx).Warnf("Failed to delete old backup %s: %v", backup.Filename, err)
+			continue
+		}
+		logging.FromContext(ctx).WithField("filename", backup.Filename).Info("Deleted old backup")
+	}
+}
+
+// backupDatabases lists the configured databases a backup covers. An in-memory keys database is
+// rebuilt from the session database on start and is left out.
+func backupDatabases() []infraBackup.Database {
+	databases := []infraBackup.Database{{Role: infraBackup.RoleWhatsApp, URI: config.DBURI}}
+	if config.DBKeysURI != "" && !strings.Contains(config.DBKeysURI, ":memory:") && !strings.Contains(config.DBKeysURI, "mode=memory") {
+		databases = append(databases, infraBackup.Database{Role: infraBackup.RoleWhatsAppKeys, URI: config.DBKeysURI})
+	}
+	return append(databases, infraBackup.Database{Role: infraBackup.RoleChatStorage, URI: config.ChatStorageURI})
+}
+
+// backupError reports problems with the backup file itself as validation errors
+func backupError(err error) error {
+	for _, target := range []error{pkgBackup.ErrWrongPassphrase, pkgBackup.ErrCorrupted, pkgBackup.ErrNotBackup} {
+		if errors.Is(err, target) {
+			return pkgError.ValidationError(err.Error())
+		}
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	domainCall "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/call"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/logging"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/validations"
+)
+
+type serviceCall struct {
+	chatStorageRepo domainChatStorage.IChatStorageRepository
+}
+
+func NewCallService(chatStorageRepo domainChatStorage.IChatStorageRepository) domainCall.ICallUsecase {
+	return &serviceCall{
+		chatStorageRepo: chatStorageRepo,
+	}
+}
+
+func (service serviceCall) ListCalls(ctx context.Context, request domainCall.ListCallsRequest) (response domainCall.ListCallsResponse, err error) {
+	if err = validations.ValidateListCalls(ctx, &request); err != nil {
+		return response, err
+	}
+
+	filter := &domainChatStorage.CallFilter{
+		CallerJID: request.CallerJID,
+		Status:    request.Status,
+		Limit:     request.Limit,
+		Offset:    request.Offset,
+	}
+
+	calls, err := service.chatStorageRepo.GetCalls(filter)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to get calls from call log")
+		return response, err
+	}
+
+	total, err := service.chatStorageRepo.GetCallCount(filter)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to count calls in call log")
+		return response, err
+	}
+
+	callInfos := make([]domainCall.CallInfo, 0, len(calls))
+	for _, call := range calls {
+		callInfo := domainCall.CallInfo{
+			ID:           call.ID,
+			CallerJID:    call.CallerJID,
+			GroupJID:     call.GroupJID,
+			IsVideo:      call.IsVideo,
+			IsGroup:      call.IsGroup,
+			Status:       call.Status,
+			Reason:       call.Reason,
+			AutoRejected: call.AutoRejected,
+			StartedAt:    call.StartedAt.Format(time.RFC3339),
+		}
+		if call.EndedAt != nil {
+			callInfo.EndedAt = call.EndedAt.Format(time.RFC3339)
+		}
+		callInfos = append(callInfos, callInfo)
+	}
+
+	response.Data = callInfos
+	response.Pagination = domainCall.PaginationResponse{
+		Limit:  request.Limit,
+		Offset: request.Offset,
+		Total:  int(total),
+	}
+
+	return response, nil
+}
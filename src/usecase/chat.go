@@ -3,12 +3,18 @@ package usecase
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	domainChat "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chat"
 	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	domainMediaStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/mediastorage"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/whatsapp"
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/logging"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/validations"
 	"github.com/sirupsen/logrus"
@@ -18,11 +24,15 @@ import (
 
 type serviceChat struct {
 	chatStorageRepo domainChatStorage.IChatStorageRepository
+	mediaStorage    domainMediaStorage.IMediaStorage
+	unsynced        *unsyncedChatCache
 }
 
-func NewChatService(chatStorageRepo domainChatStorage.IChatStorageRepository) domainChat.IChatUsecase {
+func NewChatService(chatStorageRepo domainChatStorage.IChatStorageRepository, mediaStorage domainMediaStorage.IMediaStorage) domainChat.IChatUsecase {
 	return &serviceChat{
 		chatStorageRepo: chatStorageRepo,
+		mediaStorage:    mediaStorage,
+		unsynced:        &unsyncedChatCache{fetch: whatsappChats},
 	}
 }
 
@@ -34,150 +44,260 @@ func (service serviceChat) ListChats(ctx context.Context, request domainChat.Lis
 	// Ensure we're logged in
 	utils.MustLogin(whatsapp.GetClient())
 
-	// FIRST: Get stored chats from the chat storage database
-	// This contains all chats that have been synced, including individual conversations
-	storedChats, err := service.chatStorageRepo.GetChats(&domainChatStorage.ChatFilter{})
+	cursor, err := decodeCursor(request.Cursor)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to get stored chats from database")
-		storedChats = []*domainChatStorage.Chat{}
+		return response, err
 	}
 
-	// Convert to chat infos and use a map to deduplicate by JID
-	chatMap := make(map[string]domainChat.ChatInfo)
+	filter := &domainChatStorage.ChatFilter{
+		SearchName: request.Search,
+		HasMedia:   request.HasMedia,
+	}
 
-	// Add stored chats from database FIRST (these have actual message history)
-	for _, chat := range storedChats {
-		chatInfo := domainChat.ChatInfo{
-			JID:             chat.JID,
-			Name:            chat.Name,
-			LastMessageTime: chat.LastMessageTime.Format(time.RFC3339),
-			IsGroup:         strings.Contains(chat.JID, "@g.us"),
-			CreatedAt:       chat.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:       chat.UpdatedAt.Format(time.RFC3339),
-		}
+	storedCount, err := service.chatStorageRepo.GetChatCount(filter)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to count stored chats")
+		return response, err
+	}
 
-		// Apply search filter
-		if request.Search != "" && !strings.Contains(strings.ToLower(chatInfo.Name), strings.ToLower(request.Search)) {
-			continue
-		}
+	// Groups and contacts without message history yet are listed after the stored chats
+	unsyncedChats := service.unsyncedChats(ctx, request)
 
-		chatMap[chat.JID] = chatInfo
+	entries, err := service.chatPageEntries(ctx, filter, unsyncedChats, int(storedCount), cursor, request.Limit, request.Offset)
+	if err != nil {
+		return response, err
 	}
 
-	// SECOND: Get groups from WhatsApp (in case some aren't synced yet)
-	groups, err := whatsapp.GetClient().GetJoinedGroups()
-	if err != nil {
-		logrus.WithError(err).Error("Failed to get groups from WhatsApp")
-		groups = []*types.GroupInfo{}
+	entries, nextCursor, prevCursor := keysetPage(entries, request.Limit, cursor, request.Offset, func(entry chatEntry) domainChatStorage.Cursor {
+		return entry.cursor
+	})
+
+	chatInfos := make([]domainChat.ChatInfo, 0, len(entries))
+	for _, entry := range entries {
+		chatInfos = append(chatInfos, entry.info)
 	}
 
-	// Get contacts from WhatsApp
-	contacts, err := whatsapp.GetClient().Store.Contacts.GetAllContacts(ctx)
-	if err != nil {
-		logrus.WithError(err).Error("Failed to get contacts from WhatsApp")
-		contacts = map[types.JID]types.ContactInfo{}
+	response.Data = chatInfos
+	response.Pagination = domainChat.PaginationResponse{
+		Limit:      request.Limit,
+		Offset:     request.Offset,
+		Total:      int(storedCount) + len(unsyncedChats),
+		NextCursor: nextCursor,
+		PrevCursor: prevCursor,
 	}
 
-	// Add groups from WhatsApp (in case some aren't synced yet)
-	for _, group := range groups {
-		jidStr := group.JID.String()
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"total_chats": len(chatInfos),
+		"limit":       request.Limit,
+		"offset":      request.Offset,
+	}).Info("Listed chats successfully")
 
-		// Skip if already in map from database
-		if _, exists := chatMap[jidStr]; exists {
-			continue
+	return response, nil
+}
+
+// chatEntry is a chat of the listing together with its keyset position
+type chatEntry struct {
+	info   domainChat.ChatInfo
+	cursor domainChatStorage.Cursor
+}
+
+// chatPageEntries merges stored chats with the unsynced ones listed after them into the page at the
+// cursor, or at the offset without one. One entry past the limit is returned when another page follows.
+func (service serviceChat) chatPageEntries(ctx context.Context, filter *domainChatStorage.ChatFilter, unsyncedChats []chatEntry, storedCount int, cursor *domainChatStorage.Cursor, limit, offset int) ([]chatEntry, error) {
+	var entries []chatEntry
+	if cursor == nil || !cursor.Previous {
+		// Stored chats first, fetching one extra row to tell whether another page follows
+		if cursor == nil || !cursor.Timestamp.IsZero() {
+			filter.Limit = limit + 1
+			filter.Offset = offset
+			filter.Cursor = cursor
+			var err error
+			entries, err = service.storedChatEntries(ctx, filter)
+			if err != nil {
+				return nil, err
+			}
 		}
 
-		chatInfo := domainChat.ChatInfo{
-			JID:             jidStr,
-			Name:            group.GroupName.Name,
-			LastMessageTime: time.Now().Format(time.RFC3339),
-			IsGroup:         true,
-			CreatedAt:       time.Now().Format(time.RFC3339),
-			UpdatedAt:       time.Now().Format(time.RFC3339),
+		if len(entries) <= limit {
+			tail := unsyncedChats
+			if cursor != nil && cursor.Timestamp.IsZero() {
+				idx := sort.Search(len(tail), func(i int) bool { return tail[i].cursor.ID < cursor.ID })
+				tail = tail[idx:]
+			} else if cursor == nil && offset > storedCount {
+				tail = tail[min(offset-storedCount, len(tail)):]
+			}
+			entries = append(entries, tail[:min(len(tail), limit+1-len(entries))]...)
+		}
+	} else {
+		// Previous page, walking back towards the newest chats
+		if cursor.Timestamp.IsZero() {
+			idx := sort.Search(len(unsyncedChats), func(i int) bool { return unsyncedChats[i].cursor.ID <= cursor.ID })
+			for i := idx - 1; i >= 0 && len(entries) <= limit; i-- {
+				entries = append(entries, unsyncedChats[i])
+			}
 		}
 
-		// Apply search filter
-		if request.Search != "" && !strings.Contains(strings.ToLower(chatInfo.Name), strings.ToLower(request.Search)) {
-			continue
+		if len(entries) <= limit {
+			filter.Limit = limit + 1 - len(entries)
+			filter.Cursor = cursor
+			stored, err := service.storedChatEntries(ctx, filter)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, stored...)
 		}
+	}
+
+	return entries, nil
+}
 
-		chatMap[jidStr] = chatInfo
+// storedChatEntries loads a page of chats from the chat storage database
+func (service serviceChat) storedChatEntries(ctx context.Context, filter *domainChatStorage.ChatFilter) ([]chatEntry, error) {
+	storedChats, err := service.chatStorageRepo.GetChats(filter)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to get stored chats from database")
+		return nil, err
 	}
 
-	// Add contacts from WhatsApp (individual chats)
-	for jid, contact := range contacts {
-		// Skip if it's a group (already added)
-		if strings.Contains(jid.String(), "@g.us") {
-			continue
-		}
+	entries := make([]chatEntry, 0, len(storedChats))
+	for _, chat := range storedChats {
+		entries = append(entries, chatEntry{
+			info: domainChat.ChatInfo{
+				JID:             chat.JID,
+				Name:            chat.Name,
+				LastMessageTime: chat.LastMessageTime.Format(time.RFC3339),
+				IsGroup:         strings.Contains(chat.JID, "@g.us"),
+				CreatedAt:       chat.CreatedAt.Format(time.RFC3339),
+				UpdatedAt:       chat.UpdatedAt.Format(time.RFC3339),
+			},
+			cursor: domainChatStorage.Cursor{Timestamp: chat.LastMessageTime, ID: chat.JID},
+		})
+	}
+
+	return entries, nil
+}
 
-		jidStr := jid.String()
+// unsyncedChatsTTL is how long the joined groups and contacts are reused between page requests
+const unsyncedChatsTTL = time.Minute
 
-		// Skip if already in map from database
-		if _, exists := chatMap[jidStr]; exists {
-			continue
-		}
+// unsyncedChatCache keeps the joined groups and contacts without a stored chat, so paging through
+// the chat list does not fetch every group and contact again for each page. It is rebuilt once it
+// expires or the number of stored chats changes.
+type unsyncedChatCache struct {
+	fetch func(ctx context.Context) []chatEntry
 
-		chatInfo := domainChat.ChatInfo{
-			JID:             jidStr,
-			Name:            contact.FullName,
-			LastMessageTime: time.Now().Format(time.RFC3339),
-			IsGroup:         false,
-			CreatedAt:       time.Now().Format(time.RFC3339),
-			UpdatedAt:       time.Now().Format(time.RFC3339),
-		}
+	mu          sync.Mutex
+	entries     []chatEntry
+	storedCount int64
+	loadedAt    time.Time
+}
 
-		// If no full name, use the phone number
-		if chatInfo.Name == "" {
-			chatInfo.Name = jid.User
-		}
+// unsyncedChats returns the joined groups and contacts that have no stored chat yet, ordered by JID
+// descending. They have no last message, so their keyset position uses the zero timestamp and they
+// sort after every stored chat.
+func (service serviceChat) unsyncedChats(ctx context.Context, request domainChat.ListChatsRequest) []chatEntry {
+	// Chats without stored messages cannot have media
+	if request.HasMedia {
+		return nil
+	}
 
-		// Apply search filter
-		if request.Search != "" && !strings.Contains(strings.ToLower(chatInfo.Name), strings.ToLower(request.Search)) {
-			continue
+	entries := service.cachedUnsyncedChats(ctx)
+	if request.Search == "" {
+		return entries
+	}
+
+	search := strings.ToLower(request.Search)
+	var matches []chatEntry
+	for _, entry := range entries {
+		if strings.Contains(strings.ToLower(entry.info.Name), search) {
+			matches = append(matches, entry)
 		}
+	}
+	return matches
+}
 
-		chatMap[jidStr] = chatInfo
+// cachedUnsyncedChats returns the unsynced chats of the cache, rebuilding them when they are stale
+func (service serviceChat) cachedUnsyncedChats(ctx context.Context) []chatEntry {
+	cache := service.unsynced
+	storedCount, err := service.chatStorageRepo.GetChatCount(&domainChatStorage.ChatFilter{})
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to count stored chats")
 	}
 
-	// Convert map to slice
-	chatInfos := make([]domainChat.ChatInfo, 0, len(chatMap))
-	for _, chatInfo := range chatMap {
-		chatInfos = append(chatInfos, chatInfo)
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.entries != nil && err == nil && storedCount == cache.storedCount && time.Since(cache.loadedAt) < unsyncedChatsTTL {
+		return cache.entries
 	}
-	
-	// Apply limit and offset
-	totalCount := len(chatInfos)
-	
-	// Apply offset
-	if request.Offset > 0 && request.Offset < len(chatInfos) {
-		chatInfos = chatInfos[request.Offset:]
-	} else if request.Offset >= len(chatInfos) {
-		chatInfos = []domainChat.ChatInfo{}
+
+	storedJIDs, err := service.chatStorageRepo.GetChatJIDs()
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to get stored chat JIDs from database")
 	}
-	
-	// Apply limit
-	if request.Limit > 0 && request.Limit < len(chatInfos) {
-		chatInfos = chatInfos[:request.Limit]
+	known := make(map[string]bool, len(storedJIDs))
+	for _, jid := range storedJIDs {
+		known[jid] = true
 	}
 
-	// Create pagination response
-	pagination := domainChat.PaginationResponse{
-		Limit:  request.Limit,
-		Offset: request.Offset,
-		Total:  int(totalCount),
+	entries := []chatEntry{}
+	for _, entry := range cache.fetch(ctx) {
+		if known[entry.info.JID] {
+			continue
+		}
+		known[entry.info.JID] = true
+		entries = append(entries, entry)
 	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].cursor.ID > entries[j].cursor.ID })
 
-	response.Data = chatInfos
-	response.Pagination = pagination
+	cache.entries, cache.storedCount, cache.loadedAt = entries, storedCount, time.Now()
+	return entries
+}
 
-	logrus.WithFields(logrus.Fields{
-		"total_chats": len(chatInfos),
-		"limit":       request.Limit,
-		"offset":      request.Offset,
-	}).Info("Listed chats successfully")
+// whatsappChats lists the joined groups and the contacts of the WhatsApp session as chat entries
+func whatsappChats(ctx context.Context) []chatEntry {
+	var entries []chatEntry
+	add := func(jid string, name string, isGroup bool) {
+		entries = append(entries, chatEntry{
+			info: domainChat.ChatInfo{
+				JID:             jid,
+				Name:            name,
+				LastMessageTime: time.Now().Format(time.RFC3339),
+				IsGroup:         isGroup,
+				CreatedAt:       time.Now().Format(time.RFC3339),
+				UpdatedAt:       time.Now().Format(time.RFC3339),
+			},
+			cursor: domainChatStorage.Cursor{ID: jid},
+		})
+	}
+
+	// Get groups from WhatsApp (in case some aren't synced yet)
+	groups, err := whatsapp.GetClient().GetJoinedGroups()
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to get groups from WhatsApp")
+	}
+	for _, group := range groups {
+		add(group.JID.String(), group.GroupName.Name, true)
+	}
 
-	return response, nil
+	// Get contacts from WhatsApp (individual chats)
+	contacts, err := whatsapp.GetClient().Store.Contacts.GetAllContacts(ctx)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("Failed to get contacts from WhatsApp")
+	}
+	for jid, contact := range contacts {
+		if jid.Server == types.GroupServer {
+			continue
+		}
+
+		// If no full name, use the phone number
+		name := contact.FullName
+		if name == "" {
+			name = jid.User
+		}
+		add(jid.String(), name, false)
+	}
+
+	return entries
 }
 
 func (service serviceChat) GetChatMessages(ctx context.Context, request domainChat.GetChatMessagesRequest) (response domainChat.GetChatMessagesResponse, err error) {
@@ -188,20 +308,26 @@ func (service serviceChat) GetChatMessages(ctx context.Context, request domainCh
 	// Get chat info first
 	chat, err := service.chatStorageRepo.GetChat(request.ChatJID)
 	if err != nil {
-		logrus.WithError(err).WithField("chat_jid", request.ChatJID).Error("Failed to get chat info")
+		logging.FromContext(ctx).WithError(err).WithField("chat_jid", request.ChatJID).Error("Failed to get chat info")
 		return response, err
 	}
 	if chat == nil {
 		return response, fmt.Errorf("chat with JID %s not found", request.ChatJID)
 	}
 
-	// Create message filter from request
+	cursor, err := decodeCursor(request.Cursor)
+	if err != nil {
+		return response, err
+	}
+
+	// Create message filter from request, fetching one extra row to tell whether another page follows
 	filter := &domainChatStorage.MessageFilter{
 		ChatJID:   request.ChatJID,
-		Limit:     request.Limit,
+		Limit:     request.Limit + 1,
 		Offset:    request.Offset,
 		MediaOnly: request.MediaOnly,
 		IsFromMe:  request.IsFromMe,
+		Cursor:    cursor,
 	}
 
 	// Parse time filters if provided
@@ -223,26 +349,31 @@ func (service serviceChat) GetChatMessages(ctx context.Context, request domainCh
 
 	// Get messages from storage
 	var messages []*domainChatStorage.Message
+	var nextCursor, prevCursor string
 	if request.Search != "" {
 		// Use search functionality if search query is provided
 		messages, err = service.chatStorageRepo.SearchMessages(request.ChatJID, request.Search, request.Limit)
 		if err != nil {
-			logrus.WithError(err).WithField("chat_jid", request.ChatJID).Error("Failed to search messages")
+			logging.FromContext(ctx).WithError(err).WithField("chat_jid", request.ChatJID).Error("Failed to search messages")
 			return response, err
 		}
 	} else {
 		// Use regular filter
 		messages, err = service.chatStorageRepo.GetMessages(filter)
 		if err != nil {
-			logrus.WithError(err).WithField("chat_jid", request.ChatJID).Error("Failed to get messages")
+			logging.FromContext(ctx).WithError(err).WithField("chat_jid", request.ChatJID).Error("Failed to get messages")
 			return response, err
 		}
+
+		messages, nextCursor, prevCursor = keysetPage(messages, request.Limit, cursor, request.Offset, func(message *domainChatStorage.Message) domainChatStorage.Cursor {
+			return domainChatStorage.Cursor{Timestamp: message.Timestamp, ID: message.ID}
+		})
 	}
 
 	// Get total message count for pagination
 	totalCount, err := service.chatStorageRepo.GetChatMessageCount(request.ChatJID)
 	if err != nil {
-		logrus.WithError(err).WithField("chat_jid", request.ChatJID).Error("Failed to get message count")
+		logging.FromContext(ctx).WithError(err).WithField("chat_jid", request.ChatJID).Error("Failed to get message count")
 		// Continue with partial data
 		totalCount = 0
 	}
@@ -264,6 +395,9 @@ func (service serviceChat) GetChatMessages(ctx context.Context, request domainCh
 			CreatedAt:  message.CreatedAt.Format(time.RFC3339),
 			UpdatedAt:  message.UpdatedAt.Format(time.RFC3339),
 		}
+		if message.MediaPath != "" {
+			messageInfo.MediaPath = service.mediaStorage.Locate(message.MediaPath)
+		}
 		messageInfos = append(messageInfos, messageInfo)
 	}
 
@@ -279,16 +413,18 @@ func (service serviceChat) GetChatMessages(ctx context.Context, request domainCh
 
 	// Create pagination response
 	pagination := domainChat.PaginationResponse{
-		Limit:  request.Limit,
-		Offset: request.Offset,
-		Total:  int(totalCount),
+		Limit:      request.Limit,
+		Offset:     request.Offset,
+		Total:      int(totalCount),
+		NextCursor: nextCursor,
+		PrevCursor: prevCursor,
 	}
 
 	response.Data = messageInfos
 	response.Pagination = pagination
 	response.ChatInfo = chatInfo
 
-	logrus.WithFields(logrus.Fields{
+	logging.FromContext(ctx).WithFields(logrus.Fields{
 		"chat_jid":       request.ChatJID,
 		"total_messages": len(messageInfos),
 		"limit":          request.Limit,
@@ -298,6 +434,55 @@ func (service serviceChat) GetChatMessages(ctx context.Context, request domainCh
 	return response, nil
 }
 
+// decodeCursor parses the pagination cursor of a request, returning nil when none was given
+func decodeCursor(token string) (*domainChatStorage.Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	cursor, err := domainChatStorage.DecodeCursor(token)
+	if err != nil {
+		return nil, pkgError.ValidationError(fmt.Sprintf("cursor: %s", err.Error()))
+	}
+	return cursor, nil
+}
+
+// keysetPage trims rows fetched with one row past the limit into a page ordered newest first,
+// and returns the cursors of the pages on either side of it
+func keysetPage[T any](rows []T, limit int, cursor *domainChatStorage.Cursor, offset int, key func(T) domainChatStorage.Cursor) (page []T, nextCursor, prevCursor string) {
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+	if len(rows) == 0 {
+		return rows, "", ""
+	}
+
+	// Previous pages are fetched oldest first
+	backward := cursor != nil && cursor.Previous
+	if backward {
+		slices.Reverse(rows)
+	}
+
+	first, last := key(rows[0]), key(rows[len(rows)-1])
+	first.Previous = true
+	if backward {
+		// Reached from an older page, so one always follows
+		nextCursor = last.Encode()
+		if hasMore {
+			prevCursor = first.Encode()
+		}
+	} else {
+		if hasMore {
+			nextCursor = last.Encode()
+		}
+		if cursor != nil || offset > 0 {
+			prevCursor = first.Encode()
+		}
+	}
+
+	return rows, nextCursor, prevCursor
+}
+
 func (service serviceChat) PinChat(ctx context.Context, request domainChat.PinChatRequest) (response domainChat.PinChatResponse, err error) {
 	if err = validations.ValidatePinChat(ctx, &request); err != nil {
 		return response, err
@@ -314,7 +499,7 @@ func (service serviceChat) PinChat(ctx context.Context, request domainChat.PinCh
 
 	// Send app state update
 	if err = whatsapp.GetClient().SendAppState(ctx, patchInfo); err != nil {
-		logrus.WithError(err).WithFields(logrus.Fields{
+		logging.FromContext(ctx).WithError(err).WithFields(logrus.Fields{
 			"chat_jid": request.ChatJID,
 			"pinned":   request.Pinned,
 		}).Error("Failed to send pin chat app state")
@@ -332,7 +517,7 @@ func (service serviceChat) PinChat(ctx context.Context, request domainChat.PinCh
 		response.Message = "Chat unpinned successfully"
 	}
 
-	logrus.WithFields(logrus.Fields{
+	logging.FromContext(ctx).WithFields(logrus.Fields{
 		"chat_jid": request.ChatJID,
 		"pinned":   request.Pinned,
 	}).Info("Chat pin operation completed successfully")
@@ -352,7 +537,7 @@ func (service serviceChat) ArchiveChat(ctx context.Context, request domainChat.A
 
 	// Send app state update
 	if err = whatsapp.GetClient().SendAppState(ctx, patchInfo); err != nil {
-		logrus.WithError(err).WithFields(logrus.Fields{
+		logging.FromContext(ctx).WithError(err).WithFields(logrus.Fields{
 			"chat_jid": request.ChatJID,
 			"archive":  request.Archive,
 		}).Error("Failed to send archive chat app state")
@@ -370,7 +555,7 @@ func (service serviceChat) ArchiveChat(ctx context.Context, request domainChat.A
 		response.Message = "Chat unarchived successfully"
 	}
 
-	logrus.WithFields(logrus.Fields{
+	logging.FromContext(ctx).WithFields(logrus.Fields{
 		"chat_jid": request.ChatJID,
 		"archived": request.Archive,
 	}).Info("Chat archive operation completed successfully")
@@ -390,7 +575,7 @@ func (service serviceChat) DeleteChat(ctx context.Context, request domainChat.De
 	
 	// Delete from local storage
 	if err = service.chatStorageRepo.DeleteChatAndMessages(request.ChatJID); err != nil {
-		logrus.WithError(err).WithField("chat_jid", request.ChatJID).Error("Failed to delete chat from local storage")
+		logging.FromContext(ctx).WithError(err).WithField("chat_jid", request.ChatJID).Error("Failed to delete chat from local storage")
 		// Continue anyway
 	}
 
@@ -403,7 +588,7 @@ func (service serviceChat) DeleteChat(ctx context.Context, request domainChat.De
 		response.Message += " (starred messages kept locally)"
 	}
 
-	logrus.WithField("chat_jid", request.ChatJID).Info("Chat delete operation completed (local only)")
+	logging.FromContext(ctx).WithField("chat_jid", request.ChatJID).Info("Chat delete operation completed (local only)")
 
 	return response, nil
 }
@@ -424,7 +609,7 @@ func (service serviceChat) MarkChatAsRead(ctx context.Context, request domainCha
 
 	messages, err := service.chatStorageRepo.GetMessages(filter)
 	if err != nil {
-		logrus.WithError(err).WithField("chat_jid", request.ChatJID).Error("Failed to get messages for marking as read")
+		logging.FromContext(ctx).WithError(err).WithField("chat_jid", request.ChatJID).Error("Failed to get messages for marking as read")
 		return response, err
 	}
 
@@ -448,7 +633,7 @@ func (service serviceChat) MarkChatAsRead(ctx context.Context, request domainCha
 			// Mark all messages as read at once
 			err := whatsapp.GetClient().MarkRead(msgIDTypes, timestamp, targetJID, targetJID)
 			if err != nil {
-				logrus.WithError(err).WithField("chat_jid", request.ChatJID).Warn("Failed to mark messages as read")
+				logging.FromContext(ctx).WithError(err).WithField("chat_jid", request.ChatJID).Warn("Failed to mark messages as read")
 			}
 		}
 	}
@@ -458,7 +643,7 @@ func (service serviceChat) MarkChatAsRead(ctx context.Context, request domainCha
 	response.ChatJID = request.ChatJID
 	response.Message = "All messages in chat marked as read"
 
-	logrus.WithField("chat_jid", request.ChatJID).Info("Chat mark as read operation completed successfully")
+	logging.FromContext(ctx).WithField("chat_jid", request.ChatJID).Info("Chat mark as read operation completed successfully")
 
 	return response, nil
 }
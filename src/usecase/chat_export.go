@@ -0,0 +1,498 @@
+package usecase
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	domainChat "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chat"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/whatsapp"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/logging"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/validations"
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// exportBatchSize is the number of messages read from chat storage at a time while exporting
+const exportBatchSize = 500
+
+// exportMessage is a stored message as written to a chat export
+type exportMessage struct {
+	ID         string `json:"id"`
+	Timestamp  string `json:"timestamp"`
+	SenderJID  string `json:"sender_jid"`
+	SenderName string `json:"sender_name"`
+	IsFromMe   bool   `json:"is_from_me"`
+	Content    string `json:"content,omitempty"`
+	MediaType  string `json:"media_type,omitempty"`
+	Filename   string `json:"filename,omitempty"`
+	MediaPath  string `json:"media_path,omitempty"` // Path inside the export zip
+
+	time     time.Time
+	mediaKey string // Media storage key of the downloaded media
+}
+
+// exportMedia is a downloaded media file bundled into an export zip
+type exportMedia struct {
+	mediaKey string
+	zipPath  string
+}
+
+// transcriptWriter renders the messages of a chat in one export format
+type transcriptWriter interface {
+	begin(chat *domainChatStorage.Chat) error
+	message(message exportMessage) error
+	end(messageCount int) error
+}
+
+func (service serviceChat) ExportChat(ctx context.Context, request domainChat.ExportChatRequest, w io.Writer) (response domainChat.ExportChatResponse, err error) {
+	if err = validations.ValidateExportChat(ctx, &request); err != nil {
+		return response, err
+	}
+
+	chat, err := service.chatStorageRepo.GetChat(request.ChatJID)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).WithField("chat_jid", request.ChatJID).Error("Failed to get chat info")
+		return response, err
+	}
+	if chat == nil {
+		return response, fmt.Errorf("chat with JID %s not found", request.ChatJID)
+	}
+
+	response.ChatJID = chat.JID
+	response.Filename, response.ContentType = exportFilename(chat, request)
+
+	names := newSenderNames(ctx, chat)
+	if !request.IncludeMedia {
+		response.MessageCount, err = service.writeTranscript(ctx, w, request.Format, chat, names, nil)
+		if err != nil {
+			return response, err
+		}
+		logExport(ctx, request, response)
+		return response, nil
+	}
+
+	// Media is downloaded up front, a zip entry has to be complete before the next one starts
+	media, err := service.downloadExportMedia(ctx, chat)
+	if err != nil {
+		return response, err
+	}
+
+	archive := zip.NewWriter(w)
+	transcript, err := archive.Create("chat." + request.Format)
+	if err != nil {
+		return response, fmt.Errorf("failed to create export archive: %w", err)
+	}
+
+	response.MessageCount, err = service.writeTranscript(ctx, transcript, request.Format, chat, names, media)
+	if err != nil {
+		return response, err
+	}
+
+	for _, id := range sortedKeys(media) {
+		if err = service.addMediaToZip(ctx, archive, media[id].mediaKey, media[id].zipPath); err != nil {
+			return response, err
+		}
+	}
+	response.MediaCount = len(media)
+
+	if err = archive.Close(); err != nil {
+		return response, fmt.Errorf("failed to finish export archive: %w", err)
+	}
+
+	logExport(ctx, request, response)
+	return response, nil
+}
+
+// writeTranscript streams every stored message of the chat, oldest first, through the format's writer
+func (service serviceChat) writeTranscript(ctx context.Context, w io.Writer, format string, chat *domainChatStorage.Chat, names *senderNames, media map[string]exportMedia) (int, error) {
+	var writer transcriptWriter
+	switch format {
+	case domainChat.ExportFormatTXT:
+		writer = &txtTranscript{w: w}
+	case domainChat.ExportFormatHTML:
+		writer = &htmlTranscript{w: w, embedMedia: media != nil, readMedia: func(mediaKey string) ([]byte, error) {
+			return service.readMedia(ctx, mediaKey)
+		}}
+	default:
+		writer = &jsonTranscript{w: w}
+	}
+
+	if err := writer.begin(chat); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err := service.eachMessage(ctx, chat.JID, func(message *domainChatStorage.Message) error {
+		entry := exportMessage{
+			ID:         message.ID,
+			Timestamp:  message.Timestamp.Format(time.RFC3339),
+			SenderJID:  message.Sender,
+			SenderName: names.lookup(message),
+			IsFromMe:   message.IsFromMe,
+			Content:    message.Content,
+			MediaType:  message.MediaType,
+			Filename:   message.Filename,
+			time:       message.Timestamp,
+		}
+		if file, ok := media[message.ID]; ok {
+			entry.MediaPath = file.zipPath
+			entry.mediaKey = file.mediaKey
+		}
+
+		count++
+		return writer.message(entry)
+	})
+	if err != nil {
+		return count, err
+	}
+
+	return count, writer.end(count)
+}
+
+// downloadExportMedia downloads the media of every stored message of the chat. Media that can no
+// longer be downloaded is left out of the export rather than failing it.
+func (service serviceChat) downloadExportMedia(ctx context.Context, chat *domainChatStorage.Chat) (map[string]exportMedia, error) {
+	media := make(map[string]exportMedia)
+	err := service.eachMessage(ctx, chat.JID, func(message *domainChatStorage.Message) error {
+		if message.MediaType == "" || message.URL == "" {
+			return nil
+		}
+
+		mediaKey, err := downloadStoredMedia(ctx, service.chatStorageRepo, service.mediaStorage, message)
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).WithField("message_id", message.ID).Warn("Skipping media that could not be downloaded for export")
+			return nil
+		}
+
+		media[message.ID] = exportMedia{
+			mediaKey: mediaKey,
+			zipPath:  fmt.Sprintf("media/%s_%s", message.ID, path.Base(mediaKey)),
+		}
+		return nil
+	})
+	return media, err
+}
+
+// eachMessage calls fn for every stored message of the chat, oldest first, paging through chat
+// storage with a keyset cursor so messages arriving mid-export are neither skipped nor repeated
+func (service serviceChat) eachMessage(ctx context.Context, chatJID string, fn func(message *domainChatStorage.Message) error) error {
+	cursor := &domainChatStorage.Cursor{Previous: true}
+	for {
+		messages, err := service.chatStorageRepo.GetMessages(&domainChatStorage.MessageFilter{
+			ChatJID: chatJID,
+			Limit:   exportBatchSize,
+			Cursor:  cursor,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, message := range messages {
+			if err := fn(message); err != nil {
+				return err
+			}
+		}
+
+		if len(messages) < exportBatchSize {
+			return nil
+		}
+		last := messages[len(messages)-1]
+		cursor = &domainChatStorage.Cursor{Timestamp: last.Timestamp, ID: last.ID, Previous: true}
+	}
+}
+
+// exportFilename returns the download name and content type of an export
+func exportFilename(chat *domainChatStorage.Chat, request domainChat.ExportChatRequest) (filename string, contentType string) {
+	base := fmt.Sprintf("chat-%s", utils.ExtractPhoneNumber(chat.JID))
+	if request.IncludeMedia {
+		return base + ".zip", "application/zip"
+	}
+
+	switch request.Format {
+	case domainChat.ExportFormatTXT:
+		return base + ".txt", "text/plain; charset=utf-8"
+	case domainChat.ExportFormatHTML:
+		return base + ".html", "text/html; charset=utf-8"
+	default:
+		return base + ".json", "application/json"
+	}
+}
+
+func logExport(ctx context.Context, request domainChat.ExportChatRequest, response domainChat.ExportChatResponse) {
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"chat_jid":      response.ChatJID,
+		"format":        request.Format,
+		"message_count": response.MessageCount,
+		"media_count":   response.MediaCount,
+	}).Info("Exported chat")
+}
+
+func (service serviceChat) addMediaToZip(ctx context.Context, archive *zip.Writer, mediaKey string, zipPath string) error {
+	file, err := service.mediaStorage.Open(ctx, mediaKey)
+	if err != nil {
+		return fmt.Errorf("failed to read media %s: %w", mediaKey, err)
+	}
+	defer file.Close()
+
+	entry, err := archive.Create(zipPath)
+	if err != nil {
+		return fmt.Errorf("failed to add media to export archive: %w", err)
+	}
+	_, err = io.Copy(entry, file)
+	return err
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// senderNames resolves message senders to display names from the WhatsApp contact store
+type senderNames struct {
+	ctx   context.Context
+	chat  *domainChatStorage.Chat
+	self  string
+	cache map[string]string
+}
+
+func newSenderNames(ctx context.Context, chat *domainChatStorage.Chat) *senderNames {
+	names := &senderNames{ctx: ctx, chat: chat, self: "You", cache: make(map[string]string)}
+	if device := whatsapp.GetDevice(); device != nil && device.PushName != "" {
+		names.self = device.PushName
+	}
+	return names
+}
+
+func (n *senderNames) lookup(message *domainChatStorage.Message) string {
+	if message.IsFromMe {
+		return n.self
+	}
+	if name, ok := n.cache[message.Sender]; ok {
+		return name
+	}
+
+	// Imported messages keep the transcript name of senders that could not be mapped to a JID
+	name := message.Sender
+	if jid, err := types.ParseJID(message.Sender); err == nil && strings.Contains(message.Sender, "@") {
+		name = jid.User
+		if contactName := n.contactName(jid); contactName != "" {
+			name = contactName
+		} else if !strings.HasSuffix(n.chat.JID, "@g.us") && n.chat.Name != "" {
+			// In a direct chat the chat name is the other party's name
+			name = n.chat.Name
+		}
+	}
+
+	n.cache[message.Sender] = name
+	return name
+}
+
+func (n *senderNames) contactName(jid types.JID) string {
+	device := whatsapp.GetDevice()
+	if device == nil || device.Contacts == nil {
+		return ""
+	}
+
+	contact, err := device.Contacts.GetContact(n.ctx, jid.ToNonAD())
+	if err != nil || !contact.Found {
+		return ""
+	}
+
+	for _, name := range []string{contact.FullName, contact.PushName, contact.BusinessName} {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// jsonTranscript writes {"chat": ..., "messages": [...], "message_count": n}, one message per line
+type jsonTranscript struct {
+	w     io.Writer
+	first bool
+}
+
+func (t *jsonTranscript) begin(chat *domainChatStorage.Chat) error {
+	header, err := json.Marshal(map[string]any{
+		"jid":      chat.JID,
+		"name":     chat.Name,
+		"is_group": strings.HasSuffix(chat.JID, "@g.us"),
+	})
+	if err != nil {
+		return err
+	}
+
+	t.first = true
+	_, err = fmt.Fprintf(t.w, "{\"chat\":%s,\"exported_at\":%q,\"messages\":[\n", header, time.Now().Format(time.RFC3339))
+	return err
+}
+
+func (t *jsonTranscript) message(message exportMessage) error {
+	line, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n"
+	if t.first {
+		separator, t.first = "", false
+	}
+	_, err = fmt.Fprintf(t.w, "%s%s", separator, line)
+	return err
+}
+
+func (t *jsonTranscript) end(messageCount int) error {
+	_, err := fmt.Fprintf(t.w, "\n],\"message_count\":%d}\n", messageCount)
+	return err
+}
+
+// txtTranscript writes the format of WhatsApp's own "Export chat", e.g. "15/01/2025, 10:30 - Name: text"
+type txtTranscript struct {
+	w io.Writer
+}
+
+func (t *txtTranscript) begin(_ *domainChatStorage.Chat) error {
+	return nil
+}
+
+func (t *txtTranscript) message(message exportMessage) error {
+	text := message.Content
+	if message.MediaType != "" {
+		attachment := "<Media omitted>"
+		if message.MediaPath != "" {
+			attachment = path.Base(message.MediaPath) + " (file attached)"
+		}
+		if text != "" {
+			attachment += "\n" + text
+		}
+		text = attachment
+	}
+
+	_, err := fmt.Fprintf(t.w, "%s - %s: %s\n", message.time.Format("02/01/2006, 15:04"), message.SenderName, text)
+	return err
+}
+
+func (t *txtTranscript) end(_ int) error {
+	return nil
+}
+
+// htmlTranscript writes a single page of chat bubbles that renders offline. In a media export
+// images are embedded in the page and other media links to the files next to it in the zip.
+type htmlTranscript struct {
+	w          io.Writer
+	embedMedia bool
+	readMedia  func(mediaKey string) ([]byte, error)
+	lastDay    string
+}
+
+type htmlMessage struct {
+	exportMessage
+	Day      string
+	Time     string
+	ImageSrc template.URL
+}
+
+var htmlTranscriptTemplate = template.Must(template.New("transcript").Parse(`
+{{define "header"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Name}}</title>
+<style>
+body { margin: 0; background: #efeae2; font-family: -apple-system, "Segoe UI", Roboto, Helvetica, Arial, sans-serif; font-size: 14px; color: #111b21; }
+header { position: sticky; top: 0; background: #008069; color: #fff; padding: 12px 16px; }
+header h1 { margin: 0; font-size: 17px; }
+header p { margin: 2px 0 0; font-size: 12px; opacity: .8; }
+main { max-width: 860px; margin: 0 auto; padding: 12px 16px 24px; display: flex; flex-direction: column; }
+.day { align-self: center; margin: 12px 0 6px; padding: 4px 10px; border-radius: 8px; background: #fff; color: #54656f; font-size: 12px; box-shadow: 0 1px .5px rgba(11,20,26,.13); }
+.msg { max-width: 70%; margin: 2px 0; padding: 6px 8px 4px; border-radius: 8px; background: #fff; box-shadow: 0 1px .5px rgba(11,20,26,.13); white-space: pre-wrap; overflow-wrap: anywhere; }
+.msg.me { align-self: flex-end; background: #d9fdd3; }
+.msg.them { align-self: flex-start; }
+.sender { display: block; font-size: 12.5px; font-weight: 600; color: #027eb5; margin-bottom: 2px; }
+.time { display: block; text-align: right; font-size: 11px; color: #667781; margin-top: 2px; }
+.media { display: block; margin: 2px 0 4px; color: #027eb5; }
+.media.missing { color: #667781; font-style: italic; }
+img.media, video.media { max-width: 100%; border-radius: 6px; }
+</style>
+</head>
+<body>
+<header><h1>{{.Name}}</h1><p>{{.JID}}</p></header>
+<main>
+{{end}}
+{{define "message"}}{{if .Day}}<div class="day">{{.Day}}</div>
+{{end}}<div class="msg {{if .IsFromMe}}me{{else}}them{{end}}">{{if not .IsFromMe}}<span class="sender">{{.SenderName}}</span>{{end}}
+{{- if .ImageSrc}}<img class="media" src="{{.ImageSrc}}" alt="{{.MediaType}}">
+{{- else if and .MediaPath (eq .MediaType "video")}}<video class="media" controls src="{{.MediaPath}}"></video>
+{{- else if and .MediaPath (eq .MediaType "audio")}}<audio class="media" controls src="{{.MediaPath}}"></audio>
+{{- else if .MediaPath}}<a class="media" href="{{.MediaPath}}">{{if .Filename}}{{.Filename}}{{else}}{{.MediaType}}{{end}}</a>
+{{- else if .MediaType}}<span class="media missing">{{.MediaType}} omitted</span>
+{{- end}}{{.Content}}<span class="time">{{.Time}}</span></div>
+{{end}}
+{{define "footer"}}</main>
+</body>
+</html>
+{{end}}`))
+
+func (t *htmlTranscript) begin(chat *domainChatStorage.Chat) error {
+	name := chat.Name
+	if name == "" {
+		name = chat.JID
+	}
+	return htmlTranscriptTemplate.ExecuteTemplate(t.w, "header", map[string]string{"Name": name, "JID": chat.JID})
+}
+
+func (t *htmlTranscript) message(message exportMessage) error {
+	data := htmlMessage{exportMessage: message, Time: message.time.Format("15:04")}
+
+	if day := message.time.Format("2 January 2006"); day != t.lastDay {
+		data.Day, t.lastDay = day, day
+	}
+
+	if t.embedMedia && message.mediaKey != "" && (message.MediaType == "image" || message.MediaType == "sticker") {
+		if content, err := t.readMedia(message.mediaKey); err == nil {
+			data.ImageSrc = dataURI(content, message.mediaKey)
+		}
+	}
+
+	return htmlTranscriptTemplate.ExecuteTemplate(t.w, "message", data)
+}
+
+func (t *htmlTranscript) end(_ int) error {
+	return htmlTranscriptTemplate.ExecuteTemplate(t.w, "footer", nil)
+}
+
+// readMedia returns the content of a downloaded media file
+func (service serviceChat) readMedia(ctx context.Context, mediaKey string) ([]byte, error) {
+	file, err := service.mediaStorage.Open(ctx, mediaKey)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// dataURI inlines a downloaded image so the HTML page shows it without the files next to it
+func dataURI(content []byte, name string) template.URL {
+	mimeType := mime.TypeByExtension(path.Ext(name))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	return template.URL("data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(content))
+}
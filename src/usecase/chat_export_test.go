@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	domainChat "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chat"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/mediastorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const exportChatJID = "6281234567890@s.whatsapp.net"
+
+// newExportService stores a direct chat with a text from each side and a downloaded image
+func newExportService(t *testing.T) serviceChat {
+	local := time.Local
+	time.Local = time.UTC
+	t.Cleanup(func() { time.Local = local })
+
+	repo := newChatStorageRepository(t)
+	storage := mediastorage.NewLocalStorage(t.TempDir())
+	at := func(minute int) time.Time { return time.Date(2025, 7, 13, 10, minute, 0, 0, time.UTC) }
+
+	require.NoError(t, repo.StoreChat(&domainChatStorage.Chat{JID: exportChatJID, Name: "Alice", LastMessageTime: at(10)}))
+	image := []byte("\xff\xd8 jpeg")
+	require.NoError(t, storage.Put(context.Background(), "6281234567890/2025-07-13/photo.jpg", bytes.NewReader(image), int64(len(image)), "image/jpeg"))
+	for _, message := range []*domainChatStorage.Message{
+		{ID: "M1", Sender: exportChatJID, Content: "hello", Timestamp: at(0)},
+		{ID: "M2", Sender: "6289876543210@s.whatsapp.net", IsFromMe: true, Content: "<b>hi</b>", Timestamp: at(5)},
+		{
+			ID: "M3", Sender: exportChatJID, Content: "look", Timestamp: at(10), MediaType: "image",
+			URL: "https://mmg.whatsapp.net/v/photo", MediaPath: "6281234567890/2025-07-13/photo.jpg",
+		},
+	} {
+		message.ChatJID = exportChatJID
+		require.NoError(t, repo.StoreMessage(message))
+	}
+
+	return serviceChat{chatStorageRepo: repo, mediaStorage: storage}
+}
+
+func TestExportChatTranscripts(t *testing.T) {
+	service := newExportService(t)
+
+	t.Run("should export json oldest first", func(t *testing.T) {
+		var out bytes.Buffer
+		response, err := service.ExportChat(context.Background(), domainChat.ExportChatRequest{ChatJID: exportChatJID}, &out)
+		require.NoError(t, err)
+		assert.Equal(t, domainChat.ExportChatResponse{
+			ChatJID: exportChatJID, Filename: "chat-6281234567890.json", ContentType: "application/json", MessageCount: 3,
+		}, response)
+
+		var export struct {
+			Chat         map[string]any   `json:"chat"`
+			Messages     []map[string]any `json:"messages"`
+			MessageCount int              `json:"message_count"`
+		}
+		require.NoError(t, json.Unmarshal(out.Bytes(), &export))
+		assert.Equal(t, map[string]any{"jid": exportChatJID, "name": "Alice", "is_group": false}, export.Chat)
+		assert.Equal(t, 3, export.MessageCount)
+		require.Len(t, export.Messages, 3)
+		assert.Equal(t, map[string]any{
+			"id": "M1", "timestamp": "2025-07-13T10:00:00Z", "sender_jid": exportChatJID,
+			"sender_name": "Alice", "is_from_me": false, "content": "hello",
+		}, export.Messages[0], "the other party of a direct chat goes by the chat name")
+		assert.Equal(t, "You", export.Messages[1]["sender_name"])
+		assert.Equal(t, "image", export.Messages[2]["media_type"])
+		assert.NotContains(t, export.Messages[2], "media_path", "media is only referenced in a zip")
+	})
+
+	t.Run("should export the whatsapp text format", func(t *testing.T) {
+		var out bytes.Buffer
+		_, err := service.ExportChat(context.Background(), domainChat.ExportChatRequest{ChatJID: exportChatJID, Format: "txt"}, &out)
+		require.NoError(t, err)
+		assert.Equal(t, "13/07/2025, 10:00 - Alice: hello\n"+
+			"13/07/2025, 10:05 - You: <b>hi</b>\n"+
+			"13/07/2025, 10:10 - Alice: <Media omitted>\nlook\n", out.String())
+	})
+
+	t.Run("should export escaped html", func(t *testing.T) {
+		var out bytes.Buffer
+		_, err := service.ExportChat(context.Background(), domainChat.ExportChatRequest{ChatJID: exportChatJID, Format: "html"}, &out)
+		require.NoError(t, err)
+		page := out.String()
+		assert.Contains(t, page, "<title>Alice</title>")
+		assert.Contains(t, page, "&lt;b&gt;hi&lt;/b&gt;", "message content is escaped")
+		assert.NotContains(t, page, "<b>hi</b>")
+		assert.Contains(t, page, `<span class="media missing">image omitted</span>`)
+		assert.Equal(t, 1, strings.Count(page, `<div class="day">13 July 2025</div>`), "messages of a day share one separator")
+	})
+
+	t.Run("should fail for an unknown chat", func(t *testing.T) {
+		_, err := service.ExportChat(context.Background(), domainChat.ExportChatRequest{ChatJID: "6280000000000@s.whatsapp.net"}, io.Discard)
+		assert.ErrorContains(t, err, "not found")
+	})
+}
+
+func TestExportChatWithMedia(t *testing.T) {
+	service := newExportService(t)
+
+	var out bytes.Buffer
+	response, err := service.ExportChat(context.Background(), domainChat.ExportChatRequest{ChatJID: exportChatJID, IncludeMedia: true}, &out)
+	require.NoError(t, err)
+	assert.Equal(t, "chat-6281234567890.zip", response.Filename)
+	assert.Equal(t, 1, response.MediaCount)
+
+	archive, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	require.NoError(t, err)
+	files := make(map[string]string)
+	for _, file := range archive.File {
+		body, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(body)
+		body.Close()
+		require.NoError(t, err)
+		files[file.Name] = string(content)
+	}
+	require.Contains(t, files, "chat.json")
+	assert.Equal(t, "\xff\xd8 jpeg", files["media/M3_photo.jpg"])
+
+	var export struct {
+		Messages []map[string]any `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(files["chat.json"]), &export))
+	require.Len(t, export.Messages, 3)
+	assert.Equal(t, "media/M3_photo.jpg", export.Messages[2]["media_path"], "the transcript points at the file in the zip")
+}
@@ -0,0 +1,435 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainChat "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chat"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/whatsapp"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/chatimport"
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/logging"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/validations"
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// importBatchSize is the number of messages written to chat storage per transaction while importing
+const importBatchSize = 500
+
+var (
+	// Android names its export "WhatsApp Chat with Alice.txt", iOS "WhatsApp Chat - Alice.zip"
+	importFilenamePattern = regexp.MustCompile(`(?i)^WhatsApp Chat (?:with|-)\s*(.+?)(?:\.txt|\.zip)?$`)
+
+	// Senders without a saved contact are written as their phone number, "+62 812-3456-7890"
+	importPhonePattern = regexp.MustCompile(`^\+?[\d\s\-()]{7,}$`)
+)
+
+func (service serviceChat) ImportChat(ctx context.Context, request domainChat.ImportChatRequest, file io.ReaderAt, size int64) (response domainChat.ImportChatResponse, err error) {
+	if err = validations.ValidateImportChat(ctx, &request); err != nil {
+		return response, err
+	}
+
+	chatJID, err := utils.ParseJID(request.ChatJID)
+	if err != nil {
+		return response, pkgError.ValidationError(err.Error())
+	}
+	response.ChatJID = chatJID.String()
+
+	loc := time.Local
+	if request.Timezone != "" {
+		loc, _ = time.LoadLocation(request.Timezone)
+	}
+
+	transcript, archive, err := openChatExport(file, size)
+	if err != nil {
+		return response, err
+	}
+	parsed, err := chatimport.Parse(transcript, chatimport.DateOrder(request.DateOrder), loc)
+	if err != nil {
+		return response, pkgError.ValidationError(fmt.Sprintf("failed to parse chat export: %v", err))
+	}
+
+	if err = service.ensureImportChat(ctx, chatJID, request, parsed); err != nil {
+		return response, err
+	}
+
+	senders := newImportSenders(ctx, chatJID, request.SelfName)
+	existing := newExistingMessages(ctx, service.chatStorageRepo, response.ChatJID)
+	occurrences := make(map[string]int)
+	unmapped := make(map[string]bool)
+
+	batch := make([]*domainChatStorage.Message, 0, importBatchSize)
+	flush := func() error {
+		err := service.chatStorageRepo.StoreMessagesBatch(batch)
+		if err != nil {
+			return fmt.Errorf("failed to store imported messages: %w", err)
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for _, item := range parsed {
+		sender, isFromMe, mapped := senders.resolve(item.Sender)
+		if !mapped {
+			unmapped[item.Sender] = true
+		}
+
+		message := &domainChatStorage.Message{
+			ChatJID:   response.ChatJID,
+			Sender:    sender,
+			Content:   item.Content,
+			Timestamp: item.Timestamp.Local(),
+			IsFromMe:  isFromMe,
+		}
+		if item.Attachment != "" {
+			message.MediaType = importMediaType(item.Attachment)
+			message.Filename = item.Attachment
+		}
+		if message.Content == "" && message.MediaType == "" {
+			continue
+		}
+
+		// Identical messages in the same minute are told apart by their position in the transcript,
+		// which keeps ids stable when the same export is imported again
+		fingerprint := strings.Join([]string{response.ChatJID, message.Timestamp.UTC().Format(time.RFC3339), item.Sender, message.Content, item.Attachment}, "\x00")
+		occurrences[fingerprint]++
+		message.ID = importMessageID(fingerprint, occurrences[fingerprint])
+
+		duplicate, err := existing.contains(message)
+		if err != nil {
+			return response, err
+		}
+		if duplicate {
+			response.DuplicatesSkipped++
+			continue
+		}
+
+		if archive != nil && item.Attachment != "" {
+			mediaPath, err := importMedia(ctx, archive, message)
+			if err != nil {
+				logging.FromContext(ctx).WithError(err).WithField("filename", item.Attachment).Warn("Skipping attachment that could not be imported")
+			} else if mediaPath != "" {
+				message.MediaPath = mediaPath
+				response.MediaImported++
+			}
+		}
+
+		batch = append(batch, message)
+		response.MessagesImported++
+		if len(batch) == importBatchSize {
+			if err = flush(); err != nil {
+				return response, err
+			}
+		}
+	}
+	if err = flush(); err != nil {
+		return response, err
+	}
+
+	response.UnmappedSenders = sortedKeys(unmapped)
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"chat_jid":           response.ChatJID,
+		"messages_imported":  response.MessagesImported,
+		"duplicates_skipped": response.DuplicatesSkipped,
+		"media_imported":     response.MediaImported,
+	}).Info("Imported chat export")
+
+	return response, nil
+}
+
+// openChatExport returns the transcript of an export, either a bare .txt file or the zip WhatsApp
+// creates when media is attached. The zip is returned as well so attachments can be read from it.
+func openChatExport(file io.ReaderAt, size int64) (io.Reader, *zip.Reader, error) {
+	magic := make([]byte, 4)
+	if _, err := file.ReadAt(magic, 0); err != nil && !errors.Is(err, io.EOF) {
+		return nil, nil, fmt.Errorf("failed to read chat export: %w", err)
+	}
+	if !bytes.Equal(magic, []byte("PK\x03\x04")) {
+		return io.NewSectionReader(file, 0, size), nil, nil
+	}
+
+	archive, err := zip.NewReader(file, size)
+	if err != nil {
+		return nil, nil, pkgError.ValidationError(fmt.Sprintf("invalid chat export zip: %v", err))
+	}
+	// Reject zip bombs before anything is extracted, archive/zip fails reads past the declared sizes
+	var uncompressed uint64
+	limit := uint64(config.ChatStorageImportMaxSize)
+	for _, entry := range archive.File {
+		if entry.UncompressedSize64 > limit-uncompressed {
+			return nil, nil, pkgError.ValidationError(fmt.Sprintf("chat export zip uncompresses to more than %d bytes", limit))
+		}
+		uncompressed += entry.UncompressedSize64
+	}
+
+	var transcript *zip.File
+	for _, entry := range archive.File {
+		if !strings.EqualFold(path.Ext(entry.Name), ".txt") {
+			continue
+		}
+		// iOS always names the transcript _chat.txt, Android after the chat
+		if path.Base(entry.Name) == "_chat.txt" {
+			transcript = entry
+			break
+		}
+		if transcript == nil {
+			transcript = entry
+		}
+	}
+	if transcript == nil {
+		return nil, nil, pkgError.ValidationError("chat export zip does not contain a .txt transcript")
+	}
+
+	reader, err := transcript.Open()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read chat export transcript: %w", err)
+	}
+	var content bytes.Buffer
+	_, err = utils.CopyLimited(&content, reader, config.ChatStorageImportMaxSize)
+	reader.Close()
+	if errors.Is(err, utils.ErrFileTooLarge) {
+		return nil, nil, pkgError.ValidationError(fmt.Sprintf("chat export transcript: %v", err))
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read chat export transcript: %w", err)
+	}
+
+	return &content, archive, nil
+}
+
+// ensureImportChat creates the chat the messages are imported into, or moves its last message time
+// forward when the export is newer than what is stored. An explicit chat name renames a stored chat.
+func (service serviceChat) ensureImportChat(ctx context.Context, chatJID types.JID, request domainChat.ImportChatRequest, messages []chatimport.Message) error {
+	chat, err := service.chatStorageRepo.GetChat(chatJID.String())
+	if err != nil {
+		return err
+	}
+
+	var lastMessageTime time.Time
+	for _, message := range messages {
+		if message.Timestamp.After(lastMessageTime) {
+			lastMessageTime = message.Timestamp.Local()
+		}
+	}
+
+	if chat == nil {
+		chat = &domainChatStorage.Chat{JID: chatJID.String(), Name: importChatName(chatJID, request)}
+	} else if !lastMessageTime.After(chat.LastMessageTime) && request.ChatName == "" {
+		return nil
+	}
+	if request.ChatName != "" {
+		chat.Name = request.ChatName
+	}
+	if lastMessageTime.After(chat.LastMessageTime) {
+		chat.LastMessageTime = lastMessageTime
+	}
+
+	err = service.chatStorageRepo.StoreChat(chat)
+	if err != nil {
+		return fmt.Errorf("failed to store chat: %w", err)
+	}
+	return nil
+}
+
+// importChatName names a chat that is not stored yet after the export file, falling back to the number
+func importChatName(chatJID types.JID, request domainChat.ImportChatRequest) string {
+	if request.ChatName != "" {
+		return request.ChatName
+	}
+	if match := importFilenamePattern.FindStringSubmatch(filepath.Base(request.Filename)); match != nil {
+		return match[1]
+	}
+	return chatJID.User
+}
+
+// importMessageID derives a stable id for an imported message, prefixed so it never collides with
+// ids assigned by WhatsApp
+func importMessageID(fingerprint string, occurrence int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d", fingerprint, occurrence)))
+	return "IMPORT-" + strings.ToUpper(hex.EncodeToString(sum[:10]))
+}
+
+// importMediaType maps an attachment to the media type stored for synced messages
+func importMediaType(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".heic":
+		return "image"
+	case ".mp4", ".3gp", ".mov", ".mkv":
+		return "video"
+	case ".opus", ".ogg", ".m4a", ".mp3", ".aac", ".amr", ".wav":
+		return "audio"
+	case ".webp":
+		return "sticker"
+	default:
+		return "document"
+	}
+}
+
+// importMedia adds the attachment of message from the export zip to the media store, laid out like
+// downloaded media, and returns its media storage key. Attachments already in the store are linked
+// to the stored copy. Attachments the phone left out of the export are skipped and return an empty key.
+func importMedia(ctx context.Context, archive *zip.Reader, message *domainChatStorage.Message) (string, error) {
+	var entry *zip.File
+	for _, candidate := range archive.File {
+		if path.Base(candidate.Name) == message.Filename {
+			entry = candidate
+			break
+		}
+	}
+	if entry == nil {
+		return "", nil
+	}
+
+	src, err := entry.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	// openChatExport checked the declared size, the limit guards the copy on its own
+	limited := io.LimitReader(src, int64(entry.UncompressedSize64))
+	key, err := whatsapp.AddMediaFile(ctx, mediaRef(message), message.Filename, mime.TypeByExtension(filepath.Ext(message.Filename)), limited)
+	if err != nil {
+		return "", err
+	}
+
+	message.FileLength = entry.UncompressedSize64
+	return key, nil
+}
+
+// importSenders maps the display names of a transcript to JIDs
+type importSenders struct {
+	chatJID  types.JID
+	self     string
+	selfJID  string
+	contacts map[string]string // Contact name to JID, empty when the name is ambiguous
+}
+
+func newImportSenders(ctx context.Context, chatJID types.JID, self string) *importSenders {
+	senders := &importSenders{chatJID: chatJID, self: self, contacts: make(map[string]string)}
+
+	device := whatsapp.GetDevice()
+	if device == nil {
+		return senders
+	}
+	if senders.self == "" {
+		senders.self = device.PushName
+	}
+	if device.ID != nil {
+		senders.selfJID = device.ID.ToNonAD().String()
+	}
+
+	if device.Contacts == nil {
+		return senders
+	}
+	contacts, err := device.Contacts.GetAllContacts(ctx)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Warn("Failed to get contacts for mapping imported senders")
+		return senders
+	}
+	for jid, contact := range contacts {
+		if jid.Server != types.DefaultUserServer {
+			continue
+		}
+		for _, name := range []string{contact.FullName, contact.PushName, contact.BusinessName} {
+			if name == "" {
+				continue
+			}
+			if other, ok := senders.contacts[name]; ok && other != jid.String() {
+				senders.contacts[name] = ""
+				continue
+			}
+			senders.contacts[name] = jid.String()
+		}
+	}
+	return senders
+}
+
+// resolve returns the sender JID of a transcript name, or the name itself when it cannot be mapped
+func (s *importSenders) resolve(name string) (sender string, isFromMe bool, mapped bool) {
+	if s.self != "" && name == s.self {
+		return s.selfJID, true, true
+	}
+	if importPhonePattern.MatchString(name) {
+		digits := strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, name)
+		return types.NewJID(digits, types.DefaultUserServer).String(), false, true
+	}
+	if jid := s.contacts[name]; jid != "" {
+		return jid, false, true
+	}
+	if s.chatJID.Server == types.DefaultUserServer {
+		// In a direct chat everyone but you is the other party
+		return s.chatJID.String(), false, true
+	}
+	return name, false, false
+}
+
+// existingMessages finds messages of an export that chat storage already has, from history sync or
+// an earlier import. Transcripts only keep the minute, so messages are compared per minute.
+type existingMessages struct {
+	ctx     context.Context
+	repo    domainChatStorage.IChatStorageRepository
+	chatJID string
+	minutes map[int64]map[string]int // Stored messages per minute and key
+}
+
+func newExistingMessages(ctx context.Context, repo domainChatStorage.IChatStorageRepository, chatJID string) *existingMessages {
+	return &existingMessages{ctx: ctx, repo: repo, chatJID: chatJID, minutes: make(map[int64]map[string]int)}
+}
+
+func (e *existingMessages) contains(message *domainChatStorage.Message) (bool, error) {
+	start := message.Timestamp.Truncate(time.Minute)
+	keys, ok := e.minutes[start.Unix()]
+	if !ok {
+		end := start.Add(time.Minute - time.Nanosecond)
+		stored, err := e.repo.GetMessages(&domainChatStorage.MessageFilter{
+			ChatJID:   e.chatJID,
+			StartTime: &start,
+			EndTime:   &end,
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to check for existing messages: %w", err)
+		}
+
+		keys = make(map[string]int, len(stored))
+		for _, existing := range stored {
+			keys[existingMessageKey(existing)]++
+		}
+		e.minutes[start.Unix()] = keys
+	}
+
+	// Each stored message accounts for one identical message of the export, so repeated messages
+	// such as "ok" are only skipped as often as they were already stored
+	key := existingMessageKey(message)
+	if keys[key] > 0 {
+		keys[key]--
+		return true, nil
+	}
+	return false, nil
+}
+
+func existingMessageKey(message *domainChatStorage.Message) string {
+	return fmt.Sprintf("%t\x00%s\x00%s", message.IsFromMe, message.MediaType, strings.TrimSpace(message.Content))
+}
@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func chatExportZip(t *testing.T, files map[string]string) *bytes.Reader {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for name, content := range files {
+		file, err := writer.Create(name)
+		require.NoError(t, err)
+		_, err = io.WriteString(file, content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestOpenChatExportLimitsUncompressedSize(t *testing.T) {
+	limit := config.ChatStorageImportMaxSize
+	t.Cleanup(func() { config.ChatStorageImportMaxSize = limit })
+	config.ChatStorageImportMaxSize = 100
+
+	transcript := "1/2/25, 10:00 - Alice: hello\n"
+	tests := []struct {
+		name  string
+		files map[string]string
+		valid bool
+	}{
+		{name: "should open an export within the limit", files: map[string]string{"_chat.txt": transcript, "IMG-1.jpg": "jpeg"}, valid: true},
+		{name: "should reject an oversized transcript", files: map[string]string{"_chat.txt": strings.Repeat("a", 101)}},
+		{name: "should reject an oversized attachment", files: map[string]string{"_chat.txt": transcript, "VID-1.mp4": strings.Repeat("0", 101)}},
+		{name: "should reject files over the limit together", files: map[string]string{"_chat.txt": transcript, "IMG-1.jpg": strings.Repeat("0", 60), "IMG-2.jpg": strings.Repeat("0", 60)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := chatExportZip(t, tt.files)
+			reader, archive, err := openChatExport(file, file.Size())
+			if !tt.valid {
+				assert.ErrorAs(t, err, new(pkgError.ValidationError))
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, archive)
+			content, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			assert.Equal(t, transcript, string(content))
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	domainChat "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chat"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/chatstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatPageEntriesMergesUnsyncedChats(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "chatstorage.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	repo := chatstorage.NewStorageRepository(db)
+	require.NoError(t, repo.InitializeSchema())
+
+	// Stored chats newest first s1, s2, s3, in a non-UTC offset
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	for i, jid := range []string{"s3@s.whatsapp.net", "s2@s.whatsapp.net", "s1@s.whatsapp.net"} {
+		require.NoError(t, repo.StoreChat(&domainChatStorage.Chat{
+			JID:             jid,
+			Name:            jid,
+			LastMessageTime: time.Date(2025, 1, 1, 10+i, 0, 0, 0, jakarta),
+		}))
+	}
+	// Unsynced chats follow ordered by JID descending, with the zero timestamp
+	unsynced := []chatEntry{
+		{cursor: domainChatStorage.Cursor{ID: "u2@g.us"}},
+		{cursor: domainChatStorage.Cursor{ID: "u1@g.us"}},
+	}
+
+	service := serviceChat{chatStorageRepo: repo}
+	const limit = 2
+	page := func(token string, offset int) (ids []string, next, prev string) {
+		cursor, err := decodeCursor(token)
+		require.NoError(t, err)
+		entries, err := service.chatPageEntries(context.Background(), &domainChatStorage.ChatFilter{}, unsynced, 3, cursor, limit, offset)
+		require.NoError(t, err)
+
+		entries, next, prev = keysetPage(entries, limit, cursor, offset, func(entry chatEntry) domainChatStorage.Cursor {
+			return entry.cursor
+		})
+		for _, entry := range entries {
+			ids = append(ids, entry.cursor.ID)
+		}
+		return ids, next, prev
+	}
+
+	first, next, prev := page("", 0)
+	assert.Equal(t, []string{"s1@s.whatsapp.net", "s2@s.whatsapp.net"}, first)
+	assert.Empty(t, prev)
+
+	second, next, _ := page(next, 0)
+	assert.Equal(t, []string{"s3@s.whatsapp.net", "u2@g.us"}, second, "stored chats are followed by unsynced ones")
+	require.NotEmpty(t, next)
+
+	last, next, prev := page(next, 0)
+	assert.Equal(t, []string{"u1@g.us"}, last, "a zero timestamp cursor continues within the unsynced chats")
+	assert.Empty(t, next)
+
+	back, _, prev := page(prev, 0)
+	assert.Equal(t, second, back, "walking back crosses from unsynced to stored chats")
+	back, _, prev = page(prev, 0)
+	assert.Equal(t, first, back)
+	assert.Empty(t, prev)
+
+	byOffset, _, _ := page("", 4)
+	assert.Equal(t, []string{"u1@g.us"}, byOffset, "an offset past the stored chats skips into the unsynced ones")
+}
+
+func TestUnsyncedChatsAreCachedAcrossPages(t *testing.T) {
+	repo := newChatStorageRepository(t)
+	require.NoError(t, repo.StoreChat(&domainChatStorage.Chat{JID: "c1@s.whatsapp.net", Name: "Alice", LastMessageTime: time.Now()}))
+
+	fetches := 0
+	fetch := func(context.Context) []chatEntry {
+		fetches++
+		var entries []chatEntry
+		for _, chat := range []domainChat.ChatInfo{
+			{JID: "c1@s.whatsapp.net", Name: "Alice"},
+			{JID: "c2@s.whatsapp.net", Name: "Bob"},
+			{JID: "g1@g.us", Name: "Book club", IsGroup: true},
+		} {
+			entries = append(entries, chatEntry{info: chat, cursor: domainChatStorage.Cursor{ID: chat.JID}})
+		}
+		return entries
+	}
+	service := serviceChat{chatStorageRepo: repo, unsynced: &unsyncedChatCache{fetch: fetch}}
+	jids := func(entries []chatEntry) (ids []string) {
+		for _, entry := range entries {
+			ids = append(ids, entry.info.JID)
+		}
+		return ids
+	}
+
+	first := service.unsyncedChats(context.Background(), domainChat.ListChatsRequest{Limit: 1})
+	assert.Equal(t, []string{"g1@g.us", "c2@s.whatsapp.net"}, jids(first), "stored chats are left out")
+
+	next := service.unsyncedChats(context.Background(), domainChat.ListChatsRequest{Limit: 1, Offset: 2})
+	assert.Equal(t, jids(first), jids(next))
+	assert.Equal(t, 1, fetches, "later pages reuse the groups and contacts of the first")
+
+	searched := service.unsyncedChats(context.Background(), domainChat.ListChatsRequest{Search: "bo"})
+	assert.Equal(t, []string{"g1@g.us", "c2@s.whatsapp.net"}, jids(searched))
+	searched = service.unsyncedChats(context.Background(), domainChat.ListChatsRequest{Search: "club"})
+	assert.Equal(t, []string{"g1@g.us"}, jids(searched))
+	assert.Equal(t, 1, fetches, "searches filter the cached chats")
+
+	assert.Empty(t, service.unsyncedChats(context.Background(), domainChat.ListChatsRequest{HasMedia: true}))
+
+	// A new stored chat makes the cached list stale
+	require.NoError(t, repo.StoreChat(&domainChatStorage.Chat{JID: "g1@g.us", Name: "Book club", LastMessageTime: time.Now()}))
+	rebuilt := service.unsyncedChats(context.Background(), domainChat.ListChatsRequest{})
+	assert.Equal(t, []string{"c2@s.whatsapp.net"}, jids(rebuilt))
+	assert.Equal(t, 2, fetches)
+}
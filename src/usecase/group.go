@@ -3,23 +3,28 @@ package usecase
 import (
 	"context"
 	"fmt"
-
-	"github.com/sirupsen/logrus"
+	"time"
 
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
 	domainGroup "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/group"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/whatsapp"
 	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/logging"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/validations"
 	"go.mau.fi/whatsmeow"
 	"go.mau.fi/whatsmeow/types"
 )
 
-type serviceGroup struct{}
+type serviceGroup struct {
+	chatStorageRepo domainChatStorage.IChatStorageRepository
+}
 
-func NewGroupService() domainGroup.IGroupUsecase {
-	return &serviceGroup{}
+func NewGroupService(chatStorageRepo domainChatStorage.IChatStorageRepository) domainGroup.IGroupUsecase {
+	return &serviceGroup{
+		chatStorageRepo: chatStorageRepo,
+	}
 }
 
 func (service serviceGroup) JoinGroupWithLink(ctx context.Context, request domainGroup.JoinGroupWithLinkRequest) (groupID string, err error) {
@@ -233,15 +238,15 @@ func (service serviceGroup) SetGroupPhoto(ctx context.Context, request domainGro
 	var photoBytes []byte
 	if request.Photo != nil {
 		// Process the image for WhatsApp group photo requirements
-		logrus.Printf("Processing group photo: %s (size: %d bytes)", request.Photo.Filename, request.Photo.Size)
+		logging.FromContext(ctx).Printf("Processing group photo: %s (size: %d bytes)", request.Photo.Filename, request.Photo.Size)
 
 		processedImageBuffer, err := utils.ProcessGroupPhoto(request.Photo)
 		if err != nil {
-			logrus.Printf("Failed to process group photo: %v", err)
+			logging.FromContext(ctx).Printf("Failed to process group photo: %v", err)
 			return pictureID, err
 		}
 
-		logrus.Printf("Successfully processed group photo: %d bytes -> %d bytes",
+		logging.FromContext(ctx).Printf("Successfully processed group photo: %d bytes -> %d bytes",
 			request.Photo.Size, processedImageBuffer.Len())
 
 		// Convert buffer to byte slice
@@ -250,7 +255,7 @@ func (service serviceGroup) SetGroupPhoto(ctx context.Context, request domainGro
 
 	pictureID, err = whatsapp.GetClient().SetGroupPhoto(groupJID, photoBytes)
 	if err != nil {
-		logrus.Printf("Failed to set group photo: %v", err)
+		logging.FromContext(ctx).Printf("Failed to set group photo: %v", err)
 		return pictureID, err
 	}
 
@@ -363,3 +368,47 @@ func (service serviceGroup) GetGroupInviteLink(ctx context.Context, request doma
 
 	return response, nil
 }
+
+func (service serviceGroup) GroupEvents(ctx context.Context, request domainGroup.GroupEventsRequest) (response domainGroup.GroupEventsResponse, err error) {
+	if err = validations.ValidateGroupEvents(ctx, &request); err != nil {
+		return response, err
+	}
+
+	filter := &domainChatStorage.GroupEventFilter{
+		GroupJID:  request.GroupID,
+		EventType: request.Type,
+		Limit:     request.Limit,
+		Offset:    request.Offset,
+	}
+
+	groupEvents, err := service.chatStorageRepo.GetGroupEvents(filter)
+	if err != nil {
+		return response, err
+	}
+
+	total, err := service.chatStorageRepo.GetGroupEventCount(filter)
+	if err != nil {
+		return response, err
+	}
+
+	response.Data = make([]domainGroup.GroupEventInfo, 0, len(groupEvents))
+	for _, event := range groupEvents {
+		response.Data = append(response.Data, domainGroup.GroupEventInfo{
+			ID:           event.ID,
+			GroupID:      event.GroupJID,
+			Type:         event.EventType,
+			ChangedBy:    event.ActorJID,
+			OldValue:     event.OldValue,
+			NewValue:     event.NewValue,
+			Participants: event.Participants,
+			Timestamp:    event.Timestamp.Format(time.RFC3339),
+		})
+	}
+	response.Pagination = domainGroup.GroupEventsPagination{
+		Limit:  request.Limit,
+		Offset: request.Offset,
+		Total:  int(total),
+	}
+
+	return response, nil
+}
@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	domainMedia "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/media"
+	domainMediaStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/mediastorage"
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/validations"
+)
+
+type serviceMedia struct {
+	chatStorageRepo domainChatStorage.IChatStorageRepository
+	mediaStorage    domainMediaStorage.IMediaStorage
+}
+
+func NewMediaService(chatStorageRepo domainChatStorage.IChatStorageRepository, mediaStorage domainMediaStorage.IMediaStorage) domainMedia.IMediaUsecase {
+	return &serviceMedia{
+		chatStorageRepo: chatStorageRepo,
+		mediaStorage:    mediaStorage,
+	}
+}
+
+// GetMedia returns the stored copy of the media content with the given SHA-256
+func (service serviceMedia) GetMedia(ctx context.Context, request domainMedia.GetMediaRequest) (response domainMedia.MediaFile, err error) {
+	request.SHA256 = strings.ToLower(strings.TrimSpace(request.SHA256))
+	if err = validations.ValidateGetMedia(ctx, request); err != nil {
+		return response, err
+	}
+
+	file, err := service.chatStorageRepo.GetMediaContent(request.SHA256)
+	if err != nil {
+		return response, err
+	}
+	if file == nil {
+		return response, pkgError.NotFoundError(fmt.Sprintf("media %s not found", request.SHA256))
+	}
+	object, err := service.mediaStorage.Stat(ctx, file.Path)
+	if err != nil {
+		if errors.Is(err, domainMediaStorage.ErrNotFound) {
+			return response, pkgError.NotFoundError(fmt.Sprintf("file of media %s is no longer available", request.SHA256))
+		}
+		return response, err
+	}
+
+	url, err := service.mediaStorage.URL(ctx, file.Path, config.MediaStorageURLExpiry)
+	if err != nil {
+		return response, err
+	}
+
+	return domainMedia.MediaFile{
+		SHA256:    request.SHA256,
+		Key:       file.Path,
+		URL:       url,
+		MimeType:  file.MimeType,
+		Size:      object.Size,
+		RefCount:  file.RefCount,
+		CreatedAt: file.CreatedAt,
+	}, nil
+}
+
+// OpenMedia returns the stored copy of the media content with the given SHA-256 together with its
+// content, the caller closes it
+func (service serviceMedia) OpenMedia(ctx context.Context, request domainMedia.GetMediaRequest) (response domainMedia.MediaFile, content io.ReadCloser, err error) {
+	response, err = service.GetMedia(ctx, request)
+	if err != nil {
+		return response, nil, err
+	}
+
+	content, err = service.mediaStorage.Open(ctx, response.Key)
+	if errors.Is(err, domainMediaStorage.ErrNotFound) {
+		return response, nil, pkgError.NotFoundError(fmt.Sprintf("file of media %s is no longer available", response.SHA256))
+	}
+	return response, content, err
+}
@@ -3,14 +3,14 @@ package usecase
 import (
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
+	"path"
 	"time"
 
-	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
 	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	domainMediaStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/mediastorage"
 	domainMessage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/message"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/whatsapp"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/logging"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/validations"
 	"github.com/sirupsen/logrus"
@@ -25,11 +25,13 @@ import (
 
 type serviceMessage struct {
 	chatStorageRepo domainChatStorage.IChatStorageRepository
+	mediaStorage    domainMediaStorage.IMediaStorage
 }
 
-func NewMessageService(chatStorageRepo domainChatStorage.IChatStorageRepository) domainMessage.IMessageUsecase {
+func NewMessageService(chatStorageRepo domainChatStorage.IChatStorageRepository, mediaStorage domainMediaStorage.IMediaStorage) domainMessage.IMessageUsecase {
 	return &serviceMessage{
 		chatStorageRepo: chatStorageRepo,
+		mediaStorage:    mediaStorage,
 	}
 }
 
@@ -47,12 +49,12 @@ func (service serviceMessage) MarkAsRead(ctx context.Context, request domainMess
 		return response, err
 	}
 
-	logrus.Info(map[string]any{
+	logging.FromContext(ctx).WithFields(logrus.Fields{
 		"phone":      request.Phone,
 		"message_id": request.MessageID,
 		"chat":       dataWaRecipient.String(),
 		"sender":     whatsapp.GetClient().Store.ID.String(),
-	})
+	}).Info("Marked message as read")
 
 	response.MessageID = request.MessageID
 	response.Status = fmt.Sprintf("Mark as read success %s", request.MessageID)
@@ -218,94 +220,169 @@ func (service serviceMessage) DownloadMedia(ctx context.Context, request domainM
 		return response, fmt.Errorf("message %s does not belong to chat %s", request.MessageID, dataWaRecipient.String())
 	}
 
-	// Create directory structure for organized storage
-	chatDir := filepath.Join(config.PathMedia, utils.ExtractPhoneNumber(message.ChatJID))
-	dateDir := filepath.Join(chatDir, message.Timestamp.Format("2006-01-02"))
+	// Download the media into the chat's media folder of the media storage
+	mediaKey, err := downloadStoredMedia(ctx, service.chatStorageRepo, service.mediaStorage, message)
+	if err != nil {
+		return response, err
+	}
+
+	// Get file size
+	object, err := service.mediaStorage.Stat(ctx, mediaKey)
+	if err != nil {
+		logging.FromContext(ctx).Warnf("Could not get file size for %s: %v", mediaKey, err)
+	}
+
+	// Build response
+	response.MessageID = request.MessageID
+	response.MediaType = message.MediaType
+	response.Filename = path.Base(mediaKey)
+	response.FilePath = service.mediaStorage.Locate(mediaKey)
+	response.URL = whatsapp.MediaURL(ctx, mediaKey, message.FileSHA256)
+	response.FileSize = object.Size
+	response.Status = fmt.Sprintf("Media downloaded successfully to %s", response.FilePath)
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"message_id": request.MessageID,
+		"phone":      request.Phone,
+		"chat":       dataWaRecipient.String(),
+		"media_type": response.MediaType,
+		"file_path":  response.FilePath,
+		"file_size":  response.FileSize,
+	}).Info("Downloaded media")
+
+	return response, nil
+}
+
+// downloadStoredMedia returns the media storage key of the media of a stored message, downloading it
+// into <chat>/<date> unless the message or another one with the same content already was. Media that
+// expired on the WhatsApp servers is requested from the phone again and its new URL is stored.
+func downloadStoredMedia(ctx context.Context, chatStorageRepo domainChatStorage.IChatStorageRepository, mediaStorage domainMediaStorage.IMediaStorage, message *domainChatStorage.Message) (string, error) {
+	if message.MediaPath != "" {
+		if _, err := mediaStorage.Stat(ctx, message.MediaPath); err == nil {
+			return message.MediaPath, nil
+		}
+	}
+
+	downloadableMsg, err := storedMediaMessage(message, "")
+	if err != nil {
+		return "", err
+	}
+
+	// Download the media through the media store, which reuses a copy of the same content
+	extractedMedia, err := whatsapp.DownloadMedia(ctx, mediaRef(message), downloadableMsg)
+	if whatsapp.IsMediaExpired(err) {
+		logging.FromContext(ctx).Infof("Media of message %s expired, requesting it from the phone", message.ID)
+
+		info, infoErr := storedMessageInfo(message)
+		if infoErr != nil {
+			return "", infoErr
+		}
+		directPath, url, retryErr := whatsapp.RequestMediaReupload(ctx, info, message.MediaKey)
+		if retryErr != nil {
+			return "", retryErr
+		}
 
-	err = os.MkdirAll(dateDir, 0755)
+		message.URL = url
+		if err := chatStorageRepo.UpdateMessageMediaURL(message.ChatJID, message.ID, url); err != nil {
+			logging.FromContext(ctx).Warnf("Failed to store new media URL of message %s: %v", message.ID, err)
+		}
+		if downloadableMsg, err = storedMediaMessage(message, directPath); err != nil {
+			return "", err
+		}
+		extractedMedia, err = whatsapp.DownloadMedia(ctx, mediaRef(message), downloadableMsg)
+	}
 	if err != nil {
-		return response, fmt.Errorf("failed to create directory: %v", err)
+		return "", fmt.Errorf("failed to download media: %v", err)
 	}
 
-	// Create a downloadable message interface based on media type
-	var downloadableMsg interface{}
+	return extractedMedia.MediaPath, nil
+}
+
+// storedMediaMessage returns the downloadable media of a stored message. With a directPath the media
+// is downloaded from there instead of the stored URL.
+func storedMediaMessage(message *domainChatStorage.Message, directPath string) (whatsmeow.DownloadableMessage, error) {
+	url := proto.String(message.URL)
+	var mediaPath *string
+	if directPath != "" {
+		url, mediaPath = nil, proto.String(directPath)
+	}
 
 	switch message.MediaType {
 	case "image":
-		downloadableMsg = &waE2E.ImageMessage{
-			URL:           proto.String(message.URL),
+		return &waE2E.ImageMessage{
+			URL:           url,
+			DirectPath:    mediaPath,
 			MediaKey:      message.MediaKey,
 			FileSHA256:    message.FileSHA256,
 			FileEncSHA256: message.FileEncSHA256,
 			FileLength:    proto.Uint64(message.FileLength),
-		}
+		}, nil
 	case "video":
-		downloadableMsg = &waE2E.VideoMessage{
-			URL:           proto.String(message.URL),
+		return &waE2E.VideoMessage{
+			URL:           url,
+			DirectPath:    mediaPath,
 			MediaKey:      message.MediaKey,
 			FileSHA256:    message.FileSHA256,
 			FileEncSHA256: message.FileEncSHA256,
 			FileLength:    proto.Uint64(message.FileLength),
-		}
+		}, nil
 	case "audio":
-		downloadableMsg = &waE2E.AudioMessage{
-			URL:           proto.String(message.URL),
+		return &waE2E.AudioMessage{
+			URL:           url,
+			DirectPath:    mediaPath,
 			MediaKey:      message.MediaKey,
 			FileSHA256:    message.FileSHA256,
 			FileEncSHA256: message.FileEncSHA256,
 			FileLength:    proto.Uint64(message.FileLength),
-		}
+		}, nil
 	case "document":
-		downloadableMsg = &waE2E.DocumentMessage{
-			URL:           proto.String(message.URL),
+		return &waE2E.DocumentMessage{
+			URL:           url,
+			DirectPath:    mediaPath,
 			MediaKey:      message.MediaKey,
 			FileSHA256:    message.FileSHA256,
 			FileEncSHA256: message.FileEncSHA256,
 			FileLength:    proto.Uint64(message.FileLength),
 			FileName:      proto.String(message.Filename),
-		}
+		}, nil
 	case "sticker":
-		downloadableMsg = &waE2E.StickerMessage{
-			URL:           proto.String(message.URL),
+		return &waE2E.StickerMessage{
+			URL:           url,
+			DirectPath:    mediaPath,
 			MediaKey:      message.MediaKey,
 			FileSHA256:    message.FileSHA256,
 			FileEncSHA256: message.FileEncSHA256,
 			FileLength:    proto.Uint64(message.FileLength),
-		}
+		}, nil
 	default:
-		return response, fmt.Errorf("unsupported media type: %s", message.MediaType)
+		return nil, fmt.Errorf("unsupported media type: %s", message.MediaType)
 	}
+}
 
-	// Download the media using existing utils.ExtractMedia function
-	extractedMedia, err := utils.ExtractMedia(ctx, whatsapp.GetClient(), dateDir, downloadableMsg.(whatsmeow.DownloadableMessage))
+// storedMessageInfo returns the message info a media retry receipt for a stored message needs
+func storedMessageInfo(message *domainChatStorage.Message) (types.MessageInfo, error) {
+	chat, err := types.ParseJID(message.ChatJID)
 	if err != nil {
-		return response, fmt.Errorf("failed to download media: %v", err)
+		return types.MessageInfo{}, fmt.Errorf("invalid chat of message %s: %w", message.ID, err)
 	}
 
-	// Get file size
-	fileInfo, err := os.Stat(extractedMedia.MediaPath)
-	if err != nil {
-		logrus.Warnf("Could not get file size for %s: %v", extractedMedia.MediaPath, err)
+	info := types.MessageInfo{
+		ID: message.ID,
+		MessageSource: types.MessageSource{
+			Chat:     chat,
+			IsFromMe: message.IsFromMe,
+			IsGroup:  chat.Server == types.GroupServer,
+		},
 	}
-
-	// Build response
-	response.MessageID = request.MessageID
-	response.Status = fmt.Sprintf("Media downloaded successfully to %s", extractedMedia.MediaPath)
-	response.MediaType = message.MediaType
-	response.Filename = filepath.Base(extractedMedia.MediaPath)
-	response.FilePath = extractedMedia.MediaPath
-	if fileInfo != nil {
-		response.FileSize = fileInfo.Size()
+	if info.IsGroup {
+		if info.Sender, err = types.ParseJID(message.Sender); err != nil {
+			return types.MessageInfo{}, fmt.Errorf("invalid sender of message %s: %w", message.ID, err)
+		}
 	}
+	return info, nil
+}
 
-	logrus.Info(map[string]any{
-		"message_id": request.MessageID,
-		"phone":      request.Phone,
-		"chat":       dataWaRecipient.String(),
-		"media_type": response.MediaType,
-		"file_path":  response.FilePath,
-		"file_size":  response.FileSize,
-	})
-
-	return response, nil
+// mediaRef identifies a stored message in the media store
+func mediaRef(message *domainChatStorage.Message) whatsapp.MediaRef {
+	return whatsapp.MediaRef{ChatJID: message.ChatJID, MessageID: message.ID, Timestamp: message.Timestamp}
 }
@@ -0,0 +1,286 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	domainMediaStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/mediastorage"
+	domainRetention "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/retention"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/logging"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/tracing"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/validations"
+	"github.com/sirupsen/logrus"
+)
+
+// retentionMutex keeps scheduled and requested retention runs from overlapping
+var retentionMutex sync.Mutex
+
+type serviceRetention struct {
+	chatStorageRepo domainChatStorage.IChatStorageRepository
+	mediaStorage    domainMediaStorage.IMediaStorage
+}
+
+func NewRetentionService(chatStorageRepo domainChatStorage.IChatStorageRepository, mediaStorage domainMediaStorage.IMediaStorage) domainRetention.IRetentionUsecase {
+	return &serviceRetention{
+		chatStorageRepo: chatStorageRepo,
+		mediaStorage:    mediaStorage,
+	}
+}
+
+func (service serviceRetention) ListPolicies(ctx context.Context) (response domainRetention.ListPoliciesResponse, err error) {
+	policies, err := service.chatStorageRepo.GetRetentionPolicies()
+	if err != nil {
+		return response, err
+	}
+
+	response.Global = globalRetentionPolicy()
+	response.Chats = make([]domainRetention.Policy, 0, len(policies))
+	for _, policy := range policies {
+		response.Chats = append(response.Chats, chatRetentionPolicy(policy))
+	}
+	return response, nil
+}
+
+func (service serviceRetention) SetPolicy(ctx context.Context, request domainRetention.SetPolicyRequest) (response domainRetention.Policy, err error) {
+	utils.SanitizePhone(&request.ChatJID)
+	if err = validations.ValidateSetRetentionPolicy(ctx, request); err != nil {
+		return response, err
+	}
+
+	policy := &domainChatStorage.RetentionPolicy{
+		ChatJID:     request.ChatJID,
+		MaxAgeDays:  request.MaxAgeDays,
+		MaxMessages: request.MaxMessages,
+	}
+	err = service.chatStorageRepo.StoreRetentionPolicy(policy)
+	if err != nil {
+		return response, err
+	}
+
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"chat_jid":     policy.ChatJID,
+		"max_age_days": policy.MaxAgeDays,
+		"max_messages": policy.MaxMessages,
+	}).Info("Retention policy set")
+	return chatRetentionPolicy(policy), nil
+}
+
+func (service serviceRetention) DeletePolicy(ctx context.Context, chatJID string) (err error) {
+	utils.SanitizePhone(&chatJID)
+
+	err = service.chatStorageRepo.DeleteRetentionPolicy(chatJID)
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).WithField("chat_jid", chatJID).Info("Retention policy deleted")
+	return nil
+}
+
+// Report lists what a retention run would remove right now, without deleting anything
+func (service serviceRetention) Report(ctx context.Context) (response domainRetention.Report, err error) {
+	return service.run(ctx, true)
+}
+
+// Purge deletes the messages and media files that fall outside the retention policies, records an
+// audit entry per affected chat and vacuums chat storage when anything was deleted
+func (service serviceRetention) Purge(ctx context.Context) (response domainRetention.Report, err error) {
+	return service.run(ctx, false)
+}
+
+func (service serviceRetention) run(ctx context.Context, dryRun bool) (response domainRetention.Report, err error) {
+	retentionMutex.Lock()
+	defer retentionMutex.Unlock()
+
+	ctx, span := tracing.Start(ctx, "retention.Run")
+	defer func() { tracing.End(span, err) }()
+
+	response = domainRetention.Report{DryRun: dryRun, RunAt: time.Now().UTC(), Chats: []domainRetention.ChatReport{}}
+
+	chatPolicies, err := service.chatStorageRepo.GetRetentionPolicies()
+	if err != nil {
+		return response, err
+	}
+	policies := make(map[string]domainRetention.Policy, len(chatPolicies))
+	for _, policy := range chatPolicies {
+		policies[policy.ChatJID] = chatRetentionPolicy(policy)
+	}
+
+	chatJIDs, err := service.chatStorageRepo.GetChatJIDs()
+	if err != nil {
+		return response, err
+	}
+
+	global := globalRetentionPolicy()
+	for _, chatJID := range chatJIDs {
+		policy, ok := policies[chatJID]
+		if !ok {
+			policy = global
+		}
+		if policy.MaxAgeDays == 0 && policy.MaxMessages == 0 {
+			continue
+		}
+
+		purge, err := service.chatStorageRepo.PurgeMessages(chatJID, retentionCutoff(response.RunAt, policy.MaxAgeDays), policy.MaxMessages, dryRun)
+		if err != nil {
+			return response, err
+		}
+		if purge.Messages == 0 && !purge.ChatDeleted {
+			continue
+		}
+
+		report := domainRetention.ChatReport{
+			ChatJID:         chatJID,
+			Policy:          policy,
+			MessagesDeleted: purge.Messages,
+			ChatDeleted:     purge.ChatDeleted,
+		}
+		report.MediaDeleted, report.MediaBytes = removeMediaFiles(ctx, service.mediaStorage, purge.MediaPaths, dryRun)
+		response.Chats = append(response.Chats, report)
+		response.MessagesDeleted += report.MessagesDeleted
+		response.MediaDeleted += report.MediaDeleted
+		response.MediaBytes += report.MediaBytes
+
+		if !dryRun {
+			service.audit(ctx, &domainChatStorage.RetentionAudit{
+				RunAt:           response.RunAt,
+				ChatJID:         chatJID,
+				MaxAgeDays:      policy.MaxAgeDays,
+				MaxMessages:     policy.MaxMessages,
+				MessagesDeleted: report.MessagesDeleted,
+				MediaDeleted:    report.MediaDeleted,
+				ChatDeleted:     report.ChatDeleted,
+			})
+		}
+	}
+
+	unreferenced, err := service.chatStorageRepo.PurgeUnreferencedMedia(dryRun)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Warn("Failed to find unreferenced media for retention")
+	}
+	if deleted, bytes := removeMediaFiles(ctx, service.mediaStorage, unreferenced, dryRun); deleted > 0 {
+		response.UnreferencedMedia = deleted
+		response.MediaDeleted += deleted
+		response.MediaBytes += bytes
+
+		if !dryRun {
+			service.audit(ctx, &domainChatStorage.RetentionAudit{
+				RunAt:        response.RunAt,
+				MediaDeleted: deleted,
+			})
+		}
+	}
+
+	if cutoff := retentionCutoff(response.RunAt, global.MaxAgeDays); cutoff != nil {
+		untracked, err := service.untrackedMedia(ctx, *cutoff)
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).Warn("Failed to scan media folder for retention")
+		}
+		deleted, bytes := removeMediaFiles(ctx, service.mediaStorage, untracked, dryRun)
+		response.UntrackedMedia = deleted
+		response.MediaDeleted += deleted
+		response.MediaBytes += bytes
+
+		if !dryRun && deleted > 0 {
+			service.audit(ctx, &domainChatStorage.RetentionAudit{
+				RunAt:        response.RunAt,
+				MaxAgeDays:   global.MaxAgeDays,
+				MediaDeleted: deleted,
+			})
+		}
+	}
+
+	if dryRun || len(response.Chats) == 0 {
+		return response, nil
+	}
+
+	if err := service.chatStorageRepo.Vacuum(); err != nil {
+		logging.FromContext(ctx).WithError(err).Warn("Failed to vacuum chat storage after retention")
+	}
+	logging.FromContext(ctx).WithFields(logrus.Fields{
+		"chats":    len(response.Chats),
+		"messages": response.MessagesDeleted,
+		"media":    response.MediaDeleted,
+	}).Info("Retention purge completed")
+	return response, nil
+}
+
+func (service serviceRetention) audit(ctx context.Context, audit *domainChatStorage.RetentionAudit) {
+	if err := service.chatStorageRepo.StoreRetentionAudit(audit); err != nil {
+		logging.FromContext(ctx).WithError(err).WithField("chat_jid", audit.ChatJID).Error("Failed to write retention audit entry")
+	}
+}
+
+// untrackedMedia lists media files last modified before cutoff that are not linked to a message,
+// such as files downloaded before media links were recorded
+func (service serviceRetention) untrackedMedia(ctx context.Context, cutoff time.Time) ([]string, error) {
+	var keys []string
+	err := service.mediaStorage.Walk(ctx, func(object domainMediaStorage.Object) error {
+		if !object.ModTime.Before(cutoff) {
+			return nil
+		}
+
+		tracked, err := service.chatStorageRepo.IsMediaFileTracked(object.Key)
+		if err != nil {
+			return err
+		}
+		if !tracked {
+			keys = append(keys, object.Key)
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// removeMediaFiles deletes media files, or with dryRun only measures them, and returns the number
+// and total size of the files that existed
+func removeMediaFiles(ctx context.Context, storage domainMediaStorage.IMediaStorage, keys []string, dryRun bool) (count int, bytes int64) {
+	for _, key := range keys {
+		object, err := storage.Stat(ctx, key)
+		if err != nil {
+			continue
+		}
+		count++
+		bytes += object.Size
+		if dryRun {
+			continue
+		}
+
+		if err := storage.Delete(ctx, key); err != nil {
+			logging.FromContext(ctx).WithError(err).WithField("path", key).Warn("Failed to delete media file")
+		}
+	}
+	return count, bytes
+}
+
+// retentionCutoff returns the time before which messages are purged, nil when the age is unlimited
+func retentionCutoff(now time.Time, maxAgeDays int) *time.Time {
+	if maxAgeDays <= 0 {
+		return nil
+	}
+	cutoff := now.AddDate(0, 0, -maxAgeDays)
+	return &cutoff
+}
+
+func globalRetentionPolicy() domainRetention.Policy {
+	return domainRetention.Policy{
+		MaxAgeDays:  config.ChatStorageRetentionDays,
+		MaxMessages: config.ChatStorageRetentionMessages,
+		Source:      domainRetention.SourceGlobal,
+	}
+}
+
+func chatRetentionPolicy(policy *domainChatStorage.RetentionPolicy) domainRetention.Policy {
+	updatedAt := policy.UpdatedAt
+	return domainRetention.Policy{
+		ChatJID:     policy.ChatJID,
+		MaxAgeDays:  policy.MaxAgeDays,
+		MaxMessages: policy.MaxMessages,
+		Source:      domainRetention.SourceChat,
+		UpdatedAt:   &updatedAt,
+	}
+}
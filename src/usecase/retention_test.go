@@ -0,0 +1,210 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	domainMediaStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/mediastorage"
+	domainRetention "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/retention"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/mediastorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	cappedChatJID  = "6281111111111@s.whatsapp.net" // Keeps its newest message
+	expiredChatJID = "6282222222222@s.whatsapp.net" // Keeps 30 days, all its messages are older
+	keptChatJID    = "6283333333333@s.whatsapp.net" // Falls back to the disabled global policy
+
+	sharedMediaSHA  = "aa00000000000000000000000000000000000000000000000000000000000000"
+	cappedMediaSHA  = "bb00000000000000000000000000000000000000000000000000000000000000"
+	expiredMediaSHA = "cc00000000000000000000000000000000000000000000000000000000000000"
+)
+
+type retentionFixture struct {
+	service serviceRetention
+	repo    domainChatStorage.IChatStorageRepository
+	storage domainMediaStorage.IMediaStorage
+}
+
+// newRetentionFixture stores three chats whose media is deduplicated, the shared file is linked to
+// a purged and a kept message of the capped chat and to a message of the kept chat
+func newRetentionFixture(t *testing.T) retentionFixture {
+	days, messages := config.ChatStorageRetentionDays, config.ChatStorageRetentionMessages
+	config.ChatStorageRetentionDays, config.ChatStorageRetentionMessages = 0, 0
+	t.Cleanup(func() { config.ChatStorageRetentionDays, config.ChatStorageRetentionMessages = days, messages })
+
+	f := retentionFixture{repo: newChatStorageRepository(t), storage: mediastorage.NewLocalStorage(t.TempDir())}
+	f.service = serviceRetention{chatStorageRepo: f.repo, mediaStorage: f.storage}
+	ctx := context.Background()
+
+	for sha, path := range map[string]string{sharedMediaSHA: "shared.jpg", cappedMediaSHA: "capped.jpg", expiredMediaSHA: "expired.jpg"} {
+		require.NoError(t, f.storage.Put(ctx, path, strings.NewReader(path), int64(len(path)), "image/jpeg"))
+		require.NoError(t, f.repo.StoreMediaContent(&domainChatStorage.MediaFile{SHA256: sha, Path: path, MimeType: "image/jpeg", Size: int64(len(path))}))
+	}
+
+	now := time.Now()
+	old := now.AddDate(0, 0, -60)
+	chats := []struct {
+		jid      string
+		last     time.Time
+		messages []*domainChatStorage.Message
+		media    map[string]string
+	}{
+		{
+			jid:  cappedChatJID,
+			last: now,
+			messages: []*domainChatStorage.Message{
+				{ID: "C1", Timestamp: now.Add(-3 * time.Hour)},
+				{ID: "C2", Timestamp: now.Add(-2 * time.Hour)},
+				{ID: "C3", Timestamp: now.Add(-time.Hour)},
+			},
+			media: map[string]string{"C1": "shared.jpg", "C2": "capped.jpg", "C3": "shared.jpg"},
+		},
+		{
+			jid:      expiredChatJID,
+			last:     old,
+			messages: []*domainChatStorage.Message{{ID: "E1", Timestamp: old.Add(-time.Hour)}, {ID: "E2", Timestamp: old}},
+			media:    map[string]string{"E2": "expired.jpg"},
+		},
+		{
+			jid:      keptChatJID,
+			last:     old,
+			messages: []*domainChatStorage.Message{{ID: "K1", Timestamp: old}},
+			media:    map[string]string{"K1": "shared.jpg"},
+		},
+	}
+	for _, chat := range chats {
+		require.NoError(t, f.repo.StoreChat(&domainChatStorage.Chat{JID: chat.jid, Name: chat.jid, LastMessageTime: chat.last}))
+		for _, message := range chat.messages {
+			message.ChatJID = chat.jid
+			message.Sender = chat.jid
+			message.Content = message.ID
+			require.NoError(t, f.repo.StoreMessage(message))
+			if path, ok := chat.media[message.ID]; ok {
+				require.NoError(t, f.repo.StoreMediaFile(chat.jid, message.ID, path))
+			}
+		}
+	}
+
+	require.NoError(t, f.repo.StoreRetentionPolicy(&domainChatStorage.RetentionPolicy{ChatJID: cappedChatJID, MaxMessages: 1}))
+	require.NoError(t, f.repo.StoreRetentionPolicy(&domainChatStorage.RetentionPolicy{ChatJID: expiredChatJID, MaxAgeDays: 30}))
+	return f
+}
+
+func (f retentionFixture) messageIDs(t *testing.T, chatJID string) []string {
+	messages, err := f.repo.GetMessages(&domainChatStorage.MessageFilter{ChatJID: chatJID})
+	require.NoError(t, err)
+	ids := make([]string, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+	}
+	return ids
+}
+
+// refCount returns the number of messages linked to the media content, -1 when it is not stored
+func (f retentionFixture) refCount(t *testing.T, sha string) int64 {
+	file, err := f.repo.GetMediaContent(sha)
+	require.NoError(t, err)
+	if file == nil {
+		return -1
+	}
+	return file.RefCount
+}
+
+func (f retentionFixture) fileExists(path string) bool {
+	_, err := f.storage.Stat(context.Background(), path)
+	return err == nil
+}
+
+func chatReports(report domainRetention.Report) map[string]domainRetention.ChatReport {
+	chats := make(map[string]domainRetention.ChatReport, len(report.Chats))
+	for _, chat := range report.Chats {
+		chats[chat.ChatJID] = chat
+	}
+	return chats
+}
+
+func assertRetentionReport(t *testing.T, report domainRetention.Report) {
+	t.Helper()
+	chats := chatReports(report)
+	require.Len(t, chats, 2, "chats without a purge are not reported")
+
+	capped := chats[cappedChatJID]
+	assert.Equal(t, int64(2), capped.MessagesDeleted)
+	assert.Equal(t, 1, capped.MediaDeleted, "the shared file is still linked to kept messages")
+	assert.False(t, capped.ChatDeleted)
+	assert.Equal(t, domainRetention.SourceChat, capped.Policy.Source)
+
+	expired := chats[expiredChatJID]
+	assert.Equal(t, int64(2), expired.MessagesDeleted)
+	assert.Equal(t, 1, expired.MediaDeleted)
+	assert.True(t, expired.ChatDeleted)
+
+	assert.Equal(t, int64(4), report.MessagesDeleted)
+	assert.Equal(t, 2, report.MediaDeleted)
+	assert.Equal(t, int64(len("capped.jpg")+len("expired.jpg")), report.MediaBytes)
+}
+
+func TestRetentionReport(t *testing.T) {
+	f := newRetentionFixture(t)
+
+	report, err := f.service.Report(context.Background())
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assertRetentionReport(t, report)
+
+	assert.Equal(t, []string{"C3", "C2", "C1"}, f.messageIDs(t, cappedChatJID), "a report deletes nothing")
+	assert.Equal(t, []string{"E2", "E1"}, f.messageIDs(t, expiredChatJID))
+	assert.Equal(t, int64(3), f.refCount(t, sharedMediaSHA))
+	assert.Equal(t, int64(1), f.refCount(t, cappedMediaSHA))
+	assert.True(t, f.fileExists("capped.jpg"))
+	assert.True(t, f.fileExists("expired.jpg"))
+}
+
+func TestRetentionPurge(t *testing.T) {
+	f := newRetentionFixture(t)
+
+	report, err := f.service.Purge(context.Background())
+	require.NoError(t, err)
+	assert.False(t, report.DryRun)
+	assertRetentionReport(t, report)
+
+	t.Run("should keep the newest messages of a capped chat", func(t *testing.T) {
+		assert.Equal(t, []string{"C3"}, f.messageIDs(t, cappedChatJID))
+	})
+
+	t.Run("should delete a chat whose messages all expired", func(t *testing.T) {
+		assert.Empty(t, f.messageIDs(t, expiredChatJID))
+		chat, err := f.repo.GetChat(expiredChatJID)
+		require.NoError(t, err)
+		assert.Nil(t, chat)
+	})
+
+	t.Run("should leave chats without a policy alone", func(t *testing.T) {
+		assert.Equal(t, []string{"K1"}, f.messageIDs(t, keptChatJID))
+	})
+
+	t.Run("should decrement the references of shared media", func(t *testing.T) {
+		assert.Equal(t, int64(2), f.refCount(t, sharedMediaSHA), "C3 and K1 still link the file")
+		assert.True(t, f.fileExists("shared.jpg"))
+	})
+
+	t.Run("should delete media without references", func(t *testing.T) {
+		assert.Equal(t, int64(-1), f.refCount(t, cappedMediaSHA))
+		assert.Equal(t, int64(-1), f.refCount(t, expiredMediaSHA))
+		assert.False(t, f.fileExists("capped.jpg"))
+		assert.False(t, f.fileExists("expired.jpg"))
+	})
+
+	t.Run("should find nothing left to purge", func(t *testing.T) {
+		again, err := f.service.Purge(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, again.Chats)
+		assert.Zero(t, again.MediaDeleted)
+	})
+}
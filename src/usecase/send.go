@@ -3,12 +3,17 @@ package usecase
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
@@ -17,34 +22,74 @@ import (
 	domainSend "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/send"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/whatsapp"
 	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/logging"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/metrics"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/tracing"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
-	"github.com/aldinokemal/go-whatsapp-web-multidevice/ui/rest/helpers"
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/validations"
-	"github.com/disintegration/imaging"
 	fiberUtils "github.com/gofiber/fiber/v2/utils"
-	"github.com/sirupsen/logrus"
 	"github.com/valyala/fasthttp"
 	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waCommon"
 	"go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/protobuf/proto"
 )
 
+// mediaUploadMargin is how long a reused upload must stay downloadable, so recipients can still fetch
+// the media after it was sent
+const mediaUploadMargin = time.Hour
+
+// quotedImageMaxDownload bounds the quoted images downloaded to render a missing thumbnail
+const quotedImageMaxDownload = 5 * 1024 * 1024
+
 type serviceSend struct {
 	appService      app.IAppUsecase
 	chatStorageRepo domainChatStorage.IChatStorageRepository
+	// mediaSlots holds a token for every media send in progress, nil when they are not limited
+	mediaSlots chan struct{}
 }
 
 func NewSendService(appService app.IAppUsecase, chatStorageRepo domainChatStorage.IChatStorageRepository) domainSend.ISendUsecase {
-	return &serviceSend{
+	service := &serviceSend{
 		appService:      appService,
 		chatStorageRepo: chatStorageRepo,
 	}
+	if config.WhatsappMediaSendConcurrency > 0 {
+		service.mediaSlots = make(chan struct{}, config.WhatsappMediaSendConcurrency)
+	}
+	return service
+}
+
+// acquireMediaSlot waits until fewer than WhatsappMediaSendConcurrency media sends are in progress, so
+// a burst of uploads cannot exhaust memory or disk. release frees the slot again.
+func (service serviceSend) acquireMediaSlot(ctx context.Context) (release func(), err error) {
+	if service.mediaSlots == nil {
+		return func() {}, nil
+	}
+	_, span := tracing.Start(ctx, "send.wait_media_slot")
+	defer func() { tracing.End(span, err) }()
+
+	select {
+	case service.mediaSlots <- struct{}{}:
+		return func() { <-service.mediaSlots }, nil
+	case <-ctx.Done():
+		return nil, pkgError.ContextError(fmt.Sprintf("gave up waiting for other media sends to finish: %v", ctx.Err()))
+	}
 }
 
 // wrapSendMessage wraps the message sending process with message ID saving
 func (service serviceSend) wrapSendMessage(ctx context.Context, recipient types.JID, msg *waE2E.Message, content string) (whatsmeow.SendResponse, error) {
-	ts, err := whatsapp.GetClient().SendMessage(ctx, recipient, msg)
+	msgType := utils.MessageType(msg)
+	sendCtx, span := tracing.Start(ctx, "whatsapp.send_message",
+		attribute.String("message.type", msgType),
+		attribute.String("recipient.server", recipient.Server),
+	)
+	started := time.Now()
+	ts, err := whatsapp.GetClient().SendMessage(sendCtx, recipient, msg)
+	metrics.ObserveSend(msgType, started, err)
+	tracing.End(span, err)
 	if err != nil {
 		return whatsmeow.SendResponse{}, err
 	}
@@ -54,23 +99,112 @@ func (service serviceSend) wrapSendMessage(ctx context.Context, recipient types.
 	if whatsapp.GetClient().Store.ID != nil {
 		senderJID = whatsapp.GetClient().Store.ID.String()
 	}
+	service.storeSentMessage(ctx, ts, senderJID, recipient, content)
 
-	// Store message asynchronously with timeout
-	// Use a goroutine to avoid blocking the send operation
+	return ts, nil
+}
+
+// sentMessageStores tracks sent messages still being stored so shutdown can wait for them
+var sentMessageStores sync.WaitGroup
+
+// WaitForSentMessages blocks until the sent messages being stored are written or ctx is done
+func WaitForSentMessages(ctx context.Context) error {
+	return utils.WaitGroupContext(ctx, &sentMessageStores)
+}
+
+// storeSentMessage stores a sent message in the background so the send does not wait for the database
+func (service serviceSend) storeSentMessage(ctx context.Context, ts whatsmeow.SendResponse, senderJID string, recipient types.JID, content string) {
+	sentMessageStores.Add(1)
 	go func() {
-		storeCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
+		defer sentMessageStores.Done()
 
+		// Keep the request's trace and request ID, but not its cancellation
+		storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 2*time.Second)
+		defer cancel()
 		if err := service.chatStorageRepo.StoreSentMessageWithContext(storeCtx, ts.ID, senderJID, recipient.String(), content, ts.Timestamp); err != nil {
 			if errors.Is(err, context.DeadlineExceeded) {
-				logrus.Warn("Timeout storing sent message")
+				logging.FromContext(ctx).Warn("Timeout storing sent message")
 			} else {
-				logrus.Warnf("Failed to store sent message: %v", err)
+				logging.FromContext(ctx).Warnf("Failed to store sent message: %v", err)
 			}
 		}
 	}()
+}
 
-	return ts, nil
+// validateRecipient resolves the recipient JID, checking it is on WhatsApp when account validation is enabled
+func (service serviceSend) validateRecipient(ctx context.Context, phone string) (types.JID, error) {
+	_, span := tracing.Start(ctx, "send.validate_recipient")
+	recipient, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), phone)
+	tracing.End(span, err)
+	return recipient, err
+}
+
+// downloadMedia downloads media from a URL for sending
+func downloadMedia(ctx context.Context, kind string, url string, download func(string) ([]byte, string, error)) ([]byte, string, error) {
+	_, span := tracing.Start(ctx, "send.download_"+kind)
+	data, fileName, err := download(url)
+	span.SetAttributes(attribute.Int("media.size", len(data)))
+	tracing.End(span, err)
+	return data, fileName, err
+}
+
+// downloadMediaToFile downloads media from a URL into a temporary file in PathSendItems for sending,
+// the caller removes the returned path
+func downloadMediaToFile(ctx context.Context, kind string, url string, download func(url, dir string) (string, string, error)) (string, error) {
+	_, span := tracing.Start(ctx, "send.download_"+kind)
+	mediaPath, _, err := download(url, config.PathSendItems)
+	if err == nil {
+		if info, errStat := os.Stat(mediaPath); errStat == nil {
+			span.SetAttributes(attribute.Int64("media.size", info.Size()))
+		}
+	}
+	tracing.End(span, err)
+	return mediaPath, err
+}
+
+// sendItemPath returns a scratch file in PathSendItems for media being sent. It stays on local disk
+// whatever the media storage: ffmpeg reads and writes it by path, and it is removed once the message
+// is sent, so it never holds kept media.
+func sendItemPath(name string) string {
+	return filepath.Join(config.PathSendItems, name)
+}
+
+// prepareImage runs an image through the image pipeline of utils.PrepareImage
+func prepareImage(ctx context.Context, source io.ReadSeeker, opts utils.ImageOptions) (prepared *utils.PreparedImage, err error) {
+	_, span := tracing.Start(ctx, "send.prepare_image")
+	defer func() { tracing.End(span, err) }()
+
+	prepared, err = utils.PrepareImage(source, opts)
+	if err == nil {
+		span.SetAttributes(attribute.Int("media.size", len(prepared.JPEG)))
+	}
+	return prepared, err
+}
+
+// documentThumbnail renders the preview of a document, a failure only leaves the document without one.
+// Images larger than an image send may be are not decoded.
+func documentThumbnail(ctx context.Context, file io.ReadSeeker, mimeType string, size int64) *utils.DocumentThumbnail {
+	if strings.HasPrefix(mimeType, "image/") && size > config.WhatsappSettingMaxImageSize {
+		return nil
+	}
+	thumbnail, err := utils.GenerateDocumentThumbnail(file, mimeType)
+	if err != nil {
+		logging.FromContext(ctx).Debugf("Failed to create document thumbnail: %v, continuing without thumbnail", err)
+	}
+	return thumbnail
+}
+
+// detectContentType sniffs the MIME type of media from its first bytes and rewinds it for the upload
+func detectContentType(media io.ReadSeeker) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(media, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	if _, err := media.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
 }
 
 func (service serviceSend) SendText(ctx context.Context, request domainSend.MessageRequest) (response domainSend.GenericResponse, err error) {
@@ -78,7 +212,7 @@ func (service serviceSend) SendText(ctx context.Context, request domainSend.Mess
 	if err != nil {
 		return response, err
 	}
-	dataWaRecipient, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.BaseRequest.Phone)
+	dataWaRecipient, err := service.validateRecipient(ctx, request.BaseRequest.Phone)
 	if err != nil {
 		return response, err
 	}
@@ -110,52 +244,7 @@ func (service serviceSend) SendText(ctx context.Context, request domainSend.Mess
 	}
 
 	// Reply message
-	if request.ReplyMessageID != nil && *request.ReplyMessageID != "" {
-		message, err := service.chatStorageRepo.GetMessageByID(*request.ReplyMessageID)
-		if err != nil {
-			logrus.Warnf("Error retrieving reply message ID %s: %v, continuing without reply context", *request.ReplyMessageID, err)
-		} else if message != nil { // Only set reply context if we found the message
-			// Ensure we use a full JID (user@server) for the Participant field
-			// Use the sender JID from storage as-is. Modern storage should already provide
-			// fully-qualified JIDs (e.g., user@s.whatsapp.net or group@g.us). Avoid mutating
-			// the JID here to prevent corrupting valid group or special JIDs.
-			participantJID := message.Sender
-
-			// Build base ContextInfo with reply details
-			ctxInfo := &waE2E.ContextInfo{
-				StanzaID:    request.ReplyMessageID,
-				Participant: proto.String(participantJID),
-				QuotedMessage: &waE2E.Message{
-					Conversation: proto.String(message.Content),
-				},
-			}
-
-			// Preserve forwarding flag if set
-			if request.BaseRequest.IsForwarded {
-				ctxInfo.IsForwarded = proto.Bool(true)
-				ctxInfo.ForwardingScore = proto.Uint32(100)
-			}
-
-			// Preserve disappearing message duration if provided
-			if request.BaseRequest.Duration != nil && *request.BaseRequest.Duration > 0 {
-				ctxInfo.Expiration = proto.Uint32(uint32(*request.BaseRequest.Duration))
-			} else {
-				ctxInfo.Expiration = proto.Uint32(service.getDefaultEphemeralExpiration(participantJID))
-			}
-
-			// Preserve mentions
-			if len(parsedMentions) > 0 {
-				ctxInfo.MentionedJID = parsedMentions
-			}
-
-			msg.ExtendedTextMessage = &waE2E.ExtendedTextMessage{
-				Text:        proto.String(request.Message),
-				ContextInfo: ctxInfo,
-			}
-		} else {
-			logrus.Warnf("Reply message ID %s not found in storage, continuing without reply context", *request.ReplyMessageID)
-		}
-	}
+	msg.ExtendedTextMessage.ContextInfo = service.withReplyContext(ctx, msg.ExtendedTextMessage.ContextInfo, request.BaseRequest.ReplyMessageID)
 
 	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, request.Message)
 	if err != nil {
@@ -172,125 +261,59 @@ func (service serviceSend) SendImage(ctx context.Context, request domainSend.Ima
 	if err != nil {
 		return response, err
 	}
-	dataWaRecipient, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.Phone)
+	dataWaRecipient, err := service.validateRecipient(ctx, request.Phone)
 	if err != nil {
 		return response, err
 	}
+	release, err := service.acquireMediaSlot(ctx)
+	if err != nil {
+		return response, err
+	}
+	defer release()
 
-	var (
-		imagePath      string
-		imageThumbnail string
-		imageName      string
-		deletedItems   []string
-		oriImagePath   string
-	)
-
+	// Read the image from the URL or the uploaded file
+	var source io.ReadSeeker
 	if request.ImageURL != nil && *request.ImageURL != "" {
-		// Download image from URL
-		imageData, fileName, err := utils.DownloadImageFromURL(*request.ImageURL)
+		imageData, _, err := downloadMedia(ctx, "image", *request.ImageURL, utils.DownloadImageFromURL)
 		if err != nil {
 			return response, pkgError.InternalServerError(fmt.Sprintf("failed to download image from URL %v", err))
 		}
-
-		// Check if the downloaded image is WebP and convert to PNG if needed
-		mimeType := http.DetectContentType(imageData)
-		if mimeType == "image/webp" {
-			// Convert WebP to PNG
-			webpImage, err := imaging.Decode(bytes.NewReader(imageData))
-			if err != nil {
-				return response, pkgError.InternalServerError(fmt.Sprintf("failed to decode WebP image %v", err))
-			}
-
-			// Change file extension to PNG
-			if strings.HasSuffix(strings.ToLower(fileName), ".webp") {
-				fileName = fileName[:len(fileName)-5] + ".png"
-			} else {
-				fileName = fileName + ".png"
-			}
-
-			// Convert to PNG format
-			var pngBuffer bytes.Buffer
-			err = imaging.Encode(&pngBuffer, webpImage, imaging.PNG)
-			if err != nil {
-				return response, pkgError.InternalServerError(fmt.Sprintf("failed to convert WebP to PNG %v", err))
-			}
-			imageData = pngBuffer.Bytes()
-		}
-
-		oriImagePath = fmt.Sprintf("%s/%s", config.PathSendItems, fileName)
-		imageName = fileName
-		err = os.WriteFile(oriImagePath, imageData, 0644)
-		if err != nil {
-			return response, pkgError.InternalServerError(fmt.Sprintf("failed to save downloaded image %v", err))
-		}
+		source = bytes.NewReader(imageData)
 	} else if request.Image != nil {
-		// Save image to server
-		oriImagePath = fmt.Sprintf("%s/%s", config.PathSendItems, request.Image.Filename)
-		err = fasthttp.SaveMultipartFile(request.Image, oriImagePath)
+		file, err := request.Image.Open()
 		if err != nil {
-			return response, err
+			return response, pkgError.InternalServerError(fmt.Sprintf("failed to open image %v", err))
 		}
-		imageName = request.Image.Filename
+		defer file.Close()
+		source = file
 	}
-	deletedItems = append(deletedItems, oriImagePath)
 
-	/* Generate thumbnail with smalled image size */
-	srcImage, err := imaging.Open(oriImagePath)
+	// Turn it upright, strip its metadata and fit it into the size limit, compress also scales it down
+	prepared, err := prepareImage(ctx, source, utils.SendImageOptions(request.Compress))
 	if err != nil {
-		return response, pkgError.InternalServerError(fmt.Sprintf("Failed to open image file '%s' for thumbnail generation: %v. Possible causes: file not found, unsupported format, or permission denied.", oriImagePath, err))
-	}
-
-	// Resize Thumbnail
-	resizedImage := imaging.Resize(srcImage, 100, 0, imaging.Lanczos)
-	imageThumbnail = fmt.Sprintf("%s/thumbnails-%s", config.PathSendItems, imageName)
-	if err = imaging.Save(resizedImage, imageThumbnail); err != nil {
-		return response, pkgError.InternalServerError(fmt.Sprintf("failed to save thumbnail %v", err))
-	}
-	deletedItems = append(deletedItems, imageThumbnail)
-
-	if request.Compress {
-		// Resize image
-		openImageBuffer, err := imaging.Open(oriImagePath)
-		if err != nil {
-			return response, pkgError.InternalServerError(fmt.Sprintf("Failed to open image file '%s' for compression: %v. Possible causes: file not found, unsupported format, or permission denied.", oriImagePath, err))
-		}
-		newImage := imaging.Resize(openImageBuffer, 600, 0, imaging.Lanczos)
-		newImagePath := fmt.Sprintf("%s/new-%s", config.PathSendItems, imageName)
-		if err = imaging.Save(newImage, newImagePath); err != nil {
-			return response, pkgError.InternalServerError(fmt.Sprintf("failed to save image %v", err))
-		}
-		deletedItems = append(deletedItems, newImagePath)
-		imagePath = newImagePath
-	} else {
-		imagePath = oriImagePath
+		return response, pkgError.InternalServerError(fmt.Sprintf("failed to process image %v", err))
 	}
 
 	// Send to WA server
 	dataWaCaption := request.Caption
-	dataWaImage, err := os.ReadFile(imagePath)
-	if err != nil {
-		return response, err
-	}
-	uploadedImage, err := service.uploadMedia(ctx, whatsmeow.MediaImage, dataWaImage, dataWaRecipient)
+	uploadedImage, err := service.uploadMedia(ctx, whatsmeow.MediaImage, bytes.NewReader(prepared.JPEG), dataWaRecipient)
 	if err != nil {
 		fmt.Printf("failed to upload file: %v", err)
 		return response, err
 	}
-	dataWaThumbnail, err := os.ReadFile(imageThumbnail)
-	if err != nil {
-		return response, pkgError.InternalServerError(fmt.Sprintf("failed to read thumbnail %v", err))
-	}
 
 	msg := &waE2E.Message{ImageMessage: &waE2E.ImageMessage{
-		JPEGThumbnail: dataWaThumbnail,
+		JPEGThumbnail: prepared.Thumbnail,
 		Caption:       proto.String(dataWaCaption),
 		URL:           proto.String(uploadedImage.URL),
 		DirectPath:    proto.String(uploadedImage.DirectPath),
 		MediaKey:      uploadedImage.MediaKey,
-		Mimetype:      proto.String(http.DetectContentType(dataWaImage)),
+		Mimetype:      proto.String("image/jpeg"),
 		FileEncSHA256: uploadedImage.FileEncSHA256,
 		FileSHA256:    uploadedImage.FileSHA256,
-		FileLength:    proto.Uint64(uint64(len(dataWaImage))),
+		FileLength:    proto.Uint64(uploadedImage.FileLength),
+		Width:         proto.Uint32(uint32(prepared.Width)),
+		Height:        proto.Uint32(uint32(prepared.Height)),
 		ViewOnce:      proto.Bool(request.ViewOnce),
 	}}
 
@@ -309,17 +332,13 @@ func (service serviceSend) SendImage(ctx context.Context, request domainSend.Ima
 		msg.ImageMessage.ContextInfo.Expiration = proto.Uint32(uint32(*request.BaseRequest.Duration))
 	}
 
+	msg.ImageMessage.ContextInfo = service.withReplyContext(ctx, msg.ImageMessage.ContextInfo, request.BaseRequest.ReplyMessageID)
+
 	caption := "🖼️ Image"
 	if request.Caption != "" {
 		caption = "🖼️ " + request.Caption
 	}
 	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, caption)
-	go func() {
-		errDelete := utils.RemoveFile(0, deletedItems...)
-		if errDelete != nil {
-			fmt.Println("error when deleting picture: ", errDelete)
-		}
-	}()
 	if err != nil {
 		return response, err
 	}
@@ -334,16 +353,30 @@ func (service serviceSend) SendFile(ctx context.Context, request domainSend.File
 	if err != nil {
 		return response, err
 	}
-	dataWaRecipient, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.BaseRequest.Phone)
+	dataWaRecipient, err := service.validateRecipient(ctx, request.BaseRequest.Phone)
 	if err != nil {
 		return response, err
 	}
+	release, err := service.acquireMediaSlot(ctx)
+	if err != nil {
+		return response, err
+	}
+	defer release()
 
-	fileBytes := helpers.MultipartFormFileHeaderToBytes(request.File)
-	fileMimeType := http.DetectContentType(fileBytes)
+	// The upload reads the file from the multipart form, large files were already spooled to disk
+	file, err := request.File.Open()
+	if err != nil {
+		return response, pkgError.InternalServerError(fmt.Sprintf("failed to open file %v", err))
+	}
+	defer file.Close()
+	fileMimeType, err := detectContentType(file)
+	if err != nil {
+		return response, pkgError.InternalServerError(fmt.Sprintf("failed to read file %v", err))
+	}
+	thumbnail := documentThumbnail(ctx, file, fileMimeType, request.File.Size)
 
 	// Send to WA server
-	uploadedFile, err := service.uploadMedia(ctx, whatsmeow.MediaDocument, fileBytes, dataWaRecipient)
+	uploadedFile, err := service.uploadMedia(ctx, whatsmeow.MediaDocument, file, dataWaRecipient)
 	if err != nil {
 		fmt.Printf("Failed to upload file: %v", err)
 		return response, err
@@ -361,6 +394,11 @@ func (service serviceSend) SendFile(ctx context.Context, request domainSend.File
 		DirectPath:    proto.String(uploadedFile.DirectPath),
 		Caption:       proto.String(request.Caption),
 	}}
+	if thumbnail != nil {
+		msg.DocumentMessage.JPEGThumbnail = thumbnail.JPEG
+		msg.DocumentMessage.ThumbnailWidth = proto.Uint32(uint32(thumbnail.Width))
+		msg.DocumentMessage.ThumbnailHeight = proto.Uint32(uint32(thumbnail.Height))
+	}
 
 	if request.BaseRequest.IsForwarded {
 		msg.DocumentMessage.ContextInfo = &waE2E.ContextInfo{
@@ -376,6 +414,8 @@ func (service serviceSend) SendFile(ctx context.Context, request domainSend.File
 		msg.DocumentMessage.ContextInfo.Expiration = proto.Uint32(uint32(*request.BaseRequest.Duration))
 	}
 
+	msg.DocumentMessage.ContextInfo = service.withReplyContext(ctx, msg.DocumentMessage.ContextInfo, request.BaseRequest.ReplyMessageID)
+
 	caption := "📄 Document"
 	if request.Caption != "" {
 		caption = "📄 " + request.Caption
@@ -395,15 +435,20 @@ func (service serviceSend) SendVideo(ctx context.Context, request domainSend.Vid
 	if err != nil {
 		return response, err
 	}
-	dataWaRecipient, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.BaseRequest.Phone)
+	dataWaRecipient, err := service.validateRecipient(ctx, request.BaseRequest.Phone)
 	if err != nil {
 		return response, err
 	}
 
+	release, err := service.acquireMediaSlot(ctx)
+	if err != nil {
+		return response, err
+	}
+	defer release()
+
 	var (
-		videoPath      string
-		videoThumbnail string
-		deletedItems   []string
+		videoPath    string
+		deletedItems []string
 	)
 
 	// Ensure temporary files are always removed, even on early returns
@@ -420,19 +465,15 @@ func (service serviceSend) SendVideo(ctx context.Context, request domainSend.Vid
 
 	// Determine source of video (URL or uploaded file)
 	if request.VideoURL != nil && *request.VideoURL != "" {
-		// Download video bytes
-		videoBytes, fileName, errDownload := utils.DownloadVideoFromURL(*request.VideoURL)
+		// Download the video straight into a temporary file
+		var errDownload error
+		oriVideoPath, errDownload = downloadMediaToFile(ctx, "video", *request.VideoURL, utils.DownloadVideoToFile)
 		if errDownload != nil {
 			return response, pkgError.InternalServerError(fmt.Sprintf("failed to download video from URL %v", errDownload))
 		}
-		// Build file path to save the downloaded video temporarily
-		oriVideoPath = fmt.Sprintf("%s/%s", config.PathSendItems, generateUUID+fileName)
-		if errWrite := os.WriteFile(oriVideoPath, videoBytes, 0644); errWrite != nil {
-			return response, pkgError.InternalServerError(fmt.Sprintf("failed to store downloaded video in server %v", errWrite))
-		}
 	} else if request.Video != nil {
 		// Save uploaded video to server
-		oriVideoPath = fmt.Sprintf("%s/%s", config.PathSendItems, generateUUID+request.Video.Filename)
+		oriVideoPath = sendItemPath(generateUUID + request.Video.Filename)
 		err = fasthttp.SaveMultipartFile(request.Video, oriVideoPath)
 		if err != nil {
 			return response, pkgError.InternalServerError(fmt.Sprintf("failed to store video in server %v", err))
@@ -449,54 +490,28 @@ func (service serviceSend) SendVideo(ctx context.Context, request domainSend.Vid
 	}
 
 	// Generate thumbnail using ffmpeg
-	thumbnailVideoPath := fmt.Sprintf("%s/%s", config.PathSendItems, generateUUID+".png")
-	cmdThumbnail := exec.Command("ffmpeg", "-i", oriVideoPath, "-ss", "00:00:01.000", "-vframes", "1", thumbnailVideoPath)
-	err = cmdThumbnail.Run()
-	if err != nil {
+	thumbnailVideoPath := sendItemPath(generateUUID + ".png")
+	if err = extractVideoFrame(ctx, oriVideoPath, thumbnailVideoPath); err != nil {
 		return response, pkgError.InternalServerError(fmt.Sprintf("failed to create thumbnail %v", err))
 	}
 
+	deletedItems = append(deletedItems, thumbnailVideoPath)
+
 	// Resize Thumbnail
-	srcImage, err := imaging.Open(thumbnailVideoPath)
+	frame, err := os.ReadFile(thumbnailVideoPath)
 	if err != nil {
 		return response, pkgError.InternalServerError(fmt.Sprintf("Failed to open generated video thumbnail image '%s': %v. Possible causes: file not found, unsupported format, or permission denied.", thumbnailVideoPath, err))
 	}
-	resizedImage := imaging.Resize(srcImage, 100, 0, imaging.Lanczos)
-	thumbnailResizeVideoPath := fmt.Sprintf("%s/thumbnails-%s", config.PathSendItems, generateUUID+".png")
-	if err = imaging.Save(resizedImage, thumbnailResizeVideoPath); err != nil {
-		return response, pkgError.InternalServerError(fmt.Sprintf("failed to save thumbnail %v", err))
+	dataWaThumbnail, err := utils.GenerateJPEGThumbnail(frame, utils.ThumbnailDimension)
+	if err != nil {
+		return response, pkgError.InternalServerError(fmt.Sprintf("failed to create thumbnail %v", err))
 	}
 
-	deletedItems = append(deletedItems, thumbnailVideoPath)
-	deletedItems = append(deletedItems, thumbnailResizeVideoPath)
-	videoThumbnail = thumbnailResizeVideoPath
-
 	// Compress if requested
 	if request.Compress {
-		compresVideoPath := fmt.Sprintf("%s/%s", config.PathSendItems, generateUUID+".mp4")
-
-		// Use proper compression settings to reduce file size
-		// -crf 28: Constant Rate Factor (18-28 is good range, higher = smaller file)
-		// -preset medium: Balance between encoding speed and compression efficiency
-		// -c:v libx264: Use H.264 codec for video
-		// -c:a aac: Use AAC codec for audio
-		// -movflags +faststart: Optimize for web streaming
-		// -vf scale=720:-2: Scale video to max width 720px, maintain aspect ratio
-		cmdCompress := exec.Command("ffmpeg", "-i", oriVideoPath,
-			"-c:v", "libx264",
-			"-crf", "28",
-			"-preset", "fast",
-			"-vf", "scale=720:-2",
-			"-c:a", "aac",
-			"-b:a", "128k",
-			"-movflags", "+faststart",
-			"-y", // Overwrite output file if it exists
-			compresVideoPath)
-
-		// Capture both stdout and stderr for better error reporting
-		output, err := cmdCompress.CombinedOutput()
-		if err != nil {
-			logrus.Errorf("ffmpeg compression failed: %v, output: %s", err, string(output))
+		compresVideoPath := sendItemPath(generateUUID + ".mp4")
+
+		if err := compressVideo(ctx, oriVideoPath, compresVideoPath); err != nil {
 			return response, pkgError.InternalServerError(fmt.Sprintf("failed to compress video: %v", err))
 		}
 
@@ -508,22 +523,23 @@ func (service serviceSend) SendVideo(ctx context.Context, request domainSend.Vid
 	deletedItems = append(deletedItems, oriVideoPath)
 
 	//Send to WA server
-	dataWaVideo, err := os.ReadFile(videoPath)
+	dataWaVideo, err := os.Open(videoPath)
 	if err != nil {
 		return response, err
 	}
-	uploaded, err := service.uploadMedia(ctx, whatsmeow.MediaVideo, dataWaVideo, dataWaRecipient)
+	defer dataWaVideo.Close()
+	videoMimeType, err := detectContentType(dataWaVideo)
 	if err != nil {
-		return response, pkgError.InternalServerError(fmt.Sprintf("Failed to upload file: %v", err))
+		return response, pkgError.InternalServerError(fmt.Sprintf("failed to read video %v", err))
 	}
-	dataWaThumbnail, err := os.ReadFile(videoThumbnail)
+	uploaded, err := service.uploadMedia(ctx, whatsmeow.MediaVideo, dataWaVideo, dataWaRecipient)
 	if err != nil {
-		return response, err
+		return response, pkgError.InternalServerError(fmt.Sprintf("Failed to upload file: %v", err))
 	}
 
 	msg := &waE2E.Message{VideoMessage: &waE2E.VideoMessage{
 		URL:                 proto.String(uploaded.URL),
-		Mimetype:            proto.String(http.DetectContentType(dataWaVideo)),
+		Mimetype:            proto.String(videoMimeType),
 		Caption:             proto.String(request.Caption),
 		FileLength:          proto.Uint64(uploaded.FileLength),
 		FileSHA256:          uploaded.FileSHA256,
@@ -551,6 +567,8 @@ func (service serviceSend) SendVideo(ctx context.Context, request domainSend.Vid
 		msg.VideoMessage.ContextInfo.Expiration = proto.Uint32(uint32(*request.BaseRequest.Duration))
 	}
 
+	msg.VideoMessage.ContextInfo = service.withReplyContext(ctx, msg.VideoMessage.ContextInfo, request.BaseRequest.ReplyMessageID)
+
 	caption := "🎥 Video"
 	if request.Caption != "" {
 		caption = "🎥 " + request.Caption
@@ -570,7 +588,7 @@ func (service serviceSend) SendContact(ctx context.Context, request domainSend.C
 	if err != nil {
 		return response, err
 	}
-	dataWaRecipient, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.BaseRequest.Phone)
+	dataWaRecipient, err := service.validateRecipient(ctx, request.BaseRequest.Phone)
 	if err != nil {
 		return response, err
 	}
@@ -596,6 +614,8 @@ func (service serviceSend) SendContact(ctx context.Context, request domainSend.C
 		msg.ContactMessage.ContextInfo.Expiration = proto.Uint32(uint32(*request.BaseRequest.Duration))
 	}
 
+	msg.ContactMessage.ContextInfo = service.withReplyContext(ctx, msg.ContactMessage.ContextInfo, request.BaseRequest.ReplyMessageID)
+
 	content := "👤 " + request.ContactName
 
 	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, content)
@@ -613,7 +633,7 @@ func (service serviceSend) SendLink(ctx context.Context, request domainSend.Link
 	if err != nil {
 		return response, err
 	}
-	dataWaRecipient, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.BaseRequest.Phone)
+	dataWaRecipient, err := service.validateRecipient(ctx, request.BaseRequest.Phone)
 	if err != nil {
 		return response, err
 	}
@@ -625,9 +645,9 @@ func (service serviceSend) SendLink(ctx context.Context, request domainSend.Link
 
 	// Log image dimensions if available, otherwise note it's a square image or dimensions not available
 	if metadata.Width != nil && metadata.Height != nil {
-		logrus.Debugf("Image dimensions: %dx%d", *metadata.Width, *metadata.Height)
+		logging.FromContext(ctx).Debugf("Image dimensions: %dx%d", *metadata.Width, *metadata.Height)
 	} else {
-		logrus.Debugf("Image dimensions: Square image or dimensions not available")
+		logging.FromContext(ctx).Debugf("Image dimensions: Square image or dimensions not available")
 	}
 
 	// Create the message
@@ -653,9 +673,11 @@ func (service serviceSend) SendLink(ctx context.Context, request domainSend.Link
 		msg.ExtendedTextMessage.ContextInfo.Expiration = proto.Uint32(uint32(*request.BaseRequest.Duration))
 	}
 
-	// If we have a thumbnail image, upload it to WhatsApp's servers
-	if len(metadata.ImageThumb) > 0 && metadata.Height != nil && metadata.Width != nil {
-		uploadedThumb, err := service.uploadMedia(ctx, whatsmeow.MediaLinkThumbnail, metadata.ImageThumb, dataWaRecipient)
+	msg.ExtendedTextMessage.ContextInfo = service.withReplyContext(ctx, msg.ExtendedTextMessage.ContextInfo, request.BaseRequest.ReplyMessageID)
+
+	// If we have a preview image, upload it to WhatsApp's servers as the high quality thumbnail
+	if len(metadata.ImagePreview) > 0 && metadata.Height != nil && metadata.Width != nil {
+		uploadedThumb, err := service.uploadMedia(ctx, whatsmeow.MediaLinkThumbnail, bytes.NewReader(metadata.ImagePreview), dataWaRecipient)
 		if err == nil {
 			// Update the message with the uploaded thumbnail information
 			msg.ExtendedTextMessage.ThumbnailDirectPath = proto.String(uploadedThumb.DirectPath)
@@ -665,7 +687,7 @@ func (service serviceSend) SendLink(ctx context.Context, request domainSend.Link
 			msg.ExtendedTextMessage.ThumbnailHeight = metadata.Height
 			msg.ExtendedTextMessage.ThumbnailWidth = metadata.Width
 		} else {
-			logrus.Warnf("Failed to upload thumbnail: %v, continue without uploaded thumbnail", err)
+			logging.FromContext(ctx).Warnf("Failed to upload thumbnail: %v, continue without uploaded thumbnail", err)
 		}
 	}
 
@@ -688,7 +710,7 @@ func (service serviceSend) SendLocation(ctx context.Context, request domainSend.
 	if err != nil {
 		return response, err
 	}
-	dataWaRecipient, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.BaseRequest.Phone)
+	dataWaRecipient, err := service.validateRecipient(ctx, request.BaseRequest.Phone)
 	if err != nil {
 		return response, err
 	}
@@ -715,6 +737,8 @@ func (service serviceSend) SendLocation(ctx context.Context, request domainSend.
 		msg.LocationMessage.ContextInfo.Expiration = proto.Uint32(uint32(*request.BaseRequest.Duration))
 	}
 
+	msg.LocationMessage.ContextInfo = service.withReplyContext(ctx, msg.LocationMessage.ContextInfo, request.BaseRequest.ReplyMessageID)
+
 	content := "📍 " + request.Latitude + ", " + request.Longitude
 
 	// Send WhatsApp Message Proto
@@ -735,30 +759,45 @@ func (service serviceSend) SendAudio(ctx context.Context, request domainSend.Aud
 		return response, err
 	}
 
-	dataWaRecipient, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.BaseRequest.Phone)
+	dataWaRecipient, err := service.validateRecipient(ctx, request.BaseRequest.Phone)
 	if err != nil {
 		return response, err
 	}
 
-	var (
-		audioBytes    []byte
-		audioMimeType string
-	)
+	release, err := service.acquireMediaSlot(ctx)
+	if err != nil {
+		return response, err
+	}
+	defer release()
 
-	// Handle audio from URL or file
+	// Handle audio from URL or file, both are read from disk by the upload
+	var audio io.ReadSeekCloser
 	if request.AudioURL != nil && *request.AudioURL != "" {
-		audioBytes, _, err = utils.DownloadAudioFromURL(*request.AudioURL)
+		audioPath, err := downloadMediaToFile(ctx, "audio", *request.AudioURL, utils.DownloadAudioToFile)
 		if err != nil {
 			return response, pkgError.InternalServerError(fmt.Sprintf("failed to download audio from URL %v", err))
 		}
-		audioMimeType = http.DetectContentType(audioBytes)
+		defer os.Remove(audioPath)
+		audio, err = os.Open(audioPath)
+		if err != nil {
+			return response, pkgError.InternalServerError(fmt.Sprintf("failed to open downloaded audio %v", err))
+		}
 	} else if request.Audio != nil {
-		audioBytes = helpers.MultipartFormFileHeaderToBytes(request.Audio)
-		audioMimeType = http.DetectContentType(audioBytes)
+		audio, err = request.Audio.Open()
+		if err != nil {
+			return response, pkgError.InternalServerError(fmt.Sprintf("failed to open audio %v", err))
+		}
+	} else {
+		return response, pkgError.ValidationError("either Audio or AudioURL must be provided")
+	}
+	defer audio.Close()
+	audioMimeType, err := detectContentType(audio)
+	if err != nil {
+		return response, pkgError.InternalServerError(fmt.Sprintf("failed to read audio %v", err))
 	}
 
 	// upload to WhatsApp servers
-	audioUploaded, err := service.uploadMedia(ctx, whatsmeow.MediaAudio, audioBytes, dataWaRecipient)
+	audioUploaded, err := service.uploadMedia(ctx, whatsmeow.MediaAudio, audio, dataWaRecipient)
 	if err != nil {
 		err = pkgError.WaUploadMediaError(fmt.Sprintf("Failed to upload audio: %v", err))
 		return response, err
@@ -790,6 +829,8 @@ func (service serviceSend) SendAudio(ctx context.Context, request domainSend.Aud
 		msg.AudioMessage.ContextInfo.Expiration = proto.Uint32(uint32(*request.BaseRequest.Duration))
 	}
 
+	msg.AudioMessage.ContextInfo = service.withReplyContext(ctx, msg.AudioMessage.ContextInfo, request.BaseRequest.ReplyMessageID)
+
 	content := "🎵 Audio"
 
 	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, content)
@@ -802,12 +843,303 @@ func (service serviceSend) SendAudio(ctx context.Context, request domainSend.Aud
 	return response, nil
 }
 
+// albumMedia is an album item that has been uploaded and is ready to be sent
+type albumMedia struct {
+	mediaType string
+	message   *waE2E.Message
+	content   string
+	err       error
+}
+
+func (service serviceSend) SendAlbum(ctx context.Context, request domainSend.AlbumRequest) (response domainSend.AlbumResponse, err error) {
+	err = validations.ValidateSendAlbum(ctx, request)
+	if err != nil {
+		return response, err
+	}
+	dataWaRecipient, err := service.validateRecipient(ctx, request.BaseRequest.Phone)
+	if err != nil {
+		return response, err
+	}
+
+	// Prepare and upload every item concurrently, results keep the request order
+	prepared := make([]albumMedia, len(request.Items))
+	var wg sync.WaitGroup
+	for i, item := range request.Items {
+		wg.Add(1)
+		go func(i int, item domainSend.AlbumItem) {
+			defer wg.Done()
+			prepared[i] = service.prepareAlbumItem(ctx, item, request.Compress, dataWaRecipient)
+		}(i, item)
+	}
+	wg.Wait()
+
+	return service.deliverAlbum(ctx, dataWaRecipient, request, prepared, service.wrapSendMessage)
+}
+
+// messageSender sends a message and stores it, wrapSendMessage outside of tests
+type messageSender func(ctx context.Context, recipient types.JID, msg *waE2E.Message, content string) (whatsmeow.SendResponse, error)
+
+// deliverAlbum sends the album message followed by every prepared item. An album with items that
+// were not delivered is reported as partial, with the error of each failed item.
+func (service serviceSend) deliverAlbum(ctx context.Context, recipient types.JID, request domainSend.AlbumRequest, prepared []albumMedia, send messageSender) (response domainSend.AlbumResponse, err error) {
+	var (
+		imageCount uint32
+		videoCount uint32
+		firstErr   error
+	)
+	response.Items = make([]domainSend.AlbumItemResponse, len(prepared))
+	for i, media := range prepared {
+		response.Items[i] = domainSend.AlbumItemResponse{Index: i, MediaType: media.mediaType}
+		if media.err != nil {
+			response.Items[i].Error = media.err.Error()
+			if firstErr == nil {
+				firstErr = media.err
+			}
+			continue
+		}
+		if media.mediaType == "video" {
+			videoCount++
+		} else {
+			imageCount++
+		}
+	}
+
+	if imageCount+videoCount == 0 {
+		return response, pkgError.WaUploadMediaError(fmt.Sprintf("failed to prepare album items: %v", firstErr))
+	}
+
+	// The album message announces how many items follow, every item then points back to it
+	albumMsg := &waE2E.Message{AlbumMessage: &waE2E.AlbumMessage{
+		ExpectedImageCount: proto.Uint32(imageCount),
+		ExpectedVideoCount: proto.Uint32(videoCount),
+	}}
+	albumResp, err := send(ctx, recipient, albumMsg, "🗂️ Album")
+	if err != nil {
+		return response, err
+	}
+	response.AlbumID = albumResp.ID
+	parentKey := &waCommon.MessageKey{
+		RemoteJID: proto.String(recipient.String()),
+		FromMe:    proto.Bool(true),
+		ID:        proto.String(albumResp.ID),
+	}
+
+	var sendErr error
+	sent := 0
+	replyAttached := false
+	for i, media := range prepared {
+		if media.err != nil {
+			continue
+		}
+
+		contextInfo := &waE2E.ContextInfo{}
+		if request.BaseRequest.IsForwarded {
+			contextInfo.IsForwarded = proto.Bool(true)
+			contextInfo.ForwardingScore = proto.Uint32(100)
+		}
+		if request.BaseRequest.Duration != nil && *request.BaseRequest.Duration > 0 {
+			contextInfo.Expiration = proto.Uint32(uint32(*request.BaseRequest.Duration))
+		}
+		// Only the first item quotes the replied message, like the official clients do
+		if !replyAttached {
+			contextInfo = service.withReplyContext(ctx, contextInfo, request.BaseRequest.ReplyMessageID)
+			replyAttached = true
+		}
+
+		if media.message.ImageMessage != nil {
+			media.message.ImageMessage.ContextInfo = contextInfo
+		} else {
+			media.message.VideoMessage.ContextInfo = contextInfo
+		}
+		media.message.MessageContextInfo = &waE2E.MessageContextInfo{
+			MessageAssociation: &waE2E.MessageAssociation{
+				AssociationType:  waE2E.MessageAssociation_MEDIA_ALBUM.Enum(),
+				ParentMessageKey: parentKey,
+			},
+		}
+
+		ts, err := send(ctx, recipient, media.message, media.content)
+		if err != nil {
+			response.Items[i].Error = err.Error()
+			sendErr = err
+			continue
+		}
+		response.Items[i].MessageID = ts.ID
+		sent++
+	}
+
+	if sent == 0 {
+		return response, fmt.Errorf("failed to send album items: %w", sendErr)
+	}
+	if sent < len(prepared) {
+		// Items already went out, an error would release the idempotency key and resend them on retry
+		response.Partial = true
+		response.Status = fmt.Sprintf("Album partially sent to %s (%d of %d items delivered)", request.BaseRequest.Phone, sent, len(prepared))
+		return response, nil
+	}
+	response.Status = fmt.Sprintf("Album sent to %s (%d of %d items delivered)", request.BaseRequest.Phone, sent, len(prepared))
+	return response, nil
+}
+
+// prepareAlbumItem loads, processes and uploads a single album item. Images go through the image
+// pipeline, videos are staged in a file for ffmpeg and the upload.
+func (service serviceSend) prepareAlbumItem(ctx context.Context, item domainSend.AlbumItem, compress bool, recipient types.JID) (media albumMedia) {
+	release, err := service.acquireMediaSlot(ctx)
+	if err != nil {
+		media.err = err
+		return media
+	}
+	defer release()
+
+	if item.File != nil {
+		media.mediaType = "image"
+		if strings.HasPrefix(item.File.Header.Get("Content-Type"), "video/") {
+			media.mediaType = "video"
+		}
+	} else {
+		media.mediaType = utils.AlbumMediaTypeFromURL(*item.URL)
+	}
+
+	if media.mediaType == "video" {
+		var videoPath string
+		if item.File != nil {
+			videoPath = sendItemPath(fiberUtils.UUIDv4() + ".video")
+			media.err = fasthttp.SaveMultipartFile(item.File, videoPath)
+		} else if videoPath, media.err = downloadMediaToFile(ctx, "video", *item.URL, utils.DownloadVideoToFile); media.err != nil {
+			media.err = fmt.Errorf("failed to download video from URL: %w", media.err)
+		}
+		if videoPath != "" {
+			defer func() { go utils.RemoveFile(1, videoPath) }()
+		}
+		if media.err != nil {
+			return media
+		}
+
+		media.message, media.err = service.buildAlbumVideo(ctx, videoPath, item.Caption, compress, recipient)
+		media.content = "🎥 Video"
+		if item.Caption != "" {
+			media.content = "🎥 " + item.Caption
+		}
+	} else {
+		var source io.ReadSeeker
+		if item.File != nil {
+			file, err := item.File.Open()
+			if err != nil {
+				media.err = fmt.Errorf("failed to open image: %w", err)
+				return media
+			}
+			defer file.Close()
+			source = file
+		} else {
+			data, _, err := downloadMedia(ctx, "image", *item.URL, utils.DownloadImageFromURL)
+			if err != nil {
+				media.err = fmt.Errorf("failed to download image from URL: %w", err)
+				return media
+			}
+			source = bytes.NewReader(data)
+		}
+
+		media.message, media.err = service.buildAlbumImage(ctx, source, item.Caption, compress, recipient)
+		media.content = "🖼️ Image"
+		if item.Caption != "" {
+			media.content = "🖼️ " + item.Caption
+		}
+	}
+	return media
+}
+
+func (service serviceSend) buildAlbumImage(ctx context.Context, source io.ReadSeeker, caption string, compress bool, recipient types.JID) (*waE2E.Message, error) {
+	prepared, err := prepareImage(ctx, source, utils.SendImageOptions(compress))
+	if err != nil {
+		return nil, fmt.Errorf("failed to process image: %w", err)
+	}
+
+	uploaded, err := service.uploadMedia(ctx, whatsmeow.MediaImage, bytes.NewReader(prepared.JPEG), recipient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload image: %w", err)
+	}
+
+	return &waE2E.Message{ImageMessage: &waE2E.ImageMessage{
+		JPEGThumbnail: prepared.Thumbnail,
+		Caption:       proto.String(caption),
+		URL:           proto.String(uploaded.URL),
+		DirectPath:    proto.String(uploaded.DirectPath),
+		MediaKey:      uploaded.MediaKey,
+		Mimetype:      proto.String("image/jpeg"),
+		FileEncSHA256: uploaded.FileEncSHA256,
+		FileSHA256:    uploaded.FileSHA256,
+		FileLength:    proto.Uint64(uploaded.FileLength),
+		Width:         proto.Uint32(uint32(prepared.Width)),
+		Height:        proto.Uint32(uint32(prepared.Height)),
+	}}, nil
+}
+
+func (service serviceSend) buildAlbumVideo(ctx context.Context, videoPath string, caption string, compress bool, recipient types.JID) (*waE2E.Message, error) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return nil, errors.New("ffmpeg not installed")
+	}
+
+	generateUUID := fiberUtils.UUIDv4()
+	framePath := sendItemPath(generateUUID + ".png")
+	deletedItems := []string{framePath}
+	defer func() {
+		go utils.RemoveFile(1, deletedItems...)
+	}()
+
+	if err := extractVideoFrame(ctx, videoPath, framePath); err != nil {
+		return nil, fmt.Errorf("failed to create thumbnail: %w", err)
+	}
+	frame, err := os.ReadFile(framePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read thumbnail: %w", err)
+	}
+	thumbnail, err := utils.GenerateJPEGThumbnail(frame, utils.ThumbnailDimension)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create thumbnail: %w", err)
+	}
+
+	if compress {
+		compressedPath := sendItemPath(generateUUID + ".mp4")
+		deletedItems = append(deletedItems, compressedPath)
+		if err := compressVideo(ctx, videoPath, compressedPath); err != nil {
+			return nil, fmt.Errorf("failed to compress video: %w", err)
+		}
+		videoPath = compressedPath
+	}
+
+	video, err := os.Open(videoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer video.Close()
+	mimeType, err := detectContentType(video)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read video: %w", err)
+	}
+	uploaded, err := service.uploadMedia(ctx, whatsmeow.MediaVideo, video, recipient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload video: %w", err)
+	}
+
+	return &waE2E.Message{VideoMessage: &waE2E.VideoMessage{
+		URL:           proto.String(uploaded.URL),
+		Mimetype:      proto.String(mimeType),
+		Caption:       proto.String(caption),
+		FileLength:    proto.Uint64(uploaded.FileLength),
+		FileSHA256:    uploaded.FileSHA256,
+		FileEncSHA256: uploaded.FileEncSHA256,
+		MediaKey:      uploaded.MediaKey,
+		DirectPath:    proto.String(uploaded.DirectPath),
+		JPEGThumbnail: thumbnail,
+	}}, nil
+}
+
 func (service serviceSend) SendPoll(ctx context.Context, request domainSend.PollRequest) (response domainSend.GenericResponse, err error) {
 	err = validations.ValidateSendPoll(ctx, request)
 	if err != nil {
 		return response, err
 	}
-	dataWaRecipient, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.BaseRequest.Phone)
+	dataWaRecipient, err := service.validateRecipient(ctx, request.BaseRequest.Phone)
 	if err != nil {
 		return response, err
 	}
@@ -823,6 +1155,8 @@ func (service serviceSend) SendPoll(ctx context.Context, request domainSend.Poll
 		msg.PollCreationMessage.ContextInfo.Expiration = proto.Uint32(uint32(*request.BaseRequest.Duration))
 	}
 
+	msg.PollCreationMessage.ContextInfo = service.withReplyContext(ctx, msg.PollCreationMessage.ContextInfo, request.BaseRequest.ReplyMessageID)
+
 	ts, err := service.wrapSendMessage(ctx, dataWaRecipient, msg, content)
 	if err != nil {
 		return response, err
@@ -855,7 +1189,7 @@ func (service serviceSend) SendChatPresence(ctx context.Context, request domainS
 		return response, err
 	}
 
-	userJid, err := utils.ValidateJidWithLogin(whatsapp.GetClient(), request.Phone)
+	userJid, err := service.validateRecipient(ctx, request.Phone)
 	if err != nil {
 		return response, err
 	}
@@ -887,6 +1221,46 @@ func (service serviceSend) SendChatPresence(ctx context.Context, request domainS
 	return response, nil
 }
 
+// extractVideoFrame renders the frame at the first second of the video as an image
+func extractVideoFrame(ctx context.Context, videoPath, outputPath string) (err error) {
+	_, span := tracing.Start(ctx, "ffmpeg.extract_frame")
+	defer func() { tracing.End(span, err) }()
+
+	return exec.Command("ffmpeg", "-i", videoPath, "-ss", "00:00:01.000", "-vframes", "1", outputPath).Run()
+}
+
+// compressVideo re-encodes the video with settings that keep it small enough for WhatsApp
+func compressVideo(ctx context.Context, inputPath, outputPath string) (err error) {
+	_, span := tracing.Start(ctx, "ffmpeg.compress_video")
+	defer func() { tracing.End(span, err) }()
+
+	// Use proper compression settings to reduce file size
+	// -crf 28: Constant Rate Factor (18-28 is good range, higher = smaller file)
+	// -preset medium: Balance between encoding speed and compression efficiency
+	// -c:v libx264: Use H.264 codec for video
+	// -c:a aac: Use AAC codec for audio
+	// -movflags +faststart: Optimize for web streaming
+	// -vf scale=720:-2: Scale video to max width 720px, maintain aspect ratio
+	cmdCompress := exec.Command("ffmpeg", "-i", inputPath,
+		"-c:v", "libx264",
+		"-crf", "28",
+		"-preset", "fast",
+		"-vf", "scale=720:-2",
+		"-c:a", "aac",
+		"-b:a", "128k",
+		"-movflags", "+faststart",
+		"-y", // Overwrite output file if it exists
+		outputPath)
+
+	// Capture both stdout and stderr for better error reporting
+	output, err := cmdCompress.CombinedOutput()
+	if err != nil {
+		logging.FromContext(ctx).Errorf("ffmpeg compression failed: %v, output: %s", err, string(output))
+		return err
+	}
+	return nil
+}
+
 func (service serviceSend) getMentionFromText(_ context.Context, messages string) (result []string) {
 	mentions := utils.ContainsMention(messages)
 	for _, mention := range mentions {
@@ -898,13 +1272,188 @@ func (service serviceSend) getMentionFromText(_ context.Context, messages string
 	return result
 }
 
-func (service serviceSend) uploadMedia(ctx context.Context, mediaType whatsmeow.MediaType, media []byte, recipient types.JID) (uploaded whatsmeow.UploadResponse, err error) {
+// withReplyContext attaches the quoted message referenced by replyMessageID to ctxInfo.
+// The original ctxInfo is returned untouched when no reply is requested or the message is unknown.
+func (service serviceSend) withReplyContext(ctx context.Context, ctxInfo *waE2E.ContextInfo, replyMessageID *string) *waE2E.ContextInfo {
+	if replyMessageID == nil || *replyMessageID == "" {
+		return ctxInfo
+	}
+
+	message, err := service.chatStorageRepo.GetMessageByID(*replyMessageID)
+	if err != nil {
+		logging.FromContext(ctx).Warnf("Error retrieving reply message ID %s: %v, continuing without reply context", *replyMessageID, err)
+		return ctxInfo
+	}
+	if message == nil {
+		logging.FromContext(ctx).Warnf("Reply message ID %s not found in storage, continuing without reply context", *replyMessageID)
+		return ctxInfo
+	}
+
+	if ctxInfo == nil {
+		ctxInfo = &waE2E.ContextInfo{}
+	}
+
+	// Use the sender JID from storage as-is. Modern storage should already provide
+	// fully-qualified JIDs (e.g., user@s.whatsapp.net or group@g.us). Avoid mutating
+	// the JID here to prevent corrupting valid group or special JIDs.
+	ctxInfo.StanzaID = proto.String(message.ID)
+	ctxInfo.Participant = proto.String(message.Sender)
+	ctxInfo.QuotedMessage = service.buildQuotedMessage(ctx, message)
+
+	return ctxInfo
+}
+
+// buildQuotedMessage rebuilds the quoted message from its stored media type so that
+// clients render a proper preview (thumbnail, file name, ...) instead of plain text.
+func (service serviceSend) buildQuotedMessage(ctx context.Context, message *domainChatStorage.Message) *waE2E.Message {
+	switch message.MediaType {
+	case "image":
+		imageMessage := &waE2E.ImageMessage{
+			URL:           proto.String(message.URL),
+			MediaKey:      message.MediaKey,
+			FileSHA256:    message.FileSHA256,
+			FileEncSHA256: message.FileEncSHA256,
+			FileLength:    proto.Uint64(message.FileLength),
+			Caption:       proto.String(message.Content),
+		}
+		imageMessage.JPEGThumbnail = message.Thumbnail
+		if len(imageMessage.JPEGThumbnail) == 0 {
+			imageMessage.JPEGThumbnail = service.getQuotedImageThumbnail(ctx, imageMessage)
+		}
+		return &waE2E.Message{ImageMessage: imageMessage}
+	case "video":
+		return &waE2E.Message{VideoMessage: &waE2E.VideoMessage{
+			URL:           proto.String(message.URL),
+			MediaKey:      message.MediaKey,
+			FileSHA256:    message.FileSHA256,
+			FileEncSHA256: message.FileEncSHA256,
+			FileLength:    proto.Uint64(message.FileLength),
+			Caption:       proto.String(message.Content),
+		}}
+	case "audio":
+		return &waE2E.Message{AudioMessage: &waE2E.AudioMessage{
+			URL:           proto.String(message.URL),
+			MediaKey:      message.MediaKey,
+			FileSHA256:    message.FileSHA256,
+			FileEncSHA256: message.FileEncSHA256,
+			FileLength:    proto.Uint64(message.FileLength),
+		}}
+	case "document":
+		return &waE2E.Message{DocumentMessage: &waE2E.DocumentMessage{
+			URL:           proto.String(message.URL),
+			MediaKey:      message.MediaKey,
+			FileSHA256:    message.FileSHA256,
+			FileEncSHA256: message.FileEncSHA256,
+			FileLength:    proto.Uint64(message.FileLength),
+			FileName:      proto.String(message.Filename),
+			Title:         proto.String(message.Filename),
+			Caption:       proto.String(message.Content),
+		}}
+	case "sticker":
+		return &waE2E.Message{StickerMessage: &waE2E.StickerMessage{
+			URL:           proto.String(message.URL),
+			MediaKey:      message.MediaKey,
+			FileSHA256:    message.FileSHA256,
+			FileEncSHA256: message.FileEncSHA256,
+			FileLength:    proto.Uint64(message.FileLength),
+		}}
+	default:
+		return &waE2E.Message{Conversation: proto.String(message.Content)}
+	}
+}
+
+// getQuotedImageThumbnail downloads the quoted image and renders a small JPEG preview, for images
+// stored without their embedded thumbnail. Images over quotedImageMaxDownload are not downloaded.
+// Failures are not fatal: the quote is still sent, just without a thumbnail.
+func (service serviceSend) getQuotedImageThumbnail(ctx context.Context, imageMessage *waE2E.ImageMessage) []byte {
+	if imageMessage.GetURL() == "" || len(imageMessage.GetMediaKey()) == 0 {
+		return nil
+	}
+	if imageMessage.GetFileLength() == 0 || imageMessage.GetFileLength() > quotedImageMaxDownload {
+		return nil
+	}
+
+	imageData, err := whatsapp.GetClient().Download(ctx, imageMessage)
+	if err != nil {
+		logging.FromContext(ctx).Debugf("Failed to download quoted image: %v, continuing without thumbnail", err)
+		return nil
+	}
+
+	thumbnail, err := utils.GenerateJPEGThumbnail(imageData, utils.ThumbnailDimension)
+	if err != nil {
+		logging.FromContext(ctx).Debugf("Failed to generate quoted image thumbnail: %v, continuing without thumbnail", err)
+		return nil
+	}
+	return thumbnail
+}
+
+// uploadMedia uploads media to the WhatsApp servers, or reuses an earlier upload of the same content
+// while its URL stays valid for at least mediaUploadMargin. The media is streamed from its start, it is
+// read once for the hash and once more for the upload, which encrypts it through a temporary file.
+func (service serviceSend) uploadMedia(ctx context.Context, mediaType whatsmeow.MediaType, media io.ReadSeeker, recipient types.JID) (uploaded whatsmeow.UploadResponse, err error) {
+	ctx, span := tracing.Start(ctx, "whatsapp.upload",
+		attribute.String("media.type", string(mediaType)),
+	)
+	defer func() {
+		span.SetAttributes(attribute.Int64("media.size", int64(uploaded.FileLength)))
+		tracing.End(span, err)
+	}()
+
+	if _, err = media.Seek(0, io.SeekStart); err != nil {
+		return uploaded, fmt.Errorf("failed to read media: %w", err)
+	}
+
+	// Newsletter media is not encrypted and is uploaded with every post
 	if recipient.Server == types.NewsletterServer {
-		uploaded, err = whatsapp.GetClient().UploadNewsletter(ctx, media, mediaType)
-	} else {
-		uploaded, err = whatsapp.GetClient().Upload(ctx, media, mediaType)
+		uploaded, err = whatsapp.GetClient().UploadNewsletterReader(ctx, media, mediaType)
+		return uploaded, err
+	}
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, media); err != nil {
+		return uploaded, fmt.Errorf("failed to read media: %w", err)
+	}
+	if _, err = media.Seek(0, io.SeekStart); err != nil {
+		return uploaded, fmt.Errorf("failed to read media: %w", err)
+	}
+	sum := hash.Sum(nil)
+	sha := hex.EncodeToString(sum)
+	if upload, err := service.chatStorageRepo.GetMediaUpload(sha, string(mediaType), time.Now().Add(mediaUploadMargin)); err != nil {
+		logging.FromContext(ctx).WithError(err).Warn("Failed to look up earlier media upload")
+	} else if upload != nil {
+		span.SetAttributes(attribute.Bool("media.reused", true))
+		return whatsmeow.UploadResponse{
+			URL:           upload.URL,
+			DirectPath:    upload.DirectPath,
+			MediaKey:      upload.MediaKey,
+			FileEncSHA256: upload.FileEncSHA256,
+			FileSHA256:    sum,
+			FileLength:    upload.FileLength,
+		}, nil
+	}
+
+	uploaded, err = whatsapp.GetClient().UploadReader(ctx, media, nil, mediaType)
+	if err != nil {
+		return uploaded, err
+	}
+
+	// Only uploads whose URL tells when it expires can be reused safely
+	if expiresAt, ok := utils.MediaURLExpiry(uploaded.URL); ok {
+		err := service.chatStorageRepo.StoreMediaUpload(&domainChatStorage.MediaUpload{
+			SHA256:        sha,
+			MediaType:     string(mediaType),
+			URL:           uploaded.URL,
+			DirectPath:    uploaded.DirectPath,
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileLength:    uploaded.FileLength,
+			ExpiresAt:     expiresAt,
+		})
+		if err != nil {
+			logging.FromContext(ctx).WithError(err).Warn("Failed to record media upload")
+		}
 	}
-	return uploaded, err
+	return uploaded, nil
 }
 
 func (service serviceSend) getDefaultEphemeralExpiration(jid string) (expiration uint32) {
@@ -0,0 +1,194 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	domainSend "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/send"
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/logging"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/validations"
+)
+
+// serviceSendIdempotent replays the stored response of a send request when it is retried with the
+// same idempotency key, instead of sending the message again
+type serviceSendIdempotent struct {
+	next            domainSend.ISendUsecase
+	chatStorageRepo domainChatStorage.IChatStorageRepository
+}
+
+func NewIdempotentSendService(next domainSend.ISendUsecase, chatStorageRepo domainChatStorage.IChatStorageRepository) domainSend.ISendUsecase {
+	return &serviceSendIdempotent{
+		next:            next,
+		chatStorageRepo: chatStorageRepo,
+	}
+}
+
+func (service serviceSendIdempotent) SendText(ctx context.Context, request domainSend.MessageRequest) (domainSend.GenericResponse, error) {
+	return runIdempotent(ctx, service.chatStorageRepo, "send_text", request, nil, service.next.SendText)
+}
+
+func (service serviceSendIdempotent) SendImage(ctx context.Context, request domainSend.ImageRequest) (domainSend.GenericResponse, error) {
+	return runIdempotent(ctx, service.chatStorageRepo, "send_image", request, []*multipart.FileHeader{request.Image}, service.next.SendImage)
+}
+
+func (service serviceSendIdempotent) SendFile(ctx context.Context, request domainSend.FileRequest) (domainSend.GenericResponse, error) {
+	return runIdempotent(ctx, service.chatStorageRepo, "send_file", request, []*multipart.FileHeader{request.File}, service.next.SendFile)
+}
+
+func (service serviceSendIdempotent) SendVideo(ctx context.Context, request domainSend.VideoRequest) (domainSend.GenericResponse, error) {
+	return runIdempotent(ctx, service.chatStorageRepo, "send_video", request, []*multipart.FileHeader{request.Video}, service.next.SendVideo)
+}
+
+func (service serviceSendIdempotent) SendAudio(ctx context.Context, request domainSend.AudioRequest) (domainSend.GenericResponse, error) {
+	return runIdempotent(ctx, service.chatStorageRepo, "send_audio", request, []*multipart.FileHeader{request.Audio}, service.next.SendAudio)
+}
+
+func (service serviceSendIdempotent) SendAlbum(ctx context.Context, request domainSend.AlbumRequest) (domainSend.AlbumResponse, error) {
+	files := make([]*multipart.FileHeader, 0, len(request.Items))
+	for _, item := range request.Items {
+		files = append(files, item.File)
+	}
+	return runIdempotent(ctx, service.chatStorageRepo, "send_album", request, files, service.next.SendAlbum)
+}
+
+func (service serviceSendIdempotent) SendContact(ctx context.Context, request domainSend.ContactRequest) (domainSend.GenericResponse, error) {
+	return runIdempotent(ctx, service.chatStorageRepo, "send_contact", request, nil, service.next.SendContact)
+}
+
+func (service serviceSendIdempotent) SendLink(ctx context.Context, request domainSend.LinkRequest) (domainSend.GenericResponse, error) {
+	return runIdempotent(ctx, service.chatStorageRepo, "send_link", request, nil, service.next.SendLink)
+}
+
+func (service serviceSendIdempotent) SendLocation(ctx context.Context, request domainSend.LocationRequest) (domainSend.GenericResponse, error) {
+	return runIdempotent(ctx, service.chatStorageRepo, "send_location", request, nil, service.next.SendLocation)
+}
+
+func (service serviceSendIdempotent) SendPoll(ctx context.Context, request domainSend.PollRequest) (domainSend.GenericResponse, error) {
+	return runIdempotent(ctx, service.chatStorageRepo, "send_poll", request, nil, service.next.SendPoll)
+}
+
+func (service serviceSendIdempotent) SendPresence(ctx context.Context, request domainSend.PresenceRequest) (domainSend.GenericResponse, error) {
+	return runIdempotent(ctx, service.chatStorageRepo, "send_presence", request, nil, service.next.SendPresence)
+}
+
+func (service serviceSendIdempotent) SendChatPresence(ctx context.Context, request domainSend.ChatPresenceRequest) (domainSend.GenericResponse, error) {
+	return runIdempotent(ctx, service.chatStorageRepo, "send_chat_presence", request, nil, service.next.SendChatPresence)
+}
+
+// runIdempotent calls send at most once per idempotency key carried by ctx. A retry with the same key
+// and payload gets the stored response back, a different payload or a retry while the first request
+// is still running is rejected with a conflict. Failed sends release the key so they can be retried,
+// the key of a request that never finished is released once it held it for config.AppIdempotencyLease.
+func runIdempotent[Req any, Res any](
+	ctx context.Context,
+	repo domainChatStorage.IChatStorageRepository,
+	operation string,
+	request Req,
+	files []*multipart.FileHeader,
+	send func(context.Context, Req) (Res, error),
+) (response Res, err error) {
+	key := domainSend.IdempotencyKeyFromContext(ctx)
+	if key == "" {
+		return send(ctx, request)
+	}
+	if err = validations.ValidateIdempotencyKey(ctx, key); err != nil {
+		return response, err
+	}
+
+	requestHash, err := hashSendRequest(operation, request, files)
+	if err != nil {
+		return response, err
+	}
+
+	claim := &domainChatStorage.IdempotencyKey{
+		Key:         idempotencyStorageKey(ctx, key),
+		Operation:   operation,
+		RequestHash: requestHash,
+		ExpiresAt:   time.Now().Add(config.AppIdempotencyTTL),
+	}
+	existing, err := repo.ClaimIdempotencyKey(claim, time.Now().Add(-config.AppIdempotencyLease))
+	if err != nil {
+		return response, fmt.Errorf("failed to store idempotency key: %w", err)
+	}
+
+	if existing != nil {
+		if existing.Operation != operation || existing.RequestHash != requestHash {
+			return response, pkgError.IdempotencyConflictError("idempotency key was already used with a different request")
+		}
+		if existing.Status != domainChatStorage.IdempotencyStatusCompleted {
+			return response, pkgError.IdempotencyConflictError("a request with this idempotency key is still being processed")
+		}
+		if err = json.Unmarshal([]byte(existing.Response), &response); err != nil {
+			return response, fmt.Errorf("failed to decode stored response: %w", err)
+		}
+		logging.FromContext(ctx).WithField("operation", operation).Info("Replayed send response for idempotency key")
+		return response, nil
+	}
+
+	response, err = send(ctx, request)
+	if err != nil {
+		if deleteErr := repo.DeleteIdempotencyKey(claim.Key, claim.CreatedAt); deleteErr != nil {
+			logging.FromContext(ctx).Warnf("Failed to release idempotency key: %v", deleteErr)
+		}
+		return response, err
+	}
+
+	stored, marshalErr := json.Marshal(response)
+	if marshalErr == nil {
+		marshalErr = repo.CompleteIdempotencyKey(claim.Key, claim.CreatedAt, string(stored))
+	}
+	if marshalErr != nil {
+		// The message is already sent, so report success and leave the key pending to block resends
+		// until its lease runs out
+		logging.FromContext(ctx).Warnf("Failed to store response for idempotency key: %v", marshalErr)
+	}
+	return response, nil
+}
+
+// idempotencyStorageKey stores the key of a scoped request under its scope. Keys are visible ASCII
+// without spaces, so the space keeps scoped keys apart from each other and from unscoped ones.
+func idempotencyStorageKey(ctx context.Context, key string) string {
+	if scope := domainSend.IdempotencyScopeFromContext(ctx); scope != "" {
+		return scope + " " + key
+	}
+	return key
+}
+
+// hashSendRequest fingerprints the operation, the JSON form of the request and the content of any
+// uploaded files, which are not part of the JSON form
+func hashSendRequest(operation string, request any, files []*multipart.FileHeader) (string, error) {
+	hash := sha256.New()
+	hash.Write([]byte(operation))
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+	hash.Write(payload)
+
+	for _, file := range files {
+		if file == nil {
+			continue
+		}
+		f, err := file.Open()
+		if err != nil {
+			return "", fmt.Errorf("failed to read uploaded file: %w", err)
+		}
+		_, err = io.Copy(hash, f)
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to read uploaded file: %w", err)
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
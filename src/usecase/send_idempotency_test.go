@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	domainSend "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/send"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/infrastructure/chatstorage"
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newChatStorageRepository(t *testing.T) domainChatStorage.IChatStorageRepository {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "chatstorage.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	repo := chatstorage.NewStorageRepository(db)
+	require.NoError(t, repo.InitializeSchema())
+	return repo
+}
+
+// countingSend returns a send function answering with the number of messages it sent
+func countingSend(sent *int) func(context.Context, domainSend.MessageRequest) (domainSend.GenericResponse, error) {
+	return func(context.Context, domainSend.MessageRequest) (domainSend.GenericResponse, error) {
+		*sent++
+		return domainSend.GenericResponse{MessageID: fmt.Sprintf("MSG%d", *sent)}, nil
+	}
+}
+
+func TestRunIdempotentReclaimsAbandonedKeys(t *testing.T) {
+	lease := config.AppIdempotencyLease
+	t.Cleanup(func() { config.AppIdempotencyLease = lease })
+	repo := newChatStorageRepository(t)
+	request := domainSend.MessageRequest{Message: "hello"}
+	hash, err := hashSendRequest("send_text", request, nil)
+	require.NoError(t, err)
+
+	// A request that crashed after claiming the key
+	crashed := &domainChatStorage.IdempotencyKey{Key: "key-1", Operation: "send_text", RequestHash: hash, ExpiresAt: time.Now().Add(time.Hour)}
+	existing, err := repo.ClaimIdempotencyKey(crashed, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Nil(t, existing)
+
+	sent := 0
+	ctx := domainSend.WithIdempotencyKey(context.Background(), "key-1")
+	_, err = runIdempotent(ctx, repo, "send_text", request, nil, countingSend(&sent))
+	assert.ErrorAs(t, err, new(pkgError.IdempotencyConflictError), "the key is held while its lease runs")
+	assert.Zero(t, sent)
+
+	config.AppIdempotencyLease = 0
+	response, err := runIdempotent(ctx, repo, "send_text", request, nil, countingSend(&sent))
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent, "a retry sends once the lease ran out")
+
+	// The crashed request finishing late must not replace the response of the retry
+	require.NoError(t, repo.CompleteIdempotencyKey(crashed.Key, crashed.CreatedAt, `{"message_id":"LATE"}`))
+	require.NoError(t, repo.DeleteIdempotencyKey(crashed.Key, crashed.CreatedAt))
+
+	config.AppIdempotencyLease = lease
+	replayed, err := runIdempotent(ctx, repo, "send_text", request, nil, countingSend(&sent))
+	require.NoError(t, err)
+	assert.Equal(t, response, replayed)
+	assert.Equal(t, 1, sent)
+}
+
+func TestRunIdempotentScopesKeysPerUser(t *testing.T) {
+	repo := newChatStorageRepository(t)
+	request := domainSend.MessageRequest{Message: "hello"}
+	scoped := func(user string) context.Context {
+		ctx := domainSend.WithIdempotencyKey(context.Background(), "key-1")
+		if user != "" {
+			ctx = domainSend.WithIdempotencyScope(ctx, user)
+		}
+		return ctx
+	}
+
+	sent := 0
+	alice, err := runIdempotent(scoped("alice"), repo, "send_text", request, nil, countingSend(&sent))
+	require.NoError(t, err)
+	bob, err := runIdempotent(scoped("bob"), repo, "send_text", domainSend.MessageRequest{Message: "other"}, nil, countingSend(&sent))
+	require.NoError(t, err, "another user's key does not conflict")
+	_, err = runIdempotent(scoped(""), repo, "send_text", request, nil, countingSend(&sent))
+	require.NoError(t, err)
+	assert.Equal(t, 3, sent)
+	assert.NotEqual(t, alice, bob)
+
+	replayed, err := runIdempotent(scoped("alice"), repo, "send_text", request, nil, countingSend(&sent))
+	require.NoError(t, err)
+	assert.Equal(t, alice, replayed)
+	assert.Equal(t, 3, sent)
+}
+
+func TestRunIdempotent(t *testing.T) {
+	request := domainSend.MessageRequest{Message: "hello"}
+	keyed := domainSend.WithIdempotencyKey(context.Background(), "key-1")
+	failing := func(context.Context, domainSend.MessageRequest) (domainSend.GenericResponse, error) {
+		return domainSend.GenericResponse{}, pkgError.WaCliError("not connected")
+	}
+
+	t.Run("should send every request without a key", func(t *testing.T) {
+		repo := newChatStorageRepository(t)
+		sent := 0
+		for range 2 {
+			_, err := runIdempotent(context.Background(), repo, "send_text", request, nil, countingSend(&sent))
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 2, sent)
+	})
+
+	t.Run("should replay the stored response of a retry", func(t *testing.T) {
+		repo := newChatStorageRepository(t)
+		sent := 0
+		first, err := runIdempotent(keyed, repo, "send_text", request, nil, countingSend(&sent))
+		require.NoError(t, err)
+		retried, err := runIdempotent(keyed, repo, "send_text", request, nil, countingSend(&sent))
+		require.NoError(t, err)
+		assert.Equal(t, first, retried)
+		assert.Equal(t, 1, sent)
+	})
+
+	t.Run("should reject a key reused for another request", func(t *testing.T) {
+		repo := newChatStorageRepository(t)
+		sent := 0
+		_, err := runIdempotent(keyed, repo, "send_text", request, nil, countingSend(&sent))
+		require.NoError(t, err)
+		_, err = runIdempotent(keyed, repo, "send_text", domainSend.MessageRequest{Message: "other"}, nil, countingSend(&sent))
+		assert.ErrorAs(t, err, new(pkgError.IdempotencyConflictError))
+		_, err = runIdempotent(keyed, repo, "send_link", request, nil, countingSend(&sent))
+		assert.ErrorAs(t, err, new(pkgError.IdempotencyConflictError))
+		assert.Equal(t, 1, sent)
+	})
+
+	t.Run("should release the key of a failed send", func(t *testing.T) {
+		repo := newChatStorageRepository(t)
+		_, err := runIdempotent(keyed, repo, "send_text", request, nil, failing)
+		assert.ErrorAs(t, err, new(pkgError.WaCliError))
+
+		sent := 0
+		_, err = runIdempotent(keyed, repo, "send_text", request, nil, countingSend(&sent))
+		require.NoError(t, err)
+		assert.Equal(t, 1, sent, "the retry of a failed send is sent")
+	})
+
+	t.Run("should reject an invalid key without sending", func(t *testing.T) {
+		repo := newChatStorageRepository(t)
+		sent := 0
+		ctx := domainSend.WithIdempotencyKey(context.Background(), "job 42")
+		_, err := runIdempotent(ctx, repo, "send_text", request, nil, countingSend(&sent))
+		assert.ErrorAs(t, err, new(pkgError.ValidationError))
+		assert.Zero(t, sent)
+	})
+}
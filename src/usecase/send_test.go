@@ -0,0 +1,201 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	domainSend "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/send"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/protobuf/proto"
+)
+
+// slowRepository delays storing sent messages
+type slowRepository struct {
+	domainChatStorage.IChatStorageRepository
+	delay time.Duration
+}
+
+func (r slowRepository) StoreSentMessageWithContext(ctx context.Context, messageID string, senderJID string, recipientJID string, content string, timestamp time.Time) error {
+	time.Sleep(r.delay)
+	return r.IChatStorageRepository.StoreSentMessageWithContext(ctx, messageID, senderJID, recipientJID, content, timestamp)
+}
+
+func TestWaitForSentMessagesBeforeClose(t *testing.T) {
+	repo := newChatStorageRepository(t)
+	service := serviceSend{chatStorageRepo: slowRepository{IChatStorageRepository: repo, delay: 100 * time.Millisecond}}
+	recipient := types.NewJID("6281234567890", types.DefaultUserServer)
+
+	service.storeSentMessage(context.Background(), whatsmeow.SendResponse{ID: "SENT1", Timestamp: time.Now()}, "6289876543210@s.whatsapp.net", recipient, "hello")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, WaitForSentMessages(ctx))
+
+	// Shutdown closes the database now, the message is already written
+	message, err := repo.GetMessageByID("SENT1")
+	require.NoError(t, err)
+	require.NotNil(t, message)
+	assert.Equal(t, "hello", message.Content)
+}
+
+// recordingSender records the messages it sends and fails the ones fail matches
+type recordingSender struct {
+	sent []*waE2E.Message
+	fail func(*waE2E.Message) bool
+}
+
+func (r *recordingSender) send(_ context.Context, _ types.JID, msg *waE2E.Message, _ string) (whatsmeow.SendResponse, error) {
+	if r.fail != nil && r.fail(msg) {
+		return whatsmeow.SendResponse{}, errors.New("send failed")
+	}
+	r.sent = append(r.sent, msg)
+	return whatsmeow.SendResponse{ID: fmt.Sprintf("MSG%d", len(r.sent))}, nil
+}
+
+func albumItems() []albumMedia {
+	return []albumMedia{
+		{mediaType: "image", message: &waE2E.Message{ImageMessage: &waE2E.ImageMessage{Caption: proto.String("first")}}},
+		{mediaType: "image", err: errors.New("failed to download image from URL")},
+		{mediaType: "video", message: &waE2E.Message{VideoMessage: &waE2E.VideoMessage{Caption: proto.String("third")}}},
+	}
+}
+
+func TestDeliverAlbumReportsPartialFailure(t *testing.T) {
+	service := serviceSend{chatStorageRepo: newChatStorageRepository(t)}
+	recipient := types.NewJID("6281234567890", types.DefaultUserServer)
+	request := domainSend.AlbumRequest{BaseRequest: domainSend.BaseRequest{Phone: recipient.String()}}
+	sender := &recordingSender{fail: func(msg *waE2E.Message) bool { return msg.GetVideoMessage() != nil }}
+
+	response, err := service.deliverAlbum(context.Background(), recipient, request, albumItems(), sender.send)
+	require.NoError(t, err, "delivered items must not be resent by an idempotent retry")
+
+	require.Len(t, sender.sent, 2)
+	album := sender.sent[0].GetAlbumMessage()
+	require.NotNil(t, album, "the album message goes first")
+	assert.Equal(t, uint32(1), album.GetExpectedImageCount())
+	assert.Equal(t, uint32(1), album.GetExpectedVideoCount())
+	parent := sender.sent[1].GetMessageContextInfo().GetMessageAssociation().GetParentMessageKey()
+	assert.Equal(t, "MSG1", parent.GetID(), "items point back to the album")
+	assert.Equal(t, recipient.String(), parent.GetRemoteJID())
+
+	assert.Equal(t, "MSG1", response.AlbumID)
+	assert.True(t, response.Partial)
+	assert.Contains(t, response.Status, "1 of 3 items delivered")
+	assert.Equal(t, []domainSend.AlbumItemResponse{
+		{Index: 0, MediaType: "image", MessageID: "MSG2"},
+		{Index: 1, MediaType: "image", Error: "failed to download image from URL"},
+		{Index: 2, MediaType: "video", Error: "send failed"},
+	}, response.Items)
+}
+
+func TestDeliverAlbumFailures(t *testing.T) {
+	recipient := types.NewJID("6281234567890", types.DefaultUserServer)
+	request := domainSend.AlbumRequest{BaseRequest: domainSend.BaseRequest{Phone: recipient.String()}}
+
+	tests := []struct {
+		name     string
+		fail     func(*waE2E.Message) bool
+		wantSent int
+	}{
+		{
+			name:     "should not send items when the album message fails",
+			fail:     func(msg *waE2E.Message) bool { return msg.GetAlbumMessage() != nil },
+			wantSent: 0,
+		},
+		{
+			name:     "should fail when no item is delivered",
+			fail:     func(msg *waE2E.Message) bool { return msg.GetAlbumMessage() == nil },
+			wantSent: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := serviceSend{chatStorageRepo: newChatStorageRepository(t)}
+			sender := &recordingSender{fail: tt.fail}
+			response, err := service.deliverAlbum(context.Background(), recipient, request, albumItems(), sender.send)
+			assert.ErrorContains(t, err, "send failed")
+			assert.Len(t, sender.sent, tt.wantSent)
+			assert.False(t, response.Partial)
+		})
+	}
+}
+
+// recordSpans installs a tracer provider that keeps ended spans in memory for the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestDownloadMediaSpan(t *testing.T) {
+	tests := []struct {
+		name       string
+		download   func(string) ([]byte, string, error)
+		wantSize   int
+		wantStatus codes.Code
+	}{
+		{
+			name:       "should record the downloaded size",
+			download:   func(string) ([]byte, string, error) { return []byte("jpeg bytes"), "photo.jpg", nil },
+			wantSize:   10,
+			wantStatus: codes.Unset,
+		},
+		{
+			name:       "should record a failed download",
+			download:   func(string) ([]byte, string, error) { return nil, "", errors.New("404 not found") },
+			wantStatus: codes.Error,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := recordSpans(t)
+			_, _, _ = downloadMedia(context.Background(), "image", "https://example.com/photo.jpg", tt.download)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			assert.Equal(t, "send.download_image", spans[0].Name())
+			assert.Equal(t, []attribute.KeyValue{attribute.Int("media.size", tt.wantSize)}, spans[0].Attributes())
+			assert.Equal(t, tt.wantStatus, spans[0].Status().Code)
+		})
+	}
+}
+
+func TestAcquireMediaSlotSpan(t *testing.T) {
+	recorder := recordSpans(t)
+	service := serviceSend{mediaSlots: make(chan struct{}, 1)}
+
+	release, err := service.acquireMediaSlot(context.Background())
+	require.NoError(t, err)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = service.acquireMediaSlot(ctx)
+	require.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	for _, span := range spans {
+		assert.Equal(t, "send.wait_media_slot", span.Name())
+	}
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code, "giving up on a slot is recorded")
+	assert.Contains(t, spans[1].Status().Description, "gave up waiting")
+}
@@ -0,0 +1,38 @@
+package validations
+
+import (
+	"context"
+
+	domainBackup "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/backup"
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// backupPassphraseMinLength keeps passphrases out of reach of a quick dictionary attack on a stolen backup
+const backupPassphraseMinLength = 12
+
+func ValidateBackupPassphrase(ctx context.Context, passphrase string) error {
+	if passphrase == "" {
+		return pkgError.ValidationError("backup passphrase is not configured, set --backup-passphrase or APP_BACKUP_PASSPHRASE")
+	}
+
+	err := validation.ValidateWithContext(ctx, passphrase, validation.RuneLength(backupPassphraseMinLength, 0))
+	if err != nil {
+		return pkgError.ValidationError("backup passphrase: " + err.Error())
+	}
+
+	return nil
+}
+
+func ValidateRestoreBackup(ctx context.Context, request domainBackup.RestoreBackupRequest) error {
+	err := validation.ValidateStructWithContext(ctx, &request,
+		validation.Field(&request.Path, validation.Required),
+		validation.Field(&request.Passphrase, validation.Required),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package validations
+
+import (
+	"context"
+	"testing"
+
+	domainBackup "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/backup"
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateBackupPassphrase(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+		err        any
+	}{
+		{
+			name:       "should success with long passphrase",
+			passphrase: "correct horse battery staple",
+			err:        nil,
+		},
+		{
+			name:       "should error when not configured",
+			passphrase: "",
+			err:        pkgError.ValidationError("backup passphrase is not configured, set --backup-passphrase or APP_BACKUP_PASSPHRASE"),
+		},
+		{
+			name:       "should error with short passphrase",
+			passphrase: "secret",
+			err:        pkgError.ValidationError("backup passphrase: the length must be no less than 12"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBackupPassphrase(context.Background(), tt.passphrase)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestValidateRestoreBackup(t *testing.T) {
+	tests := []struct {
+		name string
+		req  domainBackup.RestoreBackupRequest
+		err  any
+	}{
+		{
+			name: "should success with path and passphrase",
+			req:  domainBackup.RestoreBackupRequest{Path: "backups/whatsapp-backup-20250115-223000.enc", Passphrase: "secret"},
+			err:  nil,
+		},
+		{
+			name: "should error with empty path",
+			req:  domainBackup.RestoreBackupRequest{Passphrase: "secret"},
+			err:  pkgError.ValidationError("path: cannot be blank."),
+		},
+		{
+			name: "should error with empty passphrase",
+			req:  domainBackup.RestoreBackupRequest{Path: "backup.enc", VerifyOnly: true},
+			err:  pkgError.ValidationError("passphrase: cannot be blank."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRestoreBackup(context.Background(), tt.req)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package validations
+
+import (
+	"context"
+
+	domainCall "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/call"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+func ValidateListCalls(ctx context.Context, request *domainCall.ListCallsRequest) error {
+	// Set default limit if not provided
+	if request.Limit == 0 {
+		request.Limit = 25
+	}
+
+	err := validation.ValidateStructWithContext(ctx, request,
+		validation.Field(&request.Limit, validation.Min(1), validation.Max(100)),
+		validation.Field(&request.Offset, validation.Min(0)),
+		validation.Field(&request.Status, validation.In(
+			domainChatStorage.CallStatusOffered,
+			domainChatStorage.CallStatusAccepted,
+			domainChatStorage.CallStatusRejected,
+			domainChatStorage.CallStatusMissed,
+			domainChatStorage.CallStatusEnded,
+		)),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
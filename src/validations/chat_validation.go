@@ -2,8 +2,12 @@ package validations
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	domainChat "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chat"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/chatimport"
 	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 )
@@ -17,12 +21,17 @@ func ValidateListChats(ctx context.Context, request *domainChat.ListChatsRequest
 	err := validation.ValidateStructWithContext(ctx, request,
 		validation.Field(&request.Limit, validation.Min(1), validation.Max(100)),
 		validation.Field(&request.Offset, validation.Min(0)),
+		validation.Field(&request.Cursor, validation.By(validateCursor)),
 	)
 
 	if err != nil {
 		return pkgError.ValidationError(err.Error())
 	}
 
+	if request.Cursor != "" && request.Offset > 0 {
+		return pkgError.ValidationError("cursor and offset cannot be combined")
+	}
+
 	return nil
 }
 
@@ -36,15 +45,34 @@ func ValidateGetChatMessages(ctx context.Context, request *domainChat.GetChatMes
 		validation.Field(&request.ChatJID, validation.Required),
 		validation.Field(&request.Limit, validation.Min(1), validation.Max(100)),
 		validation.Field(&request.Offset, validation.Min(0)),
+		validation.Field(&request.Cursor, validation.By(validateCursor)),
 	)
 
 	if err != nil {
 		return pkgError.ValidationError(err.Error())
 	}
 
+	if request.Cursor != "" && request.Offset > 0 {
+		return pkgError.ValidationError("cursor and offset cannot be combined")
+	}
+
+	if request.Cursor != "" && request.Search != "" {
+		return pkgError.ValidationError("cursor cannot be combined with search")
+	}
+
 	return nil
 }
 
+// validateCursor checks that a pagination cursor is one previously returned by the API
+func validateCursor(value any) error {
+	cursor, _ := value.(string)
+	if cursor == "" {
+		return nil
+	}
+	_, err := domainChatStorage.DecodeCursor(cursor)
+	return err
+}
+
 func ValidatePinChat(ctx context.Context, request *domainChat.PinChatRequest) error {
 	err := validation.ValidateStructWithContext(ctx, request,
 		validation.Field(&request.ChatJID, validation.Required),
@@ -56,3 +84,47 @@ func ValidatePinChat(ctx context.Context, request *domainChat.PinChatRequest) er
 
 	return nil
 }
+
+func ValidateExportChat(ctx context.Context, request *domainChat.ExportChatRequest) error {
+	// Set default format if not provided
+	if request.Format == "" {
+		request.Format = domainChat.ExportFormatJSON
+	}
+
+	err := validation.ValidateStructWithContext(ctx, request,
+		validation.Field(&request.ChatJID, validation.Required),
+		validation.Field(&request.Format, validation.In(domainChat.ExportFormatJSON, domainChat.ExportFormatTXT, domainChat.ExportFormatHTML)),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
+
+func ValidateImportChat(ctx context.Context, request *domainChat.ImportChatRequest) error {
+	err := validation.ValidateStructWithContext(ctx, request,
+		validation.Field(&request.ChatJID, validation.Required),
+		validation.Field(&request.DateOrder, validation.In(string(chatimport.DateOrderDMY), string(chatimport.DateOrderMDY), string(chatimport.DateOrderYMD))),
+		validation.Field(&request.Timezone, validation.By(validateTimezone)),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
+
+// validateTimezone checks that a timezone is a known IANA name such as Asia/Jakarta
+func validateTimezone(value any) error {
+	timezone, _ := value.(string)
+	if timezone == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return errors.New("must be a valid IANA timezone")
+	}
+	return nil
+}
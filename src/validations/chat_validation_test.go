@@ -3,12 +3,16 @@ package validations
 import (
 	"context"
 	"testing"
+	"time"
 
 	domainChat "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chat"
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
 	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
 	"github.com/stretchr/testify/assert"
 )
 
+var testCursor = domainChatStorage.Cursor{Timestamp: time.Unix(1700000000, 0), ID: "6289685028129@s.whatsapp.net"}.Encode()
+
 func TestValidateListChats(t *testing.T) {
 	type args struct {
 		request domainChat.ListChatsRequest
@@ -58,6 +62,31 @@ func TestValidateListChats(t *testing.T) {
 			}},
 			err: pkgError.ValidationError("offset: must be no less than 0."),
 		},
+		{
+			name: "should success with cursor",
+			args: args{request: domainChat.ListChatsRequest{
+				Limit:  25,
+				Cursor: testCursor,
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with malformed cursor",
+			args: args{request: domainChat.ListChatsRequest{
+				Limit:  25,
+				Cursor: "not-a-cursor",
+			}},
+			err: pkgError.ValidationError("cursor: malformed cursor."),
+		},
+		{
+			name: "should error with cursor and offset",
+			args: args{request: domainChat.ListChatsRequest{
+				Limit:  25,
+				Offset: 25,
+				Cursor: testCursor,
+			}},
+			err: pkgError.ValidationError("cursor and offset cannot be combined"),
+		},
 	}
 
 	for _, tt := range tests {
@@ -131,6 +160,34 @@ func TestValidateGetChatMessages(t *testing.T) {
 			}},
 			err: pkgError.ValidationError("offset: must be no less than 0."),
 		},
+		{
+			name: "should success with previous page cursor",
+			args: args{request: domainChat.GetChatMessagesRequest{
+				ChatJID: "6289685028129@s.whatsapp.net",
+				Limit:   50,
+				Cursor:  domainChatStorage.Cursor{Timestamp: time.Unix(1700000000, 0), ID: "3EB0ABC", Previous: true}.Encode(),
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with malformed cursor",
+			args: args{request: domainChat.GetChatMessagesRequest{
+				ChatJID: "6289685028129@s.whatsapp.net",
+				Limit:   50,
+				Cursor:  "eyJ0IjoxfQ",
+			}},
+			err: pkgError.ValidationError("cursor: malformed cursor."),
+		},
+		{
+			name: "should error with cursor and search",
+			args: args{request: domainChat.GetChatMessagesRequest{
+				ChatJID: "6289685028129@s.whatsapp.net",
+				Limit:   50,
+				Cursor:  testCursor,
+				Search:  "hello",
+			}},
+			err: pkgError.ValidationError("cursor cannot be combined with search"),
+		},
 	}
 
 	for _, tt := range tests {
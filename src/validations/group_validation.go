@@ -3,6 +3,7 @@ package validations
 import (
 	"context"
 
+	domainChatStorage "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/chatstorage"
 	domainGroup "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/group"
 	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
@@ -216,3 +217,34 @@ func ValidateGetGroupInviteLink(ctx context.Context, request domainGroup.GetGrou
 
 	return nil
 }
+
+func ValidateGroupEvents(ctx context.Context, request *domainGroup.GroupEventsRequest) error {
+	// Set default limit if not provided
+	if request.Limit == 0 {
+		request.Limit = 25
+	}
+
+	err := validation.ValidateStructWithContext(ctx, request,
+		validation.Field(&request.GroupID, validation.Required),
+		validation.Field(&request.Type, validation.In(
+			domainChatStorage.GroupEventName,
+			domainChatStorage.GroupEventTopic,
+			domainChatStorage.GroupEventLocked,
+			domainChatStorage.GroupEventAnnounce,
+			domainChatStorage.GroupEventEphemeral,
+			domainChatStorage.GroupEventMembershipApproval,
+			domainChatStorage.GroupEventJoin,
+			domainChatStorage.GroupEventLeave,
+			domainChatStorage.GroupEventPromote,
+			domainChatStorage.GroupEventDemote,
+		)),
+		validation.Field(&request.Limit, validation.Min(1), validation.Max(100)),
+		validation.Field(&request.Offset, validation.Min(0)),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
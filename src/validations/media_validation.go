@@ -0,0 +1,24 @@
+package validations
+
+import (
+	"context"
+	"regexp"
+
+	domainMedia "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/media"
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+func ValidateGetMedia(ctx context.Context, request domainMedia.GetMediaRequest) error {
+	err := validation.ValidateStructWithContext(ctx, &request,
+		validation.Field(&request.SHA256, validation.Required, validation.Match(sha256Pattern).Error("must be a lowercase hex SHA-256")),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
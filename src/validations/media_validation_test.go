@@ -0,0 +1,46 @@
+package validations
+
+import (
+	"context"
+	"testing"
+
+	domainMedia "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/media"
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateGetMedia(t *testing.T) {
+	tests := []struct {
+		name    string
+		request domainMedia.GetMediaRequest
+		err     any
+	}{
+		{
+			name:    "should success with sha256",
+			request: domainMedia.GetMediaRequest{SHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+			err:     nil,
+		},
+		{
+			name:    "should error without sha256",
+			request: domainMedia.GetMediaRequest{},
+			err:     pkgError.ValidationError("sha256: cannot be blank."),
+		},
+		{
+			name:    "should error with short sha256",
+			request: domainMedia.GetMediaRequest{SHA256: "e3b0c44298fc1c14"},
+			err:     pkgError.ValidationError("sha256: must be a lowercase hex SHA-256."),
+		},
+		{
+			name:    "should error with path characters",
+			request: domainMedia.GetMediaRequest{SHA256: "../../../../../../../../../../../../../../../../../../etc/passwd00"},
+			err:     pkgError.ValidationError("sha256: must be a lowercase hex SHA-256."),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateGetMedia(context.Background(), tt.request)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package validations
+
+import (
+	"context"
+
+	domainRetention "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/retention"
+	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+func ValidateSetRetentionPolicy(ctx context.Context, request domainRetention.SetPolicyRequest) error {
+	err := validation.ValidateStructWithContext(ctx, &request,
+		validation.Field(&request.ChatJID, validation.Required),
+		validation.Field(&request.MaxAgeDays, validation.Min(0), validation.Max(36500)),
+		validation.Field(&request.MaxMessages, validation.Min(0)),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	return nil
+}
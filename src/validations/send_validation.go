@@ -3,11 +3,14 @@ package validations
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"sort"
+	"strings"
 
 	"github.com/aldinokemal/go-whatsapp-web-multidevice/config"
 	domainSend "github.com/aldinokemal/go-whatsapp-web-multidevice/domains/send"
 	pkgError "github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/error"
+	"github.com/aldinokemal/go-whatsapp-web-multidevice/pkg/utils"
 	"github.com/dustin/go-humanize"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/go-ozzo/ozzo-validation/v4/is"
@@ -204,6 +207,75 @@ func ValidateSendVideo(ctx context.Context, request domainSend.VideoRequest) err
 	return nil
 }
 
+// Albums need at least two items to be grouped by WhatsApp clients
+const (
+	minAlbumItems = 2
+	maxAlbumItems = 30
+)
+
+func ValidateSendAlbum(ctx context.Context, request domainSend.AlbumRequest) error {
+	err := validation.ValidateStructWithContext(ctx, &request,
+		validation.Field(&request.Phone, validation.Required),
+		validation.Field(&request.Items, validation.Required, validation.Length(minAlbumItems, maxAlbumItems)),
+	)
+
+	if err != nil {
+		return pkgError.ValidationError(err.Error())
+	}
+
+	// Custom validation for phone number format
+	if err := validatePhoneNumber(request.Phone); err != nil {
+		return err
+	}
+
+	availableMimes := map[string]bool{
+		"image/jpeg":       true,
+		"image/jpg":        true,
+		"image/png":        true,
+		"image/webp":       true,
+		"video/mp4":        true,
+		"video/x-matroska": true,
+		"video/avi":        true,
+		"video/x-msvideo":  true,
+	}
+
+	for i, item := range request.Items {
+		if item.File == nil && (item.URL == nil || *item.URL == "") {
+			return pkgError.ValidationError(fmt.Sprintf("items[%d]: either file or url must be provided", i))
+		}
+
+		if item.File != nil {
+			contentType := item.File.Header.Get("Content-Type")
+			if !availableMimes[contentType] {
+				return pkgError.ValidationError(fmt.Sprintf("items[%d]: file type is not allowed. please use jpg/jpeg/png/webp/mp4/mkv/avi", i))
+			}
+
+			maxSize := config.WhatsappSettingMaxImageSize
+			if strings.HasPrefix(contentType, "video/") {
+				maxSize = config.WhatsappSettingMaxVideoSize
+			}
+			if item.File.Size > maxSize {
+				return pkgError.ValidationError(fmt.Sprintf("items[%d]: max upload is %s", i, humanize.Bytes(uint64(maxSize))))
+			}
+		}
+
+		if item.URL != nil && *item.URL != "" {
+			if err := validation.Validate(*item.URL, is.URL); err != nil {
+				return pkgError.ValidationError(fmt.Sprintf("items[%d]: url must be a valid URL", i))
+			}
+			if utils.AlbumMediaTypeFromURL(*item.URL) == "" {
+				return pkgError.ValidationError(fmt.Sprintf("items[%d]: url must end in jpg/jpeg/png/webp/mp4/mkv/avi", i))
+			}
+		}
+	}
+
+	if err := validateDuration(request.Duration); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func ValidateSendContact(ctx context.Context, request domainSend.ContactRequest) error {
 	err := validation.ValidateStructWithContext(ctx, &request,
 		validation.Field(&request.Phone, validation.Required),
@@ -424,3 +496,18 @@ func ValidateSendChatPresence(ctx context.Context, request domainSend.ChatPresen
 
 	return nil
 }
+
+// idempotencyKeyPattern allows visible ASCII characters, the same range HTTP header tokens use
+var idempotencyKeyPattern = regexp.MustCompile(`^[\x21-\x7E]+$`)
+
+func ValidateIdempotencyKey(ctx context.Context, key string) error {
+	err := validation.ValidateWithContext(ctx, &key,
+		validation.Required,
+		validation.Length(1, 255),
+		validation.Match(idempotencyKeyPattern).Error("must only contain visible ASCII characters"),
+	)
+	if err != nil {
+		return pkgError.ValidationError(fmt.Sprintf("idempotency_key: %s", err.Error()))
+	}
+	return nil
+}
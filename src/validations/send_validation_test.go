@@ -296,6 +296,128 @@ func TestValidateSendVideo(t *testing.T) {
 	}
 }
 
+func TestValidateSendAlbum(t *testing.T) {
+	image := &multipart.FileHeader{
+		Filename: "sample-image.png",
+		Size:     100,
+		Header:   map[string][]string{"Content-Type": {"image/png"}},
+	}
+	video := &multipart.FileHeader{
+		Filename: "sample-video.mp4",
+		Size:     100,
+		Header:   map[string][]string{"Content-Type": {"video/mp4"}},
+	}
+	document := &multipart.FileHeader{
+		Filename: "sample.pdf",
+		Size:     100,
+		Header:   map[string][]string{"Content-Type": {"application/pdf"}},
+	}
+	webp := &multipart.FileHeader{
+		Filename: "sample-image.webp",
+		Size:     100,
+		Header:   map[string][]string{"Content-Type": {"image/webp"}},
+	}
+	imageURL := "https://example.com/sample.jpg"
+	videoURL := "https://example.com/sample.mp4?token=1"
+	invalidURL := "not-a-url"
+	movURL := "https://example.com/clip.mov"
+	extensionlessURL := "https://example.com/media/123"
+
+	type args struct {
+		request domainSend.AlbumRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		err  any
+	}{
+		{
+			name: "should success with files and urls",
+			args: args{request: domainSend.AlbumRequest{
+				BaseRequest: domainSend.BaseRequest{Phone: "1728937129312@s.whatsapp.net"},
+				Items: []domainSend.AlbumItem{
+					{File: image, Caption: "first"},
+					{File: video},
+					{URL: &imageURL, Caption: "third"},
+					{URL: &videoURL},
+				},
+			}},
+			err: nil,
+		},
+		{
+			name: "should success with webp image",
+			args: args{request: domainSend.AlbumRequest{
+				BaseRequest: domainSend.BaseRequest{Phone: "1728937129312@s.whatsapp.net"},
+				Items:       []domainSend.AlbumItem{{File: webp}, {File: image}},
+			}},
+			err: nil,
+		},
+		{
+			name: "should error with empty phone",
+			args: args{request: domainSend.AlbumRequest{
+				BaseRequest: domainSend.BaseRequest{Phone: ""},
+				Items:       []domainSend.AlbumItem{{File: image}, {File: video}},
+			}},
+			err: pkgError.ValidationError("phone: cannot be blank."),
+		},
+		{
+			name: "should error with single item",
+			args: args{request: domainSend.AlbumRequest{
+				BaseRequest: domainSend.BaseRequest{Phone: "1728937129312@s.whatsapp.net"},
+				Items:       []domainSend.AlbumItem{{File: image}},
+			}},
+			err: pkgError.ValidationError("items: the length must be between 2 and 30."),
+		},
+		{
+			name: "should error with item without file and url",
+			args: args{request: domainSend.AlbumRequest{
+				BaseRequest: domainSend.BaseRequest{Phone: "1728937129312@s.whatsapp.net"},
+				Items:       []domainSend.AlbumItem{{File: image}, {Caption: "empty"}},
+			}},
+			err: pkgError.ValidationError("items[1]: either file or url must be provided"),
+		},
+		{
+			name: "should error with unsupported file type",
+			args: args{request: domainSend.AlbumRequest{
+				BaseRequest: domainSend.BaseRequest{Phone: "1728937129312@s.whatsapp.net"},
+				Items:       []domainSend.AlbumItem{{File: image}, {File: document}},
+			}},
+			err: pkgError.ValidationError("items[1]: file type is not allowed. please use jpg/jpeg/png/webp/mp4/mkv/avi"),
+		},
+		{
+			name: "should error with invalid url",
+			args: args{request: domainSend.AlbumRequest{
+				BaseRequest: domainSend.BaseRequest{Phone: "1728937129312@s.whatsapp.net"},
+				Items:       []domainSend.AlbumItem{{URL: &invalidURL}, {File: image}},
+			}},
+			err: pkgError.ValidationError("items[0]: url must be a valid URL"),
+		},
+		{
+			name: "should error with url of unknown media type",
+			args: args{request: domainSend.AlbumRequest{
+				BaseRequest: domainSend.BaseRequest{Phone: "1728937129312@s.whatsapp.net"},
+				Items:       []domainSend.AlbumItem{{File: image}, {URL: &movURL}},
+			}},
+			err: pkgError.ValidationError("items[1]: url must end in jpg/jpeg/png/webp/mp4/mkv/avi"),
+		},
+		{
+			name: "should error with url without extension",
+			args: args{request: domainSend.AlbumRequest{
+				BaseRequest: domainSend.BaseRequest{Phone: "1728937129312@s.whatsapp.net"},
+				Items:       []domainSend.AlbumItem{{URL: &extensionlessURL}, {File: image}},
+			}},
+			err: pkgError.ValidationError("items[0]: url must end in jpg/jpeg/png/webp/mp4/mkv/avi"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSendAlbum(context.Background(), tt.args.request)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
 func TestValidateSendLink(t *testing.T) {
 	type args struct {
 		request domainSend.LinkRequest